@@ -15,9 +15,9 @@ import (
 	"github.com/Ontology/core/ledger"
 	sig "github.com/Ontology/core/signature"
 	"github.com/Ontology/crypto"
+	"github.com/Ontology/crypto/util"
 	. "github.com/Ontology/errors"
 	"github.com/Ontology/net/protocol"
-	"math/rand"
 	"os"
 	"sort"
 	"strings"
@@ -36,12 +36,14 @@ type Client interface {
 	GetAccount(pubKey *crypto.PubKey) (*Account, error)
 	GetDefaultAccount() (*Account, error)
 	GetBookKeepers() ([]*crypto.PubKey, error)
+	GetProgramHashes() []Uint160
 }
 
 type ClientImpl struct {
 	mu sync.Mutex
 
 	path      string
+	version   int
 	iv        []byte
 	masterKey []byte
 
@@ -51,6 +53,9 @@ type ClientImpl struct {
 	watchOnly     []Uint160
 	currentHeight uint32
 
+	hdSeed     []byte
+	hdAccounts []Uint160
+
 	FileStore
 	isrunning bool
 }
@@ -67,7 +72,12 @@ func Create(path string, passwordKey []byte) *ClientImpl {
 	return cl
 }
 
+// Open loads a wallet, a wallet in the version 1 format is upgraded in place
 func Open(path string, passwordKey []byte) *ClientImpl {
+	return open(path, passwordKey, true)
+}
+
+func open(path string, passwordKey []byte, upgrade bool) *ClientImpl {
 	cl := NewClient(path, passwordKey, false)
 	if cl == nil {
 		log.Error("Alloc new client failure")
@@ -82,6 +92,17 @@ func Open(path string, passwordKey []byte) *ClientImpl {
 	if cl.contracts == nil {
 		log.Error("Load contracts failure")
 	}
+	if err := cl.loadHDAccounts(); err != nil {
+		log.Error("Load HD accounts failure: ", err)
+	}
+	if err := cl.loadWatchOnly(); err != nil {
+		log.Error("Load watch-only addresses failure: ", err)
+	}
+	if upgrade && cl.version == WalletVersion1 {
+		if err := cl.upgrade(passwordKey); err != nil {
+			log.Error("Upgrade wallet failure: ", err)
+		}
+	}
 	return cl
 }
 
@@ -94,63 +115,93 @@ func NewClient(path string, password []byte, create bool) *ClientImpl {
 		isrunning: true,
 	}
 
-	passwordKey := crypto.ToAesKey(password)
 	if create {
 		//create new client
-		newClient.iv = make([]byte, 16)
-		newClient.masterKey = make([]byte, 32)
+		newClient.version = WalletVersion
 		newClient.watchOnly = []Uint160{}
 		newClient.currentHeight = 0
 
-		//generate random number for iv/masterkey
-		r := rand.New(rand.NewSource(time.Now().UnixNano()))
-		for i := 0; i < 16; i++ {
-			newClient.iv[i] = byte(r.Intn(256))
-		}
-		for i := 0; i < 32; i++ {
-			newClient.masterKey[i] = byte(r.Intn(256))
-		}
-
-		//new client store (build DB)
-		newClient.BuildDatabase(path)
-
-		// SaveStoredData
-		pwdhash := sha256.Sum256(passwordKey)
-		err := newClient.SaveStoredData("PasswordHash", pwdhash[:])
-		if err != nil {
-			log.Error(err)
-			return nil
-		}
-		err = newClient.SaveStoredData("IV", newClient.iv[:])
+		//generate random number for masterkey
+		masterKey, err := util.RandomNum(32)
 		if err != nil {
 			log.Error(err)
 			return nil
 		}
+		newClient.masterKey = masterKey
 
-		aesmk, err := crypto.AesEncrypt(newClient.masterKey[:], passwordKey, newClient.iv)
-		if err == nil {
-			err = newClient.SaveStoredData("MasterKey", aesmk)
-			if err != nil {
-				log.Error(err)
-				return nil
-			}
-		} else {
+		//new client store (build DB)
+		newClient.BuildDatabase(path)
+
+		if err := newClient.saveMasterKey(password); err != nil {
 			log.Error(err)
 			return nil
 		}
 	} else {
-		if b := newClient.verifyPasswordKey(passwordKey); b == false {
-			return nil
-		}
-		if err := newClient.loadClient(passwordKey); err != nil {
+		if err := newClient.loadClient(password); err != nil {
 			return nil
 		}
 	}
-	ClearBytes(passwordKey, len(passwordKey))
 	return newClient
 }
 
-func (cl *ClientImpl) loadClient(passwordKey []byte) error {
+// saveMasterKey seals the master key under a key derived from the password
+// with new scrypt parameters
+func (cl *ClientImpl) saveMasterKey(password []byte) error {
+	params, err := NewScryptParams()
+	if err != nil {
+		return err
+	}
+	passwordKey, err := params.DeriveKey(password)
+	if err != nil {
+		return err
+	}
+	defer ClearBytes(passwordKey, len(passwordKey))
+	encryptedMasterKey, err := crypto.AesGcmEncrypt(cl.masterKey, passwordKey)
+	if err != nil {
+		return err
+	}
+	return cl.SaveMasterKey(encryptedMasterKey, cl.version, params)
+}
+
+func (cl *ClientImpl) loadClient(password []byte) error {
+	var params *ScryptParams
+	var err error
+	cl.version, params, err = cl.LoadWalletFormat()
+	if err != nil {
+		fmt.Println("error: failed to load wallet format")
+		return err
+	}
+	if cl.version == WalletVersion1 {
+		passwordKey := crypto.ToAesKey(password)
+		defer ClearBytes(passwordKey, len(passwordKey))
+		if !cl.verifyPasswordKey(passwordKey) {
+			return errors.New("password verification failed")
+		}
+		return cl.loadClientV1(passwordKey)
+	}
+	if params == nil {
+		fmt.Println("error: failed to load scrypt parameters")
+		return errors.New("missing scrypt parameters")
+	}
+	passwordKey, err := params.DeriveKey(password)
+	if err != nil {
+		return err
+	}
+	defer ClearBytes(passwordKey, len(passwordKey))
+	encryptedMasterKey, err := cl.LoadStoredData("MasterKey")
+	if err != nil {
+		fmt.Println("error: failed to load master key")
+		return err
+	}
+	cl.masterKey, err = crypto.AesGcmDecrypt(encryptedMasterKey, passwordKey)
+	if err != nil {
+		fmt.Println("error: password wrong")
+		return err
+	}
+	return nil
+}
+
+func (cl *ClientImpl) loadClientV1(passwordKey []byte) error {
 	var err error
 	cl.iv, err = cl.LoadStoredData("IV")
 	if err != nil {
@@ -171,6 +222,9 @@ func (cl *ClientImpl) loadClient(passwordKey []byte) error {
 }
 
 func (cl *ClientImpl) GetDefaultAccount() (*Account, error) {
+	if len(cl.hdAccounts) > 0 {
+		return cl.GetAccountByProgramHash(cl.hdAccounts[0]), nil
+	}
 	for programHash, _ := range cl.accounts {
 		return cl.GetAccountByProgramHash(programHash), nil
 	}
@@ -178,6 +232,25 @@ func (cl *ClientImpl) GetDefaultAccount() (*Account, error) {
 	return nil, NewDetailErr(errors.New("Can't load default account."), ErrNoCode, "")
 }
 
+// GetProgramHashes returns the program hashes of the accounts and contracts
+// in the wallet
+func (cl *ClientImpl) GetProgramHashes() []Uint160 {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	programHashes := []Uint160{}
+	for programHash := range cl.accounts {
+		programHashes = append(programHashes, programHash)
+	}
+	for programHash := range cl.contracts {
+		if _, ok := cl.accounts[programHash]; !ok {
+			programHashes = append(programHashes, programHash)
+		}
+	}
+	programHashes = append(programHashes, cl.watchOnly...)
+	return programHashes
+}
+
 func (cl *ClientImpl) GetAccount(pubKey *crypto.PubKey) (*Account, error) {
 	signatureRedeemScript, err := contract.CreateSignatureRedeemScript(pubKey)
 	if err != nil {
@@ -210,14 +283,17 @@ func (cl *ClientImpl) GetContract(programHash Uint160) *ct.Contract {
 
 func (cl *ClientImpl) ChangePassword(oldPassword []byte, newPassword []byte) bool {
 	// check password
-	oldPasswordKey := crypto.ToAesKey(oldPassword)
-	if !cl.verifyPasswordKey(oldPasswordKey) {
+	if err := cl.loadClient(oldPassword); err != nil {
 		fmt.Println("error: password verification failed")
 		return false
 	}
-	if err := cl.loadClient(oldPasswordKey); err != nil {
-		fmt.Println("error: load wallet info failed")
-		return false
+	if cl.version != WalletVersion1 {
+		if err := cl.saveMasterKey(newPassword); err != nil {
+			fmt.Println("error: wallet update failed (encrypted master key)")
+			return false
+		}
+		ClearBytes(cl.masterKey, len(cl.masterKey))
+		return true
 	}
 
 	// encrypt master key with new password
@@ -257,7 +333,12 @@ func (cl *ClientImpl) ContainsAccount(pubKey *crypto.PubKey) bool {
 	}
 }
 
+// CreateAccount adds a random account to the wallet, or the account at the
+// next index of an HD wallet
 func (cl *ClientImpl) CreateAccount() (*Account, error) {
+	if cl.IsHD() {
+		return cl.createHDAccount()
+	}
 	ac, err := NewAccount()
 	if err != nil {
 		return nil, err
@@ -343,6 +424,18 @@ func (cl *ClientImpl) Sign(context *ct.ContractContext) bool {
 		if contract == nil {
 			continue
 		}
+		if contract.GetType() == ct.MultiSigContract {
+			for _, account := range cl.multiSigSigners(contract) {
+				signature, err := sig.SignBySigner(context.Data, account)
+				if err != nil {
+					return fSuccess
+				}
+				if err := context.AddContract(contract, account.PublicKey, signature); err == nil {
+					fSuccess = true
+				}
+			}
+			continue
+		}
 		account := cl.GetAccountByProgramHash(hash)
 		if account == nil {
 			continue
@@ -384,8 +477,25 @@ func (cl *ClientImpl) verifyPasswordKey(passwordKey []byte) bool {
 	return true
 }
 
+// encryptData encrypts wallet secrets under the master key, version 1
+// wallets share the wallet IV while the later ones seal each secret under its
+// own nonce
+func (cl *ClientImpl) encryptData(data []byte) ([]byte, error) {
+	if cl.version == WalletVersion1 {
+		return crypto.AesEncrypt(data, cl.masterKey, cl.iv)
+	}
+	return crypto.AesGcmEncrypt(data, cl.masterKey)
+}
+
+func (cl *ClientImpl) decryptData(data []byte) ([]byte, error) {
+	if cl.version == WalletVersion1 {
+		return crypto.AesDecrypt(data, cl.masterKey, cl.iv)
+	}
+	return crypto.AesGcmDecrypt(data, cl.masterKey)
+}
+
 func (cl *ClientImpl) EncryptPrivateKey(prikey []byte) ([]byte, error) {
-	enc, err := crypto.AesEncrypt(prikey, cl.masterKey, cl.iv)
+	enc, err := cl.encryptData(prikey)
 	if err != nil {
 		return nil, err
 	}
@@ -397,14 +507,17 @@ func (cl *ClientImpl) DecryptPrivateKey(prikey []byte) ([]byte, error) {
 	if prikey == nil {
 		return nil, NewDetailErr(errors.New("The PriKey is nil"), ErrNoCode, "")
 	}
-	if len(prikey) != 96 {
+	if cl.version == WalletVersion1 && len(prikey) != 96 {
 		return nil, NewDetailErr(errors.New("The len of PriKeyEnc is not 96bytes"), ErrNoCode, "")
 	}
 
-	dec, err := crypto.AesDecrypt(prikey, cl.masterKey, cl.iv)
+	dec, err := cl.decryptData(prikey)
 	if err != nil {
 		return nil, err
 	}
+	if len(dec) != 96 {
+		return nil, NewDetailErr(errors.New("The len of PriKey is not 96bytes"), ErrNoCode, "")
+	}
 
 	return dec, nil
 }
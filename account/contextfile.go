@@ -0,0 +1,246 @@
+package account
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"sort"
+	"strconv"
+
+	. "github.com/Ontology/common"
+	ct "github.com/Ontology/core/contract"
+	sig "github.com/Ontology/core/signature"
+	"github.com/Ontology/core/transaction"
+	"github.com/Ontology/core/transaction/utxo"
+)
+
+// ContextItem is the code and the signatures collected for one program hash
+// of a transaction
+type ContextItem struct {
+	ProgramHash string
+	Code        string
+	Parameters  []string
+	Signatures  []ct.PubkeyParameter
+}
+
+// UTXOReference is the output an input of a transaction spends, it lets an
+// offline signer find the signers and review the amounts without a ledger.
+// Transaction is the serialized transaction the input refers to, the other
+// fields repeat its output for reading the file.
+type UTXOReference struct {
+	ReferTxID          string
+	ReferTxOutputIndex uint16
+	AssetID            string
+	Value              string
+	ProgramHash        string
+	Transaction        string
+}
+
+// NewUTXOReference returns the reference of an output of a transaction
+func NewUTXOReference(txn *transaction.Transaction, index uint16) (*UTXOReference, error) {
+	if int(index) >= len(txn.Outputs) {
+		return nil, errors.New("[NewUTXOReference] transaction has no such output")
+	}
+	var buffer bytes.Buffer
+	if err := txn.Serialize(&buffer); err != nil {
+		return nil, err
+	}
+	hash := txn.Hash()
+	output := txn.Outputs[index]
+	return &UTXOReference{
+		ReferTxID:          ToHexString(hash.ToArray()),
+		ReferTxOutputIndex: index,
+		AssetID:            ToHexString(output.AssetID.ToArray()),
+		Value:              strconv.FormatInt(int64(output.Value), 10),
+		ProgramHash:        ToHexString(output.ProgramHash.ToArray()),
+		Transaction:        ToHexString(buffer.Bytes()),
+	}, nil
+}
+
+// Output returns the referenced output read from the referenced transaction,
+// once the hash of the transaction is checked against ReferTxID
+func (reference *UTXOReference) Output() (*utxo.TxOutput, error) {
+	raw, err := HexToBytes(reference.Transaction)
+	if err != nil {
+		return nil, err
+	}
+	txn := new(transaction.Transaction)
+	if err := txn.Deserialize(bytes.NewReader(raw)); err != nil {
+		return nil, err
+	}
+	hash := txn.Hash()
+	if ToHexString(hash.ToArray()) != reference.ReferTxID {
+		return nil, errors.New("[Output] referenced transaction does not match its hash")
+	}
+	if int(reference.ReferTxOutputIndex) >= len(txn.Outputs) {
+		return nil, errors.New("[Output] referenced transaction has no such output")
+	}
+	return txn.Outputs[reference.ReferTxOutputIndex], nil
+}
+
+// ContextFile is the file a partially signed transaction is passed between
+// co-signers in
+type ContextFile struct {
+	Transaction string
+	References  []*UTXOReference `json:",omitempty"`
+	Items       []ContextItem
+}
+
+// NewTransferContext returns the context of an unsigned transfer whose
+// signers are the owners of the outputs its inputs spend
+func NewTransferContext(txn *transaction.Transaction, references []*UTXOReference) (*ct.ContractContext, error) {
+	if txn.TxType != transaction.TransferAsset {
+		return nil, errors.New("[NewTransferContext] transaction is not a transfer")
+	}
+	if err := checkReferences(txn, references); err != nil {
+		return nil, err
+	}
+	uniq := make(map[Uint160]bool)
+	var programHashes []Uint160
+	add := func(programHash Uint160) {
+		if !uniq[programHash] {
+			uniq[programHash] = true
+			programHashes = append(programHashes, programHash)
+		}
+	}
+	for _, reference := range references {
+		output, err := reference.Output()
+		if err != nil {
+			return nil, err
+		}
+		add(output.ProgramHash)
+	}
+	for _, attribute := range txn.Attributes {
+		if attribute.Usage == transaction.Script {
+			programHash, err := Uint160ParseFromBytes(attribute.Data)
+			if err != nil {
+				return nil, err
+			}
+			add(programHash)
+		}
+	}
+	// the same order as Transaction.GetProgramHashes
+	sort.Slice(programHashes, func(i, j int) bool {
+		return programHashes[i].CompareTo(programHashes[j]) < 0
+	})
+	return &ct.ContractContext{
+		Data:            txn,
+		ProgramHashes:   programHashes,
+		Codes:           make([][]byte, len(programHashes)),
+		Parameters:      make([][][]byte, len(programHashes)),
+		MultiPubkeyPara: make([][]ct.PubkeyParameter, len(programHashes)),
+	}, nil
+}
+
+// checkReferences checks there is one reference per input in the order of
+// the inputs
+func checkReferences(txn *transaction.Transaction, references []*UTXOReference) error {
+	if len(references) != len(txn.UTXOInputs) {
+		return errors.New("references do not match the inputs")
+	}
+	for i, input := range txn.UTXOInputs {
+		if references[i].ReferTxID != ToHexString(input.ReferTxID.ToArray()) ||
+			references[i].ReferTxOutputIndex != input.ReferTxOutputIndex {
+			return errors.New("references do not match the inputs")
+		}
+	}
+	return nil
+}
+
+// SaveContractContext writes a transaction context with the signatures
+// collected so far to a file, together with the outputs its inputs spend
+func SaveContractContext(path string, context *ct.ContractContext, references []*UTXOReference) error {
+	file := ContextFile{
+		Transaction: ToHexString(sig.GetHashData(context.Data)),
+		References:  references,
+		Items:       make([]ContextItem, len(context.ProgramHashes)),
+	}
+	for i, programHash := range context.ProgramHashes {
+		item := ContextItem{
+			ProgramHash: ToHexString(programHash.ToArray()),
+			Code:        ToHexString(context.Codes[i]),
+		}
+		for _, parameter := range context.Parameters[i] {
+			item.Parameters = append(item.Parameters, ToHexString(parameter))
+		}
+		item.Signatures = context.MultiPubkeyPara[i]
+		file.Items[i] = item
+	}
+	data, err := json.MarshalIndent(file, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0666)
+}
+
+// LoadContractContext reads a transaction context written by
+// SaveContractContext
+func LoadContractContext(path string) (*ct.ContractContext, []*UTXOReference, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var file ContextFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, nil, err
+	}
+	raw, err := HexToBytes(file.Transaction)
+	if err != nil {
+		return nil, nil, err
+	}
+	txn := new(transaction.Transaction)
+	if err := txn.DeserializeUnsigned(bytes.NewReader(raw)); err != nil {
+		return nil, nil, err
+	}
+	// the program hashes are taken from the file, computing them needs the
+	// transactions the inputs refer to, which an offline signer doesn't have
+	context := &ct.ContractContext{
+		Data:            txn,
+		ProgramHashes:   make([]Uint160, len(file.Items)),
+		Codes:           make([][]byte, len(file.Items)),
+		Parameters:      make([][][]byte, len(file.Items)),
+		MultiPubkeyPara: make([][]ct.PubkeyParameter, len(file.Items)),
+	}
+	for i, item := range file.Items {
+		programHash, err := HexToBytes(item.ProgramHash)
+		if err != nil {
+			return nil, nil, err
+		}
+		if context.ProgramHashes[i], err = Uint160ParseFromBytes(programHash); err != nil {
+			return nil, nil, err
+		}
+		if item.Code != "" {
+			if context.Codes[i], err = HexToBytes(item.Code); err != nil {
+				return nil, nil, err
+			}
+		}
+		if len(item.Parameters) > 0 {
+			context.Parameters[i] = make([][]byte, len(item.Parameters))
+			for j, parameter := range item.Parameters {
+				if parameter == "" {
+					continue
+				}
+				if context.Parameters[i][j], err = HexToBytes(parameter); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+		context.MultiPubkeyPara[i] = item.Signatures
+	}
+	if len(file.References) > 0 {
+		if err := checkReferences(txn, file.References); err != nil {
+			return nil, nil, err
+		}
+		for _, reference := range file.References {
+			output, err := reference.Output()
+			if err != nil {
+				return nil, nil, err
+			}
+			if context.GetIndex(output.ProgramHash) < 0 {
+				return nil, nil, errors.New("[LoadContractContext] an input owner is not a signer")
+			}
+		}
+	}
+	return context, file.References, nil
+}
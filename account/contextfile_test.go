@@ -0,0 +1,99 @@
+package account
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	. "github.com/Ontology/common"
+	"github.com/Ontology/core/transaction"
+	"github.com/Ontology/core/transaction/utxo"
+)
+
+func TestTransferContextReferences(t *testing.T) {
+	dir, err := ioutil.TempDir("", "context")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	owners := []Uint160{{2}, {1}}
+	var funding []*transaction.Transaction
+	for i := 0; i < 2; i++ {
+		var outputs []*utxo.TxOutput
+		for j := 0; j < 4; j++ {
+			outputs = append(outputs, &utxo.TxOutput{AssetID: transaction.ONGTokenID, Value: Fixed64(100 + i), ProgramHash: owners[j%2]})
+		}
+		txn, err := transaction.NewTransferAssetTransaction(nil, outputs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		funding = append(funding, txn)
+	}
+	var inputs []*utxo.UTXOTxInput
+	var references []*UTXOReference
+	for _, spent := range []struct {
+		txn   *transaction.Transaction
+		index uint16
+	}{{funding[0], 0}, {funding[0], 1}, {funding[1], 2}} {
+		inputs = append(inputs, &utxo.UTXOTxInput{ReferTxID: spent.txn.Hash(), ReferTxOutputIndex: spent.index})
+		reference, err := NewUTXOReference(spent.txn, spent.index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		references = append(references, reference)
+	}
+	txn, err := transaction.NewTransferAssetTransaction(inputs, []*utxo.TxOutput{
+		{AssetID: transaction.ONGTokenID, Value: 300, ProgramHash: Uint160{3}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTransferContext(txn, references[:2]); err == nil {
+		t.Fatal("missing reference accepted")
+	}
+	context, err := NewTransferContext(txn, references)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(context.ProgramHashes) != 2 || context.ProgramHashes[0] != owners[1] || context.ProgramHashes[1] != owners[0] {
+		t.Fatal("unexpected program hashes", context.ProgramHashes)
+	}
+
+	file := path.Join(dir, "tx.json")
+	if err := SaveContractContext(file, context, references); err != nil {
+		t.Fatal(err)
+	}
+	loaded, loadedReferences, err := LoadContractContext(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.ProgramHashes) != 2 || len(loadedReferences) != len(references) {
+		t.Fatal("context file not restored")
+	}
+	for i, reference := range loadedReferences {
+		if *reference != *references[i] {
+			t.Fatal("reference not restored", i)
+		}
+	}
+
+	// a referenced transaction must match its hash
+	forged := *references[2]
+	forged.Transaction = references[0].Transaction
+	if err := SaveContractContext(file, context, []*UTXOReference{references[0], references[1], &forged}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := LoadContractContext(file); err == nil {
+		t.Fatal("context with a forged reference accepted")
+	}
+
+	// the owner of an input must be one of the signers
+	context.ProgramHashes = context.ProgramHashes[:1]
+	if err := SaveContractContext(file, context, references); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := LoadContractContext(file); err == nil {
+		t.Fatal("context without an input owner accepted")
+	}
+}
@@ -20,6 +20,12 @@ type FileData struct {
 	PasswordHash        string
 	IV                  string
 	MasterKey           string
+	HDSeed              string
+	HDAccountCount      string
+	Version             int
+	Scrypt              *ScryptParams `json:",omitempty"`
+	WatchOnly           []string      `json:",omitempty"`
+	Contracts           []string      `json:",omitempty"`
 }
 
 type FileStore struct {
@@ -51,7 +57,7 @@ func (cs *FileStore) readDB() ([]byte, error) {
 
 func (cs *FileStore) writeDB(data []byte) error {
 	var err error
-	cs.file, err = os.OpenFile(cs.path, os.O_CREATE | os.O_WRONLY, 0666)
+	cs.file, err = os.OpenFile(cs.path, os.O_CREATE | os.O_WRONLY | os.O_TRUNC, 0666)
 	if err != nil {
 		return err
 	}
@@ -99,6 +105,10 @@ func (cs *FileStore) SaveStoredData(name string, value []byte) error {
 		cs.fd.MasterKey = fmt.Sprintf("%x", value)
 	} else if name == "PasswordHash" {
 		cs.fd.PasswordHash = fmt.Sprintf("%x", value)
+	} else if name == "HDSeed" {
+		cs.fd.HDSeed = fmt.Sprintf("%x", value)
+	} else if name == "HDAccountCount" {
+		cs.fd.HDAccountCount = fmt.Sprintf("%x", value)
 	}
 
 	jsonblob, err := json.Marshal(cs.fd)
@@ -128,6 +138,10 @@ func (cs *FileStore) LoadStoredData(name string) ([]byte, error) {
 		return hex.DecodeString(cs.fd.MasterKey)
 	} else if name == "PasswordHash" {
 		return hex.DecodeString(cs.fd.PasswordHash)
+	} else if name == "HDSeed" {
+		return hex.DecodeString(cs.fd.HDSeed)
+	} else if name == "HDAccountCount" {
+		return hex.DecodeString(cs.fd.HDAccountCount)
 	}
 
 	return nil, NewDetailErr(errors.New("Can't find the key: " + name), ErrNoCode, "")
@@ -214,3 +228,151 @@ func (cs *FileStore) SaveContractData(ct *ct.Contract) error {
 	cs.writeDB(jsonblob)
 	return nil
 }
+
+// LoadWalletFormat returns the version of the wallet file and the scrypt
+// parameters deriving the key of its master key
+func (cs *FileStore) LoadWalletFormat() (int, *ScryptParams, error) {
+	jsondata, err := cs.readDB()
+	if err != nil {
+		return 0, nil, err
+	}
+	var fd FileData
+	if err := json.Unmarshal(jsondata, &fd); err != nil {
+		return 0, nil, err
+	}
+	if fd.Version == 0 {
+		fd.Version = WalletVersion1
+	}
+	return fd.Version, fd.Scrypt, nil
+}
+
+// SaveMasterKey saves the encrypted master key together with the wallet
+// version and the scrypt parameters deriving its key
+func (cs *FileStore) SaveMasterKey(encryptedMasterKey []byte, version int, params *ScryptParams) error {
+	jsondata, err := cs.readDB()
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(jsondata, &cs.fd)
+	if err != nil {
+		fmt.Println("error:", err)
+	}
+
+	cs.fd.MasterKey = fmt.Sprintf("%x", encryptedMasterKey)
+	cs.fd.Version = version
+	cs.fd.Scrypt = params
+
+	jsonblob, err := json.Marshal(cs.fd)
+	if err != nil {
+		return err
+	}
+	return cs.writeDB(jsonblob)
+}
+
+func (cs *FileStore) loadFileData() (*FileData, error) {
+	jsondata, err := cs.readDB()
+	if err != nil {
+		return nil, err
+	}
+	fd := new(FileData)
+	if err := json.Unmarshal(jsondata, fd); err != nil {
+		return nil, err
+	}
+	return fd, nil
+}
+
+// replaceFileData writes the whole wallet file to a temporary file first, so
+// an interrupted write leaves the previous wallet file intact
+func (cs *FileStore) replaceFileData(fd *FileData) error {
+	jsonblob, err := json.Marshal(fd)
+	if err != nil {
+		return err
+	}
+	tmp := cs.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, jsonblob, 0666); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, cs.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	cs.fd = *fd
+	return nil
+}
+
+// AddWatchOnlyData saves the program hash of an address the wallet watches
+// without holding its key
+func (cs *FileStore) AddWatchOnlyData(programHash []byte) error {
+	jsondata, err := cs.readDB()
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(jsondata, &cs.fd)
+	if err != nil {
+		fmt.Println("error:", err)
+	}
+
+	cs.fd.WatchOnly = append(cs.fd.WatchOnly, fmt.Sprintf("%x", programHash))
+
+	jsonblob, err := json.Marshal(cs.fd)
+	if err != nil {
+		return err
+	}
+	return cs.writeDB(jsonblob)
+}
+
+func (cs *FileStore) LoadWatchOnlyData() ([][]byte, error) {
+	fd, err := cs.loadFileData()
+	if err != nil {
+		return nil, err
+	}
+	programHashes := make([][]byte, 0, len(fd.WatchOnly))
+	for _, h := range fd.WatchOnly {
+		programHash, err := hex.DecodeString(h)
+		if err != nil {
+			return nil, err
+		}
+		programHashes = append(programHashes, programHash)
+	}
+	return programHashes, nil
+}
+
+// AddContractData saves a contract besides the contract of the account, like
+// the multi sig contracts the wallet takes part in
+func (cs *FileStore) AddContractData(ct *ct.Contract) error {
+	jsondata, err := cs.readDB()
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(jsondata, &cs.fd)
+	if err != nil {
+		fmt.Println("error:", err)
+	}
+
+	cs.fd.Contracts = append(cs.fd.Contracts, fmt.Sprintf("%x", ct.ToArray()))
+
+	jsonblob, err := json.Marshal(cs.fd)
+	if err != nil {
+		return err
+	}
+	return cs.writeDB(jsonblob)
+}
+
+func (cs *FileStore) LoadContractsData() ([][]byte, error) {
+	fd, err := cs.loadFileData()
+	if err != nil {
+		return nil, err
+	}
+	contracts := make([][]byte, 0, len(fd.Contracts))
+	for _, c := range fd.Contracts {
+		rawData, err := hex.DecodeString(c)
+		if err != nil {
+			return nil, err
+		}
+		contracts = append(contracts, rawData)
+	}
+	return contracts, nil
+}
@@ -0,0 +1,208 @@
+package account
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	. "github.com/Ontology/common"
+	"github.com/Ontology/common/log"
+	"github.com/Ontology/core/contract"
+	"github.com/Ontology/core/ledger"
+	"github.com/Ontology/crypto"
+	. "github.com/Ontology/errors"
+)
+
+const (
+	// DefaultHDPath is the derivation path of the HD accounts, the account
+	// index is the last level of the path
+	DefaultHDPath = "m/44'/1024'/0'/0"
+	// DefaultGapLimit is the number of consecutive unused accounts after
+	// which the account discovery stops
+	DefaultGapLimit = 20
+)
+
+// CreateWithMnemonic creates an HD wallet whose accounts derive from the
+// seed of a mnemonic and an optional passphrase. The first account is the
+// default account of the wallet.
+func CreateWithMnemonic(path string, passwordKey []byte, mnemonic, passphrase string) (*ClientImpl, error) {
+	seed, err := MnemonicToSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	cl := NewClient(path, passwordKey, true)
+	if cl == nil {
+		return nil, errors.New("[CreateWithMnemonic] failed to create wallet")
+	}
+	encryptedSeed, err := cl.encryptData(seed)
+	if err != nil {
+		return nil, err
+	}
+	if err := cl.SaveStoredData("HDSeed", encryptedSeed); err != nil {
+		return nil, err
+	}
+	cl.hdSeed = seed
+	if _, err := cl.CreateAccount(); err != nil {
+		return nil, err
+	}
+	return cl, nil
+}
+
+// IsHD reports whether the accounts of the wallet derive from a mnemonic
+func (cl *ClientImpl) IsHD() bool {
+	return len(cl.hdSeed) > 0
+}
+
+// GetHDAccounts returns the derived accounts ordered by their index
+func (cl *ClientImpl) GetHDAccounts() []*Account {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	accounts := make([]*Account, 0, len(cl.hdAccounts))
+	for _, programHash := range cl.hdAccounts {
+		accounts = append(accounts, cl.accounts[programHash])
+	}
+	return accounts
+}
+
+// loadHDAccounts derives the accounts of an HD wallet again from its seed,
+// the wallet file only keeps the seed and the number of accounts
+func (cl *ClientImpl) loadHDAccounts() error {
+	encryptedSeed, err := cl.LoadStoredData("HDSeed")
+	if err != nil || len(encryptedSeed) == 0 {
+		return nil
+	}
+	cl.hdSeed, err = cl.decryptData(encryptedSeed)
+	if err != nil {
+		return err
+	}
+	count := uint32(1)
+	if buf, err := cl.LoadStoredData("HDAccountCount"); err == nil && len(buf) == 4 {
+		count = binary.BigEndian.Uint32(buf)
+	}
+	for index := uint32(0); index < count; index++ {
+		if _, err := cl.addHDAccount(index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeriveHDAccount returns the account at an index of the HD wallet without
+// adding it to the wallet
+func (cl *ClientImpl) DeriveHDAccount(index uint32) (*Account, error) {
+	if !cl.IsHD() {
+		return nil, NewDetailErr(errors.New("wallet has no mnemonic seed"), ErrNoCode, "")
+	}
+	master, err := crypto.NewMasterKey(cl.hdSeed)
+	if err != nil {
+		return nil, err
+	}
+	key, err := master.Derive(fmt.Sprintf("%s/%d", DefaultHDPath, index))
+	if err != nil {
+		return nil, err
+	}
+	return NewAccountWithPrivatekey(key.PrivateKey)
+}
+
+// addHDAccount derives the account at index and adds it with its signature
+// contract to the wallet
+func (cl *ClientImpl) addHDAccount(index uint32) (*Account, error) {
+	ac, err := cl.DeriveHDAccount(index)
+	if err != nil {
+		return nil, err
+	}
+	ct, err := contract.CreateSignatureContract(ac.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	cl.mu.Lock()
+	cl.accounts[ac.ProgramHash] = ac
+	cl.contracts[ct.ProgramHash] = ct
+	cl.hdAccounts = append(cl.hdAccounts, ac.ProgramHash)
+	cl.mu.Unlock()
+	return ac, nil
+}
+
+func (cl *ClientImpl) saveHDAccountCount() error {
+	count := make([]byte, 4)
+	binary.BigEndian.PutUint32(count, uint32(len(cl.hdAccounts)))
+	return cl.SaveStoredData("HDAccountCount", count)
+}
+
+// createHDAccount adds the account at the next index of the HD wallet. Only
+// the first account is written to the wallet file, the others are derived
+// again when the wallet is opened.
+func (cl *ClientImpl) createHDAccount() (*Account, error) {
+	index := uint32(len(cl.hdAccounts))
+	ac, err := cl.addHDAccount(index)
+	if err != nil {
+		return nil, err
+	}
+	if index == 0 {
+		if err := cl.SaveAccount(ac); err != nil {
+			return nil, err
+		}
+		if err := cl.SaveContractData(cl.GetContract(ac.ProgramHash)); err != nil {
+			return nil, err
+		}
+	}
+	if err := cl.saveHDAccountCount(); err != nil {
+		return nil, err
+	}
+	address, _ := ac.ProgramHash.ToAddress()
+	log.Info("[CreateHDAccount] Address: ", address)
+	return ac, nil
+}
+
+// DiscoverAccounts derives the accounts of an HD wallet in order until
+// gapLimit consecutive accounts are unused, and adds the accounts up to the
+// last used one to the wallet. It returns the number of accounts of the
+// wallet.
+func (cl *ClientImpl) DiscoverAccounts(gapLimit int, isUsed func(programHash Uint160) (bool, error)) (int, error) {
+	if !cl.IsHD() {
+		return 0, NewDetailErr(errors.New("wallet has no mnemonic seed"), ErrNoCode, "")
+	}
+	if gapLimit <= 0 {
+		gapLimit = DefaultGapLimit
+	}
+	next := uint32(len(cl.hdAccounts))
+	for index, gap := uint32(0), 0; gap < gapLimit; index++ {
+		ac, err := cl.DeriveHDAccount(index)
+		if err != nil {
+			return 0, err
+		}
+		used, err := isUsed(ac.ProgramHash)
+		if err != nil {
+			return 0, err
+		}
+		if !used {
+			gap++
+			continue
+		}
+		gap = 0
+		if index >= next {
+			next = index + 1
+		}
+	}
+	for uint32(len(cl.hdAccounts)) < next {
+		if _, err := cl.addHDAccount(uint32(len(cl.hdAccounts))); err != nil {
+			return 0, err
+		}
+	}
+	if err := cl.saveHDAccountCount(); err != nil {
+		return 0, err
+	}
+	return len(cl.hdAccounts), nil
+}
+
+// LedgerAccountUsed reports whether the local ledger has a state for the
+// account, which it keeps from the first transfer to the account
+func LedgerAccountUsed(programHash Uint160) (bool, error) {
+	if ledger.DefaultLedger == nil || ledger.DefaultLedger.Store == nil {
+		return false, errors.New("[LedgerAccountUsed] ledger is not initialized")
+	}
+	_, err := ledger.DefaultLedger.Store.GetAccount(programHash)
+	return err == nil, nil
+}
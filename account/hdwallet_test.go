@@ -0,0 +1,86 @@
+package account
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	. "github.com/Ontology/common"
+)
+
+func TestMnemonicToSeed(t *testing.T) {
+	entropy := make([]byte, 16)
+	mnemonic, err := EntropyToMnemonic(entropy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mnemonic != "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about" {
+		t.Fatal("unexpected mnemonic", mnemonic)
+	}
+	// BIP39 test vector
+	seed, err := MnemonicToSeed(mnemonic, "TREZOR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(seed) != "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04" {
+		t.Errorf("unexpected seed %x", seed)
+	}
+	if IsMnemonicValid("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon") {
+		t.Error("mnemonic with a wrong checksum accepted")
+	}
+	random, err := NewMnemonic(256)
+	if err != nil || !IsMnemonicValid(random) {
+		t.Error("invalid random mnemonic", err)
+	}
+}
+
+func TestClientImpl_DiscoverAccounts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hdwallet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	mnemonic, _ := NewMnemonic(DefaultMnemonicEntropy)
+	walletPath := path.Join(dir, "wallet.dat")
+	wallet, err := CreateWithMnemonic(walletPath, []byte("123456"), mnemonic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	used := map[Uint160]bool{}
+	for _, index := range []uint32{0, 3, 7} {
+		ac, err := wallet.DeriveHDAccount(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		used[ac.ProgramHash] = true
+	}
+	count, err := wallet.DiscoverAccounts(5, func(programHash Uint160) (bool, error) {
+		return used[programHash], nil
+	})
+	if err != nil || count != 8 {
+		t.Fatal("unexpected account count", count, err)
+	}
+
+	restored, err := CreateWithMnemonic(path.Join(dir, "restored.dat"), []byte("123456"), mnemonic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, _ = restored.DiscoverAccounts(3, func(programHash Uint160) (bool, error) {
+		return used[programHash], nil
+	})
+	if count != 4 {
+		t.Error("the gap limit should stop the discovery after the fourth account", count)
+	}
+
+	opened := Open(walletPath, []byte("123456"))
+	if opened == nil || len(opened.GetHDAccounts()) != 8 {
+		t.Fatal("failed to derive the accounts of the opened wallet")
+	}
+	def, _ := opened.GetDefaultAccount()
+	first, _ := wallet.GetDefaultAccount()
+	if def.ProgramHash != first.ProgramHash || opened.GetContract(opened.GetHDAccounts()[7].ProgramHash) == nil {
+		t.Error("unexpected accounts of the opened wallet")
+	}
+}
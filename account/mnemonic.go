@@ -0,0 +1,112 @@
+package account
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/Ontology/crypto/util"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// DefaultMnemonicEntropy is the entropy in bits of a new mnemonic,
+	// 128 bits give 12 words
+	DefaultMnemonicEntropy = 128
+
+	mnemonicSeedRounds = 2048
+	mnemonicSeedLen    = 64
+)
+
+var wordIndex map[string]int
+
+func init() {
+	wordIndex = make(map[string]int, len(englishWords))
+	for i, w := range englishWords {
+		wordIndex[w] = i
+	}
+}
+
+// NewMnemonic returns a BIP39 mnemonic encoding bits of random entropy,
+// bits is a multiple of 32 between 128 and 256
+func NewMnemonic(bits int) (string, error) {
+	if bits%32 != 0 || bits < 128 || bits > 256 {
+		return "", errors.New("[NewMnemonic] invalid entropy size")
+	}
+	entropy, err := util.RandomNum(bits / 8)
+	if err != nil {
+		return "", err
+	}
+	return EntropyToMnemonic(entropy)
+}
+
+// EntropyToMnemonic encodes the entropy and its sha256 checksum with 11 bits
+// per word
+func EntropyToMnemonic(entropy []byte) (string, error) {
+	bits := len(entropy) * 8
+	if bits%32 != 0 || bits < 128 || bits > 256 {
+		return "", errors.New("[EntropyToMnemonic] invalid entropy size")
+	}
+	checksumBits := uint(bits / 32)
+	hash := sha256.Sum256(entropy)
+	data := new(big.Int).SetBytes(entropy)
+	data.Lsh(data, checksumBits)
+	data.Or(data, big.NewInt(int64(hash[0]>>(8-checksumBits))))
+
+	count := (bits + int(checksumBits)) / 11
+	words := make([]string, count)
+	mask := big.NewInt(2047)
+	for i := count - 1; i >= 0; i-- {
+		words[i] = englishWords[new(big.Int).And(data, mask).Int64()]
+		data.Rsh(data, 11)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// MnemonicToEntropy decodes a mnemonic and verifies its checksum
+func MnemonicToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	if len(words)%3 != 0 || len(words) < 12 || len(words) > 24 {
+		return nil, errors.New("[MnemonicToEntropy] invalid number of words")
+	}
+	data := new(big.Int)
+	for _, w := range words {
+		index, ok := wordIndex[w]
+		if !ok {
+			return nil, errors.New("[MnemonicToEntropy] unknown word: " + w)
+		}
+		data.Lsh(data, 11)
+		data.Or(data, big.NewInt(int64(index)))
+	}
+	checksumBits := uint(len(words) * 11 / 33)
+	checksum := byte(new(big.Int).And(data, big.NewInt(1<<checksumBits-1)).Int64())
+	data.Rsh(data, checksumBits)
+
+	entropy := make([]byte, len(words)*11/33*4)
+	b := data.Bytes()
+	copy(entropy[len(entropy)-len(b):], b)
+	hash := sha256.Sum256(entropy)
+	if hash[0]>>(8-checksumBits) != checksum {
+		return nil, errors.New("[MnemonicToEntropy] invalid checksum")
+	}
+	return entropy, nil
+}
+
+// IsMnemonicValid reports whether the mnemonic has known words and a valid
+// checksum
+func IsMnemonicValid(mnemonic string) bool {
+	_, err := MnemonicToEntropy(mnemonic)
+	return err == nil
+}
+
+// MnemonicToSeed returns the BIP39 seed of a mnemonic protected by an
+// optional passphrase
+func MnemonicToSeed(mnemonic, passphrase string) ([]byte, error) {
+	if !IsMnemonicValid(mnemonic) {
+		return nil, errors.New("[MnemonicToSeed] invalid mnemonic")
+	}
+	normalized := strings.Join(strings.Fields(mnemonic), " ")
+	return pbkdf2.Key([]byte(normalized), []byte("mnemonic"+passphrase), mnemonicSeedRounds, mnemonicSeedLen, sha512.New), nil
+}
@@ -0,0 +1,125 @@
+package account
+
+import (
+	"bytes"
+	"errors"
+
+	. "github.com/Ontology/common"
+	"github.com/Ontology/common/log"
+	ct "github.com/Ontology/core/contract"
+	"github.com/Ontology/crypto"
+	. "github.com/Ontology/errors"
+)
+
+// AddWatchOnly adds an address the wallet tracks without holding its key
+func (cl *ClientImpl) AddWatchOnly(programHash Uint160) error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if _, ok := cl.contracts[programHash]; ok {
+		return NewDetailErr(errors.New("address is already in the wallet"), ErrNoCode, "")
+	}
+	for _, h := range cl.watchOnly {
+		if h == programHash {
+			return NewDetailErr(errors.New("address is already watched"), ErrNoCode, "")
+		}
+	}
+	if err := cl.AddWatchOnlyData(programHash.ToArray()); err != nil {
+		return err
+	}
+	cl.watchOnly = append(cl.watchOnly, programHash)
+	return nil
+}
+
+// GetWatchOnly returns the program hashes of the watch-only addresses
+func (cl *ClientImpl) GetWatchOnly() []Uint160 {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	return append([]Uint160{}, cl.watchOnly...)
+}
+
+// CreateMultiSigAccount adds the m-of-n multi sig contract of the public
+// keys to the wallet, the wallet signs for it with the keys it holds
+func (cl *ClientImpl) CreateMultiSigAccount(m int, pubKeys []*crypto.PubKey) (*ct.Contract, error) {
+	if m < 1 || m > len(pubKeys) || len(pubKeys) > 24 {
+		return nil, NewDetailErr(errors.New("invalid multi sig parameters"), ErrNoCode, "")
+	}
+	// the contract is owned by the first key of the wallet
+	var owner Uint160
+	for _, pubKey := range pubKeys {
+		if cl.ContainsAccount(pubKey) {
+			pk, err := pubKey.EncodePoint(true)
+			if err != nil {
+				return nil, err
+			}
+			owner = ToCodeHash(pk)
+			break
+		}
+	}
+	contract, err := ct.CreateMultiSigContract(owner, m, pubKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if _, ok := cl.contracts[contract.ProgramHash]; ok {
+		return nil, NewDetailErr(errors.New("multi sig contract is already in the wallet"), ErrNoCode, "")
+	}
+	if err := cl.AddContractData(contract); err != nil {
+		return nil, err
+	}
+	cl.contracts[contract.ProgramHash] = contract
+	address, _ := contract.ProgramHash.ToAddress()
+	log.Info("[CreateMultiSigAccount] Address: ", address)
+	return contract, nil
+}
+
+// loadWatchOnly loads the watch-only addresses and the additional contracts
+// of the wallet file
+func (cl *ClientImpl) loadWatchOnly() error {
+	programHashes, err := cl.LoadWatchOnlyData()
+	if err != nil {
+		return err
+	}
+	for _, h := range programHashes {
+		programHash, err := Uint160ParseFromBytes(h)
+		if err != nil {
+			return err
+		}
+		cl.watchOnly = append(cl.watchOnly, programHash)
+	}
+	contracts, err := cl.LoadContractsData()
+	if err != nil {
+		return err
+	}
+	for _, rawData := range contracts {
+		contract := new(ct.Contract)
+		if err := contract.Deserialize(bytes.NewReader(rawData)); err != nil {
+			return err
+		}
+		contract.ProgramHash = ToCodeHash(contract.Code)
+		cl.contracts[contract.ProgramHash] = contract
+	}
+	return nil
+}
+
+// multiSigSigners returns the accounts of the wallet whose keys are in a
+// multi sig contract
+func (cl *ClientImpl) multiSigSigners(contract *ct.Contract) []*Account {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	var signers []*Account
+	for _, account := range cl.accounts {
+		pk, err := account.PublicKey.EncodePoint(true)
+		if err != nil {
+			continue
+		}
+		if bytes.Contains(contract.Code, append([]byte{byte(len(pk))}, pk...)) {
+			signers = append(signers, account)
+		}
+	}
+	return signers
+}
@@ -0,0 +1,106 @@
+package account
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	. "github.com/Ontology/common"
+	ct "github.com/Ontology/core/contract"
+	"github.com/Ontology/core/transaction"
+	"github.com/Ontology/core/transaction/payload"
+	"github.com/Ontology/core/validation"
+	"github.com/Ontology/crypto"
+)
+
+func TestMultiSigContext(t *testing.T) {
+	dir, err := ioutil.TempDir("", "multisig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// p256r1 signing needs the public key in the ecdsa private key since go1.20
+	crypto.SetAlg("SM2")
+	defer crypto.SetAlg("P256R1")
+
+	var wallets []*ClientImpl
+	var pubKeys []*crypto.PubKey
+	for i := 0; i < 3; i++ {
+		wallet := Create(path.Join(dir, fmt.Sprintf("wallet%d", i)), []byte("123456"))
+		ac, err := wallet.GetDefaultAccount()
+		if err != nil {
+			t.Fatal(err)
+		}
+		wallets = append(wallets, wallet)
+		pubKeys = append(pubKeys, ac.PublicKey)
+	}
+	var multiSig *ct.Contract
+	for _, wallet := range wallets[1:] {
+		multiSig, err = wallet.CreateMultiSigAccount(2, pubKeys)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	watched := Uint160{7}
+	if err := wallets[0].AddWatchOnly(watched); err != nil {
+		t.Fatal(err)
+	}
+	reopened := Open(path.Join(dir, "wallet2"), []byte("123456"))
+	if reopened.GetContract(multiSig.ProgramHash) == nil {
+		t.Fatal("multi sig contract not loaded")
+	}
+	if w := Open(path.Join(dir, "wallet0"), []byte("123456")).GetWatchOnly(); len(w) != 1 || w[0] != watched {
+		t.Fatal("watch-only address not loaded")
+	}
+
+	txn := &transaction.Transaction{TxType: transaction.TransferAsset, Payload: &payload.TransferAsset{}}
+	newContext := func() *ct.ContractContext {
+		return &ct.ContractContext{
+			Data:            txn,
+			ProgramHashes:   []Uint160{multiSig.ProgramHash},
+			Codes:           make([][]byte, 1),
+			Parameters:      make([][][]byte, 1),
+			MultiPubkeyPara: make([][]ct.PubkeyParameter, 1),
+		}
+	}
+	// the co-signers sign copies of the context
+	files := []string{path.Join(dir, "b.json"), path.Join(dir, "c.json")}
+	for i, wallet := range []*ClientImpl{wallets[1], reopened} {
+		context := newContext()
+		if !wallet.Sign(context) || context.IsCompleted() {
+			t.Fatal("unexpected context of a single co-signer")
+		}
+		if i == 1 {
+			// signing a signed copy again keeps one signature per key
+			wallet.Sign(context)
+		}
+		if err := SaveContractContext(files[i], context, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	context, _, err := LoadContractContext(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := LoadContractContext(files[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := context.Merge(other); err != nil {
+		t.Fatal(err)
+	}
+	if !context.IsCompleted() {
+		t.Fatal("merged context is not complete")
+	}
+	txn.SetPrograms(context.GetPrograms())
+	if err := validation.VerifySignableDataSignature(txn); err != nil {
+		t.Error(err)
+	}
+
+	other.MultiPubkeyPara[0][0].Parameter = other.MultiPubkeyPara[0][0].Parameter[2:] + "00"
+	if err := newContext().Merge(other); err == nil {
+		t.Error("invalid signature merged")
+	}
+}
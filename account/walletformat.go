@@ -0,0 +1,140 @@
+package account
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	. "github.com/Ontology/common"
+	"github.com/Ontology/common/log"
+	"github.com/Ontology/crypto"
+	"github.com/Ontology/crypto/util"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// WalletVersion1 wallets encrypt the master key with AES-CBC under the
+	// SHA-256 of the password and encrypt every key under one wallet IV
+	WalletVersion1 = 1
+	// WalletVersion2 wallets derive the key of the master key with scrypt and
+	// seal every key with AES-GCM under its own random nonce
+	WalletVersion2 = 2
+	// WalletVersion is the version of the new wallets
+	WalletVersion = WalletVersion2
+)
+
+const (
+	DefaultScryptN = 16384
+	DefaultScryptR = 8
+	DefaultScryptP = 8
+
+	scryptSaltLen = 16
+	scryptKeyLen  = 32
+)
+
+// ScryptParams are the parameters of the scrypt key derivation, they are
+// stored in the wallet file so they can be raised for new wallets
+type ScryptParams struct {
+	N    int
+	R    int
+	P    int
+	Salt string
+}
+
+// NewScryptParams returns the default parameters with a random salt
+func NewScryptParams() (*ScryptParams, error) {
+	salt, err := util.RandomNum(scryptSaltLen)
+	if err != nil {
+		return nil, err
+	}
+	return &ScryptParams{
+		N:    DefaultScryptN,
+		R:    DefaultScryptR,
+		P:    DefaultScryptP,
+		Salt: hex.EncodeToString(salt),
+	}, nil
+}
+
+// DeriveKey derives the AES key of the master key from the password
+func (p *ScryptParams) DeriveKey(password []byte) ([]byte, error) {
+	salt, err := hex.DecodeString(p.Salt)
+	if err != nil {
+		return nil, err
+	}
+	return scrypt.Key(password, salt, p.N, p.R, p.P, scryptKeyLen)
+}
+
+// Upgrade migrates a wallet file in place to the current wallet format. The
+// keys are encrypted again under a new random master key.
+func Upgrade(path string, password []byte) error {
+	cl := open(path, password, false)
+	if cl == nil {
+		return errors.New("[Upgrade] failed to open wallet")
+	}
+	return cl.upgrade(password)
+}
+
+func (cl *ClientImpl) upgrade(password []byte) error {
+	if cl.version >= WalletVersion {
+		return fmt.Errorf("[upgrade] wallet is already at version %d", cl.version)
+	}
+	fd, err := cl.loadFileData()
+	if err != nil {
+		return err
+	}
+	var prikey []byte
+	if fd.PrivateKeyEncrypted != "" {
+		prikeyenc, err := hex.DecodeString(fd.PrivateKeyEncrypted)
+		if err != nil {
+			return err
+		}
+		if prikey, err = cl.DecryptPrivateKey(prikeyenc); err != nil {
+			return err
+		}
+		defer ClearBytes(prikey, len(prikey))
+	}
+
+	masterKey, err := util.RandomNum(32)
+	if err != nil {
+		return err
+	}
+	params, err := NewScryptParams()
+	if err != nil {
+		return err
+	}
+	passwordKey, err := params.DeriveKey(password)
+	if err != nil {
+		return err
+	}
+	defer ClearBytes(passwordKey, len(passwordKey))
+	encryptedMasterKey, err := crypto.AesGcmEncrypt(masterKey, passwordKey)
+	if err != nil {
+		return err
+	}
+
+	cl.version, cl.masterKey, cl.iv = WalletVersion, masterKey, nil
+	if prikey != nil {
+		prikeyenc, err := cl.EncryptPrivateKey(prikey)
+		if err != nil {
+			return err
+		}
+		fd.PrivateKeyEncrypted = hex.EncodeToString(prikeyenc)
+	}
+	if cl.IsHD() {
+		seed, err := cl.encryptData(cl.hdSeed)
+		if err != nil {
+			return err
+		}
+		fd.HDSeed = hex.EncodeToString(seed)
+	}
+	fd.MasterKey = hex.EncodeToString(encryptedMasterKey)
+	fd.PasswordHash = ""
+	fd.IV = ""
+	fd.Version = WalletVersion
+	fd.Scrypt = params
+	if err := cl.replaceFileData(fd); err != nil {
+		return err
+	}
+	log.Infof("wallet %s upgraded to version %d", cl.path, WalletVersion)
+	return nil
+}
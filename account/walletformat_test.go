@@ -0,0 +1,96 @@
+package account
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	. "github.com/Ontology/common"
+	ct "github.com/Ontology/core/contract"
+	"github.com/Ontology/crypto"
+	"github.com/Ontology/crypto/util"
+)
+
+// createV1Wallet writes a wallet in the version 1 format
+func createV1Wallet(t *testing.T, walletPath string, password []byte) *Account {
+	cl := &ClientImpl{
+		path:      walletPath,
+		version:   WalletVersion1,
+		accounts:  map[Uint160]*Account{},
+		contracts: map[Uint160]*ct.Contract{},
+		FileStore: FileStore{path: walletPath},
+	}
+	cl.iv, _ = util.RandomNum(16)
+	cl.masterKey, _ = util.RandomNum(32)
+	cl.BuildDatabase(walletPath)
+	passwordKey := crypto.ToAesKey(password)
+	passwordHash := sha256.Sum256(passwordKey)
+	encryptedMasterKey, _ := crypto.AesEncrypt(cl.masterKey, passwordKey, cl.iv)
+	cl.SaveStoredData("PasswordHash", passwordHash[:])
+	cl.SaveStoredData("IV", cl.iv)
+	cl.SaveStoredData("MasterKey", encryptedMasterKey)
+	ac, err := cl.CreateAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ac
+}
+
+func TestUpgrade(t *testing.T) {
+	dir, err := ioutil.TempDir("", "walletformat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	walletPath := path.Join(dir, "wallet.dat")
+	ac := createV1Wallet(t, walletPath, []byte("123456"))
+
+	if err := Upgrade(walletPath, []byte("12345")); err == nil {
+		t.Fatal("upgrade with a wrong password succeeded")
+	}
+	if err := Upgrade(walletPath, []byte("123456")); err != nil {
+		t.Fatal(err)
+	}
+	if err := Upgrade(walletPath, []byte("123456")); err == nil {
+		t.Error("upgraded wallet upgraded again")
+	}
+
+	cl := Open(walletPath, []byte("123456"))
+	if cl == nil || cl.version != WalletVersion || cl.iv != nil {
+		t.Fatal("failed to open the upgraded wallet")
+	}
+	def, err := cl.GetDefaultAccount()
+	if err != nil || def.ProgramHash != ac.ProgramHash || !IsEqualBytes(def.PrivateKey, ac.PrivateKey) {
+		t.Fatal("unexpected account of the upgraded wallet")
+	}
+	if Open(walletPath, []byte("12345")) != nil {
+		t.Error("upgraded wallet opened with a wrong password")
+	}
+	if !cl.ChangePassword([]byte("123456"), []byte("654321")) || Open(walletPath, []byte("654321")) == nil {
+		t.Error("failed to change the password of the upgraded wallet")
+	}
+}
+
+func TestOpenUpgrades(t *testing.T) {
+	dir, err := ioutil.TempDir("", "walletformat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	walletPath := path.Join(dir, "wallet.dat")
+	ac := createV1Wallet(t, walletPath, []byte("123456"))
+
+	if cl := Open(walletPath, []byte("123456")); cl == nil || cl.version != WalletVersion {
+		t.Fatal("version 1 wallet not upgraded on open")
+	}
+	cl := Open(walletPath, []byte("123456"))
+	if cl == nil || cl.version != WalletVersion || cl.iv != nil {
+		t.Fatal("failed to open the upgraded wallet")
+	}
+	def, err := cl.GetDefaultAccount()
+	if err != nil || def.ProgramHash != ac.ProgramHash || !IsEqualBytes(def.PrivateKey, ac.PrivateKey) {
+		t.Fatal("unexpected account of the upgraded wallet")
+	}
+}
@@ -152,45 +152,53 @@ func makeIssueTransaction(issuer *account.Account, programHashStr, assetHashStr
 }
 
 func makeTransferTransaction(signer *account.Account, programHashStr, assetHashStr string, value Fixed64, netWorkFee Fixed64) (string, error) {
-	inputs := []*utxo.UTXOTxInput{}
-	outputs := []*utxo.TxOutput{}
 	// get user id & asset id
 	programHash, assetHash, err := getUintHash(programHashStr, assetHashStr)
 	if err != nil {
 		return "", err
 	}
+	tx, err := newTransferTransaction(signer.ProgramHash, programHash, assetHash, value, netWorkFee)
+	if err != nil {
+		return "", err
+	}
+	if err := signTransaction(signer, tx); err != nil {
+		fmt.Println("sign transfer transaction failed")
+		return "", err
+	}
+	var buffer bytes.Buffer
+	if err := tx.Serialize(&buffer); err != nil {
+		fmt.Println("serialization of transfer transaction failed")
+		return "", err
+	}
+	return hex.EncodeToString(buffer.Bytes()), nil
+}
+
+// newTransferTransaction builds the unsigned transfer of value from the
+// spender, the inputs are the unspent outputs of the spender on the node
+func newTransferTransaction(spender, to Uint160, assetHash Uint256, value Fixed64, netWorkFee Fixed64) (*transaction.Transaction, error) {
 	reverseHash, _ := Uint256ParseFromBytes(assetHash.ToArrayReverse())
 	var tx *transaction.Transaction
 	if assetHash == transaction.ONGTokenID {
-		inputs, outputs, err = calcUtxoByRpc(signer.ProgramHash, programHash, reverseHash, value, netWorkFee, false)
+		inputs, outputs, err := calcUtxoByRpc(spender, to, reverseHash, value, netWorkFee, false)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		tx, _ = transaction.NewTransferAssetTransaction(inputs, outputs)
 	} else {
-		inputs, outputs, err = calcUtxoByRpc(signer.ProgramHash, programHash, reverseHash, value, 0, false)
+		inputs, outputs, err := calcUtxoByRpc(spender, to, reverseHash, value, 0, false)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		tx, _ = transaction.NewTransferAssetTransaction(inputs, outputs)
-		tx, err = checkAndAddFees(signer.ProgramHash, tx, netWorkFee)
+		tx, err = checkAndAddFees(spender, tx, netWorkFee)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 	}
 	txAttr := transaction.NewTxAttribute(transaction.Nonce, []byte(strconv.FormatInt(rand.Int63(), 10)))
 	tx.Attributes = make([]*transaction.TxAttribute, 0)
 	tx.Attributes = append(tx.Attributes, &txAttr)
-	if err := signTransaction(signer, tx); err != nil {
-		fmt.Println("sign transfer transaction failed")
-		return "", err
-	}
-	var buffer bytes.Buffer
-	if err := tx.Serialize(&buffer); err != nil {
-		fmt.Println("serialization of transfer transaction failed")
-		return "", err
-	}
-	return hex.EncodeToString(buffer.Bytes()), nil
+	return tx, nil
 }
 
 func makeClaimTransaction(signer *account.Account, referTxID string, index string) (string, error) {
@@ -530,7 +538,8 @@ func NewCommand() *cli.Command {
 				Usage: "netWorkFee ammount",
 			},
 		},
-		Action: assetAction,
+		Action:      assetAction,
+		Subcommands: newOfflineCommands(),
 		OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
 			PrintError(c, err, "asset")
 			return cli.NewExitError("", 1)
@@ -0,0 +1,281 @@
+package asset
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Ontology/account"
+	. "github.com/Ontology/cli/common"
+	. "github.com/Ontology/common"
+	"github.com/Ontology/core/transaction"
+	"github.com/Ontology/core/transaction/utxo"
+	"github.com/Ontology/net/httpjsonrpc"
+
+	"github.com/urfave/cli"
+)
+
+// referencesByRpc looks up the transactions the inputs of a transaction spend
+// on the node, so the signer doesn't need a ledger
+func referencesByRpc(tx *transaction.Transaction) ([]*account.UTXOReference, error) {
+	var references []*account.UTXOReference
+	for _, input := range tx.UTXOInputs {
+		referTxID := ToHexString(input.ReferTxID.ToArray())
+		resp, err := httpjsonrpc.Call(Address(), "getrawtransaction", 0, []interface{}{referTxID, 1})
+		if err != nil {
+			return nil, err
+		}
+		r := make(map[string]interface{})
+		if err := json.Unmarshal(resp, &r); err != nil {
+			return nil, err
+		}
+		result, ok := r["result"].(string)
+		if !ok {
+			return nil, fmt.Errorf("[referencesByRpc] transaction %s not found", referTxID)
+		}
+		raw, err := HexToBytes(result)
+		if err != nil {
+			return nil, err
+		}
+		referTx := new(transaction.Transaction)
+		if err := referTx.Deserialize(bytes.NewReader(raw)); err != nil {
+			return nil, err
+		}
+		reference, err := account.NewUTXOReference(referTx, input.ReferTxOutputIndex)
+		if err != nil {
+			return nil, err
+		}
+		if reference.ReferTxID != referTxID {
+			return nil, fmt.Errorf("[referencesByRpc] node returned another transaction for %s", referTxID)
+		}
+		references = append(references, reference)
+	}
+	return references, nil
+}
+
+func buildAction(c *cli.Context) error {
+	from := c.String("from")
+	to := c.String("to")
+	asset := c.String("asset")
+	file := c.String("file")
+	if from == "" || to == "" || asset == "" || file == "" {
+		fmt.Println("missing flag [--from], [--to], [--asset] or [--file]")
+		return nil
+	}
+	value := c.Int64("value")
+	if value <= 0 {
+		fmt.Println("invalid value [--value]")
+		return nil
+	}
+	spender, assetHash, err := getUintHash(from, asset)
+	if err != nil {
+		return err
+	}
+	toHash, _, err := getUintHash(to, asset)
+	if err != nil {
+		return err
+	}
+	tx, err := newTransferTransaction(spender, toHash, assetHash, Fixed64(value), Fixed64(c.Int64("netWorkFee")))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	references, err := referencesByRpc(tx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	context, err := account.NewTransferContext(tx, references)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	if err := account.SaveContractContext(file, context, references); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	fmt.Println("Unsigned transaction written to", file)
+	return nil
+}
+
+func printAmount(prefix string, output *utxo.TxOutput) {
+	address, err := output.ProgramHash.ToAddress()
+	if err != nil {
+		address = ToHexString(output.ProgramHash.ToArray())
+	}
+	fmt.Printf("%s %s %d of asset %s\n", prefix, address, output.Value, ToHexString(output.AssetID.ToArray()))
+}
+
+// reviewTransaction prints what a transaction spends and pays so the owner
+// of a cold key can check it before signing, the spent outputs are read from
+// the referenced transactions after their hashes are checked
+func reviewTransaction(tx *transaction.Transaction, references []*account.UTXOReference) error {
+	if len(references) == 0 {
+		return errors.New("context file has no references of the spent outputs")
+	}
+	spent := make(map[Uint256]Fixed64)
+	for _, reference := range references {
+		output, err := reference.Output()
+		if err != nil {
+			return err
+		}
+		printAmount("spend:  ", output)
+		spent[output.AssetID] += output.Value
+	}
+	for _, output := range tx.Outputs {
+		printAmount("pay:    ", output)
+		spent[output.AssetID] -= output.Value
+	}
+	for assetID, fee := range spent {
+		if fee < 0 {
+			return fmt.Errorf("outputs of asset %s exceed the spent outputs", ToHexString(assetID.ToArray()))
+		}
+		if fee > 0 {
+			fmt.Printf("fee:     %d of asset %s\n", fee, ToHexString(assetID.ToArray()))
+		}
+	}
+	return nil
+}
+
+func offlineSignAction(c *cli.Context) error {
+	file := c.String("file")
+	if file == "" {
+		fmt.Println("missing flag [--file]")
+		return nil
+	}
+	context, references, err := account.LoadContractContext(file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	tx, ok := context.Data.(*transaction.Transaction)
+	if !ok {
+		return errors.New("context data is not a transaction")
+	}
+	if err := reviewTransaction(tx, references); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	wallet := openWallet(c.String("wallet"), WalletPassword(c.String("password")))
+	if !wallet.Sign(context) {
+		fmt.Println("Wallet holds no key of the transaction")
+		return nil
+	}
+	if err := account.SaveContractContext(file, context, references); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	if context.IsCompleted() {
+		fmt.Println("Transaction is completely signed, broadcast", file, "on an online machine")
+	} else {
+		fmt.Println("Transaction needs more signatures")
+	}
+	return nil
+}
+
+func broadcastAction(c *cli.Context) error {
+	file := c.String("file")
+	if file == "" {
+		fmt.Println("missing flag [--file]")
+		return nil
+	}
+	context, _, err := account.LoadContractContext(file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	if !context.IsCompleted() {
+		fmt.Println("Transaction needs more signatures")
+		return nil
+	}
+	tx, ok := context.Data.(*transaction.Transaction)
+	if !ok {
+		return errors.New("context data is not a transaction")
+	}
+	tx.SetPrograms(context.GetPrograms())
+	var buffer bytes.Buffer
+	if err := tx.Serialize(&buffer); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	resp, err := httpjsonrpc.Call(Address(), "sendrawtransaction", 0, []interface{}{hex.EncodeToString(buffer.Bytes())})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	FormatOutput(resp)
+	return nil
+}
+
+func newOfflineCommands() []cli.Command {
+	onUsageError := func(c *cli.Context, err error, isSubcommand bool) error {
+		PrintError(c, err, "asset")
+		return cli.NewExitError("", 1)
+	}
+	fileFlag := cli.StringFlag{
+		Name:  "file",
+		Usage: "transaction context file",
+	}
+	return []cli.Command{
+		{
+			Name:        "build",
+			Usage:       "build an unsigned transfer",
+			Description: "With nodectl asset build, you could build a transfer on an online machine and write it with the outputs it spends to a file for offline signing.",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "from",
+					Usage: "program hash of the spender",
+				},
+				cli.StringFlag{
+					Name:  "to",
+					Usage: "asset to whom",
+				},
+				cli.StringFlag{
+					Name:  "asset, a",
+					Usage: "uniq id for asset",
+				},
+				cli.Int64Flag{
+					Name:  "value, v",
+					Usage: "asset ammount",
+				},
+				cli.Int64Flag{
+					Name:  "netWorkFee, f",
+					Usage: "netWorkFee ammount",
+				},
+				fileFlag,
+			},
+			Action:       buildAction,
+			OnUsageError: onUsageError,
+		},
+		{
+			Name:        "sign",
+			Usage:       "sign an unsigned transfer offline",
+			Description: "With nodectl asset sign, you could review and sign a transfer built by nodectl asset build without connecting to a node.",
+			Flags: []cli.Flag{
+				fileFlag,
+				cli.StringFlag{
+					Name:  "wallet, w",
+					Usage: "wallet name",
+					Value: account.WalletFileName,
+				},
+				cli.StringFlag{
+					Name:  "password, p",
+					Usage: "wallet password",
+				},
+			},
+			Action:       offlineSignAction,
+			OnUsageError: onUsageError,
+		},
+		{
+			Name:         "broadcast",
+			Usage:        "send a signed transfer",
+			Description:  "With nodectl asset broadcast, you could send a transfer signed by nodectl asset sign to the node.",
+			Flags:        []cli.Flag{fileFlag},
+			Action:       broadcastAction,
+			OnUsageError: onUsageError,
+		},
+	}
+}
@@ -0,0 +1,245 @@
+package chain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Ontology/account"
+	. "github.com/Ontology/cli/common"
+	"github.com/Ontology/common/config"
+	"github.com/Ontology/core/archive"
+	"github.com/Ontology/core/ledger"
+	"github.com/Ontology/core/store/ChainStore"
+	"github.com/Ontology/core/transaction"
+	"github.com/Ontology/crypto"
+
+	"github.com/urfave/cli"
+)
+
+// progressInterval is how often the progress of an export or import is shown
+const progressInterval = 2 * time.Second
+
+// openLedger opens the block store of the node in the working directory, the
+// node must not be running
+func openLedger() error {
+	store, err := ChainStore.NewLedgerStore()
+	if err != nil {
+		return fmt.Errorf("open ledger store failed, is the node running? %s", err)
+	}
+	ledger.DefaultLedger = new(ledger.Ledger)
+	ledger.DefaultLedger.Store = store
+	ledger.DefaultLedger.Store.InitLedgerStore(ledger.DefaultLedger)
+	transaction.TxStore = store
+	return nil
+}
+
+// initBlockchain loads the chain on top of the genesis block like the node
+// does at startup
+func initBlockchain(c *cli.Context) error {
+	var bookKeepers []*crypto.PubKey
+	if config.Parameters.ConsensusType == "solo" {
+		wallet := account.Open(c.String("wallet"), WalletPassword(c.String("password")))
+		if wallet == nil {
+			return errors.New("open wallet failed")
+		}
+		var err error
+		if bookKeepers, err = wallet.GetBookKeepers(); err != nil {
+			return err
+		}
+	} else {
+		bookKeepers = account.GetBookKeepers()
+	}
+	ledger.StandbyBookKeepers = bookKeepers
+	blockChain, err := ledger.NewBlockchainWithGenesisBlock(bookKeepers)
+	if err != nil {
+		return err
+	}
+	ledger.DefaultLedger.Blockchain = blockChain
+
+	ChainStore.DefaultEventStore, err = ChainStore.NewEventStore()
+	if err != nil {
+		return err
+	}
+	return ChainStore.DefaultEventStore.DeleteEventNotifyAbove(ledger.DefaultLedger.Store.GetHeight())
+}
+
+// interruptChan is closed on SIGINT or SIGTERM
+func interruptChan() <-chan struct{} {
+	quit := make(chan struct{})
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sc
+		fmt.Println("\nInterrupted, finishing the current block...")
+		close(quit)
+	}()
+	return quit
+}
+
+func exportAction(c *cli.Context) error {
+	file := c.String("file")
+	if file == "" {
+		fmt.Println("missing flag [--file]")
+		return nil
+	}
+	start := uint32(c.Uint("start"))
+	end := uint32(math.MaxUint32)
+	if c.IsSet("end") {
+		end = uint32(c.Uint("end"))
+	}
+	if end < start {
+		fmt.Println("invalid block range [--start, --end]")
+		return nil
+	}
+	if err := openLedger(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	defer ledger.DefaultLedger.Store.Close()
+
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	defer f.Close()
+	w, err := archive.NewWriter(f)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+
+	begin := time.Now()
+	last := begin
+	err = archive.Export(ledger.DefaultLedger.Store, w, start, end, interruptChan(), func(height uint32) {
+		if now := time.Now(); now.Sub(last) >= progressInterval {
+			last = now
+			fmt.Printf("Exported block %d, %.0f blocks/s\n", height, float64(w.Count())/now.Sub(begin).Seconds())
+		}
+	})
+	fmt.Printf("%d blocks exported to %s in %.1fs\n", w.Count(), file, time.Since(begin).Seconds())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	return err
+}
+
+func importAction(c *cli.Context) error {
+	file := c.String("file")
+	if file == "" {
+		fmt.Println("missing flag [--file]")
+		return nil
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	r, err := archive.NewReader(f)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+
+	if err := openLedger(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	defer ledger.DefaultLedger.Store.Close()
+	if err := initBlockchain(c); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	startHeight := ledger.DefaultLedger.Store.GetHeight()
+	fmt.Printf("Importing %s on top of block %d\n", file, startHeight)
+
+	begin := time.Now()
+	last := begin
+	var imported uint32
+	err = archive.Import(ledger.DefaultLedger, r, interruptChan(), func(height uint32) {
+		imported++
+		if now := time.Now(); now.Sub(last) >= progressInterval {
+			last = now
+			fmt.Printf("Imported block %d, %.1f%%, %.0f blocks/s\n", height,
+				float64(r.Offset())*100/float64(info.Size()), float64(imported)/now.Sub(begin).Seconds())
+		}
+	})
+	fmt.Printf("%d blocks imported in %.1fs, current height %d\n", imported,
+		time.Since(begin).Seconds(), ledger.DefaultLedger.Store.GetHeight())
+	if err == archive.ErrTruncated {
+		fmt.Println("The archive ends with an incomplete block")
+		return nil
+	}
+	if err == archive.ErrInterrupted {
+		fmt.Println("Run the import again to resume")
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	return err
+}
+
+func NewCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "chain",
+		Usage:       "export and import the blocks of the local chain",
+		Description: "With nodectl chain, you could export the blocks of a stopped node to an archive and import them into another node.",
+		ArgsUsage:   "[args]",
+		Subcommands: []cli.Command{
+			{
+				Name:   "export",
+				Usage:  "write the stored blocks to an archive",
+				Action: exportAction,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "file, f",
+						Usage: "archive file",
+					},
+					cli.UintFlag{
+						Name:  "start",
+						Usage: "first block height",
+					},
+					cli.UintFlag{
+						Name:  "end",
+						Usage: "last block height, the current height by default",
+					},
+				},
+			},
+			{
+				Name:   "import",
+				Usage:  "verify and store the blocks of an archive, an interrupted import resumes when run again",
+				Action: importAction,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "file, f",
+						Usage: "archive file",
+					},
+					cli.StringFlag{
+						Name:  "wallet, w",
+						Usage: "wallet name, solo consensus only",
+						Value: account.WalletFileName,
+					},
+					cli.StringFlag{
+						Name:  "password, p",
+						Usage: "wallet password, solo consensus only",
+					},
+				},
+			},
+		},
+		OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+			PrintError(c, err, "chain")
+			return cli.NewExitError("", 1)
+		},
+	}
+}
@@ -160,6 +160,9 @@ func NewCommand() *cli.Command {
 				Usage: "version of connected remote node",
 			},
 		},
+		Subcommands: []cli.Command{
+			newProofCommand(),
+		},
 		Action: infoAction,
 		OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
 			PrintError(c, err, "info")
@@ -0,0 +1,157 @@
+package info
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	. "github.com/Ontology/cli/common"
+	"github.com/Ontology/net/httpjsonrpc"
+
+	"github.com/urfave/cli"
+)
+
+type proofResponse struct {
+	Result json.RawMessage `json:"result"`
+}
+
+type proofType struct {
+	Type string
+}
+
+// verifyProof checks a merkle, consistency or state proof, the proof is the
+// result of getmerkleproof, getconsistencyproof, getaccountproof or
+// getstorageproof. The root the proof leads to must be the trusted root, the
+// root sent with the proof only comes from the node being checked.
+func verifyProof(data []byte, root string) error {
+	if root == "" {
+		return errors.New("no trusted root to verify the proof against")
+	}
+	var resp proofResponse
+	if err := json.Unmarshal(data, &resp); err == nil && len(resp.Result) > 0 {
+		data = resp.Result
+	}
+	var t proofType
+	if err := json.Unmarshal(data, &t); err != nil {
+		return err
+	}
+
+	switch t.Type {
+	case "MerkleProof":
+		var proof httpjsonrpc.MerkleProof
+		if err := json.Unmarshal(data, &proof); err != nil {
+			return err
+		}
+		if root != proof.BlockRoot {
+			return errors.New("block root of the proof differs from the trusted root")
+		}
+		return httpjsonrpc.VerifyMerkleProof(&proof)
+	case "ConsistencyProof":
+		var proof httpjsonrpc.ConsistencyProof
+		if err := json.Unmarshal(data, &proof); err != nil {
+			return err
+		}
+		if root != proof.NewRoot {
+			return errors.New("new root of the proof differs from the trusted root")
+		}
+		return httpjsonrpc.VerifyConsistencyProof(&proof)
+	case "StateProof":
+		var proof httpjsonrpc.StateProof
+		if err := json.Unmarshal(data, &proof); err != nil {
+			return err
+		}
+		if root != proof.StateRoot {
+			return errors.New("state root of the proof differs from the trusted root")
+		}
+		return httpjsonrpc.VerifyStateProof(&proof)
+	default:
+		return errors.New("unknown proof type")
+	}
+}
+
+func proofAction(c *cli.Context) (err error) {
+	if c.NumFlags() == 0 {
+		cli.ShowSubcommandHelp(c)
+		return nil
+	}
+	file := c.String("file")
+	txhash := c.String("txhash")
+	from := c.Int("from")
+	to := c.Int("to")
+	root := c.String("root")
+	if root == "" {
+		fmt.Fprintln(os.Stderr, "A trusted root taken from a header obtained separately is required")
+		return errors.New("missing trusted root")
+	}
+
+	var data []byte
+	switch {
+	case file != "":
+		data, err = ioutil.ReadFile(file)
+	case txhash != "":
+		params := []interface{}{txhash}
+		if to != -1 {
+			params = append(params, to)
+		}
+		data, err = httpjsonrpc.Call(Address(), "getmerkleproof", 0, params)
+	case from != -1 && to != -1:
+		data, err = httpjsonrpc.Call(Address(), "getconsistencyproof", 0, []interface{}{from, to})
+	default:
+		cli.ShowSubcommandHelp(c)
+		return nil
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	if file == "" {
+		FormatOutput(data)
+	}
+
+	if err := verifyProof(data, root); err != nil {
+		fmt.Fprintln(os.Stderr, "Proof verification failed:", err)
+		return err
+	}
+	fmt.Println("Proof verification succeeded")
+	return nil
+}
+
+func newProofCommand() cli.Command {
+	return cli.Command{
+		Name:        "proof",
+		Usage:       "verify merkle and state proofs",
+		Description: "With nodectl info proof, you could fetch and verify inclusion and consistency proofs of the block merkle tree, and verify saved state proofs against a trusted block or state root.",
+		ArgsUsage:   "[args]",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "file, f",
+				Usage: "verify the proof saved in file offline",
+			},
+			cli.StringFlag{
+				Name:  "txhash, t",
+				Usage: "fetch and verify the inclusion proof of a transaction",
+			},
+			cli.IntFlag{
+				Name:  "from",
+				Usage: "old block height of the consistency proof",
+				Value: -1,
+			},
+			cli.IntFlag{
+				Name:  "to",
+				Usage: "new block height of the consistency proof, or the root height of the inclusion proof",
+				Value: -1,
+			},
+			cli.StringFlag{
+				Name:  "root, r",
+				Usage: "trusted block or state root the proof must lead to, required",
+			},
+		},
+		Action: proofAction,
+		OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+			PrintError(c, err, "proof")
+			return cli.NewExitError("", 1)
+		},
+	}
+}
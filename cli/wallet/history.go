@@ -0,0 +1,90 @@
+package wallet
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Ontology/account"
+	. "github.com/Ontology/cli/common"
+	"github.com/Ontology/common/password"
+	"github.com/Ontology/net/httpjsonrpc"
+
+	"github.com/urfave/cli"
+)
+
+// defaultAddress returns the address of the default account of a wallet
+func defaultAddress(name, passwd string) (string, error) {
+	if passwd == "" {
+		tmppasswd, err := password.GetPassword()
+		if err != nil {
+			return "", err
+		}
+		passwd = string(tmppasswd)
+	}
+	wallet := account.Open(name, []byte(passwd))
+	if wallet == nil {
+		return "", fmt.Errorf("failed to open wallet: %s", name)
+	}
+	acct, err := wallet.GetDefaultAccount()
+	if err != nil {
+		return "", err
+	}
+	return acct.ProgramHash.ToAddress()
+}
+
+func historyAction(c *cli.Context) error {
+	address := c.String("address")
+	if address == "" {
+		var err error
+		address, err = defaultAddress(c.String("name"), c.String("password"))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return err
+		}
+	}
+	resp, err := httpjsonrpc.Call(Address(), "getaddresshistory", 0,
+		[]interface{}{address, c.Int("limit"), c.String("cursor")})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	FormatOutput(resp)
+	return nil
+}
+
+func newHistoryCommand() cli.Command {
+	return cli.Command{
+		Name:        "history",
+		Usage:       "list the transfers of an address",
+		Description: "With nodectl wallet history, you could list the transactions touching an address, the newest first. The node must run with AddressHistory enabled.",
+		ArgsUsage:   "[args]",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "address",
+				Usage: "address to look up, the default account of the wallet when not given",
+			},
+			cli.StringFlag{
+				Name:  "name, n",
+				Usage: "wallet name",
+				Value: account.WalletFileName,
+			},
+			cli.StringFlag{
+				Name:  "password, p",
+				Usage: "wallet password",
+			},
+			cli.IntFlag{
+				Name:  "limit",
+				Usage: "number of records per page",
+			},
+			cli.StringFlag{
+				Name:  "cursor",
+				Usage: "cursor returned by the previous page",
+			},
+		},
+		Action: historyAction,
+		OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+			PrintError(c, err, "history")
+			return cli.NewExitError("", 1)
+		},
+	}
+}
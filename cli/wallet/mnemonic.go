@@ -0,0 +1,83 @@
+package wallet
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Ontology/account"
+	. "github.com/Ontology/cli/common"
+	. "github.com/Ontology/common"
+	"github.com/Ontology/net/httpjsonrpc"
+
+	"github.com/urfave/cli"
+)
+
+// createHDWallet creates a wallet from a new mnemonic and prints the
+// mnemonic, which is the only backup the wallet needs
+func createHDWallet(c *cli.Context, name string, passwd []byte) *account.ClientImpl {
+	mnemonic, err := account.NewMnemonic(account.DefaultMnemonicEntropy)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil
+	}
+	wallet, err := account.CreateWithMnemonic(name, passwd, mnemonic, c.String("passphrase"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil
+	}
+	fmt.Println("mnemonic:     ", mnemonic)
+	fmt.Println("CAUTION: write down the mnemonic, it restores all the accounts of the wallet")
+	return wallet
+}
+
+// restoreWallet creates a wallet from a mnemonic read from the terminal and
+// discovers its used accounts on the node
+func restoreWallet(c *cli.Context, name string, passwd []byte) *account.ClientImpl {
+	fmt.Print("Mnemonic:")
+	mnemonic, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil
+	}
+	wallet, err := account.CreateWithMnemonic(name, passwd, mnemonic, c.String("passphrase"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil
+	}
+	count, err := wallet.DiscoverAccounts(c.Int("gaplimit"), rpcAccountUsed)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "account discovery failed:", err)
+		return wallet
+	}
+	fmt.Printf("%d account(s) restored\n", count)
+	return wallet
+}
+
+// rpcAccountUsed reports whether the node has a state for the account
+func rpcAccountUsed(programHash Uint160) (bool, error) {
+	address, err := programHash.ToAddress()
+	if err != nil {
+		return false, err
+	}
+	resp, err := httpjsonrpc.Call(Address(), "getaccountstate", 0, []interface{}{address})
+	if err != nil {
+		return false, err
+	}
+	r := make(map[string]interface{})
+	if err := json.Unmarshal(resp, &r); err != nil {
+		return false, err
+	}
+	switch result := r["result"].(type) {
+	case map[string]interface{}:
+		return true, nil
+	case string:
+		if result == "Account not found" {
+			return false, nil
+		}
+		return false, errors.New(result)
+	}
+	return false, errors.New("unexpected getaccountstate response")
+}
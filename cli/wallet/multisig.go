@@ -0,0 +1,297 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Ontology/account"
+	. "github.com/Ontology/cli/common"
+	. "github.com/Ontology/common"
+	"github.com/Ontology/core/contract"
+	"github.com/Ontology/core/transaction"
+	"github.com/Ontology/crypto"
+
+	"github.com/urfave/cli"
+)
+
+func openWallet(c *cli.Context) (*account.ClientImpl, error) {
+	name := c.String("name")
+	wallet := account.Open(name, WalletPassword(c.String("password")))
+	if wallet == nil {
+		return nil, fmt.Errorf("failed to open wallet: %s", name)
+	}
+	return wallet, nil
+}
+
+func watchAction(c *cli.Context) error {
+	address := c.String("address")
+	if address == "" {
+		fmt.Println("missing flag [--address]")
+		return nil
+	}
+	programHash, err := ToScriptHash(address)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	wallet, err := openWallet(c)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	if err := wallet.AddWatchOnly(programHash); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	fmt.Println("watching:     ", address)
+	return nil
+}
+
+func multisigAction(c *cli.Context) error {
+	var pubKeys []*crypto.PubKey
+	for _, key := range strings.Split(c.String("pubkeys"), ",") {
+		if key = strings.TrimSpace(key); key == "" {
+			continue
+		}
+		buf, err := hex.DecodeString(key)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid public key:", key)
+			return err
+		}
+		pubKey, err := crypto.DecodePoint(buf)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid public key:", key)
+			return err
+		}
+		pubKeys = append(pubKeys, pubKey)
+	}
+	m := c.Int("m")
+	if m < 1 || m > len(pubKeys) {
+		fmt.Println("invalid flag [-m] or [--pubkeys]")
+		return nil
+	}
+	wallet, err := openWallet(c)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	ct, err := wallet.CreateMultiSigAccount(m, pubKeys)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	address, _ := ct.ProgramHash.ToAddress()
+	fmt.Printf("%d-of-%d address: %s\n", m, len(pubKeys), address)
+	return nil
+}
+
+// newContext starts the context of an unsigned transaction signed by the
+// given addresses
+func newContext(txHex string, signers string) (*contract.ContractContext, error) {
+	raw, err := hex.DecodeString(txHex)
+	if err != nil {
+		return nil, err
+	}
+	txn := new(transaction.Transaction)
+	if err := txn.DeserializeUnsigned(bytes.NewReader(raw)); err != nil {
+		return nil, err
+	}
+	var programHashes []Uint160
+	for _, address := range strings.Split(signers, ",") {
+		if address = strings.TrimSpace(address); address == "" {
+			continue
+		}
+		programHash, err := ToScriptHash(address)
+		if err != nil {
+			return nil, err
+		}
+		programHashes = append(programHashes, programHash)
+	}
+	if len(programHashes) == 0 {
+		return nil, errors.New("missing flag [--signers]")
+	}
+	// the transaction verification expects the program hashes in order
+	sort.Slice(programHashes, func(i, j int) bool {
+		return programHashes[i].CompareTo(programHashes[j]) < 0
+	})
+	return &contract.ContractContext{
+		Data:            txn,
+		ProgramHashes:   programHashes,
+		Codes:           make([][]byte, len(programHashes)),
+		Parameters:      make([][][]byte, len(programHashes)),
+		MultiPubkeyPara: make([][]contract.PubkeyParameter, len(programHashes)),
+	}, nil
+}
+
+// printContext prints the signed transaction once the context is complete
+func printContext(context *contract.ContractContext) error {
+	if !context.IsCompleted() {
+		fmt.Println("Transaction needs more signatures")
+		return nil
+	}
+	txn, ok := context.Data.(*transaction.Transaction)
+	if !ok {
+		return errors.New("context data is not a transaction")
+	}
+	txn.SetPrograms(context.GetPrograms())
+	var buffer bytes.Buffer
+	if err := txn.Serialize(&buffer); err != nil {
+		return err
+	}
+	fmt.Println("Transaction is completely signed:")
+	fmt.Println(hex.EncodeToString(buffer.Bytes()))
+	return nil
+}
+
+func signAction(c *cli.Context) error {
+	file := c.String("file")
+	if file == "" {
+		fmt.Println("missing flag [--file]")
+		return nil
+	}
+	var context *contract.ContractContext
+	var references []*account.UTXOReference
+	var err error
+	if txHex := c.String("tx"); txHex != "" {
+		context, err = newContext(txHex, c.String("signers"))
+	} else {
+		context, references, err = account.LoadContractContext(file)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	wallet, err := openWallet(c)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	if !wallet.Sign(context) {
+		fmt.Println("Wallet holds no key of the transaction")
+	}
+	if err := account.SaveContractContext(file, context, references); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	return printContext(context)
+}
+
+func mergeAction(c *cli.Context) error {
+	out := c.String("out")
+	if out == "" || c.NArg() < 2 {
+		fmt.Println("missing flag [--out] or context files")
+		return nil
+	}
+	context, references, err := account.LoadContractContext(c.Args()[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	for _, file := range c.Args()[1:] {
+		other, _, err := account.LoadContractContext(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return err
+		}
+		if err := context.Merge(other); err != nil {
+			fmt.Fprintln(os.Stderr, file, ":", err)
+			return err
+		}
+	}
+	if err := account.SaveContractContext(out, context, references); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	return printContext(context)
+}
+
+func walletFlags(flags ...cli.Flag) []cli.Flag {
+	return append(flags,
+		cli.StringFlag{
+			Name:  "name, n",
+			Usage: "wallet name",
+			Value: account.WalletFileName,
+		},
+		cli.StringFlag{
+			Name:  "password, p",
+			Usage: "wallet password",
+		},
+	)
+}
+
+func newMultiSigCommands() []cli.Command {
+	onUsageError := func(c *cli.Context, err error, isSubcommand bool) error {
+		PrintError(c, err, "wallet")
+		return cli.NewExitError("", 1)
+	}
+	return []cli.Command{
+		{
+			Name:        "watch",
+			Usage:       "watch an address without its key",
+			Description: "With nodectl wallet watch, you could add a watch-only address to the wallet.",
+			Flags: walletFlags(cli.StringFlag{
+				Name:  "address",
+				Usage: "address to watch",
+			}),
+			Action:       watchAction,
+			OnUsageError: onUsageError,
+		},
+		{
+			Name:        "multisig",
+			Usage:       "create an m-of-n multi signature account",
+			Description: "With nodectl wallet multisig, you could add the multi signature contract of a list of public keys to the wallet.",
+			Flags: walletFlags(
+				cli.IntFlag{
+					Name:  "m",
+					Usage: "number of signatures the account needs",
+				},
+				cli.StringFlag{
+					Name:  "pubkeys",
+					Usage: "comma separated public keys of the account",
+				},
+			),
+			Action:       multisigAction,
+			OnUsageError: onUsageError,
+		},
+		{
+			Name:        "sign",
+			Usage:       "sign a transaction context file",
+			Description: "With nodectl wallet sign, you could add the signatures of the wallet to a transaction context file, which is created from an unsigned transaction with --tx.",
+			Flags: walletFlags(
+				cli.StringFlag{
+					Name:  "file, f",
+					Usage: "transaction context file",
+				},
+				cli.StringFlag{
+					Name:  "tx",
+					Usage: "unsigned transaction to create the context file from",
+				},
+				cli.StringFlag{
+					Name:  "signers",
+					Usage: "comma separated addresses signing the transaction created with --tx",
+				},
+			),
+			Action:       signAction,
+			OnUsageError: onUsageError,
+		},
+		{
+			Name:        "merge",
+			Usage:       "merge the signatures of transaction context files",
+			Description: "With nodectl wallet merge, you could combine the signatures co-signers added to copies of a transaction context file.",
+			ArgsUsage:   "<file> <file>...",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "out, o",
+					Usage: "merged transaction context file",
+				},
+			},
+			Action:       mergeAction,
+			OnUsageError: onUsageError,
+		},
+	}
+}
@@ -0,0 +1,61 @@
+package wallet
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Ontology/account"
+	. "github.com/Ontology/cli/common"
+	. "github.com/Ontology/common"
+	"github.com/Ontology/common/password"
+
+	"github.com/urfave/cli"
+)
+
+func upgradeAction(c *cli.Context) error {
+	name := c.String("name")
+	if !FileExisted(name) {
+		fmt.Printf("Wallet '%s' does not exist\n", name)
+		os.Exit(1)
+	}
+	passwd := c.String("password")
+	if passwd == "" {
+		tmppasswd, err := password.GetPassword()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		passwd = string(tmppasswd)
+	}
+	if err := account.Upgrade(name, []byte(passwd)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	fmt.Printf("Wallet '%s' upgraded to version %d\n", name, account.WalletVersion)
+	return nil
+}
+
+func newUpgradeCommand() cli.Command {
+	return cli.Command{
+		Name:        "upgrade",
+		Usage:       "upgrade the wallet file format",
+		Description: "With nodectl wallet upgrade, you could encrypt an old wallet again with a scrypt derived key and AES-GCM, the wallet file is replaced in place. Opening an old wallet upgrades it as well.",
+		ArgsUsage:   "[args]",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "name, n",
+				Usage: "wallet name",
+				Value: account.WalletFileName,
+			},
+			cli.StringFlag{
+				Name:  "password, p",
+				Usage: "wallet password",
+			},
+		},
+		Action: upgradeAction,
+		OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+			PrintError(c, err, "upgrade")
+			return cli.NewExitError("", 1)
+		},
+	}
+}
@@ -25,13 +25,14 @@ func walletAction(c *cli.Context) error {
 	// wallet name is wallet.dat by default
 	name := c.String("name")
 	create := c.Bool("create")
+	restore := c.Bool("mnemonic")
 	list := c.Bool("list")
 	passwd := c.String("password")
 	if name == "" {
 		fmt.Println("Invalid wallet name.")
 		os.Exit(1)
 	}
-	if FileExisted(name) && create {
+	if FileExisted(name) && (create || restore) {
 		fmt.Printf("CAUTION: '%s' already exists!\n", name)
 		os.Exit(1)
 	}
@@ -39,7 +40,7 @@ func walletAction(c *cli.Context) error {
 	if passwd == "" {
 		var err error
 		var tmppasswd []byte
-		if create {
+		if create || restore {
 			tmppasswd, err = password.GetConfirmedPassword()
 		} else {
 			tmppasswd, err = password.GetPassword()
@@ -51,7 +52,11 @@ func walletAction(c *cli.Context) error {
 		passwd = string(tmppasswd)
 	}
 	var wallet *account.ClientImpl
-	if create {
+	if restore {
+		wallet = restoreWallet(c, name, []byte(passwd))
+	} else if create && c.Bool("hd") {
+		wallet = createHDWallet(c, name, []byte(passwd))
+	} else if create {
 		wallet = account.Create(name, []byte(passwd))
 	} else {
 		// list wallet or change wallet password
@@ -81,6 +86,12 @@ func walletAction(c *cli.Context) error {
 	fmt.Println("public key:   ", ToHexString(encodedPubKey))
 	fmt.Println("program hash: ", ToHexString(programHash.ToArray()))
 	fmt.Println("address:      ", address)
+	if wallet.IsHD() {
+		for i, ac := range wallet.GetHDAccounts() {
+			hdAddress, _ := ac.ProgramHash.ToAddress()
+			fmt.Printf("account %d:     %s\n", i, hdAddress)
+		}
+	}
 	asset := c.String("asset")
 	if list && asset != "" {
 		var buffer bytes.Buffer
@@ -132,6 +143,23 @@ func NewCommand() *cli.Command {
 				Name:  "list, l",
 				Usage: "list wallet information",
 			},
+			cli.BoolFlag{
+				Name:  "hd",
+				Usage: "create a wallet whose accounts derive from a new mnemonic",
+			},
+			cli.BoolFlag{
+				Name:  "mnemonic, m",
+				Usage: "restore a wallet from its mnemonic",
+			},
+			cli.StringFlag{
+				Name:  "passphrase",
+				Usage: "optional mnemonic passphrase",
+			},
+			cli.IntFlag{
+				Name:  "gaplimit",
+				Usage: "number of unused accounts ending the account discovery",
+				Value: account.DefaultGapLimit,
+			},
 			cli.BoolFlag{
 				Name:  "changepassword",
 				Usage: "change wallet password",
@@ -150,6 +178,10 @@ func NewCommand() *cli.Command {
 				Usage: "wallet password",
 			},
 		},
+		Subcommands: append([]cli.Command{
+			newHistoryCommand(),
+			newUpgradeCommand(),
+		}, newMultiSigCommands()...),
 		Action: walletAction,
 		OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
 			PrintError(c, err, "wallet")
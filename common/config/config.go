@@ -49,6 +49,17 @@ type Configuration struct {
 	MaxHdrSyncReqs  int      `json:"MaxConcurrentSyncHeaderReqs"`
 	ConsensusType   string   `json:"ConsensusType"`
 	SystemFee       map[string]int64 `json:"SystemFee"`
+	MaxCallDepth    int      `json:"MaxCallDepth"`
+	AddressHistory  bool     `json:"AddressHistory"`
+	TxPoolCapacity  int      `json:"TxPoolCapacity"`
+	TxPoolExpiry    uint32   `json:"TxPoolExpiryBlocks"`
+	TxPoolPerSender int      `json:"TxPoolPerSender"`
+	SnapshotInterval uint32  `json:"SnapshotInterval"`
+	FastSync        bool     `json:"FastSync"`
+	StateTrieHeight uint32   `json:"StateTrieHeight"` // The height from which the state trie holds every snapshot state, 0 never
+	PruneBlocks     uint32   `json:"PruneBlocks"` // The number of recent block bodies kept, 0 keeps all
+	ClaimWindow     uint32   `json:"ClaimWindowBlocks"` // Spent coins older than this can't be claimed any more, 0 keeps all
+	StoreBackend    string   `json:"StoreBackend"` // leveldb (default), boltdb or memory
 }
 
 type ConfigFile struct {
@@ -5,6 +5,7 @@ type InventoryType byte
 const (
 	TRANSACTION InventoryType = 0x01
 	BLOCK InventoryType = 0x02
+	FILTEREDBLOCK InventoryType = 0x03
 	CONSENSUS InventoryType = 0xe0
 )
 
@@ -6,6 +6,7 @@ import (
 	"github.com/Ontology/common/config"
 	"github.com/Ontology/common/log"
 	"github.com/Ontology/consensus/dbft"
+	"github.com/Ontology/consensus/sbft"
 	"github.com/Ontology/consensus/solo"
 	"github.com/Ontology/net"
 	"strings"
@@ -26,6 +27,7 @@ func Log(message string) {
 const (
 	CONSENSUS_DBFT = "dbft"
 	CONSENSUS_SOLO  = "solo"
+	CONSENSUS_SBFT = "sbft"
 )
 
 var ConsensusMgr = NewConsensuManager()
@@ -47,6 +49,8 @@ func (this *ConsensusManager)NewConsensusService(client cl.Client , localNet net
 		consensus = dbft.NewDbftService(client, "dbft", localNet)
 	case CONSENSUS_SOLO:
 		consensus = solo.NewSoloService(client, localNet)
+	case CONSENSUS_SBFT:
+		consensus = sbft.NewSbftService(client, localNet)
 	}
 	log.Infof("ConsensusType:%s", consensusType)
 	return consensus
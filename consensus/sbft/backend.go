@@ -0,0 +1,28 @@
+package sbft
+
+import (
+	"github.com/Ontology/core/ledger"
+	"time"
+)
+
+// Backend is the environment the SBFT state machine runs in. The service
+// implements it on top of the ledger and the p2p network, tests implement
+// it with an in-process simulated network.
+type Backend interface {
+	// Broadcast sends the message to all other bookkeepers. It must not
+	// deliver messages back into the core synchronously.
+	Broadcast(message ConsensusMessage)
+	// Propose builds a new block proposal for the current height.
+	Propose() (*ledger.Block, error)
+	// VerifyProposal checks a proposal received from the primary.
+	VerifyProposal(block *ledger.Block) error
+	// Sign signs the block header with the local bookkeeper key.
+	Sign(block *ledger.Block) ([]byte, error)
+	// VerifySignature checks a header signature of the bookkeeper at index.
+	VerifySignature(block *ledger.Block, index int, signature []byte) error
+	// Commit finalizes the block with the collected bookkeeper signatures.
+	Commit(block *ledger.Block, signatures map[int][]byte) error
+	// ResetTimer arms the consensus timer. When it fires the backend calls
+	// SbftCore.Timeout with the same height and view.
+	ResetTimer(height uint32, view uint32, d time.Duration)
+}
@@ -0,0 +1,51 @@
+package sbft
+
+import (
+	. "github.com/Ontology/common"
+	ser "github.com/Ontology/common/serialization"
+	"io"
+)
+
+// Commit carries the bookkeeper's signature over the prepared block header
+type Commit struct {
+	msgData   ConsensusMessageData
+	BlockHash Uint256
+	Signature []byte
+}
+
+func (c *Commit) Serialize(w io.Writer) error {
+	if err := c.msgData.Serialize(w); err != nil {
+		return err
+	}
+	if _, err := c.BlockHash.Serialize(w); err != nil {
+		return err
+	}
+	return ser.WriteVarBytes(w, c.Signature)
+}
+
+func (c *Commit) Deserialize(r io.Reader) error {
+	if err := c.msgData.Deserialize(r); err != nil {
+		return err
+	}
+	if err := c.BlockHash.Deserialize(r); err != nil {
+		return err
+	}
+	signature, err := ser.ReadVarBytes(r)
+	if err != nil {
+		return err
+	}
+	c.Signature = signature
+	return nil
+}
+
+func (c *Commit) Type() ConsensusMessageType {
+	return c.msgData.Type
+}
+
+func (c *Commit) ViewNumber() uint32 {
+	return c.msgData.ViewNumber
+}
+
+func (c *Commit) ConsensusMessageData() *ConsensusMessageData {
+	return &(c.msgData)
+}
@@ -0,0 +1,63 @@
+package sbft
+
+import (
+	"bytes"
+	"errors"
+	"github.com/Ontology/common/log"
+	ser "github.com/Ontology/common/serialization"
+	"io"
+)
+
+type ConsensusMessage interface {
+	ser.SerializableData
+	Type() ConsensusMessageType
+	ViewNumber() uint32
+	ConsensusMessageData() *ConsensusMessageData
+}
+
+type ConsensusMessageData struct {
+	Type       ConsensusMessageType
+	ViewNumber uint32
+}
+
+func DeserializeMessage(data []byte) (ConsensusMessage, error) {
+	if len(data) == 0 {
+		return nil, errors.New("The message is empty.")
+	}
+	var message ConsensusMessage
+	switch ConsensusMessageType(data[0]) {
+	case PrePrepareMsg:
+		message = &PrePrepare{}
+	case PrepareMsg:
+		message = &Prepare{}
+	case CommitMsg:
+		message = &Commit{}
+	case ViewChangeMsg:
+		message = &ViewChange{}
+	default:
+		return nil, errors.New("The message is invalid.")
+	}
+	if err := message.Deserialize(bytes.NewReader(data)); err != nil {
+		log.Errorf("[DeserializeMessage] type %x Deserialize Error: %s", data[0], err)
+		return nil, err
+	}
+	return message, nil
+}
+
+func (cd *ConsensusMessageData) Serialize(w io.Writer) error {
+	if _, err := w.Write([]byte{byte(cd.Type)}); err != nil {
+		return err
+	}
+	return ser.WriteUint32(w, cd.ViewNumber)
+}
+
+// read data to reader
+func (cd *ConsensusMessageData) Deserialize(r io.Reader) error {
+	msgType, err := ser.ReadBytes(r, 1)
+	if err != nil {
+		return err
+	}
+	cd.Type = ConsensusMessageType(msgType[0])
+	cd.ViewNumber, err = ser.ReadUint32(r)
+	return err
+}
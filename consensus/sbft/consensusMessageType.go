@@ -0,0 +1,10 @@
+package sbft
+
+type ConsensusMessageType byte
+
+const (
+	PrePrepareMsg ConsensusMessageType = 0x30
+	PrepareMsg    ConsensusMessageType = 0x31
+	CommitMsg     ConsensusMessageType = 0x32
+	ViewChangeMsg ConsensusMessageType = 0x33
+)
@@ -0,0 +1,82 @@
+package sbft
+
+import (
+	ser "github.com/Ontology/common/serialization"
+	"github.com/Ontology/core/ledger"
+	tx "github.com/Ontology/core/transaction"
+	. "github.com/Ontology/errors"
+	"io"
+)
+
+// PrePrepare carries the primary's block proposal for a view. The header is
+// sent unsigned, bookkeeper signatures are collected in the commit phase.
+type PrePrepare struct {
+	msgData ConsensusMessageData
+	Block   *ledger.Block
+}
+
+func (pp *PrePrepare) Serialize(w io.Writer) error {
+	if err := pp.msgData.Serialize(w); err != nil {
+		return err
+	}
+	return serializeProposal(w, pp.Block)
+}
+
+func (pp *PrePrepare) Deserialize(r io.Reader) error {
+	if err := pp.msgData.Deserialize(r); err != nil {
+		return err
+	}
+	block, err := deserializeProposal(r)
+	if err != nil {
+		return err
+	}
+	pp.Block = block
+	return nil
+}
+
+func (pp *PrePrepare) Type() ConsensusMessageType {
+	return pp.msgData.Type
+}
+
+func (pp *PrePrepare) ViewNumber() uint32 {
+	return pp.msgData.ViewNumber
+}
+
+func (pp *PrePrepare) ConsensusMessageData() *ConsensusMessageData {
+	return &(pp.msgData)
+}
+
+func serializeProposal(w io.Writer, block *ledger.Block) error {
+	if err := block.Header.SerializeUnsigned(w); err != nil {
+		return NewDetailErr(err, ErrNoCode, "[PrePrepare] header serialization failed")
+	}
+	if err := ser.WriteVarUint(w, uint64(len(block.Transactions))); err != nil {
+		return NewDetailErr(err, ErrNoCode, "[PrePrepare] length serialization failed")
+	}
+	for _, t := range block.Transactions {
+		if err := t.Serialize(w); err != nil {
+			return NewDetailErr(err, ErrNoCode, "[PrePrepare] transactions serialization failed")
+		}
+	}
+	return nil
+}
+
+func deserializeProposal(r io.Reader) (*ledger.Block, error) {
+	header := new(ledger.Header)
+	if err := header.DeserializeUnsigned(r); err != nil {
+		return nil, NewDetailErr(err, ErrNoCode, "[PrePrepare] header deserialization failed")
+	}
+	length, err := ser.ReadVarUint(r, 0)
+	if err != nil {
+		return nil, NewDetailErr(err, ErrNoCode, "[PrePrepare] length deserialization failed")
+	}
+	transactions := make([]*tx.Transaction, length)
+	for i := 0; i < len(transactions); i++ {
+		var t tx.Transaction
+		if err := t.Deserialize(r); err != nil {
+			return nil, NewDetailErr(err, ErrNoCode, "[PrePrepare] transactions deserialization failed")
+		}
+		transactions[i] = &t
+	}
+	return &ledger.Block{Header: header, Transactions: transactions}, nil
+}
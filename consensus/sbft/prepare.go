@@ -0,0 +1,39 @@
+package sbft
+
+import (
+	. "github.com/Ontology/common"
+	"io"
+)
+
+// Prepare announces that a bookkeeper accepted the proposal with BlockHash
+type Prepare struct {
+	msgData   ConsensusMessageData
+	BlockHash Uint256
+}
+
+func (p *Prepare) Serialize(w io.Writer) error {
+	if err := p.msgData.Serialize(w); err != nil {
+		return err
+	}
+	_, err := p.BlockHash.Serialize(w)
+	return err
+}
+
+func (p *Prepare) Deserialize(r io.Reader) error {
+	if err := p.msgData.Deserialize(r); err != nil {
+		return err
+	}
+	return p.BlockHash.Deserialize(r)
+}
+
+func (p *Prepare) Type() ConsensusMessageType {
+	return p.msgData.Type
+}
+
+func (p *Prepare) ViewNumber() uint32 {
+	return p.msgData.ViewNumber
+}
+
+func (p *Prepare) ConsensusMessageData() *ConsensusMessageData {
+	return &(p.msgData)
+}
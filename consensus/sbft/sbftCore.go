@@ -0,0 +1,416 @@
+package sbft
+
+import (
+	"fmt"
+	. "github.com/Ontology/common"
+	"github.com/Ontology/common/log"
+	"github.com/Ontology/core/ledger"
+	"sync"
+	"time"
+)
+
+// SbftCore is the PBFT style agreement state machine for a single height.
+//
+// The primary of a view broadcasts a PrePrepare with its proposal, every
+// bookkeeper that accepts it broadcasts a Prepare. Once a bookkeeper sees M
+// prepares for the proposal it locks on the block, signs the header and
+// broadcasts a Commit. M valid signatures over the same header finalize the
+// block. A bookkeeper whose timer expires votes for the next view, it joins
+// a view change voted by f+1 others and enters the new view on M votes.
+//
+// A locked bookkeeper only prepares another block if f+1 view change votes
+// report a lock on that block from a later view, so two different blocks can
+// never collect M signatures at the same height.
+type SbftCore struct {
+	mu        sync.Mutex
+	backend   Backend
+	blockTime time.Duration
+
+	height     uint32
+	count      int
+	index      int
+	view       uint32
+	expected   uint32
+	timerView  uint32
+	proposed   bool
+	committed  bool
+	proposal   *ledger.Block
+	locked     *ledger.Block
+	lockedView uint32
+
+	prePrepares map[uint32]*PrePrepare
+	prepares    map[uint32]map[int]Uint256
+	viewChanges map[uint32]map[int]*ViewChange
+	blocks      map[Uint256]*ledger.Block
+	signatures  map[Uint256]map[int][]byte
+	unverified  map[Uint256]map[int][]byte
+}
+
+func NewSbftCore(backend Backend, blockTime time.Duration) *SbftCore {
+	return &SbftCore{
+		backend:   backend,
+		blockTime: blockTime,
+		index:     -1,
+	}
+}
+
+// M is the quorum size for count bookkeepers
+func M(count int) int {
+	return count - (count-1)/3
+}
+
+// F is the number of faulty bookkeepers tolerated with count bookkeepers
+func F(count int) int {
+	return (count - 1) / 3
+}
+
+func (c *SbftCore) Height() uint32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.height
+}
+
+func (c *SbftCore) View() uint32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.view
+}
+
+func (c *SbftCore) Committed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.committed
+}
+
+// Reset starts the agreement on height with count bookkeepers, index is the
+// position of the local bookkeeper.
+func (c *SbftCore) Reset(height uint32, count int, index int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.height = height
+	c.count = count
+	c.index = index
+	c.view = 0
+	c.expected = 0
+	c.committed = false
+	c.locked = nil
+	c.lockedView = 0
+	c.prePrepares = make(map[uint32]*PrePrepare)
+	c.prepares = make(map[uint32]map[int]Uint256)
+	c.viewChanges = make(map[uint32]map[int]*ViewChange)
+	c.blocks = make(map[Uint256]*ledger.Block)
+	c.signatures = make(map[Uint256]map[int][]byte)
+	c.unverified = make(map[Uint256]map[int][]byte)
+	c.startView(0)
+}
+
+func (c *SbftCore) primary(view uint32) int {
+	return int((c.height + view) % uint32(c.count))
+}
+
+func (c *SbftCore) timeout(view uint32) time.Duration {
+	if view > 16 {
+		view = 16
+	}
+	return c.blockTime << (view + 1)
+}
+
+func (c *SbftCore) resetTimer(view uint32, d time.Duration) {
+	c.timerView = view
+	c.backend.ResetTimer(c.height, view, d)
+}
+
+func (c *SbftCore) broadcast(msgType ConsensusMessageType, message ConsensusMessage) {
+	data := message.ConsensusMessageData()
+	data.Type = msgType
+	data.ViewNumber = c.view
+	c.backend.Broadcast(message)
+}
+
+func (c *SbftCore) startView(view uint32) {
+	c.view = view
+	if c.expected < view {
+		c.expected = view
+	}
+	c.proposed = false
+	c.proposal = nil
+	log.Info(fmt.Sprintf("[SbftCore] start view: height=%d view=%d primary=%d", c.height, view, c.primary(view)))
+
+	if c.primary(view) == c.index {
+		if view == 0 {
+			c.resetTimer(view, c.blockTime)
+		} else {
+			c.resetTimer(view, 0)
+		}
+		return
+	}
+	c.resetTimer(view, c.timeout(view))
+	if pp, ok := c.prePrepares[view]; ok {
+		c.onPrePrepare(c.primary(view), pp)
+	}
+}
+
+// Timeout handles an expired timer armed through Backend.ResetTimer
+func (c *SbftCore) Timeout(height uint32, view uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.committed || height != c.height || view != c.timerView {
+		return
+	}
+	if c.primary(c.view) == c.index && !c.proposed && c.expected == c.view {
+		c.propose()
+		return
+	}
+	c.requestViewChange()
+}
+
+func (c *SbftCore) propose() {
+	c.proposed = true
+	block := c.highestLocked()
+	if block == nil {
+		var err error
+		block, err = c.backend.Propose()
+		if err != nil {
+			log.Error("[SbftCore] propose block failed: ", err)
+			c.requestViewChange()
+			return
+		}
+	}
+	log.Info(fmt.Sprintf("[SbftCore] send pre-prepare: height=%d view=%d block=%x", c.height, c.view, block.Hash()))
+	c.broadcast(PrePrepareMsg, &PrePrepare{Block: block})
+	c.resetTimer(c.view, c.timeout(c.view))
+	c.acceptProposal(block)
+}
+
+// highestLocked returns the block locked in the latest view reported by the
+// view change votes that brought us into the current view.
+func (c *SbftCore) highestLocked() *ledger.Block {
+	block, view := c.locked, c.lockedView
+	for _, vc := range c.viewChanges[c.view] {
+		if vc.Locked != nil && (block == nil || vc.LockedView > view) {
+			block, view = vc.Locked, vc.LockedView
+		}
+	}
+	return block
+}
+
+func (c *SbftCore) requestViewChange() {
+	c.expected++
+	if c.expected <= c.view {
+		c.expected = c.view + 1
+	}
+	log.Info(fmt.Sprintf("[SbftCore] request view change: height=%d view=%d new view=%d", c.height, c.view, c.expected))
+
+	vc := &ViewChange{NewViewNumber: c.expected}
+	if c.locked != nil {
+		vc.Locked = c.locked
+		vc.LockedView = c.lockedView
+	}
+	c.broadcast(ViewChangeMsg, vc)
+	c.resetTimer(c.expected, c.timeout(c.expected))
+	c.onViewChange(c.index, vc)
+}
+
+// Receive handles a message sent by the bookkeeper at index from
+func (c *SbftCore) Receive(from int, message ConsensusMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.committed || from < 0 || from >= c.count || from == c.index {
+		return
+	}
+	switch m := message.(type) {
+	case *PrePrepare:
+		if m.ViewNumber() > c.view {
+			if from == c.primary(m.ViewNumber()) {
+				c.prePrepares[m.ViewNumber()] = m
+			}
+			return
+		}
+		c.onPrePrepare(from, m)
+	case *Prepare:
+		c.onPrepare(from, m)
+	case *Commit:
+		c.onCommit(from, m)
+	case *ViewChange:
+		c.onViewChange(from, m)
+	}
+}
+
+func (c *SbftCore) onPrePrepare(from int, message *PrePrepare) {
+	if message.ViewNumber() != c.view || from != c.primary(c.view) || c.proposal != nil || c.expected > c.view {
+		return
+	}
+	block := message.Block
+	if block == nil || block.Header == nil || block.Header.Height != c.height {
+		return
+	}
+	hash := block.Hash()
+	log.Info(fmt.Sprintf("[SbftCore] pre-prepare received: height=%d view=%d index=%d block=%x", c.height, c.view, from, hash))
+
+	if c.locked != nil && c.locked.Hash() != hash && !c.canUnlock(hash) {
+		log.Warn("[SbftCore] proposal conflicts with the locked block")
+		return
+	}
+	if err := c.backend.VerifyProposal(block); err != nil {
+		log.Warn("[SbftCore] proposal verification failed: ", err)
+		c.requestViewChange()
+		return
+	}
+	// the pre-prepare counts as the primary's prepare
+	c.addPrepare(c.view, from, hash)
+	c.broadcast(PrepareMsg, &Prepare{BlockHash: hash})
+	c.acceptProposal(block)
+}
+
+// canUnlock reports whether f+1 view change votes for the current view
+// report a lock on hash newer than ours.
+func (c *SbftCore) canUnlock(hash Uint256) bool {
+	votes := 0
+	for _, vc := range c.viewChanges[c.view] {
+		if vc.Locked != nil && vc.LockedView > c.lockedView && vc.Locked.Hash() == hash {
+			votes++
+		}
+	}
+	return votes > F(c.count)
+}
+
+func (c *SbftCore) acceptProposal(block *ledger.Block) {
+	hash := block.Hash()
+	c.proposal = block
+	c.blocks[hash] = block
+	c.addPrepare(c.view, c.index, hash)
+
+	for index, signature := range c.unverified[hash] {
+		c.addSignature(hash, index, signature)
+	}
+	delete(c.unverified, hash)
+
+	c.checkPrepared()
+	c.checkCommitted(hash)
+}
+
+func (c *SbftCore) addPrepare(view uint32, index int, hash Uint256) {
+	if _, ok := c.prepares[view]; !ok {
+		c.prepares[view] = make(map[int]Uint256)
+	}
+	c.prepares[view][index] = hash
+}
+
+func (c *SbftCore) onPrepare(from int, message *Prepare) {
+	if message.ViewNumber() < c.view {
+		return
+	}
+	if _, ok := c.prepares[message.ViewNumber()][from]; ok {
+		return
+	}
+	c.addPrepare(message.ViewNumber(), from, message.BlockHash)
+	if message.ViewNumber() == c.view {
+		c.checkPrepared()
+	}
+}
+
+func (c *SbftCore) checkPrepared() {
+	if c.proposal == nil || c.expected > c.view {
+		return
+	}
+	hash := c.proposal.Hash()
+	if c.locked != nil && c.lockedView == c.view && c.locked.Hash() == hash {
+		return
+	}
+	count := 0
+	for _, h := range c.prepares[c.view] {
+		if h == hash {
+			count++
+		}
+	}
+	if count < M(c.count) {
+		return
+	}
+
+	c.locked = c.proposal
+	c.lockedView = c.view
+
+	// a block locked in an earlier view is re-proposed, resend its signature
+	signature, ok := c.signatures[hash][c.index]
+	if !ok {
+		var err error
+		if signature, err = c.backend.Sign(c.proposal); err != nil {
+			log.Error("[SbftCore] sign block failed: ", err)
+			return
+		}
+		c.addSignature(hash, c.index, signature)
+	}
+	log.Info(fmt.Sprintf("[SbftCore] send commit: height=%d view=%d block=%x", c.height, c.view, hash))
+	c.broadcast(CommitMsg, &Commit{BlockHash: hash, Signature: signature})
+	c.checkCommitted(hash)
+}
+
+func (c *SbftCore) onCommit(from int, message *Commit) {
+	hash := message.BlockHash
+	if _, ok := c.signatures[hash][from]; ok {
+		return
+	}
+	if _, ok := c.blocks[hash]; !ok {
+		if _, ok := c.unverified[hash]; !ok {
+			c.unverified[hash] = make(map[int][]byte)
+		}
+		c.unverified[hash][from] = message.Signature
+		return
+	}
+	c.addSignature(hash, from, message.Signature)
+	c.checkCommitted(hash)
+}
+
+func (c *SbftCore) addSignature(hash Uint256, index int, signature []byte) {
+	if err := c.backend.VerifySignature(c.blocks[hash], index, signature); err != nil {
+		log.Warn(fmt.Sprintf("[SbftCore] invalid commit signature from %d: %s", index, err))
+		return
+	}
+	if _, ok := c.signatures[hash]; !ok {
+		c.signatures[hash] = make(map[int][]byte)
+	}
+	c.signatures[hash][index] = signature
+}
+
+func (c *SbftCore) checkCommitted(hash Uint256) {
+	if c.committed || len(c.signatures[hash]) < M(c.count) {
+		return
+	}
+	block, ok := c.blocks[hash]
+	if !ok {
+		return
+	}
+	log.Info(fmt.Sprintf("[SbftCore] block committed: height=%d view=%d block=%x", c.height, c.view, hash))
+	c.committed = true
+	if err := c.backend.Commit(block, c.signatures[hash]); err != nil {
+		log.Error("[SbftCore] commit block failed: ", err)
+	}
+}
+
+func (c *SbftCore) onViewChange(from int, message *ViewChange) {
+	newView := message.NewViewNumber
+	if newView <= c.view {
+		return
+	}
+	if _, ok := c.viewChanges[newView]; !ok {
+		c.viewChanges[newView] = make(map[int]*ViewChange)
+	}
+	if _, ok := c.viewChanges[newView][from]; ok {
+		return
+	}
+	c.viewChanges[newView][from] = message
+	votes := len(c.viewChanges[newView])
+
+	// f+1 votes contain at least one honest bookkeeper, join them
+	if votes > F(c.count) && c.expected < newView {
+		c.expected = newView - 1
+		c.requestViewChange()
+		return
+	}
+	if votes >= M(c.count) && c.expected == newView {
+		c.startView(newView)
+	}
+}
@@ -0,0 +1,283 @@
+package sbft
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/Ontology/common"
+	"github.com/Ontology/common/log"
+	ser "github.com/Ontology/common/serialization"
+	"github.com/Ontology/core/ledger"
+)
+
+func init() {
+	log.Init(log.Path, log.Stdout)
+}
+
+type envelope struct {
+	from int
+	data []byte
+}
+
+type timerArgs struct {
+	height uint32
+	view   uint32
+}
+
+// testNetwork delivers serialized messages between in-process nodes
+type testNetwork struct {
+	nodes   []*testNode
+	queue   []envelope
+	crashed map[int]bool
+}
+
+type testNode struct {
+	index     int
+	net       *testNetwork
+	core      *SbftCore
+	timer     timerArgs
+	committed *ledger.Block
+	sigs      map[int][]byte
+	faulty    bool
+}
+
+func newTestNetwork(count int) *testNetwork {
+	n := &testNetwork{crashed: make(map[int]bool)}
+	for i := 0; i < count; i++ {
+		node := &testNode{index: i, net: n}
+		node.core = NewSbftCore(node, time.Second)
+		n.nodes = append(n.nodes, node)
+	}
+	return n
+}
+
+func (n *testNetwork) reset(height uint32) {
+	for _, node := range n.nodes {
+		node.core.Reset(height, len(n.nodes), node.index)
+	}
+}
+
+func (n *testNetwork) run(t *testing.T) {
+	for steps := 0; len(n.queue) > 0; steps++ {
+		if steps > 100000 {
+			t.Fatal("network did not settle")
+		}
+		e := n.queue[0]
+		n.queue = n.queue[1:]
+		if n.crashed[e.from] {
+			continue
+		}
+		for _, node := range n.nodes {
+			if node.index == e.from || n.crashed[node.index] {
+				continue
+			}
+			message, err := DeserializeMessage(e.data)
+			if err != nil {
+				t.Fatal("DeserializeMessage failed:", err)
+			}
+			node.core.Receive(e.from, message)
+		}
+	}
+}
+
+func (n *testNetwork) fire(index int) {
+	node := n.nodes[index]
+	node.core.Timeout(node.timer.height, node.timer.view)
+}
+
+func (node *testNode) Broadcast(message ConsensusMessage) {
+	node.net.queue = append(node.net.queue, envelope{from: node.index, data: ser.ToArray(message)})
+}
+
+func (node *testNode) Propose() (*ledger.Block, error) {
+	header := &ledger.Header{
+		Height:        node.core.height,
+		Timestamp:     uint32(node.index),
+		ConsensusData: uint64(node.core.view),
+	}
+	return &ledger.Block{Header: header}, nil
+}
+
+func (node *testNode) VerifyProposal(block *ledger.Block) error {
+	return nil
+}
+
+func testSignature(block *ledger.Block, index int) []byte {
+	hash := block.Hash()
+	digest := sha256.Sum256(append(hash.ToArray(), byte(index)))
+	return digest[:]
+}
+
+func (node *testNode) Sign(block *ledger.Block) ([]byte, error) {
+	return testSignature(block, node.index), nil
+}
+
+func (node *testNode) VerifySignature(block *ledger.Block, index int, signature []byte) error {
+	if !bytes.Equal(testSignature(block, index), signature) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+func (node *testNode) Commit(block *ledger.Block, signatures map[int][]byte) error {
+	node.committed = block
+	node.sigs = signatures
+	return nil
+}
+
+func (node *testNode) ResetTimer(height uint32, view uint32, d time.Duration) {
+	node.timer = timerArgs{height: height, view: view}
+}
+
+func checkCommitted(t *testing.T, n *testNetwork) Uint256 {
+	var hash Uint256
+	for _, node := range n.nodes {
+		if n.crashed[node.index] || node.faulty {
+			continue
+		}
+		if node.committed == nil {
+			t.Fatalf("node %d did not commit", node.index)
+		}
+		if len(node.sigs) < M(len(n.nodes)) {
+			t.Fatalf("node %d committed with %d signatures", node.index, len(node.sigs))
+		}
+		if hash == (Uint256{}) {
+			hash = node.committed.Hash()
+		} else if node.committed.Hash() != hash {
+			t.Fatal("nodes committed different blocks")
+		}
+	}
+	return hash
+}
+
+func TestSbftCore_Commit(t *testing.T) {
+	n := newTestNetwork(4)
+	n.reset(1)
+	primary := n.nodes[0].core.primary(0)
+	n.fire(primary)
+	n.run(t)
+
+	hash := checkCommitted(t, n)
+	if n.nodes[0].committed.Header.Timestamp != uint32(primary) {
+		t.Fatal("committed block is not the primary proposal", hash)
+	}
+}
+
+func TestSbftCore_ViewChange(t *testing.T) {
+	n := newTestNetwork(4)
+	n.reset(1)
+	primary := n.nodes[0].core.primary(0)
+	n.crashed[primary] = true
+
+	for _, node := range n.nodes {
+		if !n.crashed[node.index] {
+			n.fire(node.index)
+		}
+	}
+	n.run(t)
+
+	for _, node := range n.nodes {
+		if !n.crashed[node.index] && node.core.View() != 1 {
+			t.Fatalf("node %d is in view %d", node.index, node.core.View())
+		}
+	}
+	n.fire(n.nodes[0].core.primary(1))
+	n.run(t)
+	checkCommitted(t, n)
+}
+
+func TestSbftCore_FaultyNode(t *testing.T) {
+	n := newTestNetwork(7)
+	n.reset(5)
+	faulty := n.nodes[n.nodes[0].core.primary(0)+1]
+	faulty.faulty = true
+
+	// the faulty node prepares and signs a block nobody proposed
+	bogus := &ledger.Block{Header: &ledger.Header{Height: 5, Timestamp: 99}}
+	prepare := &Prepare{BlockHash: bogus.Hash()}
+	prepare.msgData.Type = PrepareMsg
+	commit := &Commit{BlockHash: bogus.Hash(), Signature: testSignature(bogus, faulty.index)}
+	commit.msgData.Type = CommitMsg
+	faulty.Broadcast(prepare)
+	faulty.Broadcast(commit)
+
+	n.fire(n.nodes[0].core.primary(0))
+	n.run(t)
+	if hash := checkCommitted(t, n); hash == bogus.Hash() {
+		t.Fatal("honest nodes committed the faulty block")
+	}
+}
+
+func TestSbftCore_LockedBlockReproposed(t *testing.T) {
+	n := newTestNetwork(4)
+	n.reset(1)
+	primary := n.nodes[0].core.primary(0)
+	n.fire(primary)
+
+	// deliver everything but commits, so every node locks without finalizing
+	var pending []envelope
+	for len(n.queue) > 0 {
+		e := n.queue[0]
+		n.queue = n.queue[1:]
+		if ConsensusMessageType(e.data[0]) == CommitMsg {
+			continue
+		}
+		pending = append(pending, e)
+		for _, node := range n.nodes {
+			if node.index != e.from {
+				message, _ := DeserializeMessage(e.data)
+				node.core.Receive(e.from, message)
+			}
+		}
+	}
+	locked := n.nodes[0].core.locked
+	if locked == nil {
+		t.Fatal("node did not lock")
+	}
+
+	n.crashed[primary] = true
+	for _, node := range n.nodes {
+		if !n.crashed[node.index] {
+			n.fire(node.index)
+		}
+	}
+	n.run(t)
+	n.fire(n.nodes[0].core.primary(1))
+	n.run(t)
+	if checkCommitted(t, n) != locked.Hash() {
+		t.Fatal("new primary did not re-propose the locked block")
+	}
+}
+
+func TestDeserializeMessage(t *testing.T) {
+	block := &ledger.Block{Header: &ledger.Header{Height: 3, Timestamp: 7}}
+	vc := &ViewChange{NewViewNumber: 2, LockedView: 1, Locked: block}
+	vc.msgData = ConsensusMessageData{Type: ViewChangeMsg, ViewNumber: 1}
+
+	message, err := DeserializeMessage(ser.ToArray(vc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := message.(*ViewChange)
+	if !ok {
+		t.Fatal("unexpected message type")
+	}
+	if got.ViewNumber() != 1 || got.NewViewNumber != 2 || got.LockedView != 1 || got.Locked.Hash() != block.Hash() {
+		t.Fatal("view change round trip mismatch")
+	}
+}
+
+func TestSbftService_ResetTimer(t *testing.T) {
+	ss := &SbftService{timer: time.NewTimer(time.Millisecond)}
+	time.Sleep(10 * time.Millisecond)
+	// the timeout of the previous view fired but was not received
+	ss.ResetTimer(1, 1, time.Hour)
+	select {
+	case <-ss.timer.C:
+		t.Fatal("timeout of the previous view received after the reset")
+	default:
+	}
+}
@@ -1,12 +1,436 @@
 package sbft
 
+import (
+	"errors"
+	"fmt"
+	cl "github.com/Ontology/account"
+	. "github.com/Ontology/common"
+	"github.com/Ontology/common/config"
+	"github.com/Ontology/common/log"
+	ser "github.com/Ontology/common/serialization"
+	ct "github.com/Ontology/core/contract"
+	"github.com/Ontology/core/contract/program"
+	"github.com/Ontology/core/ledger"
+	sig "github.com/Ontology/core/signature"
+	tx "github.com/Ontology/core/transaction"
+	"github.com/Ontology/core/transaction/payload"
+	"github.com/Ontology/core/transaction/utxo"
+	va "github.com/Ontology/core/validation"
+	"github.com/Ontology/core/vote"
+	"github.com/Ontology/crypto"
+	. "github.com/Ontology/errors"
+	"github.com/Ontology/events"
+	"github.com/Ontology/net"
+	msg "github.com/Ontology/net/message"
+	"sync"
+	"time"
+)
+
+const ContextVersion uint32 = 0
+
 type SbftService struct {
+	Client   cl.Client
+	core     *SbftCore
+	localNet net.Neter
+	started  bool
+
+	mu          sync.RWMutex
+	prevHash    Uint256
+	height      uint32
+	bookKeepers []*crypto.PubKey
+	index       int
+
+	timer       *time.Timer
+	timerMu     sync.Mutex
+	timerHeight uint32
+	timerView   uint32
+	timerDue    time.Time // when the timer armed last fires, earlier values from timer.C are stale
+	quit        chan struct{}
+
+	newInventorySubscriber          events.Subscriber
+	blockPersistCompletedSubscriber events.Subscriber
+}
+
+func NewSbftService(client cl.Client, localNet net.Neter) *SbftService {
+	ss := &SbftService{
+		Client:   client,
+		localNet: localNet,
+		timer:    time.NewTimer(time.Second * 15),
+		index:    -1,
+	}
+	ss.core = NewSbftCore(ss, ledger.GenBlockTime)
+	if !ss.timer.Stop() {
+		<-ss.timer.C
+	}
+	return ss
+}
+
+func (ss *SbftService) Start() error {
+	log.Info("SBFT Start")
+	ss.started = true
+
+	if config.Parameters.GenBlockTime > config.MINGENBLOCKTIME {
+		ledger.GenBlockTime = time.Duration(config.Parameters.GenBlockTime) * time.Second
+	} else {
+		log.Warn("The Generate block time should be longer than 2 seconds, so set it to be default 6 seconds.")
+	}
+	ss.core.blockTime = ledger.GenBlockTime
+	ss.quit = make(chan struct{})
+	go ss.timerRoutine(ss.quit)
+
+	ss.blockPersistCompletedSubscriber = ledger.DefaultLedger.Blockchain.BCEvents.Subscribe(events.EventBlockPersistCompleted, ss.BlockPersistCompleted)
+	ss.newInventorySubscriber = ss.localNet.GetEvent("consensus").Subscribe(events.EventNewInventory, ss.LocalNodeNewInventory)
+
+	go ss.InitializeConsensus()
+	return nil
 }
 
 func (ss *SbftService) Halt() error {
+	log.Info("SBFT Stop")
+	if ss.timer != nil {
+		ss.timer.Stop()
+	}
+	if ss.started {
+		close(ss.quit)
+		ledger.DefaultLedger.Blockchain.BCEvents.UnSubscribe(events.EventBlockPersistCompleted, ss.blockPersistCompletedSubscriber)
+		ss.localNet.GetEvent("consensus").UnSubscribe(events.EventNewInventory, ss.newInventorySubscriber)
+		ss.started = false
+	}
 	return nil
 }
 
-func (ds *SbftService) Start() error {
+func (ss *SbftService) BlockPersistCompleted(v interface{}) {
+	if block, ok := v.(*ledger.Block); ok {
+		log.Infof("persist block: %x", block.Hash())
+		if err := ss.localNet.CleanSubmittedTransactions(block); err != nil {
+			log.Warn(err)
+		}
+		ss.localNet.Xmit(block.Hash())
+	}
+	go ss.InitializeConsensus()
+}
+
+// InitializeConsensus loads the bookkeepers for the next height and restarts
+// the agreement on it.
+func (ss *SbftService) InitializeConsensus() error {
+	bookKeepers, err := vote.GetValidators([]*tx.Transaction{})
+	if err != nil {
+		log.Error("[SbftService] GetValidators failed: ", err)
+		return err
+	}
+	ac, err := ss.Client.GetDefaultAccount()
+	if err != nil {
+		log.Error("[SbftService] GetDefaultAccount failed: ", err)
+		return err
+	}
+
+	ss.mu.Lock()
+	ss.prevHash = ledger.DefaultLedger.Blockchain.CurrentBlockHash()
+	ss.height = ledger.DefaultLedger.Blockchain.BlockHeight + 1
+	ss.bookKeepers = bookKeepers
+	ss.index = crypto.ContainPubKey(ac.PublicKey, bookKeepers)
+	height, index := ss.height, ss.index
+	ss.mu.Unlock()
+
+	if index < 0 {
+		log.Info("You aren't bookkeeper")
+		return nil
+	}
+	ss.core.Reset(height, len(bookKeepers), index)
 	return nil
 }
+
+func (ss *SbftService) LocalNodeNewInventory(v interface{}) {
+	if inventory, ok := v.(Inventory); ok {
+		if inventory.Type() == CONSENSUS {
+			if payload, ok := inventory.(*msg.ConsensusPayload); ok {
+				ss.NewConsensusPayload(payload)
+			}
+		}
+	}
+}
+
+func (ss *SbftService) NewConsensusPayload(payload *msg.ConsensusPayload) {
+	ss.mu.RLock()
+	index := int(payload.BookKeeperIndex)
+	valid := ss.index >= 0 && index != ss.index && index < len(ss.bookKeepers) &&
+		payload.Version == ContextVersion && payload.PrevHash == ss.prevHash && payload.Height == ss.height &&
+		payload.Owner != nil && crypto.Equal(payload.Owner, ss.bookKeepers[index])
+	ss.mu.RUnlock()
+	if !valid {
+		return
+	}
+
+	if err := payload.Verify(); err != nil {
+		log.Warn(err.Error())
+		return
+	}
+	message, err := DeserializeMessage(payload.Data)
+	if err != nil {
+		log.Error(fmt.Sprintf("DeserializeMessage failed: %s\n", err))
+		return
+	}
+	ss.core.Receive(index, message)
+}
+
+func (ss *SbftService) Broadcast(message ConsensusMessage) {
+	ss.mu.RLock()
+	payload := &msg.ConsensusPayload{
+		Version:         ContextVersion,
+		PrevHash:        ss.prevHash,
+		Height:          ss.height,
+		BookKeeperIndex: uint16(ss.index),
+		Timestamp:       uint32(time.Now().Unix()),
+		Data:            ser.ToArray(message),
+		Owner:           ss.bookKeepers[ss.index],
+	}
+	ss.mu.RUnlock()
+
+	ctCxt := ct.NewContractContext(payload)
+	if !ss.Client.Sign(ctCxt) {
+		log.Warn("[SbftService] Sign contract failure")
+		return
+	}
+	payload.SetPrograms(ctCxt.GetPrograms())
+	ss.localNet.Xmit(payload)
+}
+
+func (ss *SbftService) Propose() (*ledger.Block, error) {
+	ss.mu.RLock()
+	prevHash, height, owner := ss.prevHash, ss.height, ss.bookKeepers[ss.index]
+	ss.mu.RUnlock()
+
+	prevHeader, err := ledger.DefaultLedger.Blockchain.GetHeader(prevHash)
+	if err != nil {
+		return nil, NewDetailErr(err, ErrNoCode, "[SbftService] GetHeader failed")
+	}
+	timestamp := uint32(time.Now().Unix())
+	if timestamp <= prevHeader.Timestamp {
+		timestamp = prevHeader.Timestamp + 1
+	}
+
+	transactionsPool, feeSum := ss.localNet.GetTxnPool(true)
+	bookKeeping, err := ss.createBookkeepingTransaction(owner, feeSum)
+	if err != nil {
+		return nil, err
+	}
+	transactions := make([]*tx.Transaction, 0, len(transactionsPool)+1)
+	transactions = append(transactions, bookKeeping)
+	for _, t := range transactionsPool {
+		transactions = append(transactions, t)
+	}
+
+	nextBookKeepers, err := vote.GetValidators(transactions)
+	if err != nil {
+		return nil, NewDetailErr(err, ErrNoCode, "[SbftService] GetValidators failed")
+	}
+	nextBookKeeper, err := ledger.GetBookKeeperAddress(nextBookKeepers)
+	if err != nil {
+		return nil, NewDetailErr(err, ErrNoCode, "[SbftService] GetBookKeeperAddress failed")
+	}
+	txRoot, err := transactionsRoot(transactions)
+	if err != nil {
+		return nil, err
+	}
+	header := &ledger.Header{
+		Version:          ContextVersion,
+		PrevBlockHash:    prevHash,
+		TransactionsRoot: txRoot,
+		BlockRoot:        ledger.DefaultLedger.Store.GetBlockRootWithNewTxRoot(txRoot),
+		StateRoot:        ledger.DefaultLedger.Store.GetCurrentStateRoot(),
+		Timestamp:        timestamp,
+		Height:           height,
+		ConsensusData:    GetNonce(),
+		NextBookKeeper:   nextBookKeeper,
+	}
+	return &ledger.Block{Header: header, Transactions: transactions}, nil
+}
+
+func (ss *SbftService) VerifyProposal(block *ledger.Block) error {
+	ss.mu.RLock()
+	prevHash := ss.prevHash
+	ss.mu.RUnlock()
+
+	header := block.Header
+	if header.Version != ContextVersion || header.PrevBlockHash != prevHash {
+		return errors.New("[SbftService] proposal is not built on the current block")
+	}
+	prevHeader, err := ledger.DefaultLedger.Blockchain.GetHeader(prevHash)
+	if err != nil {
+		return NewDetailErr(err, ErrNoCode, "[SbftService] GetHeader failed")
+	}
+	if header.Timestamp <= prevHeader.Timestamp || header.Timestamp > uint32(time.Now().Add(time.Minute*10).Unix()) {
+		return fmt.Errorf("[SbftService] proposal timestamp incorrect: %d", header.Timestamp)
+	}
+	if len(block.Transactions) == 0 || block.Transactions[0].TxType != tx.BookKeeping {
+		return errors.New("[SbftService] proposal must start with a bookkeeping transaction")
+	}
+	txRoot, err := transactionsRoot(block.Transactions)
+	if err != nil {
+		return err
+	}
+	if txRoot != header.TransactionsRoot {
+		return errors.New("[SbftService] proposal transactions root mismatch")
+	}
+	if header.BlockRoot != ledger.DefaultLedger.Store.GetBlockRootWithNewTxRoot(txRoot) {
+		return errors.New("[SbftService] proposal block root mismatch")
+	}
+
+	// the transactions not in the pool are verified without adding them to it,
+	// the conflicts between them are checked on the block
+	txpool, _ := ss.localNet.GetTxnPool(false)
+	for _, t := range block.Transactions[1:] {
+		if _, ok := txpool[t.Hash()]; ok {
+			continue
+		}
+		if errCode := va.VerifyTransaction(t); errCode != ErrNoError {
+			return errors.New("[SbftService] proposal transaction verification failed")
+		}
+		if errCode := va.VerifyTransactionWithLedger(t, ledger.DefaultLedger); errCode != ErrNoError {
+			return errors.New("[SbftService] proposal transaction verification with ledger failed")
+		}
+	}
+	if err := va.VerifyTransactionWithBlock(block.Transactions); err != nil {
+		return NewDetailErr(err, ErrNoCode, "[SbftService] proposal transactions conflict")
+	}
+
+	nextBookKeepers, err := vote.GetValidators(block.Transactions)
+	if err != nil {
+		return NewDetailErr(err, ErrNoCode, "[SbftService] GetValidators failed")
+	}
+	nextBookKeeper, err := ledger.GetBookKeeperAddress(nextBookKeepers)
+	if err != nil {
+		return NewDetailErr(err, ErrNoCode, "[SbftService] GetBookKeeperAddress failed")
+	}
+	if nextBookKeeper != header.NextBookKeeper {
+		return errors.New("[SbftService] unmatched NextBookKeeper")
+	}
+	return nil
+}
+
+func (ss *SbftService) Sign(block *ledger.Block) ([]byte, error) {
+	ss.mu.RLock()
+	owner := ss.bookKeepers[ss.index]
+	ss.mu.RUnlock()
+
+	account, err := ss.Client.GetAccount(owner)
+	if err != nil {
+		return nil, NewDetailErr(err, ErrNoCode, "[SbftService] GetAccount failed")
+	}
+	return sig.SignBySigner(block, account)
+}
+
+func (ss *SbftService) VerifySignature(block *ledger.Block, index int, signature []byte) error {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	if index < 0 || index >= len(ss.bookKeepers) {
+		return errors.New("[SbftService] invalid bookkeeper index")
+	}
+	return va.VerifySignature(block, ss.bookKeepers[index], signature)
+}
+
+func (ss *SbftService) Commit(block *ledger.Block, signatures map[int][]byte) error {
+	ss.mu.RLock()
+	bookKeepers, index := ss.bookKeepers, ss.index
+	ss.mu.RUnlock()
+
+	ep, err := bookKeepers[index].EncodePoint(true)
+	if err != nil {
+		return NewDetailErr(err, ErrNoCode, "[SbftService] EncodePoint failed")
+	}
+	m := M(len(bookKeepers))
+	contract, err := ct.CreateMultiSigContract(ToCodeHash(ep), m, bookKeepers)
+	if err != nil {
+		return NewDetailErr(err, ErrNoCode, "[SbftService] CreateMultiSigContract failed")
+	}
+
+	cxt := ct.NewContractContext(block)
+	for i, j := 0, 0; i < len(bookKeepers) && j < m; i++ {
+		if signature, ok := signatures[i]; ok {
+			if err := cxt.AddContract(contract, bookKeepers[i], signature); err != nil {
+				return NewDetailErr(err, ErrNoCode, "[SbftService] AddContract failed")
+			}
+			j++
+		}
+	}
+	block.SetPrograms(cxt.GetPrograms())
+
+	if ledger.DefaultLedger.BlockInLedger(block.Hash()) {
+		return nil
+	}
+	if err := ledger.DefaultLedger.Blockchain.AddBlock(block); err != nil {
+		return NewDetailErr(err, ErrNoCode, "[SbftService] AddBlock failed")
+	}
+	return nil
+}
+
+func (ss *SbftService) ResetTimer(height uint32, view uint32, d time.Duration) {
+	ss.timerMu.Lock()
+	defer ss.timerMu.Unlock()
+	ss.timerHeight = height
+	ss.timerView = view
+	if !ss.timer.Stop() {
+		select {
+		case <-ss.timer.C:
+		default:
+		}
+	}
+	ss.timerDue = time.Now().Add(d)
+	ss.timer.Reset(d)
+}
+
+// timerRoutine fires the view timeouts until quit is closed by Halt
+func (ss *SbftService) timerRoutine(quit chan struct{}) {
+	for {
+		select {
+		case <-quit:
+			return
+		case fired := <-ss.timer.C:
+			ss.timerMu.Lock()
+			height, view, due := ss.timerHeight, ss.timerView, ss.timerDue
+			ss.timerMu.Unlock()
+			if fired.Before(due) {
+				// received before ResetTimer drained it
+				continue
+			}
+			go ss.core.Timeout(height, view)
+		}
+	}
+}
+
+func (ss *SbftService) createBookkeepingTransaction(owner *crypto.PubKey, fee Fixed64) (*tx.Transaction, error) {
+	signatureRedeemScript, err := ct.CreateSignatureRedeemScript(owner)
+	if err != nil {
+		return nil, NewDetailErr(err, ErrNoCode, "[SbftService] CreateSignatureRedeemScript failed")
+	}
+	outputs := []*utxo.TxOutput{}
+	if fee > 0 {
+		outputs = append(outputs, &utxo.TxOutput{
+			AssetID:     tx.ONGTokenID,
+			Value:       fee,
+			ProgramHash: ToCodeHash(signatureRedeemScript),
+		})
+	}
+	return &tx.Transaction{
+		TxType:         tx.BookKeeping,
+		PayloadVersion: payload.BookKeepingPayloadVersion,
+		Payload:        &payload.BookKeeping{Nonce: uint64(time.Now().UnixNano())},
+		Attributes:     []*tx.TxAttribute{},
+		UTXOInputs:     []*utxo.UTXOTxInput{},
+		BalanceInputs:  []*tx.BalanceTxInput{},
+		Outputs:        outputs,
+		Programs:       []*program.Program{},
+	}, nil
+}
+
+func transactionsRoot(transactions []*tx.Transaction) (Uint256, error) {
+	txHash := make([]Uint256, 0, len(transactions))
+	for _, t := range transactions {
+		txHash = append(txHash, t.Hash())
+	}
+	root, err := crypto.ComputeRoot(txHash)
+	if err != nil {
+		return Uint256{}, NewDetailErr(err, ErrNoCode, "[SbftService] ComputeRoot failed")
+	}
+	return root, nil
+}
@@ -0,0 +1,70 @@
+package sbft
+
+import (
+	ser "github.com/Ontology/common/serialization"
+	"github.com/Ontology/core/ledger"
+	"io"
+)
+
+// ViewChange votes for moving to NewViewNumber. A bookkeeper that already
+// prepared a block reports it as Locked so the next primary re-proposes it.
+type ViewChange struct {
+	msgData       ConsensusMessageData
+	NewViewNumber uint32
+	LockedView    uint32
+	Locked        *ledger.Block
+}
+
+func (vc *ViewChange) Serialize(w io.Writer) error {
+	if err := vc.msgData.Serialize(w); err != nil {
+		return err
+	}
+	if err := ser.WriteUint32(w, vc.NewViewNumber); err != nil {
+		return err
+	}
+	if vc.Locked == nil {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	if _, err := w.Write([]byte{1}); err != nil {
+		return err
+	}
+	if err := ser.WriteUint32(w, vc.LockedView); err != nil {
+		return err
+	}
+	return serializeProposal(w, vc.Locked)
+}
+
+func (vc *ViewChange) Deserialize(r io.Reader) error {
+	if err := vc.msgData.Deserialize(r); err != nil {
+		return err
+	}
+	var err error
+	if vc.NewViewNumber, err = ser.ReadUint32(r); err != nil {
+		return err
+	}
+	flag, err := ser.ReadBytes(r, 1)
+	if err != nil {
+		return err
+	}
+	if flag[0] == 0 {
+		return nil
+	}
+	if vc.LockedView, err = ser.ReadUint32(r); err != nil {
+		return err
+	}
+	vc.Locked, err = deserializeProposal(r)
+	return err
+}
+
+func (vc *ViewChange) Type() ConsensusMessageType {
+	return vc.msgData.Type
+}
+
+func (vc *ViewChange) ViewNumber() uint32 {
+	return vc.msgData.ViewNumber
+}
+
+func (vc *ViewChange) ConsensusMessageData() *ConsensusMessageData {
+	return &(vc.msgData)
+}
@@ -0,0 +1,146 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"github.com/Ontology/common/serialization"
+	"github.com/Ontology/core/ledger"
+)
+
+// An archive starts with the magic and the format version, followed by one
+// record per block:
+//
+//	uint32 length | serialized block | uint32 crc32 of the serialized block
+//
+// all integers are little endian.
+const (
+	ArchiveMagic   uint32 = 0x42544e4f // "ONTB"
+	ArchiveVersion uint32 = 1
+
+	// MaxRecordSize bounds the length of a block record
+	MaxRecordSize = 64 * 1024 * 1024
+
+	// PersistTimeout bounds the wait for the store to accept a block
+	PersistTimeout = 2 * time.Minute
+)
+
+var (
+	ErrInvalidArchive = errors.New("[archive] not a block archive")
+	ErrTruncated      = errors.New("[archive] truncated block record")
+	ErrChecksum       = errors.New("[archive] block record checksum mismatch")
+	ErrInterrupted    = errors.New("[archive] interrupted")
+)
+
+// Writer writes blocks to an archive
+type Writer struct {
+	w     *bufio.Writer
+	count int
+}
+
+// NewWriter writes the archive header to w
+func NewWriter(w io.Writer) (*Writer, error) {
+	bw := bufio.NewWriter(w)
+	if err := serialization.WriteUint32(bw, ArchiveMagic); err != nil {
+		return nil, err
+	}
+	if err := serialization.WriteUint32(bw, ArchiveVersion); err != nil {
+		return nil, err
+	}
+	return &Writer{w: bw}, nil
+}
+
+func (this *Writer) WriteBlock(b *ledger.Block) error {
+	buf := new(bytes.Buffer)
+	if err := b.Serialize(buf); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+	if err := serialization.WriteUint32(this.w, uint32(len(data))); err != nil {
+		return err
+	}
+	if _, err := this.w.Write(data); err != nil {
+		return err
+	}
+	if err := serialization.WriteUint32(this.w, crc32.ChecksumIEEE(data)); err != nil {
+		return err
+	}
+	this.count++
+	return nil
+}
+
+// Count returns the number of blocks written
+func (this *Writer) Count() int {
+	return this.count
+}
+
+func (this *Writer) Flush() error {
+	return this.w.Flush()
+}
+
+// Reader reads the blocks of an archive
+type Reader struct {
+	r      *bufio.Reader
+	offset int64
+}
+
+// NewReader checks the archive header of r
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{r: bufio.NewReader(r)}
+	magic, err := serialization.ReadUint32(reader.r)
+	if err != nil || magic != ArchiveMagic {
+		return nil, ErrInvalidArchive
+	}
+	version, err := serialization.ReadUint32(reader.r)
+	if err != nil {
+		return nil, ErrInvalidArchive
+	}
+	if version != ArchiveVersion {
+		return nil, fmt.Errorf("[archive] unsupported archive version %d", version)
+	}
+	reader.offset = 8
+	return reader, nil
+}
+
+// ReadBlock returns the next block, io.EOF at the end of the archive and
+// ErrTruncated when the archive ends inside a record
+func (this *Reader) ReadBlock() (*ledger.Block, error) {
+	var head [4]byte
+	n, err := io.ReadFull(this.r, head[:])
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, ErrTruncated
+	}
+	length, _ := serialization.ReadUint32(bytes.NewReader(head[:n]))
+	if length > MaxRecordSize {
+		return nil, fmt.Errorf("[archive] block record of %d bytes exceeds the limit", length)
+	}
+	data := make([]byte, length+4)
+	if _, err := io.ReadFull(this.r, data); err != nil {
+		return nil, ErrTruncated
+	}
+	checksum, _ := serialization.ReadUint32(bytes.NewReader(data[length:]))
+	data = data[:length]
+	if crc32.ChecksumIEEE(data) != checksum {
+		return nil, ErrChecksum
+	}
+	this.offset += int64(length) + 8
+
+	block := new(ledger.Block)
+	if err := block.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// Offset returns the number of archive bytes read
+func (this *Reader) Offset() int64 {
+	return this.offset
+}
@@ -0,0 +1,87 @@
+package archive
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/Ontology/core/contract/program"
+	"github.com/Ontology/core/ledger"
+	tx "github.com/Ontology/core/transaction"
+	"github.com/Ontology/core/transaction/payload"
+)
+
+func newTestBlock(height uint32) *ledger.Block {
+	bookKeeping := &tx.Transaction{
+		TxType:  tx.BookKeeping,
+		Payload: &payload.BookKeeping{Nonce: uint64(height)},
+	}
+	return &ledger.Block{
+		Header: &ledger.Header{
+			Height:    height,
+			Timestamp: height + 1,
+			Program:   &program.Program{Code: []byte{0x51}, Parameter: []byte{}},
+		},
+		Transactions: []*tx.Transaction{bookKeeping},
+	}
+}
+
+func TestArchive(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var blocks []*ledger.Block
+	for height := uint32(0); height < 3; height++ {
+		block := newTestBlock(height)
+		block.RebuildMerkleRoot()
+		blocks = append(blocks, block)
+		if err := w.WriteBlock(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, block := range blocks {
+		read, err := r.ReadBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if read.Hash() != block.Hash() || read.Header.Height != block.Header.Height {
+			t.Fatal("block", block.Header.Height, "not restored")
+		}
+	}
+	if _, err := r.ReadBlock(); err != io.EOF {
+		t.Fatal("archive end not detected:", err)
+	}
+	if r.Offset() != int64(len(data)) {
+		t.Fatal("unexpected offset", r.Offset(), len(data))
+	}
+
+	// a record cut off by an interrupted export
+	r, _ = NewReader(bytes.NewReader(data[:len(data)-3]))
+	r.ReadBlock()
+	r.ReadBlock()
+	if _, err := r.ReadBlock(); err != ErrTruncated {
+		t.Fatal("truncated record not detected:", err)
+	}
+
+	corrupted := append([]byte(nil), data...)
+	corrupted[20] ^= 0xff
+	r, _ = NewReader(bytes.NewReader(corrupted))
+	if _, err := r.ReadBlock(); err != ErrChecksum {
+		t.Fatal("corrupted record not detected:", err)
+	}
+
+	if _, err := NewReader(bytes.NewReader([]byte("not an archive"))); err != ErrInvalidArchive {
+		t.Fatal("invalid archive accepted")
+	}
+}
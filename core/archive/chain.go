@@ -0,0 +1,125 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Ontology/common/log"
+	"github.com/Ontology/core/ledger"
+)
+
+// Export writes the stored blocks from start to end to the archive, the
+// export stops at the last stored block when end is beyond it. An interrupted
+// export still leaves a valid archive of the blocks written so far.
+func Export(store ledger.ILedgerStore, w *Writer, start, end uint32, quit <-chan struct{}, progress func(height uint32)) error {
+	for height := start; height <= end; height++ {
+		select {
+		case <-quit:
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			return ErrInterrupted
+		default:
+		}
+		hash, err := store.GetBlockHash(height)
+		if err != nil {
+			break
+		}
+		block, err := store.GetBlock(hash)
+		if err != nil {
+			return fmt.Errorf("[Export] get block %d failed: %s", height, err)
+		}
+		if err := w.WriteBlock(block); err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(height)
+		}
+		if height == end {
+			break
+		}
+	}
+	return w.Flush()
+}
+
+// Import feeds the blocks of the archive to the store of the ledger. The
+// blocks are verified by the SaveBlock path of the store and persisted in the
+// background, the next block is queued as soon as the header of its parent is
+// accepted. Blocks the store already holds are checked against the chain and
+// skipped, so an interrupted import resumes where it stopped.
+func Import(ld *ledger.Ledger, r *Reader, quit <-chan struct{}, progress func(height uint32)) error {
+	store := ld.Store
+	queued := store.GetHeight()
+	err := importBlocks(ld, r, quit, progress, &queued)
+	// wait for the queued blocks so that closing the store loses nothing
+	if werr := waitFor(func() bool { return store.GetHeight() >= queued }, nil); werr != nil && err == nil {
+		err = fmt.Errorf("[Import] block %d was not persisted", store.GetHeight()+1)
+	}
+	return err
+}
+
+func importBlocks(ld *ledger.Ledger, r *Reader, quit <-chan struct{}, progress func(height uint32), queued *uint32) error {
+	store := ld.Store
+	for {
+		select {
+		case <-quit:
+			return ErrInterrupted
+		default:
+		}
+		block, err := r.ReadBlock()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		height := block.Header.Height
+		if height <= *queued {
+			hash, err := store.GetBlockHash(height)
+			if err != nil {
+				// the block is still on the way to the store
+				continue
+			}
+			if hash != block.Hash() {
+				return fmt.Errorf("[Import] block %d of the archive does not match the chain", height)
+			}
+			continue
+		}
+		if height != *queued+1 {
+			return fmt.Errorf("[Import] archive skips from block %d to %d", *queued, height)
+		}
+		// SaveBlock only verifies a block completely when it extends the
+		// header chain
+		if err := waitFor(func() bool { return store.GetHeaderHeight() >= height-1 }, quit); err != nil {
+			if err == ErrInterrupted {
+				return err
+			}
+			return fmt.Errorf("[Import] block %d was rejected by the store", height-1)
+		}
+		if err := store.SaveBlock(block, ld); err != nil {
+			return fmt.Errorf("[Import] save block %d failed: %s", height, err)
+		}
+		*queued = height
+		if progress != nil {
+			progress(height)
+		}
+	}
+}
+
+// waitFor polls cond until it holds, quit interrupts the wait
+func waitFor(cond func() bool, quit <-chan struct{}) error {
+	deadline := time.Now().Add(PersistTimeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			log.Error("[archive] timeout waiting for the store")
+			return fmt.Errorf("[archive] timeout waiting for the store")
+		}
+		select {
+		case <-quit:
+			return ErrInterrupted
+		case <-time.After(time.Millisecond):
+		}
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package contract
 
 import (
+	"bytes"
 	"errors"
 	. "github.com/Ontology/common"
 	"github.com/Ontology/common/log"
@@ -75,41 +76,25 @@ func (cxt *ContractContext) AddContract(contract *Contract, pubkey *crypto.PubKe
 			return errors.New("The program hash is not exist.")
 		}
 
-		log.Debug("Multi Sig: contract.Code:", cxt.Codes[index])
-
 		if cxt.Codes[index] == nil {
 			cxt.Codes[index] = contract.Code
 		}
-		log.Debug("Multi Sig: cxt.Codes[index]:", cxt.Codes[index])
 
 		if cxt.Parameters[index] == nil {
 			cxt.Parameters[index] = make([][]byte, len(contract.Parameters))
 		}
-		log.Debug("Multi Sig: cxt.Parameters[index]:", cxt.Parameters[index])
 
-		if err := cxt.Add(contract, cxt.tempParaIndex, parameter); err != nil {
+		if err := cxt.AddSignatureToMultiList(index, contract, pubkey, parameter); err != nil {
 			return err
 		}
 
-		cxt.tempParaIndex++
-
-		//all paramenters added, sort the parameters
-		if cxt.tempParaIndex == len(contract.Parameters) {
-			cxt.tempParaIndex = 0
+		//enough multi sigs added, sort the sigs according contract's PK list sequence
+		if len(cxt.MultiPubkeyPara[index]) >= len(contract.Parameters) {
+			if err := cxt.AddMultiSignatures(index, contract, pubkey, parameter); err != nil {
+				return err
+			}
 		}
 
-		//TODO: Sort the parameter according contract's PK list sequence
-		//if err := cxt.AddSignatureToMultiList(index,contract,pubkey,parameter); err != nil {
-		//	return err
-		//}
-		//
-		//if(cxt.tempParaIndex == len(contract.Parameters)){
-		//	//all multi sigs added, sort the sigs and add to context
-		//	if err := cxt.AddMultiSignatures(index,contract,pubkey,parameter);err != nil {
-		//		return err
-		//	}
-		//}
-
 	} else {
 		//add non multi sig contract
 		log.Debug()
@@ -130,12 +115,24 @@ func (cxt *ContractContext) AddContract(contract *Contract, pubkey *crypto.PubKe
 
 func (cxt *ContractContext) AddSignatureToMultiList(contractIndex int, contract *Contract, pubkey *crypto.PubKey, parameter []byte) error {
 	if cxt.MultiPubkeyPara[contractIndex] == nil {
-		cxt.MultiPubkeyPara[contractIndex] = make([]PubkeyParameter, len(contract.Parameters))
+		cxt.MultiPubkeyPara[contractIndex] = make([]PubkeyParameter, 0, len(contract.Parameters))
 	}
 	pk, err := pubkey.EncodePoint(true)
 	if err != nil {
 		return err
 	}
+	pkIndexs, err := cxt.ParseContractPubKeys(contract)
+	if err != nil {
+		return err
+	}
+	if _, ok := pkIndexs[ToHexString(pk)]; !ok {
+		return errors.New("The public key is not in the contract.")
+	}
+	for _, pubkeyPara := range cxt.MultiPubkeyPara[contractIndex] {
+		if pubkeyPara.PubKey == ToHexString(pk) {
+			return nil
+		}
+	}
 
 	pubkeyPara := PubkeyParameter{
 		PubKey:    ToHexString(pk),
@@ -166,18 +163,20 @@ func (cxt *ContractContext) AddMultiSignatures(index int, contract *Contract, pu
 		paraIndexs = append(paraIndexs, paraIndex)
 	}
 
-	//sort parameter by Index
-	sort.Sort(sort.Reverse(ParameterIndexSlice(paraIndexs)))
+	//sort parameter by Index, CHECKMULTISIG matches the last signature
+	//against the last public key first
+	sort.Sort(ParameterIndexSlice(paraIndexs))
 
 	//generate sorted parameter list
 	for i, paraIndex := range paraIndexs {
+		if i == len(contract.Parameters) {
+			break
+		}
 		if err := cxt.Add(contract, i, paraIndex.Parameter); err != nil {
 			return err
 		}
 	}
 
-	cxt.MultiPubkeyPara[index] = nil
-
 	return nil
 }
 
@@ -188,6 +187,9 @@ func (cxt *ContractContext) ParseContractPubKeys(contract *Contract) (map[string
 	Index := 0
 	//parse contract's pubkeys
 	i := 0
+	if !contract.IsMultiSigContract() {
+		return nil, errors.New("The contract is not a multi sig contract.")
+	}
 	switch contract.Code[i] {
 	case 1:
 		i += 2
@@ -195,6 +197,9 @@ func (cxt *ContractContext) ParseContractPubKeys(contract *Contract) (map[string
 	case 2:
 		i += 3
 		break
+	default:
+		i++
+		break
 	}
 	for contract.Code[i] == 33 {
 		i++
@@ -204,7 +209,7 @@ func (cxt *ContractContext) ParseContractPubKeys(contract *Contract) (map[string
 		//}
 
 		//add to parameter index
-		pubkeyIndex[ToHexString(contract.Code[i:33])] = Index
+		pubkeyIndex[ToHexString(contract.Code[i:i+33])] = Index
 
 		i += 33
 		Index++
@@ -268,3 +273,64 @@ func (cxt *ContractContext) IsCompleted() bool {
 	}
 	return true
 }
+
+// Merge adds the signatures of a context of the same data, so co-signers of
+// multi sig contracts can sign separately and combine their signatures
+func (cxt *ContractContext) Merge(other *ContractContext) error {
+	data := sig.GetHashData(cxt.Data)
+	if !bytes.Equal(data, sig.GetHashData(other.Data)) {
+		return errors.New("The contexts sign different data.")
+	}
+	for i, programHash := range other.ProgramHashes {
+		if other.Codes[i] == nil || other.Parameters[i] == nil {
+			continue
+		}
+		index := cxt.GetIndex(programHash)
+		if index < 0 {
+			return errors.New("The program hash is not exist.")
+		}
+		contract := &Contract{
+			Code:        other.Codes[i],
+			Parameters:  make([]ContractParameterType, len(other.Parameters[i])),
+			ProgramHash: programHash,
+		}
+		if ToCodeHash(contract.Code) != programHash {
+			return errors.New("The code does not match the program hash.")
+		}
+		if contract.GetType() != MultiSigContract {
+			if cxt.Codes[index] == nil {
+				cxt.Codes[index] = other.Codes[i]
+			}
+			if cxt.Parameters[index] == nil {
+				cxt.Parameters[index] = make([][]byte, len(other.Parameters[i]))
+			}
+			for j, parameter := range other.Parameters[i] {
+				if cxt.Parameters[index][j] == nil {
+					cxt.Parameters[index][j] = parameter
+				}
+			}
+			continue
+		}
+		for _, pubkeyPara := range other.MultiPubkeyPara[i] {
+			pk, err := HexToBytes(pubkeyPara.PubKey)
+			if err != nil {
+				return err
+			}
+			pubkey, err := crypto.DecodePoint(pk)
+			if err != nil {
+				return err
+			}
+			parameter, err := HexToBytes(pubkeyPara.Parameter)
+			if err != nil {
+				return err
+			}
+			if err := crypto.Verify(*pubkey, data, parameter); err != nil {
+				return errors.New("The signature of " + pubkeyPara.PubKey + " is invalid.")
+			}
+			if err := cxt.AddContract(contract, pubkey, parameter); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -19,5 +19,5 @@ func (p ParameterIndexSlice) Less(i, j int) bool {
 	return p[i].Index < p[j].Index
 }
 func (p ParameterIndexSlice) Swap(i, j int) {
-	p[i].Index, p[j].Index = p[j].Index, p[i].Index
+	p[i], p[j] = p[j], p[i]
 }
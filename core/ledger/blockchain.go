@@ -77,7 +77,7 @@ func (bc *Blockchain) SaveBlock(block *Block) error {
 func (bc *Blockchain) ContainsTransaction(hash Uint256) bool {
 	//TODO: implement error catch
 	_, err := DefaultLedger.Store.GetTransaction(hash)
-	if err != nil {
+	if err != nil && err != ErrPruned {
 		return false
 	}
 	return true
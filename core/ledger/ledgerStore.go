@@ -1,6 +1,9 @@
 package ledger
 
 import (
+	"errors"
+	"fmt"
+
 	. "github.com/Ontology/common"
 	"github.com/Ontology/core/states"
 	tx "github.com/Ontology/core/transaction"
@@ -8,6 +11,27 @@ import (
 	"github.com/Ontology/crypto"
 )
 
+// ErrPruned is returned by the store for the blocks, transactions and states
+// a pruning node has discarded
+var ErrPruned = errors.New("data pruned")
+
+// ErrAddressHistoryDisabled and ErrInvalidCursor are returned by the address
+// history queries of the store
+var (
+	ErrAddressHistoryDisabled = errors.New("address history is disabled")
+	ErrInvalidCursor          = errors.New("invalid cursor")
+)
+
+// HistoryUnavailableError is returned by the state queries at a height
+// below the one the state history of the store starts from
+type HistoryUnavailableError struct {
+	Height uint32
+}
+
+func (e HistoryUnavailableError) Error() string {
+	return fmt.Sprintf("history not available below height %d", e.Height)
+}
+
 // ILedgerStore provides func with store package.
 type ILedgerStore interface {
 	//TODO: define the state store func
@@ -19,6 +43,7 @@ type ILedgerStore interface {
 	IsDoubleSpend(tx *tx.Transaction) bool
 
 	AddHeaders(headers []Header, ledger *Ledger) error
+	SaveVerifiedTransaction(tx *tx.Transaction, height uint32) error
 	GetHeader(hash Uint256) (*Header, error)
 
 	GetTransaction(hash Uint256) (*tx.Transaction, error)
@@ -27,6 +52,12 @@ type ILedgerStore interface {
 	GetAsset(hash Uint256) (*states.AssetState, error)
 	GetContract(hash Uint160) (*states.ContractState, error)
 	GetAccount(programHash Uint160) (*states.AccountState, error)
+	// The historical queries and GetStateProof take the state after the
+	// block at height was persisted, the StateRoot in the header of the next
+	// block commits to it.
+	GetAssetAt(hash Uint256, height uint32) (*states.AssetState, error)
+	GetContractAt(hash Uint160, height uint32) (*states.ContractState, error)
+	GetAccountAt(programHash Uint160, height uint32) (*states.AccountState, error)
 
 	GetCurrentBlockHash() Uint256
 	GetCurrentHeaderHash() Uint256
@@ -34,6 +65,8 @@ type ILedgerStore interface {
 	GetHeight() uint32
 	GetHeaderHashByHeight(height uint32) Uint256
 	GetBlockRootWithNewTxRoot(txRoot Uint256) Uint256
+	GetMerkleProof(proofHeight, rootHeight uint32) ([]Uint256, error)
+	GetConsistencyProof(fromHeight, toHeight uint32) ([]Uint256, error)
 
 	GetBookKeeperList() ([]*crypto.PubKey, []*crypto.PubKey, error)
 	InitLedgerStoreWithGenesisBlock(genesisblock *Block, defaultBookKeeper []*crypto.PubKey) (uint32, error)
@@ -51,10 +84,18 @@ type ILedgerStore interface {
 
 	GetUnclaimed(hash Uint256) (map[uint16]*utxo.SpentCoin, error)
 	GetCurrentStateRoot() Uint256
+	GetStateProof(key []byte, height uint32) ([]byte, Uint256, [][]byte, error)
 	GetIdentity(ontId []byte) ([]byte, error)
 
 	GetStorageItem(key *states.StorageKey) (*states.StorageItem, error)
+	GetStorageItemAt(key *states.StorageKey, height uint32) (*states.StorageItem, error)
 
 	GetSysFeeAmount(hash Uint256) (Fixed64, error)
+	GetGasConsumed(hash Uint256) (Fixed64, error)
 	GetVotesAndEnrollments(txs []*tx.Transaction) ([]*states.VoteState, []*crypto.PubKey, error)
+	GetAddressHistory(programHash Uint160, limit int, cursor []byte) ([]*states.AddressHistory, []byte, error)
+
+	CreateSnapshot() (uint32, error)
+	RestoreSnapshot(path string, ledger *Ledger) (uint32, error)
+	GetSnapshotChunk(index uint32) (uint32, uint32, []byte, error)
 }
@@ -54,6 +54,8 @@ func (this *Balance) Deserialize(r io.Reader) error {
 	if err = f.Deserialize(r); err != nil {
 		return NewDetailErr(err, ErrNoCode, "[Balance] Amount Deserialize failed.")
 	}
+	this.AssetId = *u
+	this.Amount = *f
 	return nil
 }
 
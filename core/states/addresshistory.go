@@ -0,0 +1,78 @@
+package states
+
+import (
+	"io"
+
+	"github.com/Ontology/common"
+	"github.com/Ontology/common/serialization"
+	. "github.com/Ontology/errors"
+)
+
+const (
+	TransferIn  byte = 0
+	TransferOut byte = 1
+)
+
+// AddressHistory is the amount of an asset a transaction sends to
+// (TransferIn) or spends from (TransferOut) an address
+type AddressHistory struct {
+	StateBase
+	ProgramHash common.Uint160
+	Height      uint32
+	TxHash      common.Uint256
+	AssetId     common.Uint256
+	Direction   byte
+	Amount      common.Fixed64
+}
+
+func (this *AddressHistory) Serialize(w io.Writer) error {
+	this.StateBase.Serialize(w)
+	if _, err := this.ProgramHash.Serialize(w); err != nil {
+		return NewDetailErr(err, ErrNoCode, "AddressHistory ProgramHash Serialize failed.")
+	}
+	if err := serialization.WriteUint32(w, this.Height); err != nil {
+		return NewDetailErr(err, ErrNoCode, "AddressHistory Height Serialize failed.")
+	}
+	if _, err := this.TxHash.Serialize(w); err != nil {
+		return NewDetailErr(err, ErrNoCode, "AddressHistory TxHash Serialize failed.")
+	}
+	if _, err := this.AssetId.Serialize(w); err != nil {
+		return NewDetailErr(err, ErrNoCode, "AddressHistory AssetId Serialize failed.")
+	}
+	if err := serialization.WriteByte(w, this.Direction); err != nil {
+		return NewDetailErr(err, ErrNoCode, "AddressHistory Direction Serialize failed.")
+	}
+	if err := this.Amount.Serialize(w); err != nil {
+		return NewDetailErr(err, ErrNoCode, "AddressHistory Amount Serialize failed.")
+	}
+	return nil
+}
+
+func (this *AddressHistory) Deserialize(r io.Reader) error {
+	if err := this.StateBase.Deserialize(r); err != nil {
+		return NewDetailErr(err, ErrNoCode, "AddressHistory StateBase Deserialize failed.")
+	}
+	if err := this.ProgramHash.Deserialize(r); err != nil {
+		return NewDetailErr(err, ErrNoCode, "AddressHistory ProgramHash Deserialize failed.")
+	}
+	height, err := serialization.ReadUint32(r)
+	if err != nil {
+		return NewDetailErr(err, ErrNoCode, "AddressHistory Height Deserialize failed.")
+	}
+	this.Height = height
+	if err := this.TxHash.Deserialize(r); err != nil {
+		return NewDetailErr(err, ErrNoCode, "AddressHistory TxHash Deserialize failed.")
+	}
+	if err := this.AssetId.Deserialize(r); err != nil {
+		return NewDetailErr(err, ErrNoCode, "AddressHistory AssetId Deserialize failed.")
+	}
+	direction, err := serialization.ReadByte(r)
+	if err != nil {
+		return NewDetailErr(err, ErrNoCode, "AddressHistory Direction Deserialize failed.")
+	}
+	this.Direction = direction
+	if err := this.Amount.Deserialize(r); err != nil {
+		return NewDetailErr(err, ErrNoCode, "AddressHistory Amount Deserialize failed.")
+	}
+	return nil
+}
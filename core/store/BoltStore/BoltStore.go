@@ -0,0 +1,142 @@
+package BoltStore
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/Ontology/core/store"
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltFile is the name of the database file in the store directory
+const BoltFile = "store.db"
+
+// the bucket all the entries are kept in
+var bucketName = []byte("ontology")
+
+type BoltStore struct {
+	db    *bolt.DB // BoltDB instance
+	batch []batchOp
+}
+
+type batchOp struct {
+	key    []byte
+	value  []byte
+	delete bool
+}
+
+// NewBoltStore opens the BoltDB store in the directory file
+func NewBoltStore(file string) (*BoltStore, error) {
+	if err := os.MkdirAll(file, 0755); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(filepath.Join(file, BoltFile), 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketName)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltStore{
+		db: db,
+	}, nil
+}
+
+func (self *BoltStore) Put(key []byte, value []byte) error {
+	return self.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Put(key, value)
+	})
+}
+
+func (self *BoltStore) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := self.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(bucketName).Get(key)
+		if v == nil {
+			return ErrNotFound
+		}
+		// the value is only valid in the transaction
+		value = append([]byte{}, v...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (self *BoltStore) Has(key []byte) (bool, error) {
+	var ok bool
+	err := self.db.View(func(tx *bolt.Tx) error {
+		ok = tx.Bucket(bucketName).Get(key) != nil
+		return nil
+	})
+	return ok, err
+}
+
+func (self *BoltStore) Delete(key []byte) error {
+	return self.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Delete(key)
+	})
+}
+
+func (self *BoltStore) NewBatch() error {
+	self.batch = nil
+	return nil
+}
+
+func (self *BoltStore) BatchPut(key []byte, value []byte) error {
+	self.batch = append(self.batch, batchOp{
+		key:   append([]byte{}, key...),
+		value: append([]byte{}, value...),
+	})
+	return nil
+}
+
+func (self *BoltStore) BatchDelete(key []byte) error {
+	self.batch = append(self.batch, batchOp{key: append([]byte{}, key...), delete: true})
+	return nil
+}
+
+// BatchCommit writes the batch in a single transaction
+func (self *BoltStore) BatchCommit() error {
+	err := self.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketName)
+		for _, op := range self.batch {
+			var err error
+			if op.delete {
+				err = b.Delete(op.key)
+			} else {
+				err = b.Put(op.key, op.value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	self.batch = nil
+	return nil
+}
+
+func (self *BoltStore) Close() error {
+	err := self.db.Close()
+	return err
+}
+
+func (self *BoltStore) NewIterator(prefix []byte) IIterator {
+	return &Iterator{
+		db:     self.db,
+		prefix: append([]byte{}, prefix...),
+	}
+}
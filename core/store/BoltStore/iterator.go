@@ -0,0 +1,139 @@
+package BoltStore
+
+import (
+	"bytes"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	beforeFirst = iota
+	atKey
+	afterLast
+)
+
+// Iterator walks the keys with the prefix. Every move runs in its own read
+// transaction, the store may be written while the iterator is open since no
+// transaction is held between the moves. Unlike the LevelDB and the memory
+// iterators it is not a snapshot: a move sees the writes committed since the
+// previous one. A read transaction held for the life of the iterator would
+// block the commits that grow the database file until the iterator is
+// released.
+type Iterator struct {
+	db     *bolt.DB
+	prefix []byte
+	pos    int
+	key    []byte
+	value  []byte
+}
+
+// move positions the iterator with the cursor function f
+func (it *Iterator) move(f func(c *bolt.Cursor) ([]byte, []byte), exhausted int) bool {
+	var k, v []byte
+	it.db.View(func(tx *bolt.Tx) error {
+		k, v = f(tx.Bucket(bucketName).Cursor())
+		if k != nil && bytes.HasPrefix(k, it.prefix) {
+			k = append([]byte{}, k...)
+			v = append([]byte{}, v...)
+		} else {
+			k = nil
+		}
+		return nil
+	})
+	if k == nil {
+		it.pos = exhausted
+		it.key, it.value = nil, nil
+		return false
+	}
+	it.pos = atKey
+	it.key, it.value = k, v
+	return true
+}
+
+func (it *Iterator) Next() bool {
+	switch it.pos {
+	case beforeFirst:
+		return it.First()
+	case afterLast:
+		return false
+	}
+	current := it.key
+	return it.move(func(c *bolt.Cursor) ([]byte, []byte) {
+		k, v := c.Seek(current)
+		if k != nil && bytes.Equal(k, current) {
+			return c.Next()
+		}
+		// the current key was deleted, the one after it is the next one
+		return k, v
+	}, afterLast)
+}
+
+func (it *Iterator) Prev() bool {
+	switch it.pos {
+	case beforeFirst:
+		return false
+	case afterLast:
+		return it.Last()
+	}
+	current := it.key
+	return it.move(func(c *bolt.Cursor) ([]byte, []byte) {
+		if k, _ := c.Seek(current); k == nil {
+			return c.Last()
+		}
+		return c.Prev()
+	}, beforeFirst)
+}
+
+func (it *Iterator) First() bool {
+	return it.move(func(c *bolt.Cursor) ([]byte, []byte) {
+		return c.Seek(it.prefix)
+	}, afterLast)
+}
+
+func (it *Iterator) Last() bool {
+	return it.move(func(c *bolt.Cursor) ([]byte, []byte) {
+		limit := prefixLimit(it.prefix)
+		if limit == nil {
+			return c.Last()
+		}
+		if k, _ := c.Seek(limit); k == nil {
+			return c.Last()
+		}
+		return c.Prev()
+	}, beforeFirst)
+}
+
+func (it *Iterator) Seek(key []byte) bool {
+	if bytes.Compare(key, it.prefix) < 0 {
+		key = it.prefix
+	}
+	return it.move(func(c *bolt.Cursor) ([]byte, []byte) {
+		return c.Seek(key)
+	}, afterLast)
+}
+
+func (it *Iterator) Key() []byte {
+	return it.key
+}
+
+func (it *Iterator) Value() []byte {
+	return it.value
+}
+
+func (it *Iterator) Release() {
+	it.pos = beforeFirst
+	it.key, it.value = nil, nil
+}
+
+// prefixLimit returns the first key after all the keys with the prefix, nil
+// when there is none
+func prefixLimit(prefix []byte) []byte {
+	limit := append([]byte{}, prefix...)
+	for i := len(limit) - 1; i >= 0; i-- {
+		if limit[i] < 0xff {
+			limit[i]++
+			return limit[:i+1]
+		}
+	}
+	return nil
+}
@@ -6,13 +6,16 @@ import (
 	"errors"
 	"fmt"
 	. "github.com/Ontology/common"
+	"github.com/Ontology/common/config"
 	"github.com/Ontology/common/log"
 	"github.com/Ontology/common/serialization"
 	"github.com/Ontology/core/contract/program"
 	. "github.com/Ontology/core/ledger"
 	"github.com/Ontology/core/states"
 	. "github.com/Ontology/core/store"
+	"github.com/Ontology/core/store/BoltStore"
 	. "github.com/Ontology/core/store/LevelDBStore"
+	"github.com/Ontology/core/store/MemoryStore"
 	"github.com/Ontology/core/store/statestore"
 	tx "github.com/Ontology/core/transaction"
 	"github.com/Ontology/core/transaction/payload"
@@ -21,11 +24,13 @@ import (
 	"github.com/Ontology/crypto"
 	"github.com/Ontology/events"
 	"github.com/Ontology/merkle"
+	"github.com/Ontology/trie"
 	httprestful "github.com/Ontology/net/httprestful/error"
 	sc "github.com/Ontology/smartcontract"
 	"github.com/Ontology/smartcontract/event"
 	"github.com/Ontology/smartcontract/service"
 	"github.com/Ontology/smartcontract/types"
+	"math/big"
 	"sort"
 	"sync"
 	"time"
@@ -41,6 +46,13 @@ const (
 	INVOKE_TRANSACTION  = "InvokeTransaction"
 )
 
+// the store backends of the StoreBackend config
+const (
+	LevelDBBackend = "leveldb"
+	BoltDBBackend  = "boltdb"
+	MemoryBackend  = "memory"
+)
+
 var (
 	ErrDBNotFound    = "leveldb: not found"
 	CurrentStateRoot = []byte("Current-State-Root")
@@ -66,22 +78,44 @@ type ChainStore struct {
 	headerIndex map[uint32]Uint256
 	blockCache  map[Uint256]*Block
 	headerCache map[Uint256]*Header
+	forks       map[Uint256]*Header // verified headers off the main chain
 
 	merkleTree      *merkle.CompactMerkleTree
 	merkleHashStore *merkle.FileHashStore
 
 	currentBlockHeight uint32
 	storedHeaderCount  uint32
+	// the blocks below prunedHeight have their bodies pruned
+	prunedHeight uint32
+	// the state history is recorded from historyHeight
+	historyHeight uint32
+
+	// headerOnly stores keep headers and verified wallet transactions only,
+	// they back light nodes which never persist full blocks
+	headerOnly bool
+
+	// undo records the batches of the persisted blocks to revert them
+	undo *undoStore
 }
 
+// NewStore opens the store in file with the backend selected by the
+// StoreBackend config
 func NewStore(file string) (IStore, error) {
-	ldbs, err := NewLevelDBStore(file)
-
-	return ldbs, err
+	switch config.Parameters.StoreBackend {
+	case "", LevelDBBackend:
+		ldbs, err := NewLevelDBStore(file)
+		return ldbs, err
+	case BoltDBBackend:
+		bs, err := BoltStore.NewBoltStore(file)
+		return bs, err
+	case MemoryBackend:
+		return MemoryStore.NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("[NewStore] unknown store backend %s", config.Parameters.StoreBackend)
+	}
 }
 
 func NewLedgerStore() (ILedgerStore, error) {
-	// TODO: read config file decide which db to use.
 	cs, err := NewChainStore(DBDir)
 	if err != nil {
 		return nil, err
@@ -90,18 +124,32 @@ func NewLedgerStore() (ILedgerStore, error) {
 	return cs, nil
 }
 
+// NewHeaderOnlyLedgerStore opens the store of a light node
+func NewHeaderOnlyLedgerStore() (ILedgerStore, error) {
+	cs, err := NewChainStore(DBDir)
+	if err != nil {
+		return nil, err
+	}
+	cs.headerOnly = true
+
+	return cs, nil
+}
+
 func NewChainStore(file string) (*ChainStore, error) {
 
 	st, err := NewStore(file)
 	if err != nil {
 		return nil, err
 	}
+	undo := newUndoStore(st)
 
 	chain := &ChainStore{
-		st:                 st,
+		st:                 undo,
+		undo:               undo,
 		headerIndex:        map[uint32]Uint256{},
 		blockCache:         map[Uint256]*Block{},
 		headerCache:        map[Uint256]*Header{},
+		forks:              map[Uint256]*Header{},
 		currentBlockHeight: 0,
 		storedHeaderCount:  0,
 		taskCh:             make(chan persistTask, TaskChanCap),
@@ -136,6 +184,14 @@ func (self *ChainStore) loop() {
 				self.handlePersistBlockTask(task.block, task.ledger)
 				tcall := float64(time.Now().Sub(now)) / float64(time.Second)
 				log.Debugf("handle block exetime: %g num transactions:%d \n", tcall, len(task.block.Transactions))
+
+			case *createSnapshotTask:
+				height, err := self.createSnapshot(SnapshotPath)
+				task.done <- snapshotResult{height: height, err: err}
+
+			case *restoreSnapshotTask:
+				height, err := self.restoreSnapshot(task.path, task.ledger)
+				task.done <- snapshotResult{height: height, err: err}
 			}
 
 		case closed := <-self.quit:
@@ -162,6 +218,12 @@ func (self *ChainStore) clearCache() {
 			delete(self.blockCache, hash)
 		}
 	}
+
+	for hash, header := range self.forks {
+		if header.Height+MaxReorgDepth < currBlockHeight {
+			delete(self.forks, hash)
+		}
+	}
 }
 
 func (bd *ChainStore) InitLedgerStoreWithGenesisBlock(genesisBlock *Block, defaultBookKeeper []*crypto.PubKey) (uint32, error) {
@@ -193,6 +255,19 @@ func (bd *ChainStore) InitLedgerStoreWithGenesisBlock(genesisBlock *Block, defau
 		bd.currentBlockHeight, err = serialization.ReadUint32(r)
 		current_Header_Height := bd.currentBlockHeight
 
+		// header only stores sync headers past the current block
+		if bd.headerOnly {
+			data, err := bd.st.Get([]byte{byte(SYS_CurrentHeader)})
+			if err == nil {
+				r := bytes.NewReader(data)
+				blockHash.Deserialize(r)
+				current_Header_Height, err = serialization.ReadUint32(r)
+				if err != nil {
+					return 0, err
+				}
+			}
+		}
+
 		var listHash Uint256
 		iter := bd.st.NewIterator([]byte{byte(IX_HeaderHashList)})
 		for iter.Next() {
@@ -256,22 +331,18 @@ func (bd *ChainStore) InitLedgerStoreWithGenesisBlock(genesisBlock *Block, defau
 				hash = header.PrevBlockHash
 			}
 		}
-		buf, _ := bd.st.Get([]byte{byte(SYS_BlockMerkleTree)})
-		tree_size := binary.BigEndian.Uint32(buf[0:4])
-		if tree_size != bd.currentBlockHeight+1 {
-			return 0, errors.New("Merkle tree size is inconsistent with blockheight")
+		if err := bd.loadMerkleTree(); err != nil {
+			return 0, err
 		}
-		nhashes := (len(buf) - 4) / UINT256SIZE
-		hashes := make([]Uint256, nhashes, nhashes)
-		for i := 0; i < nhashes; i++ {
-			copy(hashes[i][:], buf[4+i*UINT256SIZE:])
+		bd.loadPrunedHeight()
+		if err := bd.loadHistoryHeight(); err != nil {
+			return 0, err
 		}
-
-		bd.merkleHashStore, err = merkle.NewFileHashStore(MerkleTreeStorePath, tree_size)
-		if err != nil {
-			log.Error("merkle_tree.db is inconsistent with ChainStore. persistence will be disabled")
+		if addressHistoryEnabled(bd) {
+			if err := bd.rebuildAddressHistory(); err != nil {
+				return 0, err
+			}
 		}
-		bd.merkleTree = merkle.NewTree(tree_size, hashes, bd.merkleHashStore)
 		return bd.currentBlockHeight, nil
 
 	} else {
@@ -315,6 +386,11 @@ func (bd *ChainStore) InitLedgerStoreWithGenesisBlock(genesisBlock *Block, defau
 		bd.merkleHashStore, _ = merkle.NewFileHashStore(MerkleTreeStorePath, 0)
 		bd.merkleTree = merkle.NewTree(0, nil, bd.merkleHashStore)
 
+		// the state history starts with the genesis block
+		if err := bd.st.Put([]byte{byte(SYS_StateHistory)}, make([]byte, 4)); err != nil {
+			return 0, err
+		}
+
 		// persist genesis block
 		bd.persist(genesisBlock)
 
@@ -328,6 +404,29 @@ func (bd *ChainStore) InitLedgerStoreWithGenesisBlock(genesisBlock *Block, defau
 	}
 }
 
+// loadMerkleTree opens the block merkle tree of the current block
+func (bd *ChainStore) loadMerkleTree() error {
+	buf, _ := bd.st.Get([]byte{byte(SYS_BlockMerkleTree)})
+	tree_size := binary.BigEndian.Uint32(buf[0:4])
+	if tree_size != bd.currentBlockHeight+1 {
+		return errors.New("Merkle tree size is inconsistent with blockheight")
+	}
+	nhashes := (len(buf) - 4) / UINT256SIZE
+	hashes := make([]Uint256, nhashes, nhashes)
+	for i := 0; i < nhashes; i++ {
+		copy(hashes[i][:], buf[4+i*UINT256SIZE:])
+	}
+
+	var err error
+	bd.merkleHashStore.Close()
+	bd.merkleHashStore, err = merkle.NewFileHashStore(MerkleTreeStorePath, tree_size)
+	if err != nil {
+		log.Error("merkle_tree.db is inconsistent with ChainStore. persistence will be disabled")
+	}
+	bd.merkleTree = merkle.NewTree(tree_size, hashes, bd.merkleHashStore)
+	return nil
+}
+
 func (bd *ChainStore) InitLedgerStore(l *Ledger) error {
 	// TODO: InitLedgerStore
 	return nil
@@ -347,6 +446,30 @@ func (bd *ChainStore) GetBlockRootWithNewTxRoot(txRoot Uint256) Uint256 {
 	return bd.merkleTree.GetRootWithNewLeaf(txRoot)
 }
 
+// GetMerkleProof returns the audit path of the block at proofHeight in the
+// block merkle tree whose root is the BlockRoot of the block at rootHeight
+func (bd *ChainStore) GetMerkleProof(proofHeight, rootHeight uint32) ([]Uint256, error) {
+	if proofHeight > rootHeight || rootHeight > bd.GetHeight() {
+		return nil, errors.New("[GetMerkleProof] invalid height")
+	}
+	if bd.merkleHashStore == nil {
+		return nil, errors.New("[GetMerkleProof] merkle tree persistence is disabled")
+	}
+	return bd.merkleTree.InclusionProof(proofHeight, rootHeight+1), nil
+}
+
+// GetConsistencyProof returns the proof that the block merkle tree of the
+// block at fromHeight is a prefix of the tree of the block at toHeight
+func (bd *ChainStore) GetConsistencyProof(fromHeight, toHeight uint32) ([]Uint256, error) {
+	if fromHeight > toHeight || toHeight > bd.GetHeight() {
+		return nil, errors.New("[GetConsistencyProof] invalid height")
+	}
+	if bd.merkleHashStore == nil {
+		return nil, errors.New("[GetConsistencyProof] merkle tree persistence is disabled")
+	}
+	return bd.merkleTree.ConsistencyProof(fromHeight+1, toHeight+1), nil
+}
+
 func (bd *ChainStore) IsDoubleSpend(tx *tx.Transaction) bool {
 	if len(tx.UTXOInputs) == 0 {
 		return false
@@ -416,6 +539,10 @@ func (bd *ChainStore) getHeaderWithCache(hash Uint256) *Header {
 
 func (bd *ChainStore) verifyHeader(header *Header) bool {
 	prevHeader := bd.getHeaderWithCache(header.PrevBlockHash)
+	if prevHeader == nil {
+		// the parent of the first header after a restart is only in the db
+		prevHeader, _ = bd.GetHeader(header.PrevBlockHash)
+	}
 
 	if prevHeader == nil {
 		log.Error("[verifyHeader] failed, not found prevHeader.")
@@ -466,6 +593,10 @@ func (bd *ChainStore) GetHeader(hash Uint256) (*Header, error) {
 		bd.mu.RUnlock()
 		return header, nil
 	}
+	if header, ok := bd.forks[hash]; ok {
+		bd.mu.RUnlock()
+		return header, nil
+	}
 	bd.mu.RUnlock()
 
 	var h *Header = new(Header)
@@ -530,6 +661,77 @@ func (bd *ChainStore) GetContract(hash Uint160) (*states.ContractState, error) {
 	return contract, nil
 }
 
+// getStateAt returns the serialized state stored under key as it was after
+// the block at height was persisted
+func (bd *ChainStore) getStateAt(key []byte, height uint32) ([]byte, error) {
+	if height > bd.GetHeight() {
+		return nil, errors.New("[getStateAt] height is above the current block height")
+	}
+	if height < bd.historyHeight {
+		return nil, HistoryUnavailableError{Height: bd.historyHeight}
+	}
+	iter := bd.st.NewIterator(historyKeyPrefix(key))
+	defer iter.Release()
+	if !iter.Seek(historyKey(key, height)) || len(iter.Value()) == 0 {
+		return nil, errors.New(ErrDBNotFound)
+	}
+	data := make([]byte, len(iter.Value()))
+	copy(data, iter.Value())
+	return data, nil
+}
+
+// GetAccountAt returns the account state after the block at height
+func (bd *ChainStore) GetAccountAt(programHash Uint160, height uint32) (*states.AccountState, error) {
+	data, err := bd.getStateAt(append([]byte{byte(ST_Account)}, programHash.ToArray()...), height)
+	if err != nil {
+		return nil, err
+	}
+	account := new(states.AccountState)
+	if err := account.Deserialize(bytes.NewBuffer(data)); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// GetAssetAt returns the asset state after the block at height
+func (bd *ChainStore) GetAssetAt(hash Uint256, height uint32) (*states.AssetState, error) {
+	data, err := bd.getStateAt(append([]byte{byte(ST_Asset)}, hash.ToArray()...), height)
+	if err != nil {
+		return nil, err
+	}
+	asset := new(states.AssetState)
+	if err := asset.Deserialize(bytes.NewBuffer(data)); err != nil {
+		return nil, err
+	}
+	return asset, nil
+}
+
+// GetContractAt returns the contract state after the block at height
+func (bd *ChainStore) GetContractAt(hash Uint160, height uint32) (*states.ContractState, error) {
+	data, err := bd.getStateAt(append([]byte{byte(ST_Contract)}, hash.ToArray()...), height)
+	if err != nil {
+		return nil, err
+	}
+	contract := new(states.ContractState)
+	if err := contract.Deserialize(bytes.NewBuffer(data)); err != nil {
+		return nil, err
+	}
+	return contract, nil
+}
+
+// GetStorageItemAt returns the contract storage item after the block at height
+func (bd *ChainStore) GetStorageItemAt(key *states.StorageKey, height uint32) (*states.StorageItem, error) {
+	data, err := bd.getStateAt(append([]byte{byte(ST_Storage)}, key.ToArray()...), height)
+	if err != nil {
+		return nil, err
+	}
+	item := new(states.StorageItem)
+	if err := item.Deserialize(bytes.NewBuffer(data)); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
 func (bd *ChainStore) GetTransaction(hash Uint256) (*tx.Transaction, error) {
 	log.Debugf("GetTransaction Hash: %x\n", hash)
 
@@ -568,6 +770,10 @@ func (bd *ChainStore) getTx(tx *tx.Transaction, hash Uint256) (uint32, error) {
 		return 0, err
 	}
 
+	if len(tHash) == prunedTxLen {
+		return height, ErrPruned
+	}
+
 	// Deserialize Transaction
 	if err := tx.Deserialize(r); err != nil {
 		log.Error("[getTx] error:", err)
@@ -636,6 +842,9 @@ func (bd *ChainStore) GetBlock(hash Uint256) (*Block, error) {
 	if err != nil {
 		return nil, err
 	}
+	if bd.isPruned(b.Header.Height) {
+		return nil, ErrPruned
+	}
 
 	// Deserialize transaction
 	for i := 0; i < len(b.Transactions); i++ {
@@ -666,6 +875,7 @@ func (self *ChainStore) GetBookKeeperList() ([]*crypto.PubKey, []*crypto.PubKey,
 
 func (bd *ChainStore) persist(b *Block) error {
 	bd.st.NewBatch()
+	bd.beginUndo()
 	stateStore := NewStateStore(statestore.NewMemDatabase(), bd, b.Header.StateRoot)
 	state, err := stateStore.TryGet(ST_BookKeeper, BookerKeeper)
 	if err != nil {
@@ -675,6 +885,11 @@ func (bd *ChainStore) persist(b *Block) error {
 	bookKeeper := state.Value.(*states.BookKeeperState)
 	handleBookKeeper(stateStore, bookKeeper)
 	txids := new(states.EventTxState)
+	balances := make(map[Uint256][]*states.AddressHistory)
+	sysFee, err := bd.GetSysFeeAmount(b.Header.PrevBlockHash)
+	if err != nil {
+		return err
+	}
 	for _, t := range b.Transactions {
 		bd.SaveTransaction(t, b.Header.Height)
 		tx_id := t.Hash()
@@ -687,6 +902,9 @@ func (bd *ChainStore) persist(b *Block) error {
 		if err := handleInputs(t.UTXOInputs, stateStore, b.Header.Height, bd); err != nil {
 			return err
 		}
+		// an invocation pays its whole gas limit on top of the base fee, the
+		// gas it does not consume is not refunded
+		sysFee += t.SystemFee
 		switch t.TxType {
 		case tx.RegisterAsset:
 			p := t.Payload.(*payload.RegisterAsset)
@@ -725,6 +943,10 @@ func (bd *ChainStore) persist(b *Block) error {
 					log.Errorf("[persist] TryGet ST_SpentCoin error:", err)
 					return err
 				}
+				if state == nil {
+					// pruned, or spent before a restored snapshot
+					continue
+				}
 				spentcoins := state.(*states.SpentCoinState)
 				spentcoins.Items = remove(spentcoins.Items, int(c.ReferTxOutputIndex))
 			}
@@ -754,8 +976,23 @@ func (bd *ChainStore) persist(b *Block) error {
 		case tx.Deploy:
 			deploy := t.Payload.(*payload.DeployCode)
 			codeHash := deploy.Code.CodeHash()
+			fc := deploy.Code
+			if deploy.VmType == types.EVM {
+				cs, err := stateStore.TryGet(ST_Contract, codeHash.ToArray())
+				if err != nil {
+					log.Error("[persist] TryGet ST_Contract error:", err)
+					return err
+				}
+				if cs == nil {
+					if fc, err = createEVMContract(stateStore, t, b, deploy); err != nil {
+						log.Error("[persist] CreateContract error:", err)
+						event.PushSmartCodeEvent(t.Hash(), httprestful.SMARTCODE_ERROR, DEPLOY_TRANSACTION, err)
+						continue
+					}
+				}
+			}
 			if err := stateStore.TryGetOrAdd(ST_Contract, codeHash.ToArray(), &states.ContractState{
-				Code:        deploy.Code,
+				Code:        fc,
 				VmType:      deploy.VmType,
 				NeedStorage: deploy.NeedStorage,
 				Name:        deploy.Name,
@@ -782,29 +1019,48 @@ func (bd *ChainStore) persist(b *Block) error {
 			stateMachine := service.NewStateMachine(stateStore, types.Application, b)
 			smc, err := sc.NewSmartContract(&sc.Context{
 				VmType:         contract.VmType,
+				Caller:         Invoker(t),
 				StateMachine:   stateMachine,
 				SignableData:   t,
 				CacheCodeTable: &CacheCodeTable{stateStore},
 				Input:          invoke.Code,
 				Code:           contract.Code.Code,
+				CodeHash:       invoke.CodeHash,
+				Time:           big.NewInt(int64(b.Header.Timestamp)),
+				BlockNumber:    big.NewInt(int64(b.Header.Height)),
 				ReturnType:     contract.Code.ReturnType,
+				Gas:            invoke.GasLimit,
 			})
 			if err != nil {
 				log.Error("[persist] NewSmartContract error:", err)
 				return err
 			}
 			ret, err := smc.InvokeContract()
+			gasConsumed := smc.GasConsumed()
+			if err := addGasConsumed(bd, tx_id, gasConsumed); err != nil {
+				return err
+			}
 			if err != nil {
 				log.Error("[persist] InvokeContract error:", err)
 				event.PushSmartCodeEvent(t.Hash(), httprestful.SMARTCODE_ERROR, INVOKE_TRANSACTION, err)
 				continue
 			}
 			log.Error("result:", ret)
+			if addressHistoryEnabled(bd) {
+				if balances[tx_id], err = balanceHistory(tx_id, b.Header.Height, stateMachine.CloneCache.Memory, stateStore); err != nil {
+					return err
+				}
+			}
 			stateMachine.CloneCache.Commit()
+			stateMachine.Notifications = append(stateMachine.Notifications, smc.Notifications(tx_id)...)
 			if err := DefaultEventStore.SaveEventNotifyInTx(tx_id, stateMachine.Notifications); err != nil {
 				log.Error("[persist] SaveEventNotifyByTx error:", err)
 				return err
 			}
+			if err := DefaultEventStore.SaveEventNotifyIndex(b.Header.Height, stateMachine.Notifications); err != nil {
+				log.Error("[persist] SaveEventNotifyIndex error:", err)
+				return err
+			}
 			txids.Txids = append(txids.Txids, tx_id)
 			event.PushSmartCodeEvent(t.Hash(), 0, INVOKE_TRANSACTION, ret)
 		case tx.Vote:
@@ -814,7 +1070,15 @@ func (bd *ChainStore) persist(b *Block) error {
 			stateStore.TryAdd(ST_Vote, buf.Bytes(), &states.VoteState{PublicKeys: vote.PubKeys}, false)
 		}
 	}
-	if err := stateStore.CommitTo(); err != nil {
+	if err := addStateHistory(bd, stateStore.memoryStore.GetChangeSet(), b.Header.Height); err != nil {
+		return err
+	}
+	if b.Header.Height > 0 && b.Header.Height == config.Parameters.StateTrieHeight {
+		if err := stateStore.migrateStateTrie(); err != nil {
+			return err
+		}
+	}
+	if err := stateStore.CommitTo(b.Header.Height); err != nil {
 		return err
 	}
 	stateRoot, err := stateStore.trie.CommitTo()
@@ -830,7 +1094,7 @@ func (bd *ChainStore) persist(b *Block) error {
 	if err := addSysCurrentBlock(bd, b); err != nil {
 		return err
 	}
-	if err := addHeader(bd, b, 0); err != nil {
+	if err := addHeader(bd, b, uint64(sysFee)); err != nil {
 		return err
 	}
 	if err := addDataBlock(bd, b); err != nil {
@@ -839,6 +1103,15 @@ func (bd *ChainStore) persist(b *Block) error {
 
 	addMerkleRoot(bd, b)
 
+	if addressHistoryEnabled(bd) {
+		if err := addAddressHistory(bd, b, balances); err != nil {
+			return err
+		}
+	}
+	if err := bd.endUndo(b.Header.Height); err != nil {
+		return err
+	}
+
 	err = bd.st.BatchCommit()
 	if err != nil {
 		return err
@@ -874,7 +1147,13 @@ func (bd *ChainStore) addHeader(header *Header) {
 
 func (self *ChainStore) handlePersistHeaderTask(header *Header) {
 
-	if header.Height != uint32(len(self.headerIndex)) {
+	height := uint32(len(self.headerIndex))
+	if header.Height > height {
+		// the headers before it are not synced yet
+		return
+	}
+	if header.Height != height || header.PrevBlockHash != self.headerIndex[height-1] {
+		self.addForkHeader(header)
 		return
 	}
 
@@ -883,6 +1162,98 @@ func (self *ChainStore) handlePersistHeaderTask(header *Header) {
 	}
 
 	self.addHeader(header)
+	if DefaultLedger != nil {
+		DefaultLedger.Blockchain.BCEvents.Notify(events.EventSaveBlock, header)
+	}
+
+	if self.headerOnly {
+		if err := self.persistHeader(header); err != nil {
+			log.Error("[persistHeader] error to persist header:", err.Error())
+		}
+	}
+}
+
+// persistHeader saves a verified header of a header only store, the layout
+// matches the header part of the trimmed blocks written by persist.
+// can only be invoked by backend write goroutine
+func (self *ChainStore) persistHeader(header *Header) error {
+	hash := header.Hash()
+	self.st.NewBatch()
+
+	headerKey := bytes.NewBuffer(nil)
+	headerKey.WriteByte(byte(DATA_Header))
+	hash.Serialize(headerKey)
+	headerValue := bytes.NewBuffer(nil)
+	serialization.WriteUint64(headerValue, 0)
+	header.Serialize(headerValue)
+	self.st.BatchPut(headerKey.Bytes(), headerValue.Bytes())
+
+	hashKey := bytes.NewBuffer(nil)
+	hashKey.WriteByte(byte(DATA_Block))
+	serialization.WriteUint32(hashKey, header.Height)
+	hashValue := bytes.NewBuffer(nil)
+	hash.Serialize(hashValue)
+	self.st.BatchPut(hashKey.Bytes(), hashValue.Bytes())
+
+	currentHeader := bytes.NewBuffer(nil)
+	hash.Serialize(currentHeader)
+	serialization.WriteUint32(currentHeader, header.Height)
+	self.st.BatchPut([]byte{byte(SYS_CurrentHeader)}, currentHeader.Bytes())
+
+	storedHeaderCount := self.persistHeaderHashList(header.Height)
+	if err := self.st.BatchCommit(); err != nil {
+		return err
+	}
+
+	self.mu.Lock()
+	self.storedHeaderCount = storedHeaderCount
+	for h, cached := range self.headerCache {
+		if cached.Height+CleanCacheThreshold < header.Height {
+			delete(self.headerCache, h)
+		}
+	}
+	self.mu.Unlock()
+	return nil
+}
+
+// persistHeaderHashList adds the full header hash lists below height to the
+// current batch and returns the new stored header count.
+// can only be invoked by backend write goroutine
+func (self *ChainStore) persistHeaderHashList(height uint32) uint32 {
+	storedHeaderCount := self.storedHeaderCount
+	for height-storedHeaderCount >= HeaderHashListCount {
+		hashBuffer := new(bytes.Buffer)
+		serialization.WriteVarUint(hashBuffer, uint64(HeaderHashListCount))
+		var hashArray []byte
+		for i := 0; i < HeaderHashListCount; i++ {
+			index := storedHeaderCount + uint32(i)
+			thash := self.headerIndex[index]
+			thehash := thash.ToArray()
+			hashArray = append(hashArray, thehash...)
+		}
+		hashBuffer.Write(hashArray)
+
+		hhlPrefix := bytes.NewBuffer(nil)
+		hhlPrefix.WriteByte(byte(IX_HeaderHashList))
+		serialization.WriteUint32(hhlPrefix, storedHeaderCount)
+
+		self.st.BatchPut(hhlPrefix.Bytes(), hashBuffer.Bytes())
+		storedHeaderCount += HeaderHashListCount
+	}
+	return storedHeaderCount
+}
+
+// SaveVerifiedTransaction stores a transaction of a header only store whose
+// inclusion in the block at height was proven against the block header.
+func (self *ChainStore) SaveVerifiedTransaction(t *tx.Transaction, height uint32) error {
+	if !self.headerOnly {
+		return errors.New("[SaveVerifiedTransaction] only header only stores accept verified transactions")
+	}
+	self.st.NewBatch()
+	if err := self.SaveTransaction(t, height); err != nil {
+		return err
+	}
+	return self.st.BatchCommit()
 }
 
 func (self *ChainStore) SaveBlock(b *Block, ledger *Ledger) error {
@@ -891,12 +1262,21 @@ func (self *ChainStore) SaveBlock(b *Block, ledger *Ledger) error {
 	self.mu.RLock()
 	headerHeight := uint32(len(self.headerIndex))
 	currBlockHeight := self.currentBlockHeight
+	mainHash := self.headerIndex[b.Header.Height]
 	self.mu.RUnlock()
 
 	if b.Header.Height <= currBlockHeight {
+		if mainHash != b.Hash() {
+			// the header of a block off the main chain is kept as a fork
+			self.taskCh <- &persistHeaderTask{header: b.Header}
+		}
 		return nil
 	}
 
+	if self.headerOnly {
+		return errors.New("[SaveBlock] header only store can not save blocks")
+	}
+
 	if b.Header.Height > headerHeight {
 		return errors.New(fmt.Sprintf("Info: [SaveBlock] block height - headerIndex.count >= 1, block height:%d, headerIndex.count:%d",
 			b.Header.Height, headerHeight))
@@ -921,6 +1301,9 @@ func (self *ChainStore) SaveBlock(b *Block, ledger *Ledger) error {
 			log.Error("VerifyBlock ProgramHashes error!")
 			return err
 		}
+		if mainHash != b.Hash() {
+			self.taskCh <- &persistHeaderTask{header: b.Header}
+		}
 	}
 
 	self.taskCh <- &persistBlockTask{block: b, ledger: ledger}
@@ -937,41 +1320,28 @@ func (self *ChainStore) handlePersistBlockTask(b *Block, ledger *Ledger) {
 	self.mu.Unlock()
 
 	if b.Header.Height < uint32(len(self.headerIndex)) {
-		self.persistBlocks(ledger)
-
-		self.st.NewBatch()
-		storedHeaderCount := self.storedHeaderCount
-		for self.currentBlockHeight-storedHeaderCount >= HeaderHashListCount {
-			hashBuffer := new(bytes.Buffer)
-			serialization.WriteVarUint(hashBuffer, uint64(HeaderHashListCount))
-			var hashArray []byte
-			for i := 0; i < HeaderHashListCount; i++ {
-				index := storedHeaderCount + uint32(i)
-				thash := self.headerIndex[index]
-				thehash := thash.ToArray()
-				hashArray = append(hashArray, thehash...)
-			}
-			hashBuffer.Write(hashArray)
+		self.persistCachedBlocks(ledger)
+	}
+}
 
-			hhlPrefix := bytes.NewBuffer(nil)
-			hhlPrefix.WriteByte(byte(IX_HeaderHashList))
-			serialization.WriteUint32(hhlPrefix, storedHeaderCount)
+// persistCachedBlocks persists the cached blocks following the current block
+// can only be invoked by backend write goroutine
+func (self *ChainStore) persistCachedBlocks(ledger *Ledger) {
+	self.persistBlocks(ledger)
 
-			self.st.BatchPut(hhlPrefix.Bytes(), hashBuffer.Bytes())
-			storedHeaderCount += HeaderHashListCount
-		}
+	self.st.NewBatch()
+	storedHeaderCount := self.persistHeaderHashList(self.currentBlockHeight)
 
-		err := self.st.BatchCommit()
-		if err != nil {
-			log.Error("failed to persist header hash list:", err)
-			return
-		}
-		self.mu.Lock()
-		self.storedHeaderCount = storedHeaderCount
-		self.mu.Unlock()
-
-		self.clearCache()
+	err := self.st.BatchCommit()
+	if err != nil {
+		log.Error("failed to persist header hash list:", err)
+		return
 	}
+	self.mu.Lock()
+	self.storedHeaderCount = storedHeaderCount
+	self.mu.Unlock()
+
+	self.clearCache()
 }
 
 func (bd *ChainStore) persistBlocks(ledger *Ledger) {
@@ -996,6 +1366,18 @@ func (bd *ChainStore) persistBlocks(ledger *Ledger) {
 
 		ledger.Blockchain.BCEvents.Notify(events.EventBlockPersistCompleted, block)
 		log.Tracef("The latest block height:%d, block hash: %x", block.Header.Height, hash)
+
+		if pruningEnabled(bd) {
+			if err := bd.prune(block.Header.Height); err != nil {
+				log.Error("[persistBlocks]: error to prune blocks:", err.Error())
+			}
+		}
+
+		if snapshotDue(block.Header.Height) {
+			if _, err := bd.createSnapshot(SnapshotPath); err != nil {
+				log.Error("[persistBlocks]: error to create snapshot:", err.Error())
+			}
+		}
 	}
 
 }
@@ -1218,6 +1600,65 @@ func (bd *ChainStore) GetCurrentStateRoot() Uint256 {
 	return *u256
 }
 
+// GetStateProof returns the MPT proof of the state stored under key after the
+// block at height. The state value is returned only when it is unchanged
+// since, the proof always commits to the value hash.
+func (bd *ChainStore) GetStateProof(key []byte, height uint32) ([]byte, Uint256, [][]byte, error) {
+	if bd.isPruned(height) {
+		return nil, Uint256{}, nil, ErrPruned
+	}
+	stateRoot, err := bd.getStateRootAfter(height)
+	if err != nil {
+		return nil, Uint256{}, nil, err
+	}
+	tr, err := trie.NewSecure(stateRoot, bd.st)
+	if err != nil {
+		return nil, Uint256{}, nil, err
+	}
+	proof := tr.Prove(key)
+	valueHash, err := trie.VerifySecureProof(stateRoot, key, proof)
+	if err != nil {
+		return nil, Uint256{}, nil, err
+	}
+
+	var value []byte
+	if valueHash != nil {
+		data, err := bd.st.Get(key)
+		if err == nil {
+			h := ToHash256(data)
+			if bytes.Equal(h.ToArray(), valueHash) {
+				value = data
+			}
+		}
+	}
+	nodes := make([][]byte, len(proof))
+	for i, n := range proof {
+		nodes[i] = n
+	}
+	return value, stateRoot, nodes, nil
+}
+
+// getStateRootAfter returns the state root after the block at height, which
+// is the StateRoot in the header of the next block
+func (bd *ChainStore) getStateRootAfter(height uint32) (Uint256, error) {
+	current := bd.GetHeight()
+	if height > current {
+		return Uint256{}, errors.New("[getStateRootAfter] height is above the current block height")
+	}
+	if height == current {
+		return bd.GetCurrentStateRoot(), nil
+	}
+	hash, err := bd.GetBlockHash(height + 1)
+	if err != nil {
+		return Uint256{}, err
+	}
+	header, err := bd.GetHeader(hash)
+	if err != nil {
+		return Uint256{}, err
+	}
+	return header.StateRoot, nil
+}
+
 func (bd *ChainStore) GetIdentity(ontId []byte) ([]byte, error) {
 	idPrefix := []byte{byte(ST_Identity)}
 	idKey := append(idPrefix, ontId...)
@@ -1256,6 +1697,18 @@ func (bd *ChainStore) GetSysFeeAmount(hash Uint256) (Fixed64, error) {
 	return *amount, nil
 }
 
+func (bd *ChainStore) GetGasConsumed(hash Uint256) (Fixed64, error) {
+	gas := new(Fixed64)
+	data, err := bd.st.Get(append([]byte{byte(DATA_GasConsumed)}, hash.ToArray()...))
+	if err != nil {
+		return Fixed64(0), err
+	}
+	if err := gas.Deserialize(bytes.NewReader(data)); err != nil {
+		return Fixed64(0), err
+	}
+	return *gas, nil
+}
+
 func (bd *ChainStore) GetVoteStates() (map[Uint160]*states.VoteState, error) {
 	votes := make(map[Uint160]*states.VoteState)
 	iter := bd.st.NewIterator([]byte{byte(ST_Vote)})
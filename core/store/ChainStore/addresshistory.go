@@ -0,0 +1,246 @@
+package ChainStore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sort"
+
+	. "github.com/Ontology/common"
+	"github.com/Ontology/common/config"
+	"github.com/Ontology/common/log"
+	. "github.com/Ontology/core/ledger"
+	"github.com/Ontology/core/states"
+	. "github.com/Ontology/core/store"
+	tx "github.com/Ontology/core/transaction"
+	"github.com/Ontology/core/transaction/utxo"
+	"github.com/Ontology/smartcontract/storage"
+)
+
+// an address history key ends with the inverted block height, the txid, the
+// asset id and the direction, so the newest records of an address come first
+const historyCursorLen = 4 + UINT256SIZE + UINT256SIZE + 1
+
+func addressHistoryEnabled(bd *ChainStore) bool {
+	return config.Parameters.AddressHistory && !bd.headerOnly
+}
+
+func addressHistoryPrefix(programHash Uint160) []byte {
+	return append([]byte{byte(IX_AddressHistory)}, programHash.ToArray()...)
+}
+
+func addressHistoryKey(h *states.AddressHistory) []byte {
+	buf := make([]byte, historyCursorLen)
+	binary.BigEndian.PutUint32(buf, ^h.Height)
+	copy(buf[4:], h.TxHash.ToArray())
+	copy(buf[4+UINT256SIZE:], h.AssetId.ToArray())
+	buf[historyCursorLen-1] = h.Direction
+	return append(addressHistoryPrefix(h.ProgramHash), buf...)
+}
+
+// balanceHistory lists the balance changes an invocation makes to the
+// accounts in its cache, compared with the accounts in the state store
+func balanceHistory(txHash Uint256, height uint32, memory storage.Memory, stateStore IStateStore) ([]*states.AddressHistory, error) {
+	var history []*states.AddressHistory
+	for _, v := range memory {
+		if v.Prefix != ST_Account || v.State == None {
+			continue
+		}
+		programHash, err := Uint160ParseFromBytes([]byte(v.Key))
+		if err != nil {
+			return nil, err
+		}
+		amounts := make(map[Uint256]Fixed64)
+		if account, ok := v.Value.(*states.AccountState); ok && v.State != Deleted {
+			for _, b := range account.Balances {
+				amounts[b.AssetId] += b.Amount
+			}
+		}
+		prev, err := stateStore.TryGet(ST_Account, []byte(v.Key))
+		if err != nil {
+			return nil, err
+		}
+		if prev != nil {
+			for _, b := range prev.Value.(*states.AccountState).Balances {
+				amounts[b.AssetId] -= b.Amount
+			}
+		}
+		for assetId, amount := range amounts {
+			h := &states.AddressHistory{
+				ProgramHash: programHash,
+				Height:      height,
+				TxHash:      txHash,
+				AssetId:     assetId,
+				Direction:   states.TransferIn,
+				Amount:      amount,
+			}
+			if amount == 0 {
+				continue
+			} else if amount < 0 {
+				h.Direction, h.Amount = states.TransferOut, -amount
+			}
+			history = append(history, h)
+		}
+	}
+	return history, nil
+}
+
+// transactionHistory sums the amounts a transaction moves per address, asset
+// and direction, getTx returns the transactions the inputs refer to and
+// balances the balance changes of an invocation
+func transactionHistory(t *tx.Transaction, height uint32, getTx func(Uint256) (*tx.Transaction, error),
+	balances []*states.AddressHistory) ([]*states.AddressHistory, error) {
+	records := make(map[string]*states.AddressHistory)
+	record := func(h *states.AddressHistory) {
+		key := string(addressHistoryKey(h))
+		if r, ok := records[key]; ok {
+			r.Amount += h.Amount
+			return
+		}
+		records[key] = h
+	}
+	add := func(output *utxo.TxOutput, direction byte) {
+		record(&states.AddressHistory{
+			ProgramHash: output.ProgramHash,
+			Height:      height,
+			TxHash:      t.Hash(),
+			AssetId:     output.AssetID,
+			Direction:   direction,
+			Amount:      output.Value,
+		})
+	}
+	for _, input := range t.UTXOInputs {
+		prev, err := getTx(input.ReferTxID)
+		if err != nil {
+			return nil, err
+		}
+		if int(input.ReferTxOutputIndex) >= len(prev.Outputs) {
+			return nil, errors.New("[transactionHistory] input refers to a missing output")
+		}
+		add(prev.Outputs[input.ReferTxOutputIndex], states.TransferOut)
+	}
+	for _, output := range t.Outputs {
+		add(output, states.TransferIn)
+	}
+	for _, h := range balances {
+		b := *h
+		record(&b)
+	}
+
+	keys := make([]string, 0, len(records))
+	for k := range records {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	history := make([]*states.AddressHistory, 0, len(keys))
+	for _, k := range keys {
+		history = append(history, records[k])
+	}
+	return history, nil
+}
+
+// addAddressHistory puts the address history of a block into the current
+// batch and marks the block as indexed, balances holds the balance changes
+// of the invocations of the block by transaction
+func addAddressHistory(bd *ChainStore, b *Block, balances map[Uint256][]*states.AddressHistory) error {
+	txs := make(map[Uint256]*tx.Transaction, len(b.Transactions))
+	for _, t := range b.Transactions {
+		txs[t.Hash()] = t
+	}
+	getTx := func(hash Uint256) (*tx.Transaction, error) {
+		if t, ok := txs[hash]; ok {
+			return t, nil
+		}
+		return bd.GetTransaction(hash)
+	}
+	for _, t := range b.Transactions {
+		history, err := transactionHistory(t, b.Header.Height, getTx, balances[t.Hash()])
+		if err != nil {
+			return err
+		}
+		for _, h := range history {
+			value := new(bytes.Buffer)
+			if err := h.Serialize(value); err != nil {
+				return err
+			}
+			if err := bd.st.BatchPut(addressHistoryKey(h), value.Bytes()); err != nil {
+				return err
+			}
+		}
+	}
+	height := make([]byte, 4)
+	binary.BigEndian.PutUint32(height, b.Header.Height)
+	return bd.st.BatchPut([]byte{byte(SYS_AddressHistory)}, height)
+}
+
+// rebuildAddressHistory indexes the persisted blocks the address history
+// misses, the blocks persisted while the index was disabled. The balance
+// changes of invocations are only known while a block is executed, the
+// rebuilt history holds the transfers of the transactions.
+func (bd *ChainStore) rebuildAddressHistory() error {
+	start := uint32(0)
+	if buf, err := bd.st.Get([]byte{byte(SYS_AddressHistory)}); err == nil && len(buf) == 4 {
+		start = binary.BigEndian.Uint32(buf) + 1
+	}
+	if start < bd.prunedHeight {
+		log.Warnf("address history of the pruned blocks %d to %d can't be rebuilt", start, bd.prunedHeight-1)
+		start = bd.prunedHeight
+	}
+	if start <= bd.currentBlockHeight {
+		log.Infof("rebuilding address history from height %d to %d", start, bd.currentBlockHeight)
+		log.Warn("the rebuilt address history misses the balance changes of invocations")
+	}
+	for height := start; height <= bd.currentBlockHeight; height++ {
+		hash, err := bd.GetBlockHash(height)
+		if err != nil {
+			return err
+		}
+		b, err := bd.GetBlock(hash)
+		if err != nil {
+			return err
+		}
+		bd.st.NewBatch()
+		if err := addAddressHistory(bd, b, nil); err != nil {
+			return err
+		}
+		if err := bd.st.BatchCommit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAddressHistory returns up to limit history records of an address, the
+// newest first. The query starts at cursor when it is given, the returned
+// cursor is nil after the last page.
+func (bd *ChainStore) GetAddressHistory(programHash Uint160, limit int, cursor []byte) ([]*states.AddressHistory, []byte, error) {
+	if !addressHistoryEnabled(bd) {
+		return nil, nil, ErrAddressHistoryDisabled
+	}
+	if len(cursor) > 0 && len(cursor) != historyCursorLen {
+		return nil, nil, ErrInvalidCursor
+	}
+	limit = queryLimit(limit)
+	prefix := addressHistoryPrefix(programHash)
+	iter := bd.st.NewIterator(prefix)
+	defer iter.Release()
+
+	var history []*states.AddressHistory
+	ok := iter.First()
+	if len(cursor) > 0 {
+		ok = iter.Seek(append(prefix, cursor...))
+	}
+	for ; ok; ok = iter.Next() {
+		if len(history) == limit {
+			next := make([]byte, historyCursorLen)
+			copy(next, iter.Key()[len(prefix):])
+			return history, next, nil
+		}
+		h := new(states.AddressHistory)
+		if err := h.Deserialize(bytes.NewReader(iter.Value())); err != nil {
+			return nil, nil, err
+		}
+		history = append(history, h)
+	}
+	return history, nil, nil
+}
@@ -0,0 +1,132 @@
+package ChainStore
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/Ontology/common"
+	"github.com/Ontology/common/config"
+	. "github.com/Ontology/core/ledger"
+	"github.com/Ontology/core/states"
+	. "github.com/Ontology/core/store"
+	"github.com/Ontology/core/store/LevelDBStore"
+	"github.com/Ontology/core/store/statestore"
+	tx "github.com/Ontology/core/transaction"
+	"github.com/Ontology/core/transaction/payload"
+	"github.com/Ontology/core/transaction/utxo"
+	"github.com/Ontology/smartcontract/storage"
+)
+
+func TestAddressHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "addresshistory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	st, err := LevelDBStore.NewLevelDBStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	bd := &ChainStore{st: st}
+	enabled := config.Parameters.AddressHistory
+	config.Parameters.AddressHistory = true
+	defer func() { config.Parameters.AddressHistory = enabled }()
+
+	alice, bob := Uint160{1}, Uint160{2}
+	asset := Uint256{9}
+	funding := &tx.Transaction{TxType: tx.TransferAsset, Payload: &payload.TransferAsset{}, Outputs: []*utxo.TxOutput{
+		{AssetID: asset, ProgramHash: alice, Value: 30},
+		{AssetID: asset, ProgramHash: alice, Value: 20},
+	}}
+	// spends both outputs of the funding transaction in the same block
+	spending := &tx.Transaction{TxType: tx.TransferAsset, Payload: &payload.TransferAsset{},
+		UTXOInputs: []*utxo.UTXOTxInput{
+			{ReferTxID: funding.Hash(), ReferTxOutputIndex: 0},
+			{ReferTxID: funding.Hash(), ReferTxOutputIndex: 1},
+		},
+		Outputs: []*utxo.TxOutput{
+			{AssetID: asset, ProgramHash: bob, Value: 40},
+			{AssetID: asset, ProgramHash: alice, Value: 10},
+		},
+	}
+	st.NewBatch()
+	b := &Block{Header: &Header{Height: 3}, Transactions: []*tx.Transaction{funding, spending}}
+	if err := addAddressHistory(bd, b, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.BatchCommit(); err != nil {
+		t.Fatal(err)
+	}
+
+	history, cursor, err := bd.GetAddressHistory(alice, 0, nil)
+	if err != nil || cursor != nil {
+		t.Fatal(err, cursor)
+	}
+	amounts := make(map[Uint256]map[byte]Fixed64)
+	for _, h := range history {
+		if h.Height != 3 || h.AssetId != asset {
+			t.Errorf("unexpected record %+v", h)
+		}
+		if amounts[h.TxHash] == nil {
+			amounts[h.TxHash] = make(map[byte]Fixed64)
+		}
+		amounts[h.TxHash][h.Direction] += h.Amount
+	}
+	if len(history) != 3 || amounts[funding.Hash()][states.TransferIn] != 50 ||
+		amounts[spending.Hash()][states.TransferOut] != 50 || amounts[spending.Hash()][states.TransferIn] != 10 {
+		t.Errorf("unexpected history of alice %v", amounts)
+	}
+
+	page, cursor, err := bd.GetAddressHistory(alice, 2, nil)
+	if err != nil || len(page) != 2 || cursor == nil {
+		t.Fatal("unexpected first page", len(page), err)
+	}
+	page, cursor, err = bd.GetAddressHistory(alice, 2, cursor)
+	if err != nil || len(page) != 1 || cursor != nil {
+		t.Fatal("unexpected last page", len(page), err)
+	}
+
+	history, _, err = bd.GetAddressHistory(bob, 0, nil)
+	if err != nil || len(history) != 1 || history[0].Amount != 40 || history[0].Direction != states.TransferIn {
+		t.Errorf("unexpected history of bob %v", err)
+	}
+}
+
+func TestBalanceHistory(t *testing.T) {
+	defer func(backend string) { config.Parameters.StoreBackend = backend }(config.Parameters.StoreBackend)
+	config.Parameters.StoreBackend = MemoryBackend
+	bd, err := NewChainStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bd.Close()
+	stateStore := NewStateStore(statestore.NewMemDatabase(), bd, Uint256{})
+
+	alice, bob := Uint160{1}, Uint160{2}
+	asset := Uint256{9}
+	stateStore.TryAdd(ST_Account, alice.ToArray(), &states.AccountState{ProgramHash: alice,
+		Balances: []*states.Balance{{AssetId: asset, Amount: 50}}}, true)
+
+	// an invocation moving 20 from alice to bob
+	cache := storage.NewCloneCache(stateStore)
+	cache.Add(ST_Account, alice.ToArray(), &states.AccountState{ProgramHash: alice,
+		Balances: []*states.Balance{{AssetId: asset, Amount: 30}}})
+	cache.Add(ST_Account, bob.ToArray(), &states.AccountState{ProgramHash: bob,
+		Balances: []*states.Balance{{AssetId: asset, Amount: 20}}})
+	history, err := balanceHistory(Uint256{7}, 3, cache.Memory, stateStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("%d balance changes, want 2", len(history))
+	}
+	for _, h := range history {
+		if h.TxHash != (Uint256{7}) || h.AssetId != asset || h.Amount != 20 ||
+			h.ProgramHash == alice && h.Direction != states.TransferOut ||
+			h.ProgramHash == bob && h.Direction != states.TransferIn {
+			t.Errorf("unexpected balance change %+v", h)
+		}
+	}
+}
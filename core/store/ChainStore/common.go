@@ -6,12 +6,17 @@ import (
 	. "github.com/Ontology/common"
 	"github.com/Ontology/common/log"
 	"github.com/Ontology/common/serialization"
+	"github.com/Ontology/core/code"
 	"github.com/Ontology/core/ledger"
 	. "github.com/Ontology/core/states"
 	. "github.com/Ontology/core/store"
 	tx "github.com/Ontology/core/transaction"
+	"github.com/Ontology/core/transaction/payload"
 	"github.com/Ontology/core/transaction/utxo"
 	"github.com/Ontology/crypto"
+	sc "github.com/Ontology/smartcontract"
+	"github.com/Ontology/smartcontract/service"
+	"github.com/Ontology/smartcontract/types"
 	"math/big"
 )
 
@@ -158,7 +163,7 @@ func addHeader(bd *ChainStore, b *ledger.Block, curr_block_sysfee uint64) error
 	}
 	value := new(bytes.Buffer)
 
-	sysfee := Fixed64(0)
+	sysfee := Fixed64(curr_block_sysfee)
 	if err := sysfee.Serialize(value); err != nil {
 		return err
 	}
@@ -167,6 +172,54 @@ func addHeader(bd *ChainStore, b *ledger.Block, curr_block_sysfee uint64) error
 	return nil
 }
 
+func addGasConsumed(bd *ChainStore, txid Uint256, gas Fixed64) error {
+	key := append([]byte{byte(DATA_GasConsumed)}, txid.ToArray()...)
+	value := new(bytes.Buffer)
+	if err := gas.Serialize(value); err != nil {
+		return err
+	}
+	return bd.st.BatchPut(key, value.Bytes())
+}
+
+// Invoker is the program hash of the first signer of the transaction, it is
+// the caller of the contracts the transaction invokes
+func Invoker(t *tx.Transaction) Uint160 {
+	if len(t.Programs) == 0 {
+		return Uint160{}
+	}
+	return ToCodeHash(t.Programs[0].Code)
+}
+
+// createEVMContract runs the init code of an EVM contract and returns the
+// function code holding the runtime code it returned, the constructor is only
+// given the free gas allowance as deployments do not pay for gas
+func createEVMContract(stateStore *StateStore, t *tx.Transaction, b *ledger.Block, deploy *payload.DeployCode) (*code.FunctionCode, error) {
+	stateMachine := service.NewStateMachine(stateStore, types.Application, b)
+	smc, err := sc.NewSmartContract(&sc.Context{
+		VmType:       types.EVM,
+		Caller:       Invoker(t),
+		StateMachine: stateMachine,
+		SignableData: t,
+		Code:         deploy.Code.Code,
+		CodeHash:     deploy.Code.CodeHash(),
+		Time:         big.NewInt(int64(b.Header.Timestamp)),
+		BlockNumber:  big.NewInt(int64(b.Header.Height)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	runtime, err := smc.DeployContract()
+	if err != nil {
+		return nil, err
+	}
+	stateMachine.CloneCache.Commit()
+	return &code.FunctionCode{
+		Code:           runtime,
+		ParameterTypes: deploy.Code.ParameterTypes,
+		ReturnType:     deploy.Code.ReturnType,
+	}, nil
+}
+
 func addSysCurrentBlock(bd *ChainStore, b *ledger.Block) error {
 	key := bytes.NewBuffer(append([]byte{byte(SYS_CurrentBlock)}))
 	value := new(bytes.Buffer)
@@ -202,6 +255,12 @@ func addCurrentStateRoot(bd *ChainStore, stateRoot Uint256) error {
 func addMerkleRoot(bd *ChainStore, b *ledger.Block) {
 	// update merkle tree
 	bd.merkleTree.AppendHash(b.Header.TransactionsRoot)
+	putMerkleTree(bd)
+}
+
+// putMerkleTree flushes the hash store and adds the compact merkle tree to
+// the current batch
+func putMerkleTree(bd *ChainStore) {
 	bd.merkleHashStore.Flush()
 
 	tree_size := bd.merkleTree.TreeSize()
@@ -226,3 +285,75 @@ func groupInputs(inputs []*utxo.UTXOTxInput) map[Uint256][]*utxo.UTXOTxInput {
 func remove(items []*Item, index int) []*Item {
 	return append(items[:index], items[index+1:]...)
 }
+
+// historyPrefixes are the states whose past values are kept for queries at
+// a block height
+var historyPrefixes = map[DataEntryPrefix]bool{
+	ST_Account:  true,
+	ST_Asset:    true,
+	ST_Contract: true,
+	ST_Storage:  true,
+}
+
+// historyKeyPrefix returns the prefix of the history records of the state
+// stored under key, the key length is included so that no key is the prefix
+// of another one
+func historyKeyPrefix(key []byte) []byte {
+	buf := bytes.NewBuffer([]byte{byte(ST_History)})
+	serialization.WriteVarBytes(buf, key)
+	return buf.Bytes()
+}
+
+// historyKey appends the inverted height, the newest record at or below a
+// height is the first one found by seeking its history key
+func historyKey(key []byte, height uint32) []byte {
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, ^height)
+	return append(historyKeyPrefix(key), buf...)
+}
+
+// loadHistoryHeight reads the height the state history starts from. A chain
+// persisted before the history was kept has none, its history starts with
+// the next block.
+func (bd *ChainStore) loadHistoryHeight() error {
+	buf, err := bd.st.Get([]byte{byte(SYS_StateHistory)})
+	if err == nil && len(buf) == 4 {
+		bd.historyHeight = binary.BigEndian.Uint32(buf)
+		return nil
+	}
+	bd.historyHeight = bd.currentBlockHeight + 1
+	log.Infof("state history starts at height %d", bd.historyHeight)
+	buf = make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, bd.historyHeight)
+	return bd.st.Put([]byte{byte(SYS_StateHistory)}, buf)
+}
+
+// addStateHistory records the states of the change set written by the block
+// at height, it runs before the change set is committed. The states read for
+// a change which did not happen are in the change set too, they are only
+// recorded when their value differs from the stored one.
+func addStateHistory(bd *ChainStore, changeSet map[string]*StateItem, height uint32) error {
+	for k, v := range changeSet {
+		if len(k) == 0 || !historyPrefixes[DataEntryPrefix(k[0])] {
+			continue
+		}
+		data := new(bytes.Buffer)
+		if v.State != Deleted {
+			if err := v.Value.Serialize(data); err != nil {
+				return err
+			}
+		}
+		prev, err := bd.st.Get([]byte(k))
+		if err != nil && err.Error() != ErrDBNotFound {
+			return err
+		}
+		existed := err == nil
+		if v.State == Deleted && !existed || v.State != Deleted && existed && bytes.Equal(prev, data.Bytes()) {
+			continue
+		}
+		if err := bd.st.BatchPut(historyKey([]byte(k), height), data.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
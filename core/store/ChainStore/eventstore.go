@@ -8,12 +8,23 @@ import (
 	"github.com/Ontology/core/states"
 	"bytes"
 	"github.com/Ontology/common/serialization"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
 )
 
 const (
 	EventDBDir = "Event"
+
+	// bounds of the page size of the event and address history queries
+	DefaultQueryLimit = 100
+	MaxQueryLimit     = 1000
 )
 
+// an event index key ends with the block height, the txid and the position of
+// the notification in the transaction
+const eventCursorLen = 4 + common.UINT256SIZE + 2
+
 var DefaultEventStore IEventStore
 
 type IEventStore interface {
@@ -21,9 +32,19 @@ type IEventStore interface {
 	SaveEventNotifyInBlock(height uint32, txids *states.EventTxState) error
 	GetEventNotifyByTx(txid common.Uint256) ([]*event.NotifyEventInfo, error)
 	GetEventNotifyTxIds(height uint32) (*states.EventTxState, error)
+	SaveEventNotifyIndex(height uint32, notifies []*event.NotifyEventInfo) error
+	DeleteEventNotifyInBlock(height uint32) error
+	DeleteEventNotifyAbove(height uint32) error
+	GetEvents(codeHash common.Uint160, topic string, fromHeight, toHeight uint32, limit int, cursor []byte) ([]*IndexedNotify, []byte, error)
 	BatchCommit() error
 }
 
+// IndexedNotify is a notification found by the event index
+type IndexedNotify struct {
+	Height uint32
+	Notify *event.NotifyEventInfo
+}
+
 type EventStore struct {
 	st IStore
 }
@@ -33,6 +54,9 @@ func NewEventStore() (IEventStore, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := st.NewBatch(); err != nil {
+		return nil, err
+	}
 	return &EventStore{st}, nil
 }
 
@@ -85,7 +109,149 @@ func (this *EventStore) GetEventNotifyTxIds(height uint32) (*states.EventTxState
 	return txids, nil
 }
 
+// SaveEventNotifyIndex indexes the notifications of a transaction by contract
+// and by contract and topic
+func (this *EventStore) SaveEventNotifyIndex(height uint32, notifies []*event.NotifyEventInfo) error {
+	for i, notify := range notifies {
+		value, err := json.Marshal(notify)
+		if err != nil {
+			return err
+		}
+		suffix := eventCursor(height, notify.Container, uint16(i))
+		if err := this.st.BatchPut(append(eventIndexPrefix(notify.CodeHash, ""), suffix...), value); err != nil {
+			return err
+		}
+		if topic := notify.Topic(); len(topic) > 0 {
+			if err := this.st.BatchPut(append(eventIndexPrefix(notify.CodeHash, topic), suffix...), value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DeleteEventNotifyInBlock removes the notifications of the transactions of
+// the block at height and their index, the block was reverted. Deleting the
+// notifications of a block again does nothing.
+func (this *EventStore) DeleteEventNotifyInBlock(height uint32) error {
+	txids, err := this.GetEventNotifyTxIds(height)
+	if err != nil {
+		// the block has no notification
+		return nil
+	}
+	for _, txid := range txids.Txids {
+		notifies, err := this.GetEventNotifyByTx(txid)
+		if err != nil && err.Error() != ErrDBNotFound {
+			return err
+		}
+		for i, notify := range notifies {
+			suffix := eventCursor(height, notify.Container, uint16(i))
+			if err := this.st.BatchDelete(append(eventIndexPrefix(notify.CodeHash, ""), suffix...)); err != nil {
+				return err
+			}
+			if topic := notify.Topic(); len(topic) > 0 {
+				if err := this.st.BatchDelete(append(eventIndexPrefix(notify.CodeHash, topic), suffix...)); err != nil {
+					return err
+				}
+			}
+		}
+		if err := this.st.BatchDelete(append([]byte{byte(EVENT_Notify)}, txid.ToArray()...)); err != nil {
+			return err
+		}
+	}
+	f := new(bytes.Buffer)
+	if err := serialization.WriteUint32(f, height); err != nil {
+		return err
+	}
+	if err := this.st.BatchDelete(append([]byte{byte(EVENT_Notify)}, f.Bytes()...)); err != nil {
+		return err
+	}
+	return this.BatchCommit()
+}
+
+// DeleteEventNotifyAbove deletes the notifications of the blocks above
+// height. A block is reverted before its notifications are deleted, the
+// deletion interrupted by a crash is done again at startup.
+func (this *EventStore) DeleteEventNotifyAbove(height uint32) error {
+	for h := height + 1; h <= height+MaxReorgDepth; h++ {
+		if err := this.DeleteEventNotifyInBlock(h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetEvents returns up to limit notifications of a contract, of one topic
+// when topic is not empty, from fromHeight to toHeight. The query starts at
+// cursor when it is given, the returned cursor is nil after the last page.
+func (this *EventStore) GetEvents(codeHash common.Uint160, topic string, fromHeight, toHeight uint32,
+	limit int, cursor []byte) ([]*IndexedNotify, []byte, error) {
+	limit = queryLimit(limit)
+	start := eventCursor(fromHeight, common.Uint256{}, 0)
+	if len(cursor) > 0 {
+		if len(cursor) != eventCursorLen {
+			return nil, nil, errors.New("[GetEvents] invalid cursor")
+		}
+		if bytes.Compare(cursor, start) > 0 {
+			start = cursor
+		}
+	}
+	prefix := eventIndexPrefix(codeHash, topic)
+	iter := this.st.NewIterator(prefix)
+	defer iter.Release()
+
+	var notifies []*IndexedNotify
+	for ok := iter.Seek(append(prefix, start...)); ok; ok = iter.Next() {
+		suffix := iter.Key()[len(prefix):]
+		height := binary.BigEndian.Uint32(suffix)
+		if height > toHeight {
+			break
+		}
+		if len(notifies) == limit {
+			next := make([]byte, len(suffix))
+			copy(next, suffix)
+			return notifies, next, nil
+		}
+		notify := new(event.NotifyEventInfo)
+		if err := json.Unmarshal(iter.Value(), notify); err != nil {
+			return nil, nil, err
+		}
+		notifies = append(notifies, &IndexedNotify{Height: height, Notify: notify})
+	}
+	return notifies, nil, nil
+}
+
 func (this *EventStore) BatchCommit() error {
-	return this.st.BatchCommit()
+	if err := this.st.BatchCommit(); err != nil {
+		return err
+	}
+	return this.st.NewBatch()
+}
+
+func queryLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultQueryLimit
+	}
+	if limit > MaxQueryLimit {
+		return MaxQueryLimit
+	}
+	return limit
+}
+
+func eventIndexPrefix(codeHash common.Uint160, topic string) []byte {
+	if len(topic) == 0 {
+		return append([]byte{byte(IX_EventContract)}, codeHash.ToArray()...)
+	}
+	topicHash := sha256.Sum256([]byte(topic))
+	prefix := append([]byte{byte(IX_EventTopic)}, codeHash.ToArray()...)
+	return append(prefix, topicHash[:]...)
+}
+
+func eventCursor(height uint32, txid common.Uint256, index uint16) []byte {
+	buf := make([]byte, eventCursorLen)
+	binary.BigEndian.PutUint32(buf, height)
+	copy(buf[4:], txid.ToArray())
+	binary.BigEndian.PutUint16(buf[4+common.UINT256SIZE:], index)
+	return buf
 }
 
@@ -0,0 +1,115 @@
+package ChainStore
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/Ontology/common"
+	"github.com/Ontology/core/states"
+	"github.com/Ontology/core/store/LevelDBStore"
+	scommon "github.com/Ontology/smartcontract/common"
+	"github.com/Ontology/smartcontract/event"
+	"github.com/Ontology/vm/neovm/types"
+)
+
+func TestEventStore_GetEvents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eventstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	st, err := LevelDBStore.NewLevelDBStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	st.NewBatch()
+	store := &EventStore{st}
+
+	codeHash, other := Uint160{1}, Uint160{2}
+	notify := func(txid byte, hash Uint160, topic string) *event.NotifyEventInfo {
+		// the states are stored as the NeoVM notify converts them
+		states := types.NewArray([]types.StackItemInterface{
+			types.NewByteArray([]byte(topic)),
+			types.NewByteArray([]byte{0}),
+		})
+		return &event.NotifyEventInfo{
+			Container: Uint256{txid},
+			CodeHash:  hash,
+			States:    scommon.ConvertReturnTypes(states),
+		}
+	}
+	for height := uint32(1); height <= 5; height++ {
+		notifies := []*event.NotifyEventInfo{
+			notify(byte(height), codeHash, "transfer"),
+			notify(byte(height), codeHash, "approve"),
+			notify(byte(height), other, "transfer"),
+		}
+		if err := store.SaveEventNotifyIndex(height, notifies); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SaveEventNotifyInTx(Uint256{byte(height)}, notifies); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SaveEventNotifyInBlock(height, &states.EventTxState{Txids: []Uint256{{byte(height)}}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.BatchCommit(); err != nil {
+		t.Fatal(err)
+	}
+
+	all, cursor, err := store.GetEvents(codeHash, "", 2, 4, 0, nil)
+	if err != nil || cursor != nil {
+		t.Fatal(err, cursor)
+	}
+	if len(all) != 6 || all[0].Height != 2 || all[5].Height != 4 {
+		t.Fatalf("unexpected events %d", len(all))
+	}
+
+	var pages [][]*IndexedNotify
+	cursor = nil
+	for {
+		page, next, err := store.GetEvents(codeHash, "transfer", 0, 5, 2, cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, page)
+		if next == nil {
+			break
+		}
+		cursor = next
+	}
+	if len(pages) != 3 || len(pages[2]) != 1 {
+		t.Fatalf("unexpected pages %d", len(pages))
+	}
+	for _, page := range pages {
+		for _, n := range page {
+			if n.Notify.CodeHash != codeHash || n.Notify.Topic() != "transfer" {
+				t.Errorf("unexpected event at height %d", n.Height)
+			}
+		}
+	}
+	if _, _, err := store.GetEvents(codeHash, "", 0, 5, 0, []byte{1}); err == nil {
+		t.Error("invalid cursor accepted")
+	}
+
+	// the notifications of the blocks reverted above height 3, the deletion
+	// of block 5 is done again
+	if err := store.DeleteEventNotifyInBlock(5); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeleteEventNotifyAbove(3); err != nil {
+		t.Fatal(err)
+	}
+	if all, _, err := store.GetEvents(codeHash, "", 0, 5, 0, nil); err != nil || len(all) != 6 || all[5].Height != 3 {
+		t.Fatalf("unexpected events after the deletion %d, %v", len(all), err)
+	}
+	if _, err := store.GetEventNotifyByTx(Uint256{4}); err == nil {
+		t.Error("notifications of a reverted block kept")
+	}
+	if _, err := store.GetEventNotifyByTx(Uint256{3}); err != nil {
+		t.Error("notifications of a block below the height deleted")
+	}
+}
@@ -0,0 +1,254 @@
+package ChainStore
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	. "github.com/Ontology/common"
+	"github.com/Ontology/common/config"
+	"github.com/Ontology/common/log"
+	"github.com/Ontology/common/serialization"
+	"github.com/Ontology/core/contract/program"
+	. "github.com/Ontology/core/ledger"
+	"github.com/Ontology/core/states"
+	. "github.com/Ontology/core/store"
+	"github.com/Ontology/trie"
+)
+
+// PruneInterval is the number of blocks between two collections of the
+// expired spent coins and the unreachable trie nodes
+const PruneInterval = 1000
+
+// prunedTxLen is the length of the record left for a discarded transaction,
+// the height it keeps makes the duplicate check still find the transaction
+const prunedTxLen = 4
+
+// pruningEnabled tells whether the store keeps the bodies of the last
+// PruneBlocks blocks only
+func pruningEnabled(bd *ChainStore) bool {
+	return config.Parameters.PruneBlocks > 0 && !bd.headerOnly
+}
+
+// loadPrunedHeight reads the height below which the block bodies are pruned
+func (bd *ChainStore) loadPrunedHeight() {
+	if buf, err := bd.st.Get([]byte{byte(SYS_PrunedHeight)}); err == nil && len(buf) == 4 {
+		bd.prunedHeight = binary.BigEndian.Uint32(buf)
+	}
+}
+
+func putPrunedHeight(bd *ChainStore, height uint32) error {
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, height)
+	return bd.st.BatchPut([]byte{byte(SYS_PrunedHeight)}, buf)
+}
+
+// isPruned tells whether the block at height has no body
+func (bd *ChainStore) isPruned(height uint32) bool {
+	bd.mu.RLock()
+	defer bd.mu.RUnlock()
+	return height < bd.prunedHeight
+}
+
+// prune discards the bodies of the blocks older than the last PruneBlocks
+// ones, and every PruneInterval blocks the spent coins older than the claim
+// window and the trie nodes no kept state root references. The last
+// MaxReorgDepth blocks are kept whatever the config so that they can be
+// reverted.
+// can only be invoked by backend write goroutine
+func (bd *ChainStore) prune(height uint32) error {
+	keep := config.Parameters.PruneBlocks
+	if keep < MaxReorgDepth {
+		keep = MaxReorgDepth
+	}
+	if height+1 <= keep {
+		return nil
+	}
+	target := height + 1 - keep
+	if target > bd.prunedHeight {
+		bd.st.NewBatch()
+		for h := bd.prunedHeight; h < target; h++ {
+			if err := bd.pruneBlock(h); err != nil {
+				return err
+			}
+		}
+		if err := putPrunedHeight(bd, target); err != nil {
+			return err
+		}
+		if err := bd.st.BatchCommit(); err != nil {
+			return err
+		}
+		bd.mu.Lock()
+		bd.prunedHeight = target
+		bd.mu.Unlock()
+	}
+	if height%PruneInterval != 0 {
+		return nil
+	}
+	if err := bd.pruneSpentCoins(height); err != nil {
+		return err
+	}
+	return bd.pruneTrie(height)
+}
+
+// pruneBlock adds the removal of the transactions of the block at height to
+// the current batch, the transactions with outputs left to spend or claim
+// are kept
+func (bd *ChainStore) pruneBlock(height uint32) error {
+	hash := bd.headerIndex[height]
+	data, err := bd.st.Get(append([]byte{byte(DATA_Header)}, hash.ToArray()...))
+	if err != nil {
+		return err
+	}
+	r := bytes.NewReader(data)
+	if _, err := serialization.ReadUint64(r); err != nil {
+		return err
+	}
+	b := new(Block)
+	b.Header = new(Header)
+	b.Header.Program = new(program.Program)
+	if err := b.FromTrimmedData(r); err != nil {
+		return err
+	}
+	for _, t := range b.Transactions {
+		if err := bd.pruneTx(t.Hash()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneTx replaces the transaction with its height unless it is still needed
+func (bd *ChainStore) pruneTx(txid Uint256) error {
+	if data, err := bd.st.Get(append([]byte{byte(ST_Coin)}, txid.ToArray()...)); err == nil {
+		coins := new(states.UnspentCoinState)
+		if err := coins.Deserialize(bytes.NewReader(data)); err != nil {
+			return err
+		}
+		for _, item := range coins.Item {
+			if item != states.Spent {
+				return nil
+			}
+		}
+	}
+	if ok, _ := bd.st.Has(append([]byte{byte(ST_SpentCoin)}, txid.ToArray()...)); ok {
+		return nil
+	}
+	key := append([]byte{byte(DATA_Transaction)}, txid.ToArray()...)
+	data, err := bd.st.Get(key)
+	if err != nil || len(data) <= prunedTxLen {
+		return nil
+	}
+	return bd.st.BatchPut(key, data[:prunedTxLen])
+}
+
+// pruneSpentCoins drops the spent outputs spent before the claim window, the
+// transactions of the pruned blocks they kept are pruned with them. The spent
+// coins are not in the state trie, and the outputs spent by the last
+// MaxReorgDepth blocks are kept so that the blocks can be reverted.
+// can only be invoked by backend write goroutine
+func (bd *ChainStore) pruneSpentCoins(height uint32) error {
+	window := config.Parameters.ClaimWindow
+	if window == 0 {
+		return nil
+	}
+	if window < MaxReorgDepth {
+		window = MaxReorgDepth
+	}
+	if height < window {
+		return nil
+	}
+	var expired []Uint256
+	bd.st.NewBatch()
+	iter := bd.st.NewIterator([]byte{byte(ST_SpentCoin)})
+	for iter.Next() {
+		spent := new(states.SpentCoinState)
+		if err := spent.Deserialize(bytes.NewReader(iter.Value())); err != nil {
+			iter.Release()
+			return err
+		}
+		items := make([]*states.Item, 0, len(spent.Items))
+		for _, item := range spent.Items {
+			if item.EndHeight+window > height {
+				items = append(items, item)
+			}
+		}
+		if len(items) == len(spent.Items) && len(items) > 0 {
+			continue
+		}
+		if len(items) == 0 {
+			bd.st.BatchDelete(iter.Key())
+			if spent.TransactionHeight < bd.prunedHeight {
+				expired = append(expired, spent.TransactionHash)
+			}
+			continue
+		}
+		spent.Items = items
+		value := new(bytes.Buffer)
+		if err := spent.Serialize(value); err != nil {
+			iter.Release()
+			return err
+		}
+		bd.st.BatchPut(iter.Key(), value.Bytes())
+	}
+	iter.Release()
+	if err := bd.st.BatchCommit(); err != nil {
+		return err
+	}
+
+	bd.st.NewBatch()
+	for _, txid := range expired {
+		if err := bd.pruneTx(txid); err != nil {
+			return err
+		}
+	}
+	return bd.st.BatchCommit()
+}
+
+// pruneTrie deletes the trie nodes unreachable from the current state root
+// and the state roots of the blocks which kept their bodies
+// can only be invoked by backend write goroutine
+func (bd *ChainStore) pruneTrie(height uint32) error {
+	roots := []Uint256{bd.GetCurrentStateRoot()}
+	for h := bd.prunedHeight; h <= height; h++ {
+		header, err := bd.GetHeader(bd.headerIndex[h])
+		if err != nil {
+			return err
+		}
+		roots = append(roots, header.StateRoot)
+	}
+	// the node hashes and the hashed keys, whose preimages share the
+	// namespace of the nodes
+	live := make(map[string]bool)
+	for _, root := range roots {
+		err := trie.Walk(root, bd.st, func(hash, enc []byte) error {
+			if live[string(hash)] {
+				return trie.SkipNode
+			}
+			live[string(hash)] = true
+			return nil
+		}, func(key, value []byte) error {
+			live[string(key)] = true
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var pruned int
+	bd.st.NewBatch()
+	prefix := trie.NodeKey(nil)
+	iter := bd.st.NewIterator(prefix)
+	for iter.Next() {
+		if !live[string(iter.Key()[len(prefix):])] {
+			bd.st.BatchDelete(iter.Key())
+			pruned++
+		}
+	}
+	iter.Release()
+	if err := bd.st.BatchCommit(); err != nil {
+		return err
+	}
+	log.Infof("pruned %d trie nodes at height %d", pruned, height)
+	return nil
+}
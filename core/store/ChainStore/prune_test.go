@@ -0,0 +1,182 @@
+package ChainStore
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/Ontology/common"
+	"github.com/Ontology/common/config"
+	"github.com/Ontology/common/log"
+	"github.com/Ontology/core/contract/program"
+	. "github.com/Ontology/core/ledger"
+	"github.com/Ontology/core/states"
+	. "github.com/Ontology/core/store"
+	"github.com/Ontology/core/store/MemoryStore"
+	"github.com/Ontology/core/store/statestore"
+	tx "github.com/Ontology/core/transaction"
+	"github.com/Ontology/core/transaction/payload"
+	"github.com/Ontology/trie"
+)
+
+func TestPrune(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prune")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	log.Init(log.Path, log.Stdout)
+	defer func(keep uint32) { config.Parameters.PruneBlocks = keep }(config.Parameters.PruneBlocks)
+	config.Parameters.PruneBlocks = 2
+
+	bd := newSnapshotTestStore(t, dir)
+	defer bd.st.Close()
+	testPrune(t, bd)
+}
+
+// TestPruneMemoryStore prunes a store kept in memory, its iterators and
+// not found errors have to match the LevelDB ones
+func TestPruneMemoryStore(t *testing.T) {
+	log.Init(log.Path, log.Stdout)
+	defer func(keep uint32) { config.Parameters.PruneBlocks = keep }(config.Parameters.PruneBlocks)
+	config.Parameters.PruneBlocks = 2
+
+	testPrune(t, &ChainStore{
+		st:          MemoryStore.NewMemoryStore(),
+		headerIndex: map[uint32]Uint256{},
+		blockCache:  map[Uint256]*Block{},
+		headerCache: map[Uint256]*Header{},
+	})
+}
+
+func testPrune(t *testing.T, bd *ChainStore) {
+	// the state of the first blocks is replaced afterwards
+	storageKey := append([]byte{byte(ST_Storage)}, (&states.StorageKey{CodeHash: Uint160{1}, Key: []byte("k")}).ToArray()...)
+	var roots []Uint256
+	root := Uint256{}
+	for _, v := range []string{"old", "new"} {
+		tr, err := statestore.NewTrieStore(bd.st).OpenTrie(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bd.st.NewBatch()
+		value := ToHash256([]byte(v))
+		tr.TryUpdate(storageKey, value.ToArray())
+		if root, err = tr.CommitTo(); err != nil {
+			t.Fatal(err)
+		}
+		if err := bd.st.BatchCommit(); err != nil {
+			t.Fatal(err)
+		}
+		roots = append(roots, root)
+	}
+	bd.st.NewBatch()
+	addCurrentStateRoot(bd, roots[1])
+
+	var blocks []*Block
+	var prevHash Uint256
+	for height := uint32(0); height < 4; height++ {
+		block := &Block{
+			Header: &Header{
+				Height:        height,
+				PrevBlockHash: prevHash,
+				Timestamp:     height + 1,
+				StateRoot:     roots[height/2],
+				Program:       &program.Program{Code: []byte{0x51}, Parameter: []byte{}},
+			},
+			Transactions: []*tx.Transaction{{
+				TxType:  tx.BookKeeping,
+				Payload: &payload.BookKeeping{Nonce: uint64(height)},
+			}},
+		}
+		block.RebuildMerkleRoot()
+		prevHash = block.Hash()
+		blocks = append(blocks, block)
+		bd.headerIndex[height] = prevHash
+		if err := addHeader(bd, block, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := addDataBlock(bd, block); err != nil {
+			t.Fatal(err)
+		}
+		bd.SaveTransaction(block.Transactions[0], height)
+	}
+	// the output of the second block is left to spend
+	unspent := blocks[1].Transactions[0].Hash()
+	coins := new(bytes.Buffer)
+	(&states.UnspentCoinState{Item: []states.CoinState{states.Confirmed}}).Serialize(coins)
+	bd.st.BatchPut(append([]byte{byte(ST_Coin)}, unspent.ToArray()...), coins.Bytes())
+	if err := bd.st.BatchCommit(); err != nil {
+		t.Fatal(err)
+	}
+	bd.currentBlockHeight = 3
+
+	// the blocks a reorganisation may revert are kept
+	if err := bd.prune(3); err != nil {
+		t.Fatal(err)
+	}
+	if bd.prunedHeight != 0 {
+		t.Fatal("pruned within the reorganisation depth")
+	}
+	if err := bd.prune(MaxReorgDepth + 1); err != nil {
+		t.Fatal(err)
+	}
+	if bd.prunedHeight != 2 {
+		t.Fatal("pruned height", bd.prunedHeight)
+	}
+	if _, err := bd.GetBlock(blocks[1].Hash()); err != ErrPruned {
+		t.Fatal("body of a pruned block returned:", err)
+	}
+	if _, err := bd.GetBlock(blocks[2].Hash()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bd.GetTransaction(blocks[0].Transactions[0].Hash()); err != ErrPruned {
+		t.Fatal("spent transaction not pruned:", err)
+	}
+	if !bd.IsTxHashDuplicate(blocks[0].Transactions[0].Hash()) {
+		t.Fatal("pruned transaction not found by the duplicate check")
+	}
+	if _, err := bd.GetTransaction(unspent); err != nil {
+		t.Fatal("transaction with an unspent output pruned:", err)
+	}
+	if _, _, _, err := bd.GetStateProof(storageKey, 1); err != ErrPruned {
+		t.Fatal("proof against a pruned state returned:", err)
+	}
+
+	if err := bd.pruneTrie(3); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := bd.st.Has(trie.NodeKey(roots[0].ToArray())); ok {
+		t.Fatal("unreachable trie node kept")
+	}
+	value, _, _, err := bd.GetStateProof(storageKey, 3)
+	if err != nil || value != nil {
+		t.Fatal("current state not proven:", err)
+	}
+	if data, err := bd.st.Get(trie.NodeKey(trie.ToHash256(storageKey))); err != nil || !bytes.Equal(data, storageKey) {
+		t.Fatal("preimage of a live key pruned")
+	}
+
+	// the outputs spent within the reorganisation depth are kept
+	defer func(window uint32) { config.Parameters.ClaimWindow = window }(config.Parameters.ClaimWindow)
+	config.Parameters.ClaimWindow = 1
+	spentKey := append([]byte{byte(ST_SpentCoin)}, unspent.ToArray()...)
+	spent := new(bytes.Buffer)
+	(&states.SpentCoinState{TransactionHash: unspent, TransactionHeight: 1, Items: []*states.Item{{EndHeight: 2}}}).Serialize(spent)
+	if err := bd.st.Put(spentKey, spent.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := bd.pruneSpentCoins(MaxReorgDepth + 1); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := bd.st.Has(spentKey); !ok {
+		t.Fatal("spent coin within the reorganisation depth pruned")
+	}
+	if err := bd.pruneSpentCoins(MaxReorgDepth + 2); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := bd.st.Has(spentKey); ok {
+		t.Fatal("expired spent coin kept")
+	}
+}
@@ -0,0 +1,317 @@
+package ChainStore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/Ontology/common/log"
+	"github.com/Ontology/common/serialization"
+	. "github.com/Ontology/core/ledger"
+	. "github.com/Ontology/core/store"
+	"github.com/Ontology/events"
+	"github.com/Ontology/trie"
+)
+
+// MaxReorgDepth is the number of blocks a reorganisation may revert, the undo
+// records of the older blocks are dropped
+const MaxReorgDepth = 100
+
+// undoStore records the previous values of the keys written by the batch of
+// a block, except the trie nodes which are only referenced by their hash. The
+// record is written with the block and restores the store as it was before.
+type undoStore struct {
+	IStore
+	recording bool
+	keys      []string
+	prev      map[string][]byte // nil for the keys added by the batch
+}
+
+func newUndoStore(st IStore) *undoStore {
+	return &undoStore{IStore: st}
+}
+
+// begin starts recording the writes of the current batch
+// can only be invoked by backend write goroutine
+func (self *undoStore) begin() {
+	self.recording = true
+	self.keys = nil
+	self.prev = make(map[string][]byte)
+}
+
+func (self *undoStore) record(key []byte) {
+	if !self.recording || bytes.HasPrefix(key, trie.NodeKey(nil)) {
+		return
+	}
+	if _, ok := self.prev[string(key)]; ok {
+		return
+	}
+	value, err := self.IStore.Get(key)
+	if err != nil {
+		value = nil
+	}
+	self.keys = append(self.keys, string(key))
+	self.prev[string(key)] = value
+}
+
+func (self *undoStore) BatchPut(key []byte, value []byte) error {
+	self.record(key)
+	return self.IStore.BatchPut(key, value)
+}
+
+func (self *undoStore) BatchDelete(key []byte) error {
+	self.record(key)
+	return self.IStore.BatchDelete(key)
+}
+
+// end stops recording and adds the undo record of the block at height to the
+// batch, the record of the block MaxReorgDepth blocks below is dropped
+func (self *undoStore) end(height uint32) error {
+	self.recording = false
+	value := new(bytes.Buffer)
+	if err := serialization.WriteVarUint(value, uint64(len(self.keys))); err != nil {
+		return err
+	}
+	for _, k := range self.keys {
+		prev := self.prev[k]
+		if err := serialization.WriteVarBytes(value, []byte(k)); err != nil {
+			return err
+		}
+		if err := serialization.WriteBool(value, prev != nil); err != nil {
+			return err
+		}
+		if err := serialization.WriteVarBytes(value, prev); err != nil {
+			return err
+		}
+	}
+	self.keys = nil
+	self.prev = nil
+	if err := self.IStore.BatchPut(undoKey(height), value.Bytes()); err != nil {
+		return err
+	}
+	if height > MaxReorgDepth {
+		return self.IStore.BatchDelete(undoKey(height - MaxReorgDepth))
+	}
+	return nil
+}
+
+func undoKey(height uint32) []byte {
+	key := make([]byte, 5)
+	key[0] = byte(DATA_Undo)
+	binary.BigEndian.PutUint32(key[1:], height)
+	return key
+}
+
+// beginUndo starts recording the undo record of the block being persisted
+func (bd *ChainStore) beginUndo() {
+	if bd.undo != nil {
+		bd.undo.begin()
+	}
+}
+
+// endUndo adds the undo record of the block at height to the batch
+func (bd *ChainStore) endUndo(height uint32) error {
+	if bd.undo == nil {
+		return nil
+	}
+	return bd.undo.end(height)
+}
+
+// canRevert tells whether the blocks above height can be reverted
+func (bd *ChainStore) canRevert(height uint32) bool {
+	if bd.undo == nil || height+1 < bd.prunedHeight || bd.currentBlockHeight-height > MaxReorgDepth {
+		return false
+	}
+	for h := height + 1; h <= bd.currentBlockHeight; h++ {
+		if ok, _ := bd.st.Has(undoKey(h)); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// revertBlock restores the store as it was before the current block was
+// persisted
+// can only be invoked by backend write goroutine
+func (bd *ChainStore) revertBlock(ledger *Ledger) error {
+	height := bd.currentBlockHeight
+	if height == 0 {
+		return errors.New("[revertBlock] genesis block can not be reverted")
+	}
+	data, err := bd.st.Get(undoKey(height))
+	if err != nil {
+		return fmt.Errorf("[revertBlock] undo record of block %d not found: %v", height, err)
+	}
+	block, err := bd.GetBlock(bd.headerIndex[height])
+	if err != nil {
+		return err
+	}
+
+	r := bytes.NewReader(data)
+	n, err := serialization.ReadVarUint(r, 0)
+	if err != nil {
+		return err
+	}
+	bd.st.NewBatch()
+	for i := uint64(0); i < n; i++ {
+		key, err := serialization.ReadVarBytes(r)
+		if err != nil {
+			return err
+		}
+		existed, err := serialization.ReadBool(r)
+		if err != nil {
+			return err
+		}
+		value, err := serialization.ReadVarBytes(r)
+		if err != nil {
+			return err
+		}
+		if existed {
+			bd.st.BatchPut(key, value)
+		} else {
+			bd.st.BatchDelete(key)
+		}
+	}
+	bd.st.BatchDelete(undoKey(height))
+	if err := bd.st.BatchCommit(); err != nil {
+		return err
+	}
+	if DefaultEventStore != nil {
+		if err := DefaultEventStore.DeleteEventNotifyInBlock(height); err != nil {
+			return err
+		}
+	}
+
+	bd.mu.Lock()
+	bd.currentBlockHeight = height - 1
+	bd.mu.Unlock()
+	if err := bd.loadMerkleTree(); err != nil {
+		return err
+	}
+	if ledger != nil {
+		ledger.Blockchain.BlockHeight = height - 1
+		ledger.Blockchain.BCEvents.Notify(events.EventBlockReverted, block)
+	}
+	log.Infof("block %d reverted, block hash: %x", height, block.Hash())
+	return nil
+}
+
+// addForkHeader keeps a verified header which is not on the main chain, the
+// chain is switched to its branch once the branch is longer
+// can only be invoked by backend write goroutine
+func (bd *ChainStore) addForkHeader(header *Header) {
+	hash := header.Hash()
+	tip := uint32(len(bd.headerIndex)) - 1
+	if header.Height == 0 || header.Height+MaxReorgDepth <= tip {
+		return
+	}
+	bd.mu.RLock()
+	_, known := bd.forks[hash]
+	bd.mu.RUnlock()
+	if known || bd.headerIndex[header.Height] == hash {
+		return
+	}
+	if !bd.verifyHeader(header) {
+		return
+	}
+	bd.mu.Lock()
+	bd.forks[hash] = header
+	bd.mu.Unlock()
+	log.Infof("fork header %d added, header hash: %x", header.Height, hash)
+
+	if header.Height > tip {
+		if err := bd.switchFork(header); err != nil {
+			log.Error("[switchFork] error to switch to the fork:", err.Error())
+		}
+	}
+}
+
+// switchFork makes the branch of the fork header tip the main chain. The
+// blocks of the old branch above the common ancestor are reverted and the
+// headers of the new branch replace the old ones, the blocks of the new
+// branch are persisted as they are received.
+// can only be invoked by backend write goroutine
+func (bd *ChainStore) switchFork(tip *Header) error {
+	var branch []*Header
+	bd.mu.RLock()
+	for header := tip; ; {
+		branch = append([]*Header{header}, branch...)
+		parent := header.PrevBlockHash
+		if bd.headerIndex[header.Height-1] == parent {
+			break
+		}
+		header = bd.forks[parent]
+		if header == nil {
+			bd.mu.RUnlock()
+			return fmt.Errorf("parent %x of the fork not found", parent)
+		}
+	}
+	bd.mu.RUnlock()
+	ancestor := branch[0].Height - 1
+
+	if ancestor < bd.currentBlockHeight && !bd.canRevert(ancestor) {
+		return fmt.Errorf("blocks above the common ancestor %d can not be reverted", ancestor)
+	}
+	log.Warnf("switching to the fork at height %d, common ancestor %d", tip.Height, ancestor)
+
+	// the headers of the old branch are kept to switch back
+	var old []*Header
+	for h := ancestor + 1; h < uint32(len(bd.headerIndex)); h++ {
+		header, err := bd.GetHeader(bd.headerIndex[h])
+		if err != nil {
+			return err
+		}
+		old = append(old, header)
+	}
+	for bd.currentBlockHeight > ancestor {
+		if err := bd.revertBlock(DefaultLedger); err != nil {
+			return err
+		}
+	}
+
+	bd.mu.Lock()
+	for _, header := range old {
+		bd.forks[header.Hash()] = header
+		delete(bd.headerIndex, header.Height)
+	}
+	for _, header := range branch {
+		delete(bd.forks, header.Hash())
+	}
+	bd.mu.Unlock()
+	for _, header := range branch {
+		bd.addHeader(header)
+	}
+
+	// the header hash lists holding old headers are written again
+	bd.st.NewBatch()
+	storedHeaderCount := (ancestor + 1) / HeaderHashListCount * HeaderHashListCount
+	for start := storedHeaderCount; start < bd.storedHeaderCount; start += HeaderHashListCount {
+		key := bytes.NewBuffer([]byte{byte(IX_HeaderHashList)})
+		serialization.WriteUint32(key, start)
+		bd.st.BatchDelete(key.Bytes())
+	}
+	if err := bd.st.BatchCommit(); err != nil {
+		return err
+	}
+	bd.mu.Lock()
+	if storedHeaderCount < bd.storedHeaderCount {
+		bd.storedHeaderCount = storedHeaderCount
+	}
+	bd.mu.Unlock()
+
+	for _, header := range branch {
+		if DefaultLedger != nil {
+			DefaultLedger.Blockchain.BCEvents.Notify(events.EventSaveBlock, header)
+		}
+		if bd.headerOnly {
+			if err := bd.persistHeader(header); err != nil {
+				return err
+			}
+		}
+	}
+	if DefaultLedger != nil && !bd.headerOnly {
+		bd.persistCachedBlocks(DefaultLedger)
+	}
+	return nil
+}
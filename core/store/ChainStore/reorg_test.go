@@ -0,0 +1,206 @@
+package ChainStore
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/Ontology/common"
+	"github.com/Ontology/common/config"
+	"github.com/Ontology/common/log"
+	"github.com/Ontology/core/contract/program"
+	. "github.com/Ontology/core/ledger"
+	"github.com/Ontology/core/states"
+	. "github.com/Ontology/core/store"
+	"github.com/Ontology/core/store/MemoryStore"
+	tx "github.com/Ontology/core/transaction"
+	"github.com/Ontology/core/transaction/payload"
+	"github.com/Ontology/core/transaction/utxo"
+	"github.com/Ontology/events"
+	"github.com/Ontology/merkle"
+	"github.com/Ontology/trie"
+)
+
+var reorgTestCode = []byte{0x51}
+
+func newReorgTestStore(t *testing.T, genesis *Block, hashStore *merkle.FileHashStore) *ChainStore {
+	undo := newUndoStore(MemoryStore.NewMemoryStore())
+	bd := &ChainStore{
+		st:              undo,
+		undo:            undo,
+		headerIndex:     map[uint32]Uint256{0: genesis.Hash()},
+		blockCache:      map[Uint256]*Block{},
+		headerCache:     map[Uint256]*Header{},
+		forks:           map[Uint256]*Header{},
+		merkleHashStore: hashStore,
+		merkleTree:      merkle.NewTree(0, nil, hashStore),
+	}
+	bookKeeper := new(bytes.Buffer)
+	(&states.BookKeeperState{}).Serialize(bookKeeper)
+	bd.st.NewBatch()
+	bd.st.BatchPut(append([]byte{byte(ST_BookKeeper)}, BookerKeeper...), bookKeeper.Bytes())
+	addHeader(bd, genesis, 0)
+	addDataBlock(bd, genesis)
+	addSysCurrentBlock(bd, genesis)
+	addMerkleRoot(bd, genesis)
+	if err := bd.st.BatchCommit(); err != nil {
+		t.Fatal(err)
+	}
+	return bd
+}
+
+func newReorgTestBlock(prev *Header, stateRoot Uint256, nonce uint64, to Uint160) *Block {
+	block := &Block{
+		Header: &Header{
+			Height:         prev.Height + 1,
+			PrevBlockHash:  prev.Hash(),
+			Timestamp:      prev.Timestamp + 1,
+			StateRoot:      stateRoot,
+			NextBookKeeper: ToCodeHash(reorgTestCode),
+			Program:        &program.Program{Code: reorgTestCode, Parameter: []byte{}},
+		},
+		Transactions: []*tx.Transaction{{
+			TxType:  tx.BookKeeping,
+			Payload: &payload.BookKeeping{Nonce: nonce},
+			Outputs: []*utxo.TxOutput{{AssetID: Uint256{1}, Value: Fixed64(nonce), ProgramHash: to}},
+		}},
+	}
+	block.RebuildMerkleRoot()
+	return block
+}
+
+// extendChain adds n blocks paying to to the chain of bd
+func extendChain(t *testing.T, bd *ChainStore, prev *Header, n int, to Uint160) []*Block {
+	var blocks []*Block
+	for i := 0; i < n; i++ {
+		block := newReorgTestBlock(prev, bd.GetCurrentStateRoot(), uint64(prev.Height+1), to)
+		bd.handlePersistHeaderTask(block.Header)
+		bd.handlePersistBlockTask(block, DefaultLedger)
+		if bd.GetHeight() != block.Header.Height {
+			t.Fatalf("block %d not persisted", block.Header.Height)
+		}
+		blocks = append(blocks, block)
+		prev = block.Header
+	}
+	return blocks
+}
+
+// dumpStore returns the entries of the store but the trie nodes, which are
+// left behind by reverted blocks, and the undo records
+func dumpStore(bd *ChainStore) map[string]string {
+	entries := make(map[string]string)
+	iter := bd.st.NewIterator(nil)
+	defer iter.Release()
+	for iter.Next() {
+		key := iter.Key()
+		if bytes.HasPrefix(key, trie.NodeKey(nil)) || key[0] == byte(DATA_Undo) {
+			continue
+		}
+		entries[string(key)] = string(iter.Value())
+	}
+	return entries
+}
+
+func TestReorg(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reorg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// the merkle tree is reopened in the working directory
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(DBDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	log.Init(log.Path, log.Stdout)
+	defer func(height uint32) { config.Parameters.StateTrieHeight = height }(config.Parameters.StateTrieHeight)
+	config.Parameters.StateTrieHeight = 1
+	defer func(l *Ledger) { DefaultLedger = l }(DefaultLedger)
+
+	genesis := &Block{
+		Header: &Header{
+			NextBookKeeper: ToCodeHash(reorgTestCode),
+			Program:        &program.Program{Code: reorgTestCode, Parameter: []byte{}},
+		},
+		Transactions: []*tx.Transaction{{TxType: tx.BookKeeping, Payload: &payload.BookKeeping{}}},
+	}
+	genesis.RebuildMerkleRoot()
+
+	// the store which only saw the fork
+	ref := newReorgTestStore(t, genesis, nil)
+	DefaultLedger = &Ledger{Blockchain: NewBlockchain(0), Store: ref}
+	fork := extendChain(t, ref, genesis.Header, 3, Uint160{2})
+
+	hashStore, err := merkle.NewFileHashStore(MerkleTreeStorePath, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bd := newReorgTestStore(t, genesis, hashStore)
+	defer bd.merkleHashStore.Close()
+	DefaultLedger = &Ledger{Blockchain: NewBlockchain(0), Store: bd}
+	reverted := make(chan *Block, 2)
+	DefaultLedger.Blockchain.BCEvents.Subscribe(events.EventBlockReverted, func(v interface{}) {
+		reverted <- v.(*Block)
+	})
+	main := extendChain(t, bd, genesis.Header, 2, Uint160{1})
+	if bd.GetCurrentStateRoot() == ref.GetCurrentStateRoot() {
+		t.Fatal("the branches have the same state")
+	}
+
+	// the fork is switched to once it is longer
+	for _, block := range fork[:2] {
+		bd.handlePersistHeaderTask(block.Header)
+		if bd.GetHeight() != 2 || bd.GetCurrentBlockHash() != main[1].Hash() {
+			t.Fatal("switched to a fork not longer than the main chain")
+		}
+	}
+	bd.handlePersistHeaderTask(fork[2].Header)
+	if bd.GetHeight() != 0 || bd.GetHeaderHeight() != 3 || bd.GetHeaderHashByHeight(3) != fork[2].Hash() {
+		t.Fatalf("fork not switched to, block height %d, header height %d", bd.GetHeight(), bd.GetHeaderHeight())
+	}
+	if bd.GetCurrentStateRoot() != (Uint256{}) || bd.merkleTree.TreeSize() != 1 {
+		t.Fatal("state not reverted to the common ancestor")
+	}
+	if _, err := bd.GetTransaction(main[0].Transactions[0].Hash()); err == nil {
+		t.Fatal("transaction of a reverted block found")
+	}
+	if header, err := bd.GetHeader(main[1].Hash()); err != nil || header.Height != 2 {
+		t.Fatal("header of the old branch not kept")
+	}
+	// the subscribers are notified concurrently
+	notified := make(map[Uint256]bool)
+	for i := 0; i < 2; i++ {
+		select {
+		case block := <-reverted:
+			notified[block.Hash()] = true
+		case <-time.After(time.Second):
+			t.Fatal("revert not notified")
+		}
+	}
+	if !notified[main[0].Hash()] || !notified[main[1].Hash()] {
+		t.Fatal("reverted blocks not notified")
+	}
+
+	// the blocks of the fork are persisted as the store which only saw them
+	for _, block := range fork {
+		bd.handlePersistBlockTask(block, DefaultLedger)
+	}
+	if bd.GetHeight() != 3 || bd.GetCurrentStateRoot() != ref.GetCurrentStateRoot() {
+		t.Fatal("fork blocks not persisted")
+	}
+	want, got := dumpStore(ref), dumpStore(bd)
+	if len(want) != len(got) {
+		t.Fatalf("%d entries in the store, want %d", len(got), len(want))
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("entry %x is %x, want %x", k, got[k], v)
+		}
+	}
+}
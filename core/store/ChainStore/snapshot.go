@@ -0,0 +1,609 @@
+package ChainStore
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sort"
+
+	. "github.com/Ontology/common"
+	"github.com/Ontology/common/config"
+	"github.com/Ontology/common/log"
+	"github.com/Ontology/common/serialization"
+	. "github.com/Ontology/core/ledger"
+	. "github.com/Ontology/core/store"
+	tx "github.com/Ontology/core/transaction"
+	"github.com/Ontology/merkle"
+	"github.com/Ontology/trie"
+)
+
+const (
+	SnapshotPath      = "Chain/state.snapshot"
+	SnapshotMagic     = 0x534e544f
+	SnapshotVersion   = 1
+	SnapshotChunkSize = 64 * 1024
+)
+
+// record kinds of a snapshot, the records are written in this order
+const (
+	snapshotEnd byte = iota
+	snapshotHeader
+	snapshotTrieNode
+	snapshotState
+	snapshotTransaction
+)
+
+// snapshotPrefixes are the states a snapshot holds, from StateTrieHeight on
+// every change of them is committed to the state trie so that a snapshot can
+// be verified. The spent coins are left out, pruning drops them from the
+// store of a single node.
+var snapshotPrefixes = []DataEntryPrefix{
+	ST_Account,
+	ST_Coin,
+	ST_BookKeeper,
+	ST_Asset,
+	ST_Contract,
+	ST_Storage,
+	ST_Identity,
+	ST_Program_Coin,
+	ST_Validator,
+	ST_Vote,
+}
+
+type snapshotResult struct {
+	height uint32
+	err    error
+}
+type createSnapshotTask struct {
+	done chan snapshotResult
+}
+type restoreSnapshotTask struct {
+	path   string
+	ledger *Ledger
+	done   chan snapshotResult
+}
+
+// snapshotInfo is the header of a snapshot file
+type snapshotInfo struct {
+	height    uint32
+	blockHash Uint256
+	stateRoot Uint256
+}
+
+func (s *snapshotInfo) Serialize(w io.Writer) error {
+	if err := serialization.WriteUint32(w, SnapshotMagic); err != nil {
+		return err
+	}
+	if err := serialization.WriteByte(w, SnapshotVersion); err != nil {
+		return err
+	}
+	if err := serialization.WriteUint32(w, s.height); err != nil {
+		return err
+	}
+	if _, err := s.blockHash.Serialize(w); err != nil {
+		return err
+	}
+	_, err := s.stateRoot.Serialize(w)
+	return err
+}
+
+func (s *snapshotInfo) Deserialize(r io.Reader) error {
+	magic, err := serialization.ReadUint32(r)
+	if err != nil {
+		return err
+	}
+	version, err := serialization.ReadByte(r)
+	if err != nil {
+		return err
+	}
+	if magic != SnapshotMagic || version != SnapshotVersion {
+		return errors.New("[Snapshot] not a snapshot file")
+	}
+	if s.height, err = serialization.ReadUint32(r); err != nil {
+		return err
+	}
+	if err := s.blockHash.Deserialize(r); err != nil {
+		return err
+	}
+	return s.stateRoot.Deserialize(r)
+}
+
+// snapshotWriter writes the records of a snapshot, the file ends with the
+// sha256 of everything before it
+type snapshotWriter struct {
+	w   *bufio.Writer
+	sum hash.Hash
+	out io.Writer
+}
+
+func newSnapshotWriter(w io.Writer, info *snapshotInfo) (*snapshotWriter, error) {
+	sw := &snapshotWriter{w: bufio.NewWriter(w), sum: sha256.New()}
+	sw.out = io.MultiWriter(sw.w, sw.sum)
+	return sw, info.Serialize(sw.out)
+}
+
+func (sw *snapshotWriter) writeRecord(kind byte, key, value []byte) error {
+	if err := serialization.WriteByte(sw.out, kind); err != nil {
+		return err
+	}
+	if err := serialization.WriteVarBytes(sw.out, key); err != nil {
+		return err
+	}
+	return serialization.WriteVarBytes(sw.out, value)
+}
+
+func (sw *snapshotWriter) close() error {
+	if err := serialization.WriteByte(sw.out, snapshotEnd); err != nil {
+		return err
+	}
+	if _, err := sw.w.Write(sw.sum.Sum(nil)); err != nil {
+		return err
+	}
+	return sw.w.Flush()
+}
+
+type snapshotReader struct {
+	r    *bufio.Reader
+	sum  hash.Hash
+	in   io.Reader
+	kind byte
+}
+
+func newSnapshotReader(r io.Reader, info *snapshotInfo) (*snapshotReader, error) {
+	sr := &snapshotReader{r: bufio.NewReader(r), sum: sha256.New()}
+	sr.in = io.TeeReader(sr.r, sr.sum)
+	return sr, info.Deserialize(sr.in)
+}
+
+// next reads the next record, the kind of the last record is snapshotEnd
+// and the checksum has been verified when it is returned
+func (sr *snapshotReader) next() (byte, []byte, []byte, error) {
+	kind, err := serialization.ReadByte(sr.in)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if (kind != snapshotEnd && kind < sr.kind) || kind > snapshotTransaction {
+		return 0, nil, nil, fmt.Errorf("[Snapshot] unexpected record kind %d", kind)
+	}
+	sr.kind = kind
+	if kind == snapshotEnd {
+		expected := sr.sum.Sum(nil)
+		sum := make([]byte, sha256.Size)
+		if _, err := io.ReadFull(sr.r, sum); err != nil {
+			return 0, nil, nil, err
+		}
+		if !bytes.Equal(sum, expected) {
+			return 0, nil, nil, errors.New("[Snapshot] checksum mismatch")
+		}
+		return kind, nil, nil, nil
+	}
+	key, err := serialization.ReadVarBytes(sr.in)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	value, err := serialization.ReadVarBytes(sr.in)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	return kind, key, value, nil
+}
+
+// CreateSnapshot writes the state and the header chain of the current block
+// to SnapshotPath and returns its height. Blocks are not persisted while the
+// snapshot is written so that it is consistent. There is no snapshot below
+// StateTrieHeight, the state trie does not hold every state there.
+func (self *ChainStore) CreateSnapshot() (uint32, error) {
+	done := make(chan snapshotResult, 1)
+	self.taskCh <- &createSnapshotTask{done: done}
+	result := <-done
+	return result.height, result.err
+}
+
+// RestoreSnapshot replaces the state of a store which holds the genesis
+// block only with the snapshot at path. The headers up to the block after the
+// snapshot must be synced: the snapshot has to match the header chain and the
+// state root in the header of the next block. Every state is verified against
+// that root and the snapshot has to hold every state of the trie, the
+// transactions it holds have to be referred to by the coin states. The
+// outputs spent before the snapshot can't be claimed on the restored store.
+func (self *ChainStore) RestoreSnapshot(path string, ledger *Ledger) (uint32, error) {
+	done := make(chan snapshotResult, 1)
+	self.taskCh <- &restoreSnapshotTask{path: path, ledger: ledger, done: done}
+	result := <-done
+	return result.height, result.err
+}
+
+// GetSnapshotChunk returns the height of the snapshot at SnapshotPath, its
+// number of chunks and the chunk at index
+func (self *ChainStore) GetSnapshotChunk(index uint32) (uint32, uint32, []byte, error) {
+	f, err := os.Open(SnapshotPath)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	defer f.Close()
+	info := new(snapshotInfo)
+	if err := info.Deserialize(f); err != nil {
+		return 0, 0, nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	total := uint32((stat.Size() + SnapshotChunkSize - 1) / SnapshotChunkSize)
+	if index >= total {
+		return 0, 0, nil, errors.New("[GetSnapshotChunk] chunk index out of range")
+	}
+	data := make([]byte, SnapshotChunkSize)
+	n, err := f.ReadAt(data, int64(index)*SnapshotChunkSize)
+	if err != nil && err != io.EOF {
+		return 0, 0, nil, err
+	}
+	return info.height, total, data[:n], nil
+}
+
+// snapshotDue tells whether a snapshot is taken after the block at height
+func snapshotDue(height uint32) bool {
+	interval := config.Parameters.SnapshotInterval
+	return interval > 0 && height > 0 && height%interval == 0 && stateTrieActive(height)
+}
+
+// stateTrieActive tells whether the state trie holds every snapshot state
+// after the block at height. The states committed before StateTrieHeight are
+// added to the trie by the block at that height.
+func stateTrieActive(height uint32) bool {
+	start := config.Parameters.StateTrieHeight
+	return start > 0 && height >= start
+}
+
+// migrateStateTrie adds the snapshot states committed before StateTrieHeight
+// to the state trie, the changed ones are added by CommitTo
+func (self *StateStore) migrateStateTrie() error {
+	changes := self.memoryStore.GetChangeSet()
+	var count int
+	for _, prefix := range snapshotPrefixes {
+		iter := self.db.st.NewIterator([]byte{byte(prefix)})
+		for iter.Next() {
+			key := append([]byte{}, iter.Key()...)
+			if _, ok := changes[string(key)]; ok {
+				continue
+			}
+			value := ToHash256(iter.Value())
+			if err := self.trie.TryUpdate(key, value.ToArray()); err != nil {
+				iter.Release()
+				return err
+			}
+			count++
+		}
+		iter.Release()
+	}
+	log.Infof("%d states added to the state trie", count)
+	return nil
+}
+
+// can only be invoked by backend write goroutine
+func (bd *ChainStore) createSnapshot(path string) (uint32, error) {
+	if bd.headerOnly {
+		return 0, errors.New("[CreateSnapshot] header only store has no state")
+	}
+	if bd.currentBlockHeight == 0 {
+		return 0, errors.New("[CreateSnapshot] no block above the genesis block")
+	}
+	if !stateTrieActive(bd.currentBlockHeight) {
+		return 0, fmt.Errorf("[CreateSnapshot] the state trie holds every state from block %d only", config.Parameters.StateTrieHeight)
+	}
+	info := &snapshotInfo{
+		height:    bd.currentBlockHeight,
+		blockHash: bd.headerIndex[bd.currentBlockHeight],
+		stateRoot: bd.GetCurrentStateRoot(),
+	}
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp)
+	defer f.Close()
+
+	sw, err := newSnapshotWriter(f, info)
+	if err != nil {
+		return 0, err
+	}
+	for height := uint32(0); height <= info.height; height++ {
+		hash := bd.headerIndex[height]
+		value, err := bd.st.Get(append([]byte{byte(DATA_Header)}, hash.ToArray()...))
+		if err != nil {
+			return 0, fmt.Errorf("[CreateSnapshot] header %d not found: %v", height, err)
+		}
+		if err := sw.writeRecord(snapshotHeader, hash.ToArray(), value); err != nil {
+			return 0, err
+		}
+	}
+
+	nodes := make(map[string]bool)
+	leaves := make(map[string]bool)
+	err = trie.Walk(info.stateRoot, bd.st, func(hash, enc []byte) error {
+		if nodes[string(hash)] {
+			return nil
+		}
+		nodes[string(hash)] = true
+		return sw.writeRecord(snapshotTrieNode, hash, enc)
+	}, func(key, value []byte) error {
+		leaves[string(key)] = true
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// the transactions of the unspent outputs are needed to spend them
+	txs := make(map[Uint256]bool)
+	for _, prefix := range snapshotPrefixes {
+		iter := bd.st.NewIterator([]byte{byte(prefix)})
+		for iter.Next() {
+			if !leaves[string(trie.ToHash256(iter.Key()))] {
+				// a restored store would miss it
+				err := fmt.Errorf("[CreateSnapshot] state %x is not in the state trie", iter.Key())
+				iter.Release()
+				return 0, err
+			}
+			if err := sw.writeRecord(snapshotState, iter.Key(), iter.Value()); err != nil {
+				iter.Release()
+				return 0, err
+			}
+			if prefix == ST_Coin {
+				var txid Uint256
+				if err := txid.Deserialize(bytes.NewReader(iter.Key()[1:])); err == nil {
+					txs[txid] = true
+				}
+			}
+		}
+		iter.Release()
+	}
+	txids := make([]Uint256, 0, len(txs))
+	for txid := range txs {
+		txids = append(txids, txid)
+	}
+	sort.Slice(txids, func(i, j int) bool { return txids[i].CompareTo(txids[j]) < 0 })
+	for _, txid := range txids {
+		value, err := bd.st.Get(append([]byte{byte(DATA_Transaction)}, txid.ToArray()...))
+		if err != nil {
+			return 0, fmt.Errorf("[CreateSnapshot] transaction %x not found: %v", txid, err)
+		}
+		if len(value) == prunedTxLen {
+			// spent and pruned, only its height is left
+			continue
+		}
+		if err := sw.writeRecord(snapshotTransaction, txid.ToArray(), value); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := sw.close(); err != nil {
+		return 0, err
+	}
+	if err := f.Sync(); err != nil {
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return 0, err
+	}
+	log.Infof("state snapshot of block %d written to %s", info.height, path)
+	return info.height, nil
+}
+
+// can only be invoked by backend write goroutine
+func (bd *ChainStore) restoreSnapshot(path string, ledger *Ledger) (uint32, error) {
+	if bd.headerOnly {
+		return 0, errors.New("[RestoreSnapshot] header only store can not hold state")
+	}
+	if bd.currentBlockHeight != 0 {
+		return 0, errors.New("[RestoreSnapshot] the store already holds blocks")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	info := new(snapshotInfo)
+	sr, err := newSnapshotReader(f, info)
+	if err != nil {
+		return 0, err
+	}
+	height := info.height
+	if !stateTrieActive(height) {
+		return 0, fmt.Errorf("[RestoreSnapshot] the state trie holds every state from block %d only", config.Parameters.StateTrieHeight)
+	}
+	if height == 0 || height+1 >= uint32(len(bd.headerIndex)) {
+		return 0, fmt.Errorf("[RestoreSnapshot] header %d of the snapshot is not synced", height+1)
+	}
+	if bd.headerIndex[height] != info.blockHash {
+		return 0, errors.New("[RestoreSnapshot] snapshot block is not in the header chain")
+	}
+	next, err := bd.GetHeader(bd.headerIndex[height+1])
+	if err != nil {
+		return 0, err
+	}
+	if next.StateRoot != info.stateRoot {
+		return 0, errors.New("[RestoreSnapshot] snapshot state root does not match the header chain")
+	}
+
+	bd.st.NewBatch()
+	for _, prefix := range snapshotPrefixes {
+		iter := bd.st.NewIterator([]byte{byte(prefix)})
+		for iter.Next() {
+			bd.st.BatchDelete(iter.Key())
+		}
+		iter.Release()
+	}
+
+	var roots []Uint256
+	nodes := trie.NewMemDatabase()
+	// leaves maps the hashed keys of the state trie not restored yet to the
+	// value hashes
+	var leaves map[string][]byte
+	// txs are the transactions the restored coin states refer to
+	txs := make(map[Uint256]bool)
+	for {
+		kind, key, value, err := sr.next()
+		if err != nil {
+			return 0, err
+		}
+		if kind != snapshotHeader && len(roots) != int(height)+1 {
+			return 0, errors.New("[RestoreSnapshot] snapshot misses headers")
+		}
+		if (kind == snapshotEnd || kind > snapshotTrieNode) && leaves == nil {
+			leaves = make(map[string][]byte)
+			err := trie.Walk(info.stateRoot, nodes, nil, func(key, value []byte) error {
+				leaves[string(key)] = value
+				return nil
+			})
+			if err != nil {
+				return 0, err
+			}
+		}
+		switch kind {
+		case snapshotHeader:
+			block := new(Block)
+			r := bytes.NewReader(value)
+			if _, err := serialization.ReadUint64(r); err != nil {
+				return 0, err
+			}
+			if err := block.FromTrimmedData(r); err != nil {
+				return 0, err
+			}
+			h := uint32(len(roots))
+			hash := block.Hash()
+			if h > height || !bytes.Equal(key, hash.ToArray()) || hash != bd.headerIndex[h] {
+				return 0, fmt.Errorf("[RestoreSnapshot] header %d does not match the header chain", h)
+			}
+			roots = append(roots, block.Header.TransactionsRoot)
+			bd.st.BatchPut(append([]byte{byte(DATA_Header)}, key...), value)
+			if err := addDataBlock(bd, block); err != nil {
+				return 0, err
+			}
+		case snapshotTrieNode:
+			if !bytes.Equal(trie.ToHash256(value), key) {
+				return 0, errors.New("[RestoreSnapshot] trie node hash mismatch")
+			}
+			nodes.BatchPut(trie.NodeKey(key), value)
+			bd.st.BatchPut(trie.NodeKey(key), value)
+		case snapshotState:
+			if len(key) == 0 || !isSnapshotPrefix(DataEntryPrefix(key[0])) {
+				return 0, fmt.Errorf("[RestoreSnapshot] unexpected state key %x", key)
+			}
+			hashedKey := trie.ToHash256(key)
+			leaf, ok := leaves[string(hashedKey)]
+			if !ok {
+				return 0, fmt.Errorf("[RestoreSnapshot] state %x is not in the state trie", key)
+			}
+			if !bytes.Equal(leaf, trie.ToHash256(value)) {
+				return 0, fmt.Errorf("[RestoreSnapshot] state %x does not match the state root", key)
+			}
+			delete(leaves, string(hashedKey))
+			if DataEntryPrefix(key[0]) == ST_Coin {
+				txid, err := Uint256ParseFromBytes(key[1:])
+				if err != nil {
+					return 0, err
+				}
+				txs[txid] = true
+			}
+			// the preimage of the hashed key, as written by the trie
+			bd.st.BatchPut(trie.NodeKey(hashedKey), key)
+			bd.st.BatchPut(key, value)
+			if historyPrefixes[DataEntryPrefix(key[0])] {
+				bd.st.BatchPut(historyKey(key, height), value)
+			}
+		case snapshotTransaction:
+			t := new(tx.Transaction)
+			r := bytes.NewReader(value)
+			if _, err := serialization.ReadUint32(r); err != nil {
+				return 0, err
+			}
+			if err := t.Deserialize(r); err != nil {
+				return 0, err
+			}
+			hash := t.Hash()
+			if !bytes.Equal(key, hash.ToArray()) {
+				return 0, errors.New("[RestoreSnapshot] transaction hash mismatch")
+			}
+			if !txs[hash] {
+				return 0, fmt.Errorf("[RestoreSnapshot] transaction %x is not referred to by a coin state", key)
+			}
+			bd.st.BatchPut(append([]byte{byte(DATA_Transaction)}, key...), value)
+		}
+		if kind == snapshotEnd {
+			break
+		}
+	}
+	if len(leaves) > 0 {
+		return 0, fmt.Errorf("[RestoreSnapshot] snapshot misses %d states of the state trie", len(leaves))
+	}
+
+	current := new(bytes.Buffer)
+	info.blockHash.Serialize(current)
+	serialization.WriteUint32(current, height)
+	bd.st.BatchPut([]byte{byte(SYS_CurrentBlock)}, current.Bytes())
+	if err := addCurrentStateRoot(bd, info.stateRoot); err != nil {
+		return 0, err
+	}
+	// the state history starts with the states of the snapshot
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, height)
+	bd.st.BatchPut([]byte{byte(SYS_StateHistory)}, buf)
+	if addressHistoryEnabled(bd) {
+		// the address history starts after the snapshot
+		bd.st.BatchPut([]byte{byte(SYS_AddressHistory)}, buf)
+	}
+	// the blocks up to the snapshot have no bodies
+	if err := putPrunedHeight(bd, height+1); err != nil {
+		return 0, err
+	}
+	storedHeaderCount := bd.persistHeaderHashList(height)
+
+	// the merkle tree is rebuilt before the commit, a store left at the
+	// genesis block still finds the hash of its first block in the file
+	bd.merkleHashStore.Close()
+	bd.merkleHashStore, err = merkle.NewFileHashStore(MerkleTreeStorePath, 0)
+	if err != nil {
+		return 0, err
+	}
+	bd.merkleTree = merkle.NewTree(0, nil, bd.merkleHashStore)
+	for _, root := range roots {
+		bd.merkleTree.AppendHash(root)
+	}
+	putMerkleTree(bd)
+
+	if err := bd.st.BatchCommit(); err != nil {
+		return 0, err
+	}
+	bd.mu.Lock()
+	bd.currentBlockHeight = height
+	bd.storedHeaderCount = storedHeaderCount
+	bd.prunedHeight = height + 1
+	bd.historyHeight = height
+	bd.mu.Unlock()
+	ledger.Blockchain.BlockHeight = height
+	bd.clearCache()
+
+	log.Infof("state snapshot of block %d restored", height)
+	return height, nil
+}
+
+func isSnapshotPrefix(prefix DataEntryPrefix) bool {
+	for _, p := range snapshotPrefixes {
+		if p == prefix {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,333 @@
+package ChainStore
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/Ontology/common"
+	"github.com/Ontology/common/config"
+	"github.com/Ontology/common/log"
+	"github.com/Ontology/core/contract/program"
+	. "github.com/Ontology/core/ledger"
+	"github.com/Ontology/core/states"
+	. "github.com/Ontology/core/store"
+	"github.com/Ontology/core/store/LevelDBStore"
+	"github.com/Ontology/core/store/statestore"
+	tx "github.com/Ontology/core/transaction"
+	"github.com/Ontology/core/transaction/payload"
+	"github.com/Ontology/trie"
+)
+
+const snapshotTestHeight = 3
+
+func newSnapshotTestStore(t *testing.T, dir string) *ChainStore {
+	st, err := LevelDBStore.NewLevelDBStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ChainStore{
+		st:          st,
+		headerIndex: map[uint32]Uint256{},
+		blockCache:  map[Uint256]*Block{},
+		headerCache: map[Uint256]*Header{},
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// the merkle tree of the restored store is kept in the working directory
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(DBDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	log.Init(log.Path, log.Stdout)
+	defer func(height uint32) { config.Parameters.StateTrieHeight = height }(config.Parameters.StateTrieHeight)
+	config.Parameters.StateTrieHeight = 1
+
+	src := newSnapshotTestStore(t, filepath.Join(dir, "src"))
+	defer src.st.Close()
+
+	var blocks []*Block
+	var prevHash Uint256
+	for height := uint32(0); height <= snapshotTestHeight+1; height++ {
+		block := &Block{
+			Header: &Header{
+				Height:        height,
+				PrevBlockHash: prevHash,
+				Timestamp:     height + 1,
+				Program:       &program.Program{Code: []byte{0x51}, Parameter: []byte{}},
+			},
+			Transactions: []*tx.Transaction{{
+				TxType:  tx.BookKeeping,
+				Payload: &payload.BookKeeping{Nonce: uint64(height)},
+			}},
+		}
+		block.RebuildMerkleRoot()
+		prevHash = block.Hash()
+		blocks = append(blocks, block)
+	}
+
+	// a storage item and an unspent output of the first block
+	storageKey := append([]byte{byte(ST_Storage)}, (&states.StorageKey{CodeHash: Uint160{1}, Key: []byte("k")}).ToArray()...)
+	storage := new(bytes.Buffer)
+	(&states.StorageItem{Value: []byte("v")}).Serialize(storage)
+	txid := blocks[1].Transactions[0].Hash()
+	coinKey := append([]byte{byte(ST_Coin)}, txid.ToArray()...)
+	coin := []byte{1, 0}
+	tr, err := statestore.NewTrieStore(src.st).OpenTrie(Uint256{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src.st.NewBatch()
+	for _, state := range [][2][]byte{{storageKey, storage.Bytes()}, {coinKey, coin}} {
+		value := ToHash256(state[1])
+		tr.TryUpdate(state[0], value.ToArray())
+		src.st.BatchPut(state[0], state[1])
+	}
+	root, err := tr.CommitTo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addCurrentStateRoot(src, root)
+	// a state committed before the state trie held it
+	voteKey := append([]byte{byte(ST_Vote)}, 1)
+	src.st.BatchPut(voteKey, []byte{0})
+	blocks[snapshotTestHeight+1].Header.StateRoot = root
+	for height, block := range blocks {
+		src.headerIndex[uint32(height)] = block.Hash()
+	}
+	for _, block := range blocks[:snapshotTestHeight+1] {
+		if err := addHeader(src, block, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	src.SaveTransaction(blocks[1].Transactions[0], 1)
+	if err := src.st.BatchCommit(); err != nil {
+		t.Fatal(err)
+	}
+	src.currentBlockHeight = snapshotTestHeight
+
+	path := filepath.Join(dir, "state.snapshot")
+	if _, err := src.createSnapshot(path); err == nil {
+		t.Fatal("snapshot created with a state outside the state trie")
+	}
+	src.st.Delete(voteKey)
+	if height, err := src.createSnapshot(path); err != nil || height != snapshotTestHeight {
+		t.Fatal("create snapshot failed:", height, err)
+	}
+
+	restore := func(name string) (*ChainStore, error) {
+		dst := newSnapshotTestStore(t, filepath.Join(dir, name))
+		for height, hash := range src.headerIndex {
+			dst.headerIndex[height] = hash
+		}
+		next := blocks[snapshotTestHeight+1]
+		dst.headerCache[next.Hash()] = next.Header
+		_, err := dst.restoreSnapshot(path, &Ledger{Blockchain: NewBlockchain(0)})
+		return dst, err
+	}
+	dst, err := restore("dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.st.Close()
+	if dst.GetHeight() != snapshotTestHeight || dst.GetCurrentStateRoot() != root {
+		t.Fatal("current block not restored")
+	}
+	if data, err := dst.st.Get(storageKey); err != nil || !bytes.Equal(data, storage.Bytes()) {
+		t.Fatal("state not restored")
+	}
+	if _, err := dst.GetTransaction(txid); err != nil {
+		t.Fatal("transaction of an unspent output not restored")
+	}
+	if hash, err := dst.GetBlockHash(2); err != nil || hash != blocks[2].Hash() {
+		t.Fatal("header chain not restored")
+	}
+	if dst.historyHeight != snapshotTestHeight {
+		t.Fatal("state history does not start at the snapshot", dst.historyHeight)
+	}
+	if dst.merkleTree.TreeSize() != snapshotTestHeight+1 {
+		t.Fatal("unexpected merkle tree size", dst.merkleTree.TreeSize())
+	}
+	dst.merkleHashStore.Close()
+
+	// rewrite copies the snapshot and lets edit change its records
+	rewrite := func(edit func(kind byte, key, value []byte, sw *snapshotWriter) error) {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info := new(snapshotInfo)
+		sr, err := newSnapshotReader(bytes.NewReader(data), info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := new(bytes.Buffer)
+		sw, err := newSnapshotWriter(out, info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for {
+			kind, key, value, err := sr.next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kind == snapshotEnd {
+				break
+			}
+			if err := edit(kind, key, value, sw); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := sw.close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, out.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, c := range []struct {
+		name string
+		edit func(kind byte, key, value []byte, sw *snapshotWriter) error
+	}{
+		{"state outside the state trie", func(kind byte, key, value []byte, sw *snapshotWriter) error {
+			if kind == snapshotState && bytes.Equal(key, storageKey) {
+				if err := sw.writeRecord(kind, voteKey, []byte{0}); err != nil {
+					return err
+				}
+			}
+			return sw.writeRecord(kind, key, value)
+		}},
+		{"snapshot missing a state of the state trie", func(kind byte, key, value []byte, sw *snapshotWriter) error {
+			if kind == snapshotState && bytes.Equal(key, storageKey) {
+				return nil
+			}
+			return sw.writeRecord(kind, key, value)
+		}},
+	} {
+		if _, err := src.createSnapshot(path); err != nil {
+			t.Fatal(err)
+		}
+		rewrite(c.edit)
+		rejected, err := restore(fmt.Sprintf("rejected%d", i))
+		rejected.st.Close()
+		if err == nil {
+			t.Fatal(c.name, "accepted")
+		}
+	}
+
+	// a state that does not match the state root
+	src.st.Put(storageKey, []byte{0})
+	if _, err := src.createSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+	tampered, err := restore("tampered")
+	defer tampered.st.Close()
+	if err == nil {
+		t.Fatal("state not matching the state root accepted")
+	}
+	if tampered.GetHeight() != 0 {
+		t.Fatal("rejected snapshot changed the current block")
+	}
+
+	// a corrupted file
+	data, _ := ioutil.ReadFile(path)
+	data[len(data)-1] ^= 0xff
+	ioutil.WriteFile(path, data, 0644)
+	info := new(snapshotInfo)
+	sr, err := newSnapshotReader(bytes.NewReader(data), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		kind, _, _, err := sr.next()
+		if err != nil {
+			break
+		}
+		if kind == snapshotEnd {
+			t.Fatal("corrupted snapshot accepted")
+		}
+	}
+}
+
+// TestStateTrieMigration persists blocks of a chain written before
+// StateTrieHeight, the block at that height adds the earlier states to the
+// state trie
+func TestStateTrieMigration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	log.Init(log.Path, log.Stdout)
+	defer func(height uint32) { config.Parameters.StateTrieHeight = height }(config.Parameters.StateTrieHeight)
+	config.Parameters.StateTrieHeight = 3
+	defer func(l *Ledger) { DefaultLedger = l }(DefaultLedger)
+
+	genesis := &Block{
+		Header: &Header{
+			NextBookKeeper: ToCodeHash(reorgTestCode),
+			Program:        &program.Program{Code: reorgTestCode, Parameter: []byte{}},
+		},
+		Transactions: []*tx.Transaction{{TxType: tx.BookKeeping, Payload: &payload.BookKeeping{}}},
+	}
+	genesis.RebuildMerkleRoot()
+	bd := newReorgTestStore(t, genesis, nil)
+	DefaultLedger = &Ledger{Blockchain: NewBlockchain(0), Store: bd}
+
+	leaves := func() map[string]bool {
+		leaves := make(map[string]bool)
+		err := trie.Walk(bd.GetCurrentStateRoot(), bd.st, nil, func(key, value []byte) error {
+			leaves[string(key)] = true
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return leaves
+	}
+	blocks := extendChain(t, bd, genesis.Header, 2, Uint160{1})
+	txid := blocks[0].Transactions[0].Hash()
+	coinKey := append([]byte{byte(ST_Coin)}, txid.ToArray()...)
+	if leaves()[string(trie.ToHash256(coinKey))] {
+		t.Fatal("state trie changed below StateTrieHeight")
+	}
+	path := filepath.Join(dir, "state.snapshot")
+	if _, err := bd.createSnapshot(path); err == nil {
+		t.Fatal("snapshot created below StateTrieHeight")
+	}
+
+	extendChain(t, bd, blocks[1].Header, 2, Uint160{1})
+	added := leaves()
+	var states int
+	for _, prefix := range snapshotPrefixes {
+		iter := bd.st.NewIterator([]byte{byte(prefix)})
+		for iter.Next() {
+			if !added[string(trie.ToHash256(iter.Key()))] {
+				t.Fatalf("state %x not added to the state trie", iter.Key())
+			}
+			states++
+		}
+		iter.Release()
+	}
+	// the account, the outputs of the four blocks, the book keepers and the
+	// coins of the address
+	if states != 7 {
+		t.Fatal("unexpected number of states", states)
+	}
+	if height, err := bd.createSnapshot(path); err != nil || height != 4 {
+		t.Fatal("create snapshot failed:", height, err)
+	}
+}
@@ -0,0 +1,121 @@
+package ChainStore
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/Ontology/common"
+	"github.com/Ontology/common/log"
+	. "github.com/Ontology/core/ledger"
+	"github.com/Ontology/core/states"
+	. "github.com/Ontology/core/store"
+	"github.com/Ontology/core/store/LevelDBStore"
+)
+
+func TestStateHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "statehistory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	st, err := LevelDBStore.NewLevelDBStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	log.Init(log.Path, log.Stdout)
+	bd := &ChainStore{st: st, currentBlockHeight: 20}
+
+	codeHash := Uint160{1}
+	// a second contract whose storage key extends the first one
+	other := &states.StorageKey{CodeHash: codeHash, Key: []byte("ab")}
+	key := &states.StorageKey{CodeHash: codeHash, Key: []byte("a")}
+	changes := map[uint32]map[string]*StateItem{
+		2: {
+			string(append([]byte{byte(ST_Storage)}, key.ToArray()...)):   {Value: &states.StorageItem{Value: []byte{2}}, State: Changed},
+			string(append([]byte{byte(ST_Storage)}, other.ToArray()...)): {Value: &states.StorageItem{Value: []byte{9}}, State: Changed},
+		},
+		5: {
+			string(append([]byte{byte(ST_Storage)}, key.ToArray()...)): {Value: &states.StorageItem{Value: []byte{5}}, State: Changed},
+		},
+		9: {
+			string(append([]byte{byte(ST_Storage)}, key.ToArray()...)): {State: Deleted},
+		},
+		12: {
+			string(append([]byte{byte(ST_Storage)}, key.ToArray()...)): {Value: &states.StorageItem{Value: []byte{12}}, State: Changed},
+		},
+	}
+	// the states are committed after the history as persist does
+	commit := func(height uint32, changeSet map[string]*StateItem) {
+		st.NewBatch()
+		if err := addStateHistory(bd, changeSet, height); err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range changeSet {
+			if v.State == Deleted {
+				st.BatchDelete([]byte(k))
+				continue
+			}
+			data := new(bytes.Buffer)
+			v.Value.Serialize(data)
+			st.BatchPut([]byte(k), data.Bytes())
+		}
+		if err := st.BatchCommit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, height := range []uint32{2, 5, 9, 12} {
+		commit(height, changes[height])
+	}
+	// the states read for a change which did not happen are not recorded
+	unchanged := string(append([]byte{byte(ST_Storage)}, key.ToArray()...))
+	missing := string(append([]byte{byte(ST_Storage)}, (&states.StorageKey{CodeHash: codeHash, Key: []byte("c")}).ToArray()...))
+	commit(15, map[string]*StateItem{
+		unchanged: {Value: &states.StorageItem{Value: []byte{12}}, State: Changed},
+		missing:   {State: Deleted},
+	})
+	for _, k := range []string{unchanged, missing} {
+		if ok, _ := st.Has(historyKey([]byte(k), 15)); ok {
+			t.Errorf("unchanged state %x recorded", k)
+		}
+	}
+
+	expected := map[uint32]int{1: -1, 2: 2, 4: 2, 5: 5, 8: 5, 9: -1, 11: -1, 12: 12, 20: 12}
+	for height, value := range expected {
+		item, err := bd.GetStorageItemAt(key, height)
+		if value == -1 {
+			if err == nil {
+				t.Errorf("height %d: expected no item, got %x", height, item.Value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("height %d: %v", height, err)
+			continue
+		}
+		if len(item.Value) != 1 || int(item.Value[0]) != value {
+			t.Errorf("height %d: expected %d, got %x", height, value, item.Value)
+		}
+	}
+	if _, err := bd.GetStorageItemAt(key, 21); err == nil {
+		t.Error("height above the current block accepted")
+	}
+
+	// a chain persisted before the history was kept records it from the
+	// next block on
+	if err := bd.loadHistoryHeight(); err != nil {
+		t.Fatal(err)
+	}
+	if bd.historyHeight != 21 {
+		t.Fatal("unexpected history height", bd.historyHeight)
+	}
+	bd.currentBlockHeight = 30
+	if err := bd.loadHistoryHeight(); err != nil || bd.historyHeight != 21 {
+		t.Fatal("history height not kept", bd.historyHeight, err)
+	}
+	if _, err := bd.GetStorageItemAt(key, 20); err != (HistoryUnavailableError{Height: 21}) {
+		t.Fatal("query below the history height not rejected:", err)
+	}
+}
@@ -126,10 +126,15 @@ func (self *StateStore) TryDelete(prefix DataEntryPrefix, key []byte) {
 	self.memoryStore.Delete(byte(prefix), key)
 }
 
-func (self *StateStore) CommitTo() error {
+// CommitTo puts the changed states of the block at height into the current
+// batch, once the state trie holds every state a snapshot holds they are
+// committed to the trie as well
+func (self *StateStore) CommitTo(height uint32) error {
+	allStates := stateTrieActive(height)
 	for k, v := range self.memoryStore.GetChangeSet() {
+		trie := v.Trie || allStates && isSnapshotPrefix(DataEntryPrefix(k[0]))
 		if v.State == Deleted {
-			if v.Trie {
+			if trie {
 				if err := self.trie.TryDelete([]byte(k)); err != nil {
 					return err
 				}
@@ -144,7 +149,7 @@ func (self *StateStore) CommitTo() error {
 				log.Errorf("[CommitTo] error: key %v, value:%v", k, v.Value)
 				return err
 			}
-			if v.Trie {
+			if trie {
 				value := common.ToHash256(data.Bytes())
 				if err := self.trie.TryUpdate([]byte(k), value.ToArray()); err != nil {
 					return err
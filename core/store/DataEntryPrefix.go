@@ -33,4 +33,26 @@ const (
 
 	EVENT_Notify
 
+	DATA_GasConsumed
+	SYS_CurrentHeader
+
+	// HISTORY
+	ST_History
+
+	// EVENT INDEX
+	IX_EventContract
+	IX_EventTopic
+
+	// ADDRESS HISTORY
+	IX_AddressHistory
+	SYS_AddressHistory
+
+	// PRUNING
+	SYS_PrunedHeight
+
+	// REORGANISATION
+	DATA_Undo
+
+	// HISTORY START
+	SYS_StateHistory
 )
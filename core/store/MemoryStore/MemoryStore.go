@@ -0,0 +1,125 @@
+package MemoryStore
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	. "github.com/Ontology/core/store"
+)
+
+// MemoryStore is an IStore keeping its data in memory, nothing survives a
+// restart. It backs the tests of the stores built on IStore.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	data  map[string][]byte
+	batch []batchOp
+}
+
+type batchOp struct {
+	key    []byte
+	value  []byte
+	delete bool
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		data: make(map[string][]byte),
+	}
+}
+
+func (self *MemoryStore) Put(key []byte, value []byte) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.data[string(key)] = copyBytes(value)
+	return nil
+}
+
+func (self *MemoryStore) Get(key []byte) ([]byte, error) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	value, ok := self.data[string(key)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyBytes(value), nil
+}
+
+func (self *MemoryStore) Has(key []byte) (bool, error) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	_, ok := self.data[string(key)]
+	return ok, nil
+}
+
+func (self *MemoryStore) Delete(key []byte) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	delete(self.data, string(key))
+	return nil
+}
+
+func (self *MemoryStore) NewBatch() error {
+	self.batch = nil
+	return nil
+}
+
+func (self *MemoryStore) BatchPut(key []byte, value []byte) error {
+	self.batch = append(self.batch, batchOp{key: copyBytes(key), value: copyBytes(value)})
+	return nil
+}
+
+func (self *MemoryStore) BatchDelete(key []byte) error {
+	self.batch = append(self.batch, batchOp{key: copyBytes(key), delete: true})
+	return nil
+}
+
+func (self *MemoryStore) BatchCommit() error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	for _, op := range self.batch {
+		if op.delete {
+			delete(self.data, string(op.key))
+		} else {
+			self.data[string(op.key)] = op.value
+		}
+	}
+	self.batch = nil
+	return nil
+}
+
+func (self *MemoryStore) Close() error {
+	return nil
+}
+
+// NewIterator iterates a snapshot of the keys with the prefix taken when the
+// iterator is created
+func (self *MemoryStore) NewIterator(prefix []byte) IIterator {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	keys := make([]string, 0)
+	for k := range self.data {
+		if bytes.HasPrefix([]byte(k), prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	values := make([][]byte, len(keys))
+	for i, k := range keys {
+		values[i] = self.data[k]
+	}
+	return &Iterator{
+		keys:   keys,
+		values: values,
+		pos:    -1,
+	}
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return []byte{}
+	}
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
+}
@@ -0,0 +1,66 @@
+package MemoryStore
+
+import (
+	"sort"
+)
+
+// Iterator walks the sorted keys, pos is -1 before the first key and
+// len(keys) after the last one
+type Iterator struct {
+	keys   []string
+	values [][]byte
+	pos    int
+}
+
+func (it *Iterator) valid() bool {
+	return it.pos >= 0 && it.pos < len(it.keys)
+}
+
+func (it *Iterator) Next() bool {
+	if it.pos < len(it.keys) {
+		it.pos++
+	}
+	return it.valid()
+}
+
+func (it *Iterator) Prev() bool {
+	if it.pos >= 0 {
+		it.pos--
+	}
+	return it.valid()
+}
+
+func (it *Iterator) First() bool {
+	it.pos = 0
+	return it.valid()
+}
+
+func (it *Iterator) Last() bool {
+	it.pos = len(it.keys) - 1
+	return it.valid()
+}
+
+func (it *Iterator) Seek(key []byte) bool {
+	it.pos = sort.SearchStrings(it.keys, string(key))
+	return it.valid()
+}
+
+func (it *Iterator) Key() []byte {
+	if !it.valid() {
+		return nil
+	}
+	return []byte(it.keys[it.pos])
+}
+
+func (it *Iterator) Value() []byte {
+	if !it.valid() {
+		return nil
+	}
+	return it.values[it.pos]
+}
+
+func (it *Iterator) Release() {
+	it.keys = nil
+	it.values = nil
+	it.pos = -1
+}
@@ -1,9 +1,19 @@
 package store
 
 import (
+	"errors"
+
 	states "github.com/Ontology/core/states"
 )
 
+// ErrNotFound is returned by the stores for a missing key, it has the message
+// of the LevelDB error the callers compare with
+var ErrNotFound = errors.New("leveldb: not found")
+
+// IIterator walks the keys with a prefix in order. The LevelDB and the memory
+// iterators read a snapshot taken when they are created, the BoltDB one sees
+// the writes made while it is open. Every iterator returns the keys kept
+// during the whole iteration once and in order.
 type IIterator interface {
 	Next() bool
 	Prev() bool
@@ -0,0 +1,203 @@
+package store_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/Ontology/core/store"
+	"github.com/Ontology/core/store/BoltStore"
+	"github.com/Ontology/core/store/LevelDBStore"
+	"github.com/Ontology/core/store/MemoryStore"
+)
+
+// TestStores runs the same checks against every IStore backend
+func TestStores(t *testing.T) {
+	backends := map[string]func(dir string) (IStore, error){
+		"leveldb": func(dir string) (IStore, error) { return LevelDBStore.NewLevelDBStore(dir) },
+		"boltdb":  func(dir string) (IStore, error) { return BoltStore.NewBoltStore(dir) },
+		"memory":  func(dir string) (IStore, error) { return MemoryStore.NewMemoryStore(), nil },
+	}
+	for name, open := range backends {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "store")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			st, err := open(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer st.Close()
+			testKeys(t, st)
+			testBatch(t, st)
+			testIterator(t, st)
+			testIteratorWrites(t, st)
+		})
+	}
+}
+
+func testKeys(t *testing.T, st IStore) {
+	if _, err := st.Get([]byte("a")); err == nil || err.Error() != ErrNotFound.Error() {
+		t.Fatal("missing key found:", err)
+	}
+	if err := st.Put([]byte("a"), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Put([]byte("a"), []byte("2")); err != nil {
+		t.Fatal(err)
+	}
+	value, err := st.Get([]byte("a"))
+	if err != nil || !bytes.Equal(value, []byte("2")) {
+		t.Fatalf("get %q, %v", value, err)
+	}
+	value[0] = '3'
+	if value, _ := st.Get([]byte("a")); !bytes.Equal(value, []byte("2")) {
+		t.Fatal("stored value changed through the returned one")
+	}
+	if ok, err := st.Has([]byte("a")); !ok || err != nil {
+		t.Fatal("key not found")
+	}
+	if err := st.Delete([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := st.Has([]byte("a")); ok {
+		t.Fatal("deleted key found")
+	}
+}
+
+func testBatch(t *testing.T, st IStore) {
+	st.Put([]byte("b"), []byte("1"))
+	st.NewBatch()
+	st.BatchPut([]byte("c"), []byte("1"))
+	st.BatchDelete([]byte("b"))
+	st.BatchPut([]byte("d"), []byte("1"))
+	st.BatchDelete([]byte("d"))
+	if ok, _ := st.Has([]byte("c")); ok {
+		t.Fatal("batch written before the commit")
+	}
+	if err := st.BatchCommit(); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]bool{"b": false, "c": true, "d": false} {
+		if ok, _ := st.Has([]byte(key)); ok != want {
+			t.Fatalf("key %s found %v after the commit", key, ok)
+		}
+	}
+
+	// a new batch drops the writes not committed
+	st.NewBatch()
+	st.BatchPut([]byte("e"), []byte("1"))
+	st.NewBatch()
+	if err := st.BatchCommit(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := st.Has([]byte("e")); ok {
+		t.Fatal("dropped batch written")
+	}
+	st.Delete([]byte("c"))
+}
+
+func testIterator(t *testing.T, st IStore) {
+	keys := []string{"p\x00", "p\x01", "p\x01\xff", "p\xff", "p\xff\xff"}
+	st.NewBatch()
+	st.BatchPut([]byte("o\xff"), []byte("o"))
+	for _, k := range keys {
+		st.BatchPut([]byte(k), []byte("v"+k))
+	}
+	st.BatchPut([]byte("q"), []byte("q"))
+	if err := st.BatchCommit(); err != nil {
+		t.Fatal(err)
+	}
+
+	iter := st.NewIterator([]byte("p"))
+	var found []string
+	for iter.Next() {
+		if !bytes.Equal(iter.Value(), []byte("v"+string(iter.Key()))) {
+			t.Fatalf("value %q of key %q", iter.Value(), iter.Key())
+		}
+		found = append(found, string(iter.Key()))
+	}
+	iter.Release()
+	if len(found) != len(keys) {
+		t.Fatalf("iterated %q, want %q", found, keys)
+	}
+	for i := range keys {
+		if found[i] != keys[i] {
+			t.Fatalf("iterated %q, want %q", found, keys)
+		}
+	}
+
+	iter = st.NewIterator([]byte("p"))
+	defer iter.Release()
+	if !iter.Last() || string(iter.Key()) != keys[4] {
+		t.Fatalf("last key %q", iter.Key())
+	}
+	if !iter.Prev() || string(iter.Key()) != keys[3] {
+		t.Fatalf("previous key %q", iter.Key())
+	}
+	if !iter.First() || string(iter.Key()) != keys[0] {
+		t.Fatalf("first key %q", iter.Key())
+	}
+	if iter.Prev() {
+		t.Fatalf("key %q before the first one", iter.Key())
+	}
+	if !iter.Seek([]byte("p\x01\x00")) || string(iter.Key()) != keys[2] {
+		t.Fatalf("seek key %q", iter.Key())
+	}
+	if !iter.Seek([]byte("a")) || string(iter.Key()) != keys[0] {
+		t.Fatalf("seek before the prefix key %q", iter.Key())
+	}
+	if iter.Seek([]byte("q")) {
+		t.Fatalf("seek after the prefix key %q", iter.Key())
+	}
+
+	all := st.NewIterator(nil)
+	defer all.Release()
+	n := 0
+	for all.Next() {
+		n++
+	}
+	if n != len(keys)+2 {
+		t.Fatalf("iterated %d keys without prefix, want %d", n, len(keys)+2)
+	}
+}
+
+// testIteratorWrites writes the store while iterating it, the writes may or
+// may not be seen but the keys kept meanwhile are found once and in order
+func testIteratorWrites(t *testing.T, st IStore) {
+	for _, k := range []string{"r0", "r1", "r3", "r5"} {
+		st.Put([]byte(k), []byte(k))
+	}
+	iter := st.NewIterator([]byte("r"))
+	defer iter.Release()
+	if !iter.Next() || string(iter.Key()) != "r0" {
+		t.Fatalf("first key %q", iter.Key())
+	}
+	st.Delete([]byte("r0"))
+	st.Put([]byte("r2"), []byte("r2"))
+	st.Put([]byte("r4"), []byte("r4"))
+
+	found := []string{"r0"}
+	for iter.Next() {
+		key := string(iter.Key())
+		if key <= found[len(found)-1] {
+			t.Fatalf("key %q after %q", key, found[len(found)-1])
+		}
+		if !bytes.Equal(iter.Value(), iter.Key()) {
+			t.Fatalf("value %q of key %q", iter.Value(), key)
+		}
+		found = append(found, key)
+	}
+	kept := map[string]bool{}
+	for _, k := range found {
+		kept[k] = true
+	}
+	for _, k := range []string{"r1", "r3", "r5"} {
+		if !kept[k] {
+			t.Fatalf("iterated %q, missing %q", found, k)
+		}
+	}
+}
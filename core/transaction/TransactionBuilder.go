@@ -184,20 +184,22 @@ func NewDeployTransaction(fc *code.FunctionCode, programHash common.Uint160, nam
 	}, nil
 }
 
-func NewInvokeTransaction(fc []byte, codeHash common.Uint160) (*Transaction, error) {
+func NewInvokeTransaction(fc []byte, codeHash common.Uint160, gasLimit common.Fixed64) (*Transaction, error) {
 	//TODO: check arguments
 	InvokeCodePayload := &payload.InvokeCode{
 		Code:     fc,
 		CodeHash: codeHash,
+		GasLimit: gasLimit,
 	}
 
 	return &Transaction{
-		TxType:        Invoke,
-		Payload:       InvokeCodePayload,
-		Attributes:    []*TxAttribute{},
-		UTXOInputs:    []*UTXOTxInput{},
-		BalanceInputs: []*BalanceTxInput{},
-		Programs:      []*program.Program{},
+		TxType:         Invoke,
+		PayloadVersion: payload.InvokeCodePayloadVersion,
+		Payload:        InvokeCodePayload,
+		Attributes:     []*TxAttribute{},
+		UTXOInputs:     []*UTXOTxInput{},
+		BalanceInputs:  []*BalanceTxInput{},
+		Programs:       []*program.Program{},
 	}, nil
 }
 
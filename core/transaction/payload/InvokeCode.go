@@ -6,9 +6,14 @@ import (
 	"github.com/Ontology/common/serialization"
 )
 
+// InvokeCodePayloadVersion is the first payload version carrying the gas limit,
+// the payloads of version 0 are executed with the free gas only
+const InvokeCodePayloadVersion byte = 0x01
+
 type InvokeCode struct {
 	CodeHash common.Uint160
 	Code     []byte
+	GasLimit common.Fixed64
 }
 
 func (ic *InvokeCode) Data(version byte) []byte {
@@ -21,6 +26,12 @@ func (ic *InvokeCode) Serialize(w io.Writer, version byte) error {
 	if err != nil {
 		return err
 	}
+	if version >= InvokeCodePayloadVersion {
+		err = ic.GasLimit.Serialize(w)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -35,5 +46,10 @@ func (ic *InvokeCode) Deserialize(r io.Reader, version byte) error {
 		return err
 	}
 	ic.Code = code
+	if version >= InvokeCodePayloadVersion {
+		if err := ic.GasLimit.Deserialize(r); err != nil {
+			return err
+		}
+	}
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,40 @@
+package payload
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Ontology/common"
+)
+
+func TestInvokeCodeVersion(t *testing.T) {
+	ic := &InvokeCode{CodeHash: common.Uint160{1}, Code: []byte{0x51}, GasLimit: 100}
+
+	// version 0 keeps the layout without the gas limit
+	b := new(bytes.Buffer)
+	if err := ic.Serialize(b, 0); err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != 20+1+1 {
+		t.Fatalf("version 0 payload of %d bytes", b.Len())
+	}
+	old := new(InvokeCode)
+	if err := old.Deserialize(bytes.NewReader(b.Bytes()), 0); err != nil {
+		t.Fatal(err)
+	}
+	if old.GasLimit != 0 || !bytes.Equal(old.Code, ic.Code) {
+		t.Fatal("version 0 payload not read back")
+	}
+
+	b.Reset()
+	if err := ic.Serialize(b, InvokeCodePayloadVersion); err != nil {
+		t.Fatal(err)
+	}
+	v1 := new(InvokeCode)
+	if err := v1.Deserialize(bytes.NewReader(b.Bytes()), InvokeCodePayloadVersion); err != nil {
+		t.Fatal(err)
+	}
+	if v1.GasLimit != ic.GasLimit || v1.CodeHash != ic.CodeHash {
+		t.Fatal("version 1 payload not read back")
+	}
+}
@@ -560,7 +560,11 @@ func (tx *Transaction) GetMergedAssetIDValueFromReference() (TransactionResult,
 }
 
 func (tx *Transaction) GetSysFee() Fixed64 {
-	return Fixed64(Parameters.SystemFee[TxName[tx.TxType]])
+	fee := Fixed64(Parameters.SystemFee[TxName[tx.TxType]])
+	if invoke, ok := tx.Payload.(*payload.InvokeCode); ok {
+		fee += invoke.GasLimit
+	}
+	return fee
 }
 
 func (tx *Transaction) GetNetworkFee() (Fixed64, error) {
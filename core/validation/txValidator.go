@@ -306,6 +306,12 @@ func CheckTransactionPayload(Tx *tx.Transaction) error {
 	case *payload.Record:
 	case *payload.DeployCode:
 	case *payload.InvokeCode:
+		if pld.GasLimit < 0 {
+			return errors.New("[CheckTransactionPayload], Invalid invoke gas limit.")
+		}
+		if pld.GasLimit != 0 && Tx.PayloadVersion < payload.InvokeCodePayloadVersion {
+			return errors.New("[CheckTransactionPayload], Invoke gas limit needs payload version 1.")
+		}
 	case *payload.DataFile:
 	case *payload.Claim:
 		claims := Tx.Payload.(*payload.Claim).Claims
@@ -41,7 +41,7 @@ func VerifySignableDataSignature(signableData sig.SignableData) error {
 		var cryptos interfaces.ICrypto
 		cryptos = new(vm.ECDsaCrypto)
 		stateReader := service.NewStateReader(types.Verification)
-		se := vm.NewExecutionEngine(signableData, cryptos, nil, stateReader)
+		se := vm.NewExecutionEngine(signableData, cryptos, nil, stateReader, 0)
 		se.LoadCode(programs[i].Code, false)
 		se.LoadCode(programs[i].Parameter, true)
 		se.Execute()
@@ -0,0 +1,138 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/Ontology/crypto/util"
+)
+
+// HardenedKeyStart is the first index of the hardened child keys, which
+// can't be derived from the parent public key
+const HardenedKeyStart uint32 = 0x80000000
+
+// ExtendedKey is a node of a hierarchical deterministic key tree, the keys
+// are derived on the curve selected by SetAlg following SLIP-0010
+type ExtendedKey struct {
+	PrivateKey []byte
+	ChainCode  []byte
+}
+
+// masterHMACKey is the curve specific HMAC key of the master key derivation
+func masterHMACKey() []byte {
+	if SM2 == AlgChoice {
+		return []byte("SM2 seed")
+	}
+	return []byte("Nist256p1 seed")
+}
+
+func hmacSHA512(key []byte, data ...[]byte) []byte {
+	mac := hmac.New(sha512.New, key)
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
+
+func paddedKey(k *big.Int) []byte {
+	buf := make([]byte, util.PRIVATEKEYLEN)
+	b := k.Bytes()
+	copy(buf[util.PRIVATEKEYLEN-len(b):], b)
+	return buf
+}
+
+// NewMasterKey derives the root of the key tree from a seed
+func NewMasterKey(seed []byte) (*ExtendedKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, errors.New("[NewMasterKey] invalid seed length")
+	}
+	n := algSet.EccParams.N
+	I := hmacSHA512(masterHMACKey(), seed)
+	for {
+		k := new(big.Int).SetBytes(I[:32])
+		if k.Sign() != 0 && k.Cmp(n) < 0 {
+			return &ExtendedKey{PrivateKey: paddedKey(k), ChainCode: I[32:]}, nil
+		}
+		I = hmacSHA512(masterHMACKey(), I)
+	}
+}
+
+// Child derives the child key at index, the indexes from HardenedKeyStart
+// derive hardened keys
+func (k *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	n := algSet.EccParams.N
+	parent := new(big.Int).SetBytes(k.PrivateKey)
+	if parent.Sign() == 0 || parent.Cmp(n) >= 0 || len(k.ChainCode) != 32 {
+		return nil, errors.New("[Child] invalid extended key")
+	}
+	var data []byte
+	if index >= HardenedKeyStart {
+		data = append([]byte{0}, paddedKey(parent)...)
+	} else {
+		var err error
+		data, err = NewPubKey(k.PrivateKey).EncodePoint(true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	indexBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(indexBytes, index)
+
+	I := hmacSHA512(k.ChainCode, data, indexBytes)
+	for {
+		il := new(big.Int).SetBytes(I[:32])
+		child := new(big.Int).Add(il, parent)
+		child.Mod(child, n)
+		if il.Cmp(n) < 0 && child.Sign() != 0 {
+			return &ExtendedKey{PrivateKey: paddedKey(child), ChainCode: I[32:]}, nil
+		}
+		I = hmacSHA512(k.ChainCode, []byte{1}, I[32:], indexBytes)
+	}
+}
+
+// Derive derives the descendant key at a path like m/44'/1024'/0'/0/1,
+// the indexes marked by ' or h are hardened
+func (k *ExtendedKey) Derive(path string) (*ExtendedKey, error) {
+	indexes, err := ParseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+	key := k
+	for _, index := range indexes {
+		key, err = key.Child(index)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// ParseDerivationPath parses a path like m/44'/1024'/0'/0/1 into the child
+// indexes from the master key
+func ParseDerivationPath(path string) ([]uint32, error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if len(parts) == 0 || parts[0] != "m" {
+		return nil, errors.New("[ParseDerivationPath] path must start with m")
+	}
+	indexes := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		hardened := strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h")
+		if hardened {
+			part = part[:len(part)-1]
+		}
+		index, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || uint32(index) >= HardenedKeyStart {
+			return nil, errors.New("[ParseDerivationPath] invalid index: " + part)
+		}
+		if hardened {
+			index += uint64(HardenedKeyStart)
+		}
+		indexes = append(indexes, uint32(index))
+	}
+	return indexes, nil
+}
@@ -0,0 +1,46 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestExtendedKey_Derive(t *testing.T) {
+	SetAlg("P256R1")
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewMasterKey(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// SLIP-0010 test vector 1 for nist256p1
+	if hex.EncodeToString(master.PrivateKey) != "612091aaa12e22dd2abef664f8a01a82cae99ad7441b7ef8110424915c268bc2" ||
+		hex.EncodeToString(master.ChainCode) != "beeb672fe4621673f722f38529c07392fecaa61015c80c34f29ce8b41b3cb6ea" {
+		t.Fatalf("unexpected master key %x", master.PrivateKey)
+	}
+	child, err := master.Derive("m/0'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(child.PrivateKey) != "6939694369114c67917a182c59ddb8cafc3004e63ca5d3b84403ba8613debc0c" {
+		t.Errorf("unexpected m/0' key %x", child.PrivateKey)
+	}
+
+	SetAlg("SM2")
+	defer SetAlg("P256R1")
+	sm2Key, err := NewMasterKey(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := sm2Key.Derive("m/44'/1024'/0'/0/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := sm2Key.Derive("m/44h/1024h/0h/0/1")
+	if !bytes.Equal(a.PrivateKey, b.PrivateKey) || bytes.Equal(a.PrivateKey, child.PrivateKey) {
+		t.Error("unexpected SM2 derivation")
+	}
+	if _, err := ParseDerivationPath("44'/0"); err == nil {
+		t.Error("path without master accepted")
+	}
+}
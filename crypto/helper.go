@@ -7,6 +7,7 @@ import (
 	"crypto/sha256"
 	"errors"
 	. "github.com/Ontology/common"
+	"github.com/Ontology/crypto/util"
 )
 
 func ToAesKey(pwd []byte) []byte {
@@ -61,6 +62,42 @@ func AesDecrypt(ciphertext []byte, key []byte, iv []byte) ([]byte, error) {
 	return plaintext, nil
 }
 
+// AesGcmEncrypt seals plaintext with AES-GCM under a random nonce, the nonce
+// prefixes the returned ciphertext
+func AesGcmEncrypt(plaintext []byte, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.New("invalid encrypt key")
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := util.RandomNum(gcm.NonceSize())
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// AesGcmDecrypt opens a ciphertext sealed by AesGcmEncrypt, it fails when the
+// key is wrong or the ciphertext was modified
+func AesGcmDecrypt(ciphertext []byte, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.New("invalid decrypt key")
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce := ciphertext[:gcm.NonceSize()]
+	return gcm.Open(nil, nonce, ciphertext[gcm.NonceSize():], nil)
+}
+
 func PKCS5Padding(src []byte, blockSize int) []byte {
 	padding := blockSize - len(src) % blockSize
 	padtext := bytes.Repeat([]byte{byte(padding)}, padding)
@@ -0,0 +1,202 @@
+package crypto
+
+import (
+	"errors"
+	. "github.com/Ontology/common"
+	"github.com/Ontology/common/serialization"
+	. "github.com/Ontology/errors"
+	"io"
+	"math"
+)
+
+// MaxBlockTransactions bounds the leaf count of a partial merkle tree read
+// from a peer
+const MaxBlockTransactions = 1 << 16
+
+// PartialMerkleTree is the pruned form of the transaction merkle tree used
+// by merkleblock messages. It keeps the hashes needed to recompute the root
+// and a depth first traversal bitmap marking the branches that lead to
+// matched transactions.
+type PartialMerkleTree struct {
+	Total  uint32
+	Hashes []Uint256
+	Flags  []byte
+}
+
+// NewPartialMerkleTree builds the partial tree over hashes keeping the
+// branches of the leaves whose matches entry is true.
+func NewPartialMerkleTree(hashes []Uint256, matches []bool) (*PartialMerkleTree, error) {
+	if len(hashes) == 0 || len(hashes) != len(matches) {
+		return nil, NewDetailErr(errors.New("NewPartialMerkleTree input error."), ErrNoCode, "")
+	}
+	p := &PartialMerkleTree{Total: uint32(len(hashes))}
+	var bits []bool
+	p.build(p.height(), 0, hashes, matches, &bits)
+
+	p.Flags = make([]byte, (len(bits)+7)/8)
+	for i, bit := range bits {
+		if bit {
+			p.Flags[i/8] |= 1 << uint(i%8)
+		}
+	}
+	return p, nil
+}
+
+// ExtractMatches verifies the tree and returns its merkle root together
+// with the matched leaf hashes. The caller compares the root with the
+// TransactionsRoot of a trusted header.
+func (p *PartialMerkleTree) ExtractMatches() (Uint256, []Uint256, error) {
+	if p.Total == 0 {
+		return Uint256{}, nil, NewDetailErr(errors.New("PartialMerkleTree has no leaves."), ErrNoCode, "")
+	}
+	if uint32(len(p.Hashes)) > p.Total {
+		return Uint256{}, nil, NewDetailErr(errors.New("PartialMerkleTree has more hashes than leaves."), ErrNoCode, "")
+	}
+	if len(p.Flags)*8 < len(p.Hashes) {
+		return Uint256{}, nil, NewDetailErr(errors.New("PartialMerkleTree has too few flag bits."), ErrNoCode, "")
+	}
+	var bitsUsed, hashUsed int
+	var matches []Uint256
+	root, err := p.extract(p.height(), 0, &bitsUsed, &hashUsed, &matches)
+	if err != nil {
+		return Uint256{}, nil, err
+	}
+	if (bitsUsed+7)/8 != len(p.Flags) || hashUsed != len(p.Hashes) {
+		return Uint256{}, nil, NewDetailErr(errors.New("PartialMerkleTree has unused data."), ErrNoCode, "")
+	}
+	return root, matches, nil
+}
+
+func (p *PartialMerkleTree) Serialize(w io.Writer) error {
+	if err := serialization.WriteUint32(w, p.Total); err != nil {
+		return err
+	}
+	if err := serialization.WriteVarUint(w, uint64(len(p.Hashes))); err != nil {
+		return err
+	}
+	for _, hash := range p.Hashes {
+		if _, err := hash.Serialize(w); err != nil {
+			return err
+		}
+	}
+	return serialization.WriteVarBytes(w, p.Flags)
+}
+
+func (p *PartialMerkleTree) Deserialize(r io.Reader) error {
+	var err error
+	if p.Total, err = serialization.ReadUint32(r); err != nil {
+		return err
+	}
+	if p.Total == 0 || p.Total > MaxBlockTransactions {
+		return NewDetailErr(errors.New("PartialMerkleTree leaf count out of range."), ErrNoCode, "")
+	}
+	count, err := serialization.ReadVarUint(r, 0)
+	if err != nil {
+		return err
+	}
+	if count > uint64(p.Total) || count > remaining(r)/uint64(UINT256SIZE) {
+		return NewDetailErr(errors.New("PartialMerkleTree has too many hashes."), ErrNoCode, "")
+	}
+	p.Hashes = make([]Uint256, count)
+	for i := range p.Hashes {
+		if err := p.Hashes[i].Deserialize(r); err != nil {
+			return err
+		}
+	}
+	// the traversal visits at most 2*Total-1 nodes, one flag bit each
+	size, err := serialization.ReadVarUint(r, 0)
+	if err != nil {
+		return err
+	}
+	if size > (2*uint64(p.Total)+6)/8 || size > remaining(r) {
+		return NewDetailErr(errors.New("PartialMerkleTree has too many flags."), ErrNoCode, "")
+	}
+	p.Flags, err = serialization.ReadBytes(r, size)
+	return err
+}
+
+// remaining is the number of bytes left in r if it tells it
+func remaining(r io.Reader) uint64 {
+	if l, ok := r.(interface {
+		Len() int
+	}); ok {
+		return uint64(l.Len())
+	}
+	return math.MaxUint64
+}
+
+// width returns the number of nodes at height h, leaves are at height 0
+func (p *PartialMerkleTree) width(h uint) uint32 {
+	return uint32((uint64(p.Total) + (1 << h) - 1) >> h)
+}
+
+func (p *PartialMerkleTree) height() uint {
+	var h uint
+	for p.width(h) > 1 {
+		h++
+	}
+	return h
+}
+
+func (p *PartialMerkleTree) hash(h uint, pos uint32, hashes []Uint256) Uint256 {
+	if h == 0 {
+		return hashes[pos]
+	}
+	left := p.hash(h-1, pos*2, hashes)
+	right := left
+	if pos*2+1 < p.width(h-1) {
+		right = p.hash(h-1, pos*2+1, hashes)
+	}
+	return DOUBLE_SHA256([]Uint256{left, right})
+}
+
+func (p *PartialMerkleTree) build(h uint, pos uint32, hashes []Uint256, matches []bool, bits *[]bool) {
+	parentOfMatch := false
+	for i := pos << h; i < (pos+1)<<h && i < p.Total; i++ {
+		parentOfMatch = parentOfMatch || matches[i]
+	}
+	*bits = append(*bits, parentOfMatch)
+	if h == 0 || !parentOfMatch {
+		p.Hashes = append(p.Hashes, p.hash(h, pos, hashes))
+		return
+	}
+	p.build(h-1, pos*2, hashes, matches, bits)
+	if pos*2+1 < p.width(h-1) {
+		p.build(h-1, pos*2+1, hashes, matches, bits)
+	}
+}
+
+func (p *PartialMerkleTree) extract(h uint, pos uint32, bitsUsed, hashUsed *int, matches *[]Uint256) (Uint256, error) {
+	if *bitsUsed >= len(p.Flags)*8 {
+		return Uint256{}, NewDetailErr(errors.New("PartialMerkleTree overflowed the flag bits."), ErrNoCode, "")
+	}
+	parentOfMatch := p.Flags[*bitsUsed/8]&(1<<uint(*bitsUsed%8)) != 0
+	*bitsUsed++
+	if h == 0 || !parentOfMatch {
+		if *hashUsed >= len(p.Hashes) {
+			return Uint256{}, NewDetailErr(errors.New("PartialMerkleTree overflowed the hashes."), ErrNoCode, "")
+		}
+		hash := p.Hashes[*hashUsed]
+		*hashUsed++
+		if h == 0 && parentOfMatch {
+			*matches = append(*matches, hash)
+		}
+		return hash, nil
+	}
+	left, err := p.extract(h-1, pos*2, bitsUsed, hashUsed, matches)
+	if err != nil {
+		return Uint256{}, err
+	}
+	right := left
+	if pos*2+1 < p.width(h-1) {
+		right, err = p.extract(h-1, pos*2+1, bitsUsed, hashUsed, matches)
+		if err != nil {
+			return Uint256{}, err
+		}
+		if right == left {
+			// a duplicated right branch would let two trees share a root
+			return Uint256{}, NewDetailErr(errors.New("PartialMerkleTree has duplicated branches."), ErrNoCode, "")
+		}
+	}
+	return DOUBLE_SHA256([]Uint256{left, right}), nil
+}
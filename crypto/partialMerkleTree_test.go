@@ -0,0 +1,94 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	. "github.com/Ontology/common"
+	"github.com/Ontology/common/serialization"
+	"testing"
+)
+
+func TestPartialMerkleTree(t *testing.T) {
+	for total := 1; total <= 9; total++ {
+		var hashes []Uint256
+		for i := 0; i < total; i++ {
+			hashes = append(hashes, Uint256(sha256.Sum256([]byte{byte(i)})))
+		}
+		root, _ := ComputeRoot(hashes)
+
+		for mask := 0; mask < 1<<uint(total); mask++ {
+			matches := make([]bool, total)
+			var expected []Uint256
+			for i := range matches {
+				if mask&(1<<uint(i)) != 0 {
+					matches[i] = true
+					expected = append(expected, hashes[i])
+				}
+			}
+			tree, err := NewPartialMerkleTree(hashes, matches)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			buf := new(bytes.Buffer)
+			tree.Serialize(buf)
+			var decoded PartialMerkleTree
+			if err := decoded.Deserialize(buf); err != nil {
+				t.Fatal(err)
+			}
+
+			got, matched, err := decoded.ExtractMatches()
+			if err != nil {
+				t.Fatalf("total %d mask %b: %s", total, mask, err)
+			}
+			if got != root {
+				t.Fatalf("total %d mask %b: root mismatch", total, mask)
+			}
+			if len(matched) != len(expected) {
+				t.Fatalf("total %d mask %b: %d matches, expected %d", total, mask, len(matched), len(expected))
+			}
+			for i := range matched {
+				if matched[i] != expected[i] {
+					t.Fatalf("total %d mask %b: unexpected match", total, mask)
+				}
+			}
+		}
+	}
+}
+
+func TestPartialMerkleTreeTampered(t *testing.T) {
+	var hashes []Uint256
+	for i := 0; i < 5; i++ {
+		hashes = append(hashes, Uint256(sha256.Sum256([]byte{byte(i)})))
+	}
+	root, _ := ComputeRoot(hashes)
+	tree, _ := NewPartialMerkleTree(hashes, []bool{false, false, true, false, false})
+	tree.Hashes[0][0] ^= 1
+	got, _, err := tree.ExtractMatches()
+	if err == nil && got == root {
+		t.Fatal("tampered tree verified")
+	}
+}
+
+func TestPartialMerkleTreeOversized(t *testing.T) {
+	for _, c := range []struct {
+		name         string
+		total        uint32
+		count, flags uint64
+	}{
+		{"leaf count", MaxBlockTransactions + 1, 1, 1},
+		{"hash count", 1 << 10, 1 << 10, 1},
+		{"flag count", 1, 1, 1 << 30},
+	} {
+		buf := new(bytes.Buffer)
+		serialization.WriteUint32(buf, c.total)
+		serialization.WriteVarUint(buf, c.count)
+		buf.Write(make([]byte, UINT256SIZE))
+		serialization.WriteVarUint(buf, c.flags)
+		buf.WriteByte(1)
+		var decoded PartialMerkleTree
+		if err := decoded.Deserialize(buf); err == nil {
+			t.Fatalf("tree with an oversized %s decoded", c.name)
+		}
+	}
+}
@@ -27,6 +27,9 @@ const (
 	ErrSummaryAsset         ErrCode = 45012
 	ErrXmitFail             ErrCode = 45013
 	ErrNoAccount            ErrCode = 45014
+	ErrTxPoolFull           ErrCode = 45015
+	ErrReplaceUnderpriced   ErrCode = 45016
+	ErrSenderQuota          ErrCode = 45017
 )
 
 func (err ErrCode) Error() string {
@@ -61,6 +64,12 @@ func (err ErrCode) Error() string {
 		return "invalid summary asset"
 	case ErrXmitFail:
 		return "transmit error"
+	case ErrTxPoolFull:
+		return "transaction pool is full"
+	case ErrReplaceUnderpriced:
+		return "fee too low to replace the conflicting transaction"
+	case ErrSenderQuota:
+		return "too many transactions of the sender in pool"
 	}
 
 	return fmt.Sprintf("Unknown error? Error code = %d", err)
@@ -9,4 +9,6 @@ const (
 	EventNewInventory EventType = 3
 	EventNodeDisconnect EventType = 4
 	EventSmartCode EventType = 5
+	EventNewTransaction EventType = 6
+	EventBlockReverted EventType = 7
 )
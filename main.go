@@ -52,7 +52,11 @@ func main() {
 
 	log.Info("0. Loading the Ledger")
 	ledger.DefaultLedger = new(ledger.Ledger)
-	ledger.DefaultLedger.Store, err = ChainStore.NewLedgerStore()
+	if config.Parameters.NodeType == protocol.LIGHTNODENAME {
+		ledger.DefaultLedger.Store, err = ChainStore.NewHeaderOnlyLedgerStore()
+	} else {
+		ledger.DefaultLedger.Store, err = ChainStore.NewLedgerStore()
+	}
 	if err != nil {
 		log.Fatal("open LedgerStore err:", err)
 		os.Exit(1)
@@ -89,16 +93,33 @@ func main() {
 	}
 	ledger.DefaultLedger.Blockchain = blockChain
 
+	log.Info("--Loading Event Store--")
+	ChainStore.DefaultEventStore, err = ChainStore.NewEventStore()
+	if err != nil {
+		log.Fatal("open event notify store err:", err)
+		os.Exit(1)
+	}
+	if err := ChainStore.DefaultEventStore.DeleteEventNotifyAbove(ledger.DefaultLedger.Store.GetHeight()); err != nil {
+		log.Fatal("delete reverted event notify err:", err)
+		os.Exit(1)
+	}
+
 	log.Info("4. Start the P2P networks")
 	// Don't need two return value.
 	noder = net.StartProtocol(acct.PublicKey)
 	go httprestful.StartServer(noder)
 	httpjsonrpc.RegistRpcNode(noder)
 
-	noder.SyncNodeHeight()
-	noder.WaitForPeersStart()
-	noder.WaitForSyncBlkFinish()
-	if protocol.SERVICENODENAME != config.Parameters.NodeType {
+	if noder.IsLightNode() {
+		// light nodes only sync headers and the wallet transactions
+		noder.WatchAddresses(client.GetProgramHashes())
+		noder.WaitForPeersStart()
+	} else {
+		noder.SyncNodeHeight()
+		noder.WaitForPeersStart()
+		noder.WaitForSyncBlkFinish()
+	}
+	if protocol.SERVICENODENAME != config.Parameters.NodeType && !noder.IsLightNode() {
 		log.Info("5. Start Consensus Services")
 		consensusSrv := consensus.ConsensusMgr.NewConsensusService(client, noder)
 		httpjsonrpc.RegistConsensusService(consensusSrv)
@@ -114,13 +135,6 @@ func main() {
 		go httpnodeinfo.StartServer(noder)
 	}
 
-	log.Info("--Loading Event Store--")
-	ChainStore.DefaultEventStore, err = ChainStore.NewEventStore()
-	if err != nil {
-		log.Fatal("open event notify store err:", err)
-		os.Exit(1)
-	}
-
 	go func() {
 		ticker := time.NewTicker(config.DEFAULTGENBLOCKTIME * time.Second)
 		for {
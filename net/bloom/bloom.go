@@ -0,0 +1,253 @@
+package bloom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"sync"
+
+	. "github.com/Ontology/common"
+	tx "github.com/Ontology/core/transaction"
+)
+
+const (
+	// MaxFilterSize is the maximum size in bytes of a loaded filter
+	MaxFilterSize = 36000
+	// MaxHashFuncs is the maximum number of hash functions of a loaded filter
+	MaxHashFuncs = 50
+	// MaxFilterAddDataSize is the maximum size of an element added by filteradd
+	MaxFilterAddDataSize = 520
+
+	ln2Squared = math.Ln2 * math.Ln2
+	hashSeed   = 0xfba4c795
+)
+
+// The filter update flags, they decide whether the outpoints of matched
+// outputs are inserted into the filter so that spending transactions match.
+const (
+	UpdateNone UpdateFlag = 0
+	UpdateAll  UpdateFlag = 1
+)
+
+type UpdateFlag uint8
+
+// Filter is a BIP37 style bloom filter. The zero value is an unloaded filter
+// which matches every transaction, so peers without a filter get full relay.
+type Filter struct {
+	mu        sync.RWMutex
+	loaded    bool
+	data      []byte
+	hashFuncs uint32
+	tweak     uint32
+	flags     UpdateFlag
+}
+
+// NewFilter creates a filter sized for elements entries with the false
+// positive rate fpRate.
+func NewFilter(elements uint32, fpRate float64, tweak uint32, flags UpdateFlag) *Filter {
+	if elements == 0 {
+		elements = 1
+	}
+	if fpRate <= 0 {
+		fpRate = 1e-9
+	}
+	if fpRate > 1 {
+		fpRate = 1
+	}
+	size := uint32(-1 * float64(elements) * math.Log(fpRate) / ln2Squared / 8)
+	if size < 1 {
+		size = 1
+	}
+	if size > MaxFilterSize {
+		size = MaxFilterSize
+	}
+	hashFuncs := uint32(float64(size*8) / float64(elements) * math.Ln2)
+	if hashFuncs < 1 {
+		hashFuncs = 1
+	}
+	if hashFuncs > MaxHashFuncs {
+		hashFuncs = MaxHashFuncs
+	}
+	f := &Filter{}
+	f.Load(make([]byte, size), hashFuncs, tweak, flags)
+	return f
+}
+
+// Load replaces the filter content
+func (f *Filter) Load(data []byte, hashFuncs uint32, tweak uint32, flags UpdateFlag) error {
+	if len(data) == 0 || len(data) > MaxFilterSize {
+		return errors.New("[Bloom] invalid filter size")
+	}
+	if hashFuncs == 0 || hashFuncs > MaxHashFuncs {
+		return errors.New("[Bloom] invalid number of hash functions")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.data = data
+	f.hashFuncs = hashFuncs
+	f.tweak = tweak
+	f.flags = flags
+	f.loaded = true
+	return nil
+}
+
+// Clear unloads the filter
+func (f *Filter) Clear() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.data = nil
+	f.loaded = false
+}
+
+func (f *Filter) IsLoaded() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.loaded
+}
+
+// Data returns the filter content in the form sent by filterload
+func (f *Filter) Data() (data []byte, hashFuncs uint32, tweak uint32, flags UpdateFlag) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	data = make([]byte, len(f.data))
+	copy(data, f.data)
+	return data, f.hashFuncs, f.tweak, f.flags
+}
+
+// Add inserts data into a loaded filter
+func (f *Filter) Add(data []byte) error {
+	if len(data) > MaxFilterAddDataSize {
+		return errors.New("[Bloom] filter element too large")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.loaded {
+		return errors.New("[Bloom] filter not loaded")
+	}
+	f.add(data)
+	return nil
+}
+
+// Matches reports whether data may be in the filter. An unloaded filter
+// matches everything.
+func (f *Filter) Matches(data []byte) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if !f.loaded {
+		return true
+	}
+	return f.matches(data)
+}
+
+// MatchTxAndUpdate reports whether the transaction is relevant to the
+// filter. The transaction hash, the program hashes of its outputs and
+// balance inputs and the outpoints it spends are tested. With UpdateAll the
+// outpoints of matched outputs are added to the filter.
+func (f *Filter) MatchTxAndUpdate(txn *tx.Transaction) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.loaded {
+		return true
+	}
+
+	hash := txn.Hash()
+	matched := f.matches(hash.ToArray())
+	for i, output := range txn.Outputs {
+		if !f.matches(output.ProgramHash.ToArray()) {
+			continue
+		}
+		matched = true
+		if f.flags == UpdateAll {
+			f.add(outpoint(hash, uint16(i)))
+		}
+	}
+	if matched {
+		return true
+	}
+	for _, input := range txn.UTXOInputs {
+		if f.matches(outpoint(input.ReferTxID, input.ReferTxOutputIndex)) {
+			return true
+		}
+	}
+	for _, input := range txn.BalanceInputs {
+		if f.matches(input.ProgramHash.ToArray()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Filter) add(data []byte) {
+	bits := uint32(len(f.data) * 8)
+	for i := uint32(0); i < f.hashFuncs; i++ {
+		index := f.hash(i, data) % bits
+		f.data[index>>3] |= 1 << (index & 7)
+	}
+}
+
+func (f *Filter) matches(data []byte) bool {
+	bits := uint32(len(f.data) * 8)
+	for i := uint32(0); i < f.hashFuncs; i++ {
+		index := f.hash(i, data) % bits
+		if f.data[index>>3]&(1<<(index&7)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *Filter) hash(n uint32, data []byte) uint32 {
+	return murmurHash3(n*hashSeed+f.tweak, data)
+}
+
+func outpoint(hash Uint256, index uint16) []byte {
+	buf := bytes.NewBuffer(hash.ToArray())
+	binary.Write(buf, binary.LittleEndian, index)
+	return buf.Bytes()
+}
+
+// murmurHash3 is the 32 bit x86 variant of MurmurHash3
+func murmurHash3(seed uint32, data []byte) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+	h := seed
+	length := len(data)
+	blocks := length / 4
+	for i := 0; i < blocks; i++ {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= c1
+		k = (k << 15) | (k >> 17)
+		k *= c2
+		h ^= k
+		h = (h << 13) | (h >> 19)
+		h = h*5 + 0xe6546b64
+	}
+
+	tail := data[blocks*4:]
+	var k uint32
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = (k << 15) | (k >> 17)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(length)
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
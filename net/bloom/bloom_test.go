@@ -0,0 +1,66 @@
+package bloom
+
+import (
+	"testing"
+
+	. "github.com/Ontology/common"
+	tx "github.com/Ontology/core/transaction"
+	"github.com/Ontology/core/transaction/payload"
+	"github.com/Ontology/core/transaction/utxo"
+)
+
+func TestMurmurHash3(t *testing.T) {
+	// vectors from the BIP37 reference implementation
+	cases := []struct {
+		seed     uint32
+		data     []byte
+		expected uint32
+	}{
+		{0x00000000, []byte{}, 0x00000000},
+		{0xfba4c795, []byte{}, 0x6a396f08},
+		{0x00000000, []byte{0x00}, 0x514e28b7},
+		{0xfba4c795, []byte{0x00}, 0xea3f0b17},
+		{0x00000000, []byte{0xff}, 0xfd6cf10d},
+		{0x00000000, []byte{0x00, 0x11}, 0x16c6b7ab},
+		{0x00000000, []byte{0x00, 0x11, 0x22}, 0x8eb51c3d},
+		{0x00000000, []byte{0x00, 0x11, 0x22, 0x33}, 0xb4471bf8},
+	}
+	for _, c := range cases {
+		if got := murmurHash3(c.seed, c.data); got != c.expected {
+			t.Fatalf("murmurHash3(%x, %x) = %x, expected %x", c.seed, c.data, got, c.expected)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	var unloaded Filter
+	if !unloaded.Matches([]byte("anything")) {
+		t.Fatal("unloaded filter must match everything")
+	}
+
+	f := NewFilter(10, 0.0001, 0, UpdateAll)
+	f.Add([]byte("alice"))
+	if !f.Matches([]byte("alice")) {
+		t.Fatal("added element not matched")
+	}
+	if f.Matches([]byte("bob")) {
+		t.Fatal("unexpected match")
+	}
+
+	var owner Uint160
+	owner[0] = 1
+	f.Add(owner.ToArray())
+	funding := &tx.Transaction{TxType: tx.TransferAsset, Payload: &payload.TransferAsset{}, Outputs: []*utxo.TxOutput{{ProgramHash: owner}}}
+	if !f.MatchTxAndUpdate(funding) {
+		t.Fatal("output to watched address not matched")
+	}
+	spending := &tx.Transaction{TxType: tx.TransferAsset, Payload: &payload.TransferAsset{}, UTXOInputs: []*utxo.UTXOTxInput{{ReferTxID: funding.Hash(), ReferTxOutputIndex: 0}}}
+	if !f.MatchTxAndUpdate(spending) {
+		t.Fatal("spend of matched output not matched")
+	}
+
+	f.Clear()
+	if f.IsLoaded() {
+		t.Fatal("filter still loaded after clear")
+	}
+}
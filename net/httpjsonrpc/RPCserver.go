@@ -21,8 +21,12 @@ func StartRPCServer() {
 	HandleFunc("getrawtransaction", getRawTransaction)
 	HandleFunc("getcalculateBouns", getCalculateBouns)
 	HandleFunc("sendrawtransaction", sendRawTransaction)
+	HandleFunc("simulatetransaction", simulateTransaction)
 	HandleFunc("getstorage", getStorage)
+	HandleFunc("getevents", getEvents)
+	HandleFunc("getaddresshistory", getAddressHistory)
 	HandleFunc("getbalance", getBalance)
+	HandleFunc("getaccountstate", getAccountState)
 	HandleFunc("submitblock", submitBlock)
 	HandleFunc("getversion", getVersion)
 	HandleFunc("getdataile", getDataFile)
@@ -30,6 +34,10 @@ func StartRPCServer() {
 	HandleFunc("regdatafile", regDataFile)
 	HandleFunc("uploadDataFile", uploadDataFile)
 	HandleFunc("getsmartcodeevent", getSmartCodeEvent)
+	HandleFunc("getmerkleproof", getMerkleProof)
+	HandleFunc("getconsistencyproof", getConsistencyProof)
+	HandleFunc("getaccountproof", getAccountProof)
+	HandleFunc("getstorageproof", getStorageProof)
 
 	err := http.ListenAndServe(":" + strconv.Itoa(Parameters.HttpJsonPort), nil)
 	if err != nil {
@@ -26,9 +26,11 @@ type FunctionCodeInfo struct {
 type InvokeCodeInfo struct {
 	CodeHash string
 	Code     string
+	GasLimit int64
 }
 type DeployCodeInfo struct {
 	Code        *FunctionCodeInfo
+	VmType      uint8
 	Name        string
 	CodeVersion string
 	Author      string
@@ -127,6 +129,7 @@ func TransPayloadToHex(p tx.Payload) PayloadInfo {
 		obj := new(InvokeCodeInfo)
 		obj.CodeHash = ToHexString(object.CodeHash.ToArray())
 		obj.Code = ToHexString(object.Code)
+		obj.GasLimit = object.GasLimit.GetData()
 		return obj
 	case *payload.DeployCode:
 		obj := new(DeployCodeInfo)
@@ -134,6 +137,7 @@ func TransPayloadToHex(p tx.Payload) PayloadInfo {
 		obj.Code.Code = ToHexString(object.Code.Code)
 		obj.Code.ParameterTypes = ToHexString(ContractParameterTypeToByte(object.Code.ParameterTypes))
 		obj.Code.ReturnType = uint8(object.Code.ReturnType)
+		obj.VmType = uint8(object.VmType)
 		obj.Name = object.Name
 		obj.CodeVersion = object.CodeVersion
 		obj.Author = object.Author
@@ -4,6 +4,9 @@ import (
 	. "github.com/Ontology/common"
 	."github.com/Ontology/consensus"
 	"github.com/Ontology/common/log"
+	"github.com/Ontology/core/ledger"
+	"github.com/Ontology/core/states"
+	"github.com/Ontology/core/store/ChainStore"
 	tx "github.com/Ontology/core/transaction"
 	. "github.com/Ontology/errors"
 	. "github.com/Ontology/net/protocol"
@@ -12,6 +15,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"github.com/Ontology/core/transaction/utxo"
@@ -81,6 +85,7 @@ type Transactions struct {
 	Programs          []ProgramInfo
 	NetworkFee        string
 	SystemFee         string
+	GasConsumed       string
 
 	AssetOutputs      []TxoutputMap
 	AssetInputAmount  []AmountMap
@@ -135,10 +140,96 @@ type NodeInfo struct {
 	RxTxnCnt uint64 // The transaction received by this node
 }
 
+type EventInfo struct {
+	Height   uint32
+	TxHash   string
+	CodeHash string
+	States   interface{}
+}
+
+type EventsInfo struct {
+	Events []*EventInfo
+	Cursor string
+}
+
+type AddressHistoryInfo struct {
+	Height    uint32
+	TxHash    string
+	Direction string
+	AssetId   string
+	Value     string
+}
+
+type AddressHistoryPage struct {
+	History []*AddressHistoryInfo
+	Cursor  string
+}
+
+type BalanceInfo struct {
+	AssetId string
+	Value   string
+}
+
+type AccountStateInfo struct {
+	ProgramHash string
+	IsFrozen    bool
+	Balances    []BalanceInfo
+}
+
 type ConsensusInfo struct {
 	// TODO
 }
 
+// QueryEvents reads a page of the event index of a contract, the cursor of
+// the result is empty after the last page
+func QueryEvents(codeHash Uint160, topic string, fromHeight, toHeight uint32, limit int, cursor string) (*EventsInfo, error) {
+	if ChainStore.DefaultEventStore == nil {
+		return nil, NewErr("event store is not loaded")
+	}
+	start, err := HexToBytes(cursor)
+	if err != nil {
+		return nil, err
+	}
+	notifies, next, err := ChainStore.DefaultEventStore.GetEvents(codeHash, topic, fromHeight, toHeight, limit, start)
+	if err != nil {
+		return nil, err
+	}
+	result := &EventsInfo{Events: []*EventInfo{}, Cursor: ToHexString(next)}
+	for _, n := range notifies {
+		result.Events = append(result.Events, &EventInfo{
+			Height:   n.Height,
+			TxHash:   ToHexString(n.Notify.Container.ToArray()),
+			CodeHash: ToHexString(n.Notify.CodeHash.ToArray()),
+			States:   n.Notify.States,
+		})
+	}
+	return result, nil
+}
+
+// QueryAddressHistory reads a page of the transfers of an address, the
+// newest first, the cursor of the result is empty after the last page
+func QueryAddressHistory(programHash Uint160, limit int, cursor []byte) (*AddressHistoryPage, error) {
+	history, next, err := ledger.DefaultLedger.Store.GetAddressHistory(programHash, limit, cursor)
+	if err != nil {
+		return nil, err
+	}
+	result := &AddressHistoryPage{History: []*AddressHistoryInfo{}, Cursor: ToHexString(next)}
+	for _, h := range history {
+		direction := "in"
+		if h.Direction == states.TransferOut {
+			direction = "out"
+		}
+		result.History = append(result.History, &AddressHistoryInfo{
+			Height:    h.Height,
+			TxHash:    ToHexString(h.TxHash.ToArray()),
+			Direction: direction,
+			AssetId:   ToHexString(h.AssetId.ToArray()),
+			Value:     strconv.FormatInt(int64(h.Amount), 10),
+		})
+	}
+	return result, nil
+}
+
 func RegistRpcNode(n Noder) {
 	if node == nil {
 		node = n
@@ -15,10 +15,13 @@ import (
 	"github.com/Ontology/core/transaction/payload"
 	"github.com/Ontology/core/transaction/utxo"
 	. "github.com/Ontology/errors"
+	. "github.com/Ontology/net/protocol"
+	"github.com/Ontology/smartcontract/pre_exec"
 	"math/rand"
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 )
 
 const (
@@ -80,6 +83,12 @@ func TransArryByteToHexString(ptx *tx.Transaction) *Transactions {
 
 	mhash := ptx.Hash()
 	trans.Hash = ToHexString(mhash.ToArray())
+	if _, ok := ptx.Payload.(*payload.InvokeCode); ok && ledger.DefaultLedger != nil {
+		// only known once the transaction is in a block
+		if gas, err := ledger.DefaultLedger.Store.GetGasConsumed(mhash); err == nil {
+			trans.GasConsumed = strconv.FormatInt(int64(gas), 10)
+		}
+	}
 
 	return trans
 }
@@ -127,6 +136,9 @@ func getBlock(params []interface{}) map[string]interface{} {
 	}
 
 	block, err := ledger.DefaultLedger.Store.GetBlock(hash)
+	if err == ledger.ErrPruned {
+		return DnaRpcPrunedData
+	}
 	if err != nil {
 		return DnaRpcUnknownBlock
 	}
@@ -219,9 +231,19 @@ func getRawTransaction(params []interface{}) map[string]interface{} {
 			return DnaRpcInvalidTransaction
 		}
 		tx, err := ledger.DefaultLedger.Store.GetTransaction(hash)
+		if err == ledger.ErrPruned {
+			return DnaRpcPrunedData
+		}
 		if err != nil {
 			return DnaRpcUnknownTransaction
 		}
+		if len(params) > 1 {
+			if raw, ok := params[1].(float64); ok && raw == 1 {
+				w := bytes.NewBuffer(nil)
+				tx.Serialize(w)
+				return DnaRpc(ToHexString(w.Bytes()))
+			}
+		}
 		tran := TransArryByteToHexString(tx)
 		return DnaRpc(tran)
 	default:
@@ -229,7 +251,8 @@ func getRawTransaction(params []interface{}) map[string]interface{} {
 	}
 }
 
-// A JSON example for getrawtransaction method as following:
+// A JSON example for getrawtransaction method as following, the optional
+// second parameter 1 returns the serialized transaction in hex:
 //   {"jsonrpc": "2.0", "method": "getrawtransaction", "params": ["transactioin hash in hex"], "id": 0}
 func getCalculateBouns(params []interface{}) map[string]interface{} {
 	if len(params) < 2 {
@@ -271,6 +294,41 @@ func getCalculateBouns(params []interface{}) map[string]interface{} {
 	}
 }
 
+// A JSON example for getaccountstate method as following:
+//   {"jsonrpc": "2.0", "method": "getaccountstate", "params": ["address"], "id": 0}
+func getAccountState(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return DnaRpcNil
+	}
+	addr, ok := params[0].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	programHash, err := ToScriptHash(addr)
+	if err != nil {
+		return DnaRpcInvalidParameter
+	}
+	account, err := ledger.DefaultLedger.Store.GetAccount(programHash)
+	if err != nil {
+		return DnaRpcAccountNotFound
+	}
+	info := AccountStateInfo{
+		ProgramHash: ToHexString(programHash.ToArray()),
+		IsFrozen:    account.IsFrozen,
+		Balances:    []BalanceInfo{},
+	}
+	for _, v := range account.Balances {
+		info.Balances = append(info.Balances, BalanceInfo{
+			AssetId: ToHexString(v.AssetId.ToArray()),
+			Value:   strconv.FormatInt(int64(v.Amount), 10),
+		})
+	}
+	return DnaRpc(info)
+}
+
+// A JSON example for getbalance method as following, the optional third
+// parameter queries the balance after the block at that height:
+//   {"jsonrpc": "2.0", "method": "getbalance", "params": ["address", "asset id", 100], "id": 0}
 func getBalance(params []interface{}) map[string]interface{} {
 	if len(params) < 2 {
 		return DnaRpcNil
@@ -289,7 +347,19 @@ func getBalance(params []interface{}) map[string]interface{} {
 	if err != nil {
 		return DnaRpcInvalidParameter
 	}
-	account, err := ledger.DefaultLedger.Store.GetAccount(programHash)
+	var account *states.AccountState
+	if len(params) > 2 {
+		height, ok := params[2].(float64)
+		if !ok {
+			return DnaRpcInvalidParameter
+		}
+		account, err = ledger.DefaultLedger.Store.GetAccountAt(programHash, uint32(height))
+	} else {
+		account, err = ledger.DefaultLedger.Store.GetAccount(programHash)
+	}
+	if e, ok := err.(ledger.HistoryUnavailableError); ok {
+		return DnaRpc(e.Error())
+	}
 	if err != nil {
 		return DnaRpcAccountNotFound
 	}
@@ -312,6 +382,8 @@ func getBalance(params []interface{}) map[string]interface{} {
 }
 
 //   {"jsonrpc": "2.0", "method": "getstorage", "params": ["code hash", "key"], "id": 0}
+// the optional third parameter queries the item after the block at that height:
+//   {"jsonrpc": "2.0", "method": "getstorage", "params": ["code hash", "key", 100], "id": 0}
 func getStorage(params []interface{}) map[string]interface{} {
 	if len(params) < 2 {
 		return DnaRpcNil
@@ -344,7 +416,21 @@ func getStorage(params []interface{}) map[string]interface{} {
 	default:
 		return DnaRpcInvalidParameter
 	}
-	item, err := ledger.DefaultLedger.Store.GetStorageItem(&states.StorageKey{CodeHash: codeHash, Key: key})
+	storageKey := &states.StorageKey{CodeHash: codeHash, Key: key}
+	var item *states.StorageItem
+	var err error
+	if len(params) > 2 {
+		height, ok := params[2].(float64)
+		if !ok {
+			return DnaRpcInvalidParameter
+		}
+		item, err = ledger.DefaultLedger.Store.GetStorageItemAt(storageKey, uint32(height))
+	} else {
+		item, err = ledger.DefaultLedger.Store.GetStorageItem(storageKey)
+	}
+	if e, ok := err.(ledger.HistoryUnavailableError); ok {
+		return DnaRpc(e.Error())
+	}
 	if err != nil {
 		return DnaRpcInternalError
 	}
@@ -379,6 +465,36 @@ func sendRawTransaction(params []interface{}) map[string]interface{} {
 	return DnaRpc(ToHexString(hash.ToArray()))
 }
 
+// A JSON example for simulatetransaction method as following:
+//   {"jsonrpc": "2.0", "method": "simulatetransaction", "params": ["unsigned invoke transaction in hex"], "id": 0}
+func simulateTransaction(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return DnaRpcNil
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	buf, err := hex.DecodeString(str)
+	if err != nil {
+		return DnaRpcInvalidParameter
+	}
+	var txn tx.Transaction
+	if err := txn.Deserialize(bytes.NewReader(buf)); err != nil {
+		return DnaRpcInvalidTransaction
+	}
+	result, err := pre_exec.Simulate(&txn)
+	switch err {
+	case nil:
+	case pre_exec.ErrNotInvokeTransaction, pre_exec.ErrContractNotFound:
+		return DnaRpcInvalidTransaction
+	default:
+		log.Error("simulatetransaction error: ", err)
+		return DnaRpcInternalError
+	}
+	return DnaRpc(result)
+}
+
 func getUnspendOutput(params []interface{}) map[string]interface{} {
 	if len(params) < 2 {
 		return DnaRpcNil
@@ -482,6 +598,76 @@ func getNodeState(params []interface{}) map[string]interface{} {
 	return DnaRpc(n)
 }
 
+// PeerList is the result of listpeers
+type PeerList struct {
+	Peers  []PeerInfo
+	Banned []BanInfo
+}
+
+// A JSON example for listpeers method as following:
+//   {"jsonrpc": "2.0", "method": "listpeers", "params": [], "id": 0}
+func listPeers(params []interface{}) map[string]interface{} {
+	return DnaRpc(PeerList{
+		Peers:  node.GetPeerInfos(),
+		Banned: node.GetBannedPeers(),
+	})
+}
+
+// A JSON example for banpeer method as following, the optional second
+// parameter is the ban time in seconds:
+//   {"jsonrpc": "2.0", "method": "banpeer", "params": ["127.0.0.1", 3600], "id": 0}
+func banPeer(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return DnaRpcNil
+	}
+	addr, ok := params[0].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	duration := DEFAULTBANTIME
+	if len(params) > 1 {
+		seconds, ok := params[1].(float64)
+		if !ok || seconds <= 0 {
+			return DnaRpcInvalidParameter
+		}
+		duration = time.Duration(seconds) * time.Second
+	}
+	if err := node.BanPeer(addr, duration); err != nil {
+		log.Error("banpeer error: ", err)
+		return DnaRpcFailed
+	}
+	return DnaRpcSuccess
+}
+
+// A JSON example for unbanpeer method as following:
+//   {"jsonrpc": "2.0", "method": "unbanpeer", "params": ["127.0.0.1"], "id": 0}
+func unbanPeer(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return DnaRpcNil
+	}
+	addr, ok := params[0].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	if err := node.UnbanPeer(addr); err != nil {
+		log.Error("unbanpeer error: ", err)
+		return DnaRpcFailed
+	}
+	return DnaRpcSuccess
+}
+
+// A JSON example for createsnapshot method as following, the result is the
+// height of the snapshot:
+//   {"jsonrpc": "2.0", "method": "createsnapshot", "params": [], "id": 0}
+func createSnapshot(params []interface{}) map[string]interface{} {
+	height, err := ledger.DefaultLedger.Store.CreateSnapshot()
+	if err != nil {
+		log.Error("createsnapshot error: ", err)
+		return DnaRpcInternalError
+	}
+	return DnaRpc(height)
+}
+
 func startConsensus(params []interface{}) map[string]interface{} {
 	if err := consensusSrv.Start(); err != nil {
 		return DnaRpcFailed
@@ -602,6 +788,239 @@ func getSmartCodeEvent(params []interface{}) map[string]interface{} {
 	}
 	return DnaRpcInvalidParameter
 }
+// A JSON example for getevents method as following, the parameters after the
+// contract hash are the topic, the height range, the page size and the cursor
+// returned by the previous page, all of them optional:
+//   {"jsonrpc": "2.0", "method": "getevents", "params": ["contract hash in hex", "topic", 0, 100, 10, ""], "id": 0}
+func getEvents(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return DnaRpcNil
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	buf, err := hex.DecodeString(str)
+	if err != nil {
+		return DnaRpcInvalidParameter
+	}
+	var codeHash Uint160
+	if err := codeHash.Deserialize(bytes.NewReader(buf)); err != nil {
+		return DnaRpcInvalidHash
+	}
+	var topic, cursor string
+	if len(params) > 1 {
+		if topic, ok = params[1].(string); !ok {
+			return DnaRpcInvalidParameter
+		}
+	}
+	var fromHeight float64
+	if len(params) > 2 {
+		if fromHeight, ok = params[2].(float64); !ok {
+			return DnaRpcInvalidParameter
+		}
+	}
+	toHeight, ok := proofHeight(params, 3)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	var limit float64
+	if len(params) > 4 {
+		if limit, ok = params[4].(float64); !ok {
+			return DnaRpcInvalidParameter
+		}
+	}
+	if len(params) > 5 {
+		if cursor, ok = params[5].(string); !ok {
+			return DnaRpcInvalidParameter
+		}
+	}
+	result, err := QueryEvents(codeHash, topic, uint32(fromHeight), toHeight, int(limit), cursor)
+	if err != nil {
+		log.Error("getevents error: ", err)
+		return DnaRpcInvalidParameter
+	}
+	return DnaRpc(result)
+}
+
+// A JSON example for getaddresshistory method as following, the optional
+// parameters are the page size and the cursor returned by the previous page:
+//   {"jsonrpc": "2.0", "method": "getaddresshistory", "params": ["address", 10, ""], "id": 0}
+func getAddressHistory(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return DnaRpcNil
+	}
+	addr, ok := params[0].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	programHash, err := ToScriptHash(addr)
+	if err != nil {
+		return DnaRpcInvalidParameter
+	}
+	var limit float64
+	var cursor []byte
+	if len(params) > 1 {
+		if limit, ok = params[1].(float64); !ok {
+			return DnaRpcInvalidParameter
+		}
+	}
+	if len(params) > 2 {
+		str, ok := params[2].(string)
+		if !ok {
+			return DnaRpcInvalidParameter
+		}
+		if cursor, err = HexToBytes(str); err != nil {
+			return DnaRpcInvalidParameter
+		}
+	}
+	result, err := QueryAddressHistory(programHash, int(limit), cursor)
+	switch err {
+	case nil:
+	case ledger.ErrAddressHistoryDisabled:
+		return DnaRpcUnsupported
+	case ledger.ErrInvalidCursor:
+		return DnaRpcInvalidParameter
+	default:
+		log.Error("getaddresshistory error: ", err)
+		return DnaRpcInternalError
+	}
+	return DnaRpc(result)
+}
+
+// A JSON example for getmerkleproof method as following, the optional
+// second parameter is the height of the block whose BlockRoot is proven:
+//   {"jsonrpc": "2.0", "method": "getmerkleproof", "params": ["transaction hash in hex", 100], "id": 0}
+func getMerkleProof(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return DnaRpcNil
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	hex, err := hex.DecodeString(str)
+	if err != nil {
+		return DnaRpcInvalidParameter
+	}
+	var hash Uint256
+	if err := hash.Deserialize(bytes.NewReader(hex)); err != nil {
+		return DnaRpcInvalidTransaction
+	}
+	_, height, err := ledger.DefaultLedger.Store.GetTransactionWithHeight(hash)
+	if err != nil {
+		return DnaRpcUnknownTransaction
+	}
+	rootHeight, ok := proofHeight(params, 1)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	proof, err := NewMerkleProof(hash, height, rootHeight)
+	if err != nil {
+		log.Error("getmerkleproof error: ", err)
+		return DnaRpcInvalidParameter
+	}
+	return DnaRpc(proof)
+}
+
+// A JSON example for getconsistencyproof method as following:
+//   {"jsonrpc": "2.0", "method": "getconsistencyproof", "params": [10, 100], "id": 0}
+func getConsistencyProof(params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return DnaRpcNil
+	}
+	from, ok := params[0].(float64)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	to, ok := params[1].(float64)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	proof, err := NewConsistencyProof(uint32(from), uint32(to))
+	if err != nil {
+		log.Error("getconsistencyproof error: ", err)
+		return DnaRpcInvalidParameter
+	}
+	return DnaRpc(proof)
+}
+
+// proofHeight returns the optional height parameter at index, the current
+// block height by default
+func proofHeight(params []interface{}, index int) (uint32, bool) {
+	if len(params) <= index {
+		return ledger.DefaultLedger.Store.GetHeight(), true
+	}
+	h, ok := params[index].(float64)
+	return uint32(h), ok
+}
+
+// A JSON example for getaccountproof method as following, the optional
+// second parameter proves the state after the block at that height:
+//   {"jsonrpc": "2.0", "method": "getaccountproof", "params": ["address", 100], "id": 0}
+func getAccountProof(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return DnaRpcNil
+	}
+	addr, ok := params[0].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	programHash, err := ToScriptHash(addr)
+	if err != nil {
+		return DnaRpcInvalidParameter
+	}
+	height, ok := proofHeight(params, 1)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	proof, err := NewAccountStateProof(programHash, height)
+	if err != nil {
+		log.Error("getaccountproof error: ", err)
+		return DnaRpcInvalidParameter
+	}
+	return DnaRpc(proof)
+}
+
+// A JSON example for getstorageproof method as following, the optional
+// third parameter proves the item after the block at that height:
+//   {"jsonrpc": "2.0", "method": "getstorageproof", "params": ["code hash", "key", 100], "id": 0}
+func getStorageProof(params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return DnaRpcNil
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	hex, err := hex.DecodeString(str)
+	if err != nil {
+		return DnaRpcInvalidParameter
+	}
+	var codeHash Uint160
+	if err := codeHash.Deserialize(bytes.NewReader(hex)); err != nil {
+		return DnaRpcInvalidHash
+	}
+	str, ok = params[1].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	key, err := HexToBytes(str)
+	if err != nil {
+		return DnaRpcInvalidParameter
+	}
+	height, ok := proofHeight(params, 2)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	proof, err := NewStorageStateProof(&states.StorageKey{CodeHash: codeHash, Key: key}, height)
+	if err != nil {
+		log.Error("getstorageproof error: ", err)
+		return DnaRpcInvalidParameter
+	}
+	return DnaRpc(proof)
+}
+
 func regDataFile(params []interface{}) map[string]interface{} {
 	if len(params) < 1 {
 		return DnaRpcNil
@@ -646,6 +1065,9 @@ func catDataRecord(params []interface{}) map[string]interface{} {
 			return DnaRpcInvalidTransaction
 		}
 		tx, err := ledger.DefaultLedger.Store.GetTransaction(hash)
+		if err == ledger.ErrPruned {
+			return DnaRpcPrunedData
+		}
 		if err != nil {
 			return DnaRpcUnknownTransaction
 		}
@@ -675,6 +1097,9 @@ func getDataFile(params []interface{}) map[string]interface{} {
 			return DnaRpcInvalidTransaction
 		}
 		tx, err := ledger.DefaultLedger.Store.GetTransaction(hash)
+		if err == ledger.ErrPruned {
+			return DnaRpcPrunedData
+		}
 		if err != nil {
 			return DnaRpcUnknownTransaction
 		}
@@ -22,6 +22,10 @@ func StartLocalServer() {
 	HandleFunc("stopconsensus", stopConsensus)
 	HandleFunc("sendsampletransaction", sendSampleTransaction)
 	HandleFunc("setdebuginfo", setDebugInfo)
+	HandleFunc("listpeers", listPeers)
+	HandleFunc("banpeer", banPeer)
+	HandleFunc("unbanpeer", unbanPeer)
+	HandleFunc("createsnapshot", createSnapshot)
 
 	// TODO: only listen to local host
 	err := http.ListenAndServe(":" + strconv.Itoa(Parameters.HttpLocalPort), nil)
@@ -0,0 +1,215 @@
+package httpjsonrpc
+
+import (
+	"bytes"
+	"errors"
+
+	. "github.com/Ontology/common"
+	"github.com/Ontology/core/ledger"
+	"github.com/Ontology/crypto"
+	"github.com/Ontology/merkle"
+)
+
+// MerkleProof proves that the transaction TxHash is in the block at
+// BlockHeight and that the block is the leaf BlockHeight of the block merkle
+// tree of size TreeSize, whose root is the BlockRoot of the block at
+// TreeSize-1. TxProof is the serialized partial merkle tree of the block
+// transactions.
+type MerkleProof struct {
+	Type             string
+	TxHash           string
+	TxProof          string
+	TransactionsRoot string
+	BlockHeight      uint32
+	BlockRoot        string
+	TreeSize         uint32
+	AuditPath        []string
+}
+
+// ConsistencyProof proves that the block merkle tree of size OldTreeSize is
+// a prefix of the tree of size NewTreeSize.
+type ConsistencyProof struct {
+	Type        string
+	OldTreeSize uint32
+	OldRoot     string
+	NewTreeSize uint32
+	NewRoot     string
+	AuditPath   []string
+}
+
+// NewMerkleProof builds the proof of the transaction txHash included in the
+// block at height against the BlockRoot of the block at rootHeight
+func NewMerkleProof(txHash Uint256, height, rootHeight uint32) (*MerkleProof, error) {
+	blockHash, err := ledger.DefaultLedger.Store.GetBlockHash(height)
+	if err != nil {
+		return nil, err
+	}
+	block, err := ledger.DefaultLedger.Store.GetBlock(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	rootHash, err := ledger.DefaultLedger.Store.GetBlockHash(rootHeight)
+	if err != nil {
+		return nil, err
+	}
+	rootHeader, err := ledger.DefaultLedger.Store.GetHeader(rootHash)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]Uint256, len(block.Transactions))
+	matches := make([]bool, len(block.Transactions))
+	for i, txn := range block.Transactions {
+		hashes[i] = txn.Hash()
+		matches[i] = hashes[i] == txHash
+	}
+	tree, err := crypto.NewPartialMerkleTree(hashes, matches)
+	if err != nil {
+		return nil, err
+	}
+	w := bytes.NewBuffer(nil)
+	if err := tree.Serialize(w); err != nil {
+		return nil, err
+	}
+
+	path, err := ledger.DefaultLedger.Store.GetMerkleProof(height, rootHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MerkleProof{
+		Type:             "MerkleProof",
+		TxHash:           ToHexString(txHash.ToArray()),
+		TxProof:          ToHexString(w.Bytes()),
+		TransactionsRoot: ToHexString(block.Header.TransactionsRoot.ToArray()),
+		BlockHeight:      height,
+		BlockRoot:        ToHexString(rootHeader.BlockRoot.ToArray()),
+		TreeSize:         rootHeight + 1,
+		AuditPath:        hashesToHexStrings(path),
+	}, nil
+}
+
+// NewConsistencyProof builds the proof between the block merkle trees of the
+// blocks at fromHeight and toHeight
+func NewConsistencyProof(fromHeight, toHeight uint32) (*ConsistencyProof, error) {
+	var roots [2]Uint256
+	for i, height := range []uint32{fromHeight, toHeight} {
+		hash, err := ledger.DefaultLedger.Store.GetBlockHash(height)
+		if err != nil {
+			return nil, err
+		}
+		header, err := ledger.DefaultLedger.Store.GetHeader(hash)
+		if err != nil {
+			return nil, err
+		}
+		roots[i] = header.BlockRoot
+	}
+	path, err := ledger.DefaultLedger.Store.GetConsistencyProof(fromHeight, toHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConsistencyProof{
+		Type:        "ConsistencyProof",
+		OldTreeSize: fromHeight + 1,
+		OldRoot:     ToHexString(roots[0].ToArray()),
+		NewTreeSize: toHeight + 1,
+		NewRoot:     ToHexString(roots[1].ToArray()),
+		AuditPath:   hashesToHexStrings(path),
+	}, nil
+}
+
+// VerifyMerkleProof checks the transaction and block inclusion proofs, the
+// caller compares BlockRoot with a trusted header.
+func VerifyMerkleProof(proof *MerkleProof) error {
+	txHash, err := hexStringToHash(proof.TxHash)
+	if err != nil {
+		return err
+	}
+	txRoot, err := hexStringToHash(proof.TransactionsRoot)
+	if err != nil {
+		return err
+	}
+	blockRoot, err := hexStringToHash(proof.BlockRoot)
+	if err != nil {
+		return err
+	}
+	path, err := hexStringsToHashes(proof.AuditPath)
+	if err != nil {
+		return err
+	}
+
+	buf, err := HexToBytes(proof.TxProof)
+	if err != nil {
+		return err
+	}
+	var tree crypto.PartialMerkleTree
+	if err := tree.Deserialize(bytes.NewReader(buf)); err != nil {
+		return err
+	}
+	root, matches, err := tree.ExtractMatches()
+	if err != nil {
+		return err
+	}
+	if root != txRoot {
+		return errors.New("transaction proof does not match the transactions root")
+	}
+	found := false
+	for _, hash := range matches {
+		if hash == txHash {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return errors.New("transaction is not matched by the transaction proof")
+	}
+
+	return merkle.NewMerkleVerifier().VerifyLeafHashInclusion(txRoot, proof.BlockHeight, path, blockRoot, proof.TreeSize)
+}
+
+// VerifyConsistencyProof checks that the old root is a prefix of the new one
+func VerifyConsistencyProof(proof *ConsistencyProof) error {
+	oldRoot, err := hexStringToHash(proof.OldRoot)
+	if err != nil {
+		return err
+	}
+	newRoot, err := hexStringToHash(proof.NewRoot)
+	if err != nil {
+		return err
+	}
+	path, err := hexStringsToHashes(proof.AuditPath)
+	if err != nil {
+		return err
+	}
+
+	return merkle.NewMerkleVerifier().VerifyConsistency(proof.OldTreeSize, proof.NewTreeSize, oldRoot, newRoot, path)
+}
+
+func hashesToHexStrings(hashes []Uint256) []string {
+	strs := make([]string, len(hashes))
+	for i, hash := range hashes {
+		strs[i] = ToHexString(hash.ToArray())
+	}
+	return strs
+}
+
+func hexStringToHash(str string) (Uint256, error) {
+	buf, err := HexToBytes(str)
+	if err != nil {
+		return Uint256{}, err
+	}
+	return Uint256ParseFromBytes(buf)
+}
+
+func hexStringsToHashes(strs []string) ([]Uint256, error) {
+	hashes := make([]Uint256, len(strs))
+	for i, str := range strs {
+		hash, err := hexStringToHash(str)
+		if err != nil {
+			return nil, err
+		}
+		hashes[i] = hash
+	}
+	return hashes, nil
+}
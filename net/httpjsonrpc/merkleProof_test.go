@@ -0,0 +1,90 @@
+package httpjsonrpc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	. "github.com/Ontology/common"
+	"github.com/Ontology/crypto"
+	"github.com/Ontology/merkle"
+)
+
+func testBlockTree(t *testing.T, size uint32) (*merkle.CompactMerkleTree, []Uint256, []Uint256) {
+	tree := merkle.NewTree(0, nil, &merkle.MemHashStore{})
+	var leaves, roots []Uint256
+	for i := uint32(0); i < size; i++ {
+		leaf := Uint256(sha256.Sum256([]byte{byte(i)}))
+		leaves = append(leaves, leaf)
+		tree.AppendHash(leaf)
+		roots = append(roots, tree.Root())
+	}
+	return tree, leaves, roots
+}
+
+func TestVerifyMerkleProof(t *testing.T) {
+	txHashes := []Uint256{
+		Uint256(sha256.Sum256([]byte("tx0"))),
+		Uint256(sha256.Sum256([]byte("tx1"))),
+		Uint256(sha256.Sum256([]byte("tx2"))),
+	}
+	txTree, err := crypto.NewPartialMerkleTree(txHashes, []bool{false, true, false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txRoot, err := crypto.ComputeRoot(txHashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := bytes.NewBuffer(nil)
+	txTree.Serialize(w)
+
+	tree, _, _ := testBlockTree(t, 6)
+	tree.AppendHash(txRoot)
+	tree.AppendHash(Uint256(sha256.Sum256([]byte("next"))))
+
+	proof := &MerkleProof{
+		Type:             "MerkleProof",
+		TxHash:           ToHexString(txHashes[1].ToArray()),
+		TxProof:          ToHexString(w.Bytes()),
+		TransactionsRoot: ToHexString(txRoot.ToArray()),
+		BlockHeight:      6,
+		BlockRoot:        hashesToHexStrings([]Uint256{tree.Root()})[0],
+		TreeSize:         8,
+		AuditPath:        hashesToHexStrings(tree.InclusionProof(6, 8)),
+	}
+	if err := VerifyMerkleProof(proof); err != nil {
+		t.Fatal(err)
+	}
+
+	proof.TxHash = ToHexString(txHashes[0].ToArray())
+	if VerifyMerkleProof(proof) == nil {
+		t.Error("unmatched transaction verified")
+	}
+	proof.TxHash = ToHexString(txHashes[1].ToArray())
+	proof.BlockHeight = 5
+	if VerifyMerkleProof(proof) == nil {
+		t.Error("wrong block height verified")
+	}
+}
+
+func TestVerifyConsistencyProof(t *testing.T) {
+	tree, _, roots := testBlockTree(t, 20)
+	for _, from := range []uint32{1, 3, 8, 13} {
+		proof := &ConsistencyProof{
+			Type:        "ConsistencyProof",
+			OldTreeSize: from,
+			OldRoot:     ToHexString(roots[from-1].ToArray()),
+			NewTreeSize: 20,
+			NewRoot:     ToHexString(roots[19].ToArray()),
+			AuditPath:   hashesToHexStrings(tree.ConsistencyProof(from, 20)),
+		}
+		if err := VerifyConsistencyProof(proof); err != nil {
+			t.Fatal(from, err)
+		}
+		proof.OldRoot = ToHexString(roots[from].ToArray())
+		if VerifyConsistencyProof(proof) == nil {
+			t.Error("wrong old root verified", from)
+		}
+	}
+}
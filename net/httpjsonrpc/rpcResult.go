@@ -8,6 +8,7 @@ var (
 
 	DnaRpcUnknownBlock = responsePacking("unknown block")
 	DnaRpcUnknownTransaction = responsePacking("unknown transaction")
+	DnaRpcPrunedData = responsePacking("pruned data")
 
 	DnaRpcNil = responsePacking(nil)
 	DnaRpcUnsupported = responsePacking("Unsupported")
@@ -0,0 +1,112 @@
+package httpjsonrpc
+
+import (
+	"bytes"
+	"errors"
+
+	. "github.com/Ontology/common"
+	"github.com/Ontology/core/ledger"
+	"github.com/Ontology/core/states"
+	"github.com/Ontology/core/store"
+	"github.com/Ontology/rlp"
+	"github.com/Ontology/trie"
+)
+
+// StateProof proves the state stored under Key after the block at Height
+// against StateRoot. The trie commits to ValueHash, Value is the serialized
+// state and is empty when the key is absent or the state changed since.
+type StateProof struct {
+	Type      string
+	Key       string
+	Value     string
+	ValueHash string
+	Height    uint32
+	StateRoot string
+	Proof     []string
+}
+
+// NewAccountStateProof builds the proof of the account state of programHash
+func NewAccountStateProof(programHash Uint160, height uint32) (*StateProof, error) {
+	key := append([]byte{byte(store.ST_Account)}, programHash.ToArray()...)
+	return newStateProof(key, height)
+}
+
+// NewStorageStateProof builds the proof of the contract storage item
+func NewStorageStateProof(storageKey *states.StorageKey, height uint32) (*StateProof, error) {
+	key := append([]byte{byte(store.ST_Storage)}, storageKey.ToArray()...)
+	return newStateProof(key, height)
+}
+
+func newStateProof(key []byte, height uint32) (*StateProof, error) {
+	value, stateRoot, nodes, err := ledger.DefaultLedger.Store.GetStateProof(key, height)
+	if err != nil {
+		return nil, err
+	}
+	proof := &StateProof{
+		Type:      "StateProof",
+		Key:       ToHexString(key),
+		Value:     ToHexString(value),
+		Height:    height,
+		StateRoot: ToHexString(stateRoot.ToArray()),
+		Proof:     make([]string, len(nodes)),
+	}
+	valueHash, err := trie.VerifySecureProof(stateRoot, key, toRawValues(nodes))
+	if err != nil {
+		return nil, err
+	}
+	proof.ValueHash = ToHexString(valueHash)
+	for i, n := range nodes {
+		proof.Proof[i] = ToHexString(n)
+	}
+	return proof, nil
+}
+
+// VerifyStateProof checks the proof against StateRoot, the caller compares
+// StateRoot with the header of a trusted block at Height+1.
+func VerifyStateProof(proof *StateProof) error {
+	key, err := HexToBytes(proof.Key)
+	if err != nil {
+		return err
+	}
+	value, err := HexToBytes(proof.Value)
+	if err != nil {
+		return err
+	}
+	valueHash, err := HexToBytes(proof.ValueHash)
+	if err != nil {
+		return err
+	}
+	stateRoot, err := hexStringToHash(proof.StateRoot)
+	if err != nil {
+		return err
+	}
+	nodes := make([][]byte, len(proof.Proof))
+	for i, str := range proof.Proof {
+		if nodes[i], err = HexToBytes(str); err != nil {
+			return err
+		}
+	}
+
+	leaf, err := trie.VerifySecureProof(stateRoot, key, toRawValues(nodes))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(leaf, valueHash) {
+		return errors.New("value hash differs from the proven one")
+	}
+	if len(value) > 0 {
+		h := ToHash256(value)
+		if !bytes.Equal(h.ToArray(), leaf) {
+			return errors.New("value does not match the proven hash")
+		}
+	}
+	return nil
+}
+
+func toRawValues(nodes [][]byte) []rlp.RawValue {
+	raw := make([]rlp.RawValue, len(nodes))
+	for i, n := range nodes {
+		raw[i] = n
+	}
+	return raw
+}
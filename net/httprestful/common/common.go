@@ -4,6 +4,7 @@ import (
 	. "github.com/Ontology/common"
 	"github.com/Ontology/common/config"
 	"github.com/Ontology/core/ledger"
+	"github.com/Ontology/core/states"
 	tx "github.com/Ontology/core/transaction"
 	. "github.com/Ontology/errors"
 	. "github.com/Ontology/net/httpjsonrpc"
@@ -102,24 +103,29 @@ func GetTotalIssued(cmd map[string]interface{}) map[string]interface{} {
 	resp["Result"] = val
 	return resp
 }
-func GetBlockInfo(block *ledger.Block) BlockInfo {
-	hash := block.Hash()
-	blockHead := &BlockHead{
-		Version:          block.Header.Version,
-		PrevBlockHash:    ToHexString(block.Header.PrevBlockHash.ToArray()),
-		TransactionsRoot: ToHexString(block.Header.TransactionsRoot.ToArray()),
-		BlockRoot:        ToHexString(block.Header.BlockRoot.ToArray()),
-		StateRoot:        ToHexString(block.Header.StateRoot.ToArray()),
-		Timestamp:        block.Header.Timestamp,
-		Height:           block.Header.Height,
-		ConsensusData:    block.Header.ConsensusData,
-		NextBookKeeper:   ToHexString(block.Header.NextBookKeeper.ToArray()),
+func GetHeaderInfo(header *ledger.Header) *BlockHead {
+	hash := header.Hash()
+	return &BlockHead{
+		Version:          header.Version,
+		PrevBlockHash:    ToHexString(header.PrevBlockHash.ToArray()),
+		TransactionsRoot: ToHexString(header.TransactionsRoot.ToArray()),
+		BlockRoot:        ToHexString(header.BlockRoot.ToArray()),
+		StateRoot:        ToHexString(header.StateRoot.ToArray()),
+		Timestamp:        header.Timestamp,
+		Height:           header.Height,
+		ConsensusData:    header.ConsensusData,
+		NextBookKeeper:   ToHexString(header.NextBookKeeper.ToArray()),
 		Program: ProgramInfo{
-			Code:      ToHexString(block.Header.Program.Code),
-			Parameter: ToHexString(block.Header.Program.Parameter),
+			Code:      ToHexString(header.Program.Code),
+			Parameter: ToHexString(header.Program.Parameter),
 		},
 		Hash: ToHexString(hash.ToArray()),
 	}
+}
+
+func GetBlockInfo(block *ledger.Block) BlockInfo {
+	hash := block.Hash()
+	blockHead := GetHeaderInfo(block.Header)
 
 	trans := make([]*Transactions, len(block.Transactions))
 	for i := 0; i < len(block.Transactions); i++ {
@@ -155,6 +161,9 @@ func GetBlockTransactions(block *ledger.Block) interface{} {
 }
 func getBlock(hash Uint256, getTxBytes bool) (interface{}, int64) {
 	block, err := ledger.DefaultLedger.Store.GetBlock(hash)
+	if err == ledger.ErrPruned {
+		return "", Err.PRUNED_DATA
+	}
 	if err != nil {
 		return "", Err.UNKNOWN_BLOCK
 	}
@@ -211,6 +220,10 @@ func GetBlockTxsByHeight(cmd map[string]interface{}) map[string]interface{} {
 		return resp
 	}
 	block, err := ledger.DefaultLedger.Store.GetBlock(hash)
+	if err == ledger.ErrPruned {
+		resp["Error"] = Err.PRUNED_DATA
+		return resp
+	}
 	if err != nil {
 		resp["Error"] = Err.UNKNOWN_BLOCK
 		return resp
@@ -277,7 +290,24 @@ func GetAssetByHash(cmd map[string]interface{}) map[string]interface{} {
 		resp["Error"] = Err.INVALID_ASSET
 		return resp
 	}
-	asset, err := ledger.DefaultLedger.Store.GetAsset(hash)
+	height, atHeight, err := stateHeight(cmd)
+	if err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	var asset *states.AssetState
+	if atHeight {
+		asset, err = ledger.DefaultLedger.Store.GetAssetAt(hash, height)
+	} else {
+		asset, err = ledger.DefaultLedger.Store.GetAsset(hash)
+	}
+	if historyUnavailable(resp, err) {
+		return resp
+	}
+	if err != nil {
+		resp["Error"] = Err.UNKNOWN_ASSET
+		return resp
+	}
 	assetInfo := new(AssetStateInfo)
 	assetInfo.StateVersion = int(asset.StateVersion)
 	assetInfo.AssetId = ToHexString(asset.AssetId.ToArray())
@@ -293,10 +323,6 @@ func GetAssetByHash(cmd map[string]interface{}) map[string]interface{} {
 	assetInfo.Expiration = asset.Expiration
 	assetInfo.IsFrozen = asset.IsFrozen
 
-	if err != nil {
-		resp["Error"] = Err.UNKNOWN_ASSET
-		return resp
-	}
 	if raw, ok := cmd["Raw"].(string); ok && raw == "1" {
 		w := bytes.NewBuffer(nil)
 		asset.Serialize(w)
@@ -307,6 +333,40 @@ func GetAssetByHash(cmd map[string]interface{}) map[string]interface{} {
 	return resp
 }
 
+// stateHeight parses the optional height query of the state getters, it
+// tells whether the query is given
+func stateHeight(cmd map[string]interface{}) (uint32, bool, error) {
+	param, ok := cmd["Height"].(string)
+	if !ok || len(param) == 0 {
+		return 0, false, nil
+	}
+	height, err := strconv.ParseUint(param, 10, 32)
+	if err != nil {
+		return 0, false, err
+	}
+	return uint32(height), true, nil
+}
+
+// historyUnavailable tells whether err rejects a query below the start of
+// the state history, the response then carries the height it starts from
+func historyUnavailable(resp map[string]interface{}, err error) bool {
+	e, ok := err.(ledger.HistoryUnavailableError)
+	if ok {
+		resp["Error"] = Err.HISTORY_UNAVAILABLE
+		resp["Result"] = e.Error()
+	}
+	return ok
+}
+
+// getAccount returns the current account state, or the state after the
+// block at height when atHeight is set
+func getAccount(programHash Uint160, height uint32, atHeight bool) (*states.AccountState, error) {
+	if atHeight {
+		return ledger.DefaultLedger.Store.GetAccountAt(programHash, height)
+	}
+	return ledger.DefaultLedger.Store.GetAccount(programHash)
+}
+
 func GetBalanceByAddr(cmd map[string]interface{}) map[string]interface{} {
 	resp := ResponsePack(Err.SUCCESS)
 	addr, ok := cmd["Addr"].(string)
@@ -320,7 +380,15 @@ func GetBalanceByAddr(cmd map[string]interface{}) map[string]interface{} {
 		resp["Error"] = Err.INVALID_PARAMS
 		return resp
 	}
-	account, err := ledger.DefaultLedger.Store.GetAccount(programHash)
+	height, atHeight, err := stateHeight(cmd)
+	if err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	account, err := getAccount(programHash, height, atHeight)
+	if historyUnavailable(resp, err) {
+		return resp
+	}
 	if err != nil {
 		resp["Error"] = Err.UNKNOWN_PROGRAM
 		return resp
@@ -339,6 +407,52 @@ func GetBalanceByAddr(cmd map[string]interface{}) map[string]interface{} {
 	return resp
 }
 
+// GetAddressHistory returns a page of the transfers of an address, the limit
+// and cursor queries select the page
+func GetAddressHistory(cmd map[string]interface{}) map[string]interface{} {
+	resp := ResponsePack(Err.SUCCESS)
+	addr, ok := cmd["Addr"].(string)
+	if !ok {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	var limit uint64
+	if param, ok := cmd["Limit"].(string); ok && len(param) > 0 {
+		var err error
+		if limit, err = strconv.ParseUint(param, 10, 32); err != nil {
+			resp["Error"] = Err.INVALID_PARAMS
+			return resp
+		}
+	}
+	programHash, err := ToScriptHash(addr)
+	if err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	param, _ := cmd["Cursor"].(string)
+	cursor, err := HexToBytes(param)
+	if err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	result, err := QueryAddressHistory(programHash, int(limit), cursor)
+	switch err {
+	case nil:
+	case ledger.ErrAddressHistoryDisabled:
+		resp["Error"] = Err.INVALID_METHOD
+		return resp
+	case ledger.ErrInvalidCursor:
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	default:
+		log.Error("GetAddressHistory error: ", err)
+		resp["Error"] = Err.INTERNAL_ERROR
+		return resp
+	}
+	resp["Result"] = result
+	return resp
+}
+
 func GetBalanceByAsset(cmd map[string]interface{}) map[string]interface{} {
 	resp := ResponsePack(Err.SUCCESS)
 	addr, ok := cmd["Addr"].(string)
@@ -353,7 +467,15 @@ func GetBalanceByAsset(cmd map[string]interface{}) map[string]interface{} {
 		resp["Error"] = Err.INVALID_PARAMS
 		return resp
 	}
-	account, err := ledger.DefaultLedger.Store.GetAccount(programHash)
+	height, atHeight, err := stateHeight(cmd)
+	if err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	account, err := getAccount(programHash, height, atHeight)
+	if historyUnavailable(resp, err) {
+		return resp
+	}
 	if err != nil {
 		resp["Error"] = Err.UNKNOWN_PROGRAM
 		return resp
@@ -431,6 +553,10 @@ func GetTransactionByHash(cmd map[string]interface{}) map[string]interface{} {
 		return resp
 	}
 	tx, err := ledger.DefaultLedger.Store.GetTransaction(hash)
+	if err == ledger.ErrPruned {
+		resp["Error"] = Err.PRUNED_DATA
+		return resp
+	}
 	if err != nil {
 		resp["Error"] = Err.UNKNOWN_TRANSACTION
 		return resp
@@ -532,6 +658,223 @@ func GetSmartCodeEvent(cmd map[string]interface{}) map[string]interface{} {
 	return resp
 }
 
+// GetEvents returns a page of the notifications of a contract, the topic,
+// from, to, limit and cursor queries narrow the page
+func GetEvents(cmd map[string]interface{}) map[string]interface{} {
+	resp := ResponsePack(Err.SUCCESS)
+	bys, err := HexToBytes(cmd["Hash"].(string))
+	if err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	var codeHash Uint160
+	if err := codeHash.Deserialize(bytes.NewReader(bys)); err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	var fromHeight, toHeight, limit uint64
+	if param, ok := cmd["From"].(string); ok && len(param) > 0 {
+		if fromHeight, err = strconv.ParseUint(param, 10, 32); err != nil {
+			resp["Error"] = Err.INVALID_PARAMS
+			return resp
+		}
+	}
+	toHeight = uint64(ledger.DefaultLedger.Store.GetHeight())
+	if param, ok := cmd["To"].(string); ok && len(param) > 0 {
+		if toHeight, err = strconv.ParseUint(param, 10, 32); err != nil {
+			resp["Error"] = Err.INVALID_PARAMS
+			return resp
+		}
+	}
+	if param, ok := cmd["Limit"].(string); ok && len(param) > 0 {
+		if limit, err = strconv.ParseUint(param, 10, 32); err != nil {
+			resp["Error"] = Err.INVALID_PARAMS
+			return resp
+		}
+	}
+	topic, _ := cmd["Topic"].(string)
+	cursor, _ := cmd["Cursor"].(string)
+	result, err := QueryEvents(codeHash, topic, uint32(fromHeight), uint32(toHeight), int(limit), cursor)
+	if err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	resp["Result"] = result
+	return resp
+}
+
+// GetMerkleProof returns the proof of the transaction against the BlockRoot
+// of the block at the optional height, the current block by default
+func GetMerkleProof(cmd map[string]interface{}) map[string]interface{} {
+	resp := ResponsePack(Err.SUCCESS)
+
+	str := cmd["Hash"].(string)
+	bys, err := HexToBytes(str)
+	if err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	var hash Uint256
+	err = hash.Deserialize(bytes.NewReader(bys))
+	if err != nil {
+		resp["Error"] = Err.INVALID_TRANSACTION
+		return resp
+	}
+	_, height, err := ledger.DefaultLedger.Store.GetTransactionWithHeight(hash)
+	if err != nil {
+		resp["Error"] = Err.UNKNOWN_TRANSACTION
+		return resp
+	}
+	rootHeight, err := proofHeight(cmd)
+	if err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	proof, err := NewMerkleProof(hash, height, rootHeight)
+	if err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	resp["Result"] = proof
+	return resp
+}
+
+func GetConsistencyProof(cmd map[string]interface{}) map[string]interface{} {
+	resp := ResponsePack(Err.SUCCESS)
+
+	from, err := strconv.ParseUint(cmd["From"].(string), 10, 32)
+	if err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	to, err := strconv.ParseUint(cmd["To"].(string), 10, 32)
+	if err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	proof, err := NewConsistencyProof(uint32(from), uint32(to))
+	if err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	resp["Result"] = proof
+	return resp
+}
+
+// proofHeight parses the optional height query, the current block height by
+// default
+func proofHeight(cmd map[string]interface{}) (uint32, error) {
+	param, ok := cmd["Height"].(string)
+	if !ok || len(param) == 0 {
+		return ledger.DefaultLedger.Store.GetHeight(), nil
+	}
+	height, err := strconv.ParseUint(param, 10, 32)
+	return uint32(height), err
+}
+
+// GetStorage returns the contract storage item, the height query selects
+// the item after the block at that height
+func GetStorage(cmd map[string]interface{}) map[string]interface{} {
+	resp := ResponsePack(Err.SUCCESS)
+	bys, err := HexToBytes(cmd["Hash"].(string))
+	if err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	var codeHash Uint160
+	if err := codeHash.Deserialize(bytes.NewReader(bys)); err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	key, err := HexToBytes(cmd["Key"].(string))
+	if err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	storageKey := &states.StorageKey{CodeHash: codeHash, Key: key}
+	height, atHeight, err := stateHeight(cmd)
+	if err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	var item *states.StorageItem
+	if atHeight {
+		item, err = ledger.DefaultLedger.Store.GetStorageItemAt(storageKey, height)
+	} else {
+		item, err = ledger.DefaultLedger.Store.GetStorageItem(storageKey)
+	}
+	if historyUnavailable(resp, err) {
+		return resp
+	}
+	if err != nil {
+		resp["Error"] = Err.INTERNAL_ERROR
+		return resp
+	}
+	resp["Result"] = ToHexString(item.Value)
+	return resp
+}
+
+// GetAccountProof returns the account state with its proof, the height query
+// selects the state after the block at that height
+func GetAccountProof(cmd map[string]interface{}) map[string]interface{} {
+	resp := ResponsePack(Err.SUCCESS)
+	addr, ok := cmd["Addr"].(string)
+	if !ok {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	programHash, err := ToScriptHash(addr)
+	if err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	height, err := proofHeight(cmd)
+	if err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	proof, err := NewAccountStateProof(programHash, height)
+	if err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	resp["Result"] = proof
+	return resp
+}
+
+// GetStorageProof returns the contract storage item with its proof, the
+// height query selects the item after the block at that height
+func GetStorageProof(cmd map[string]interface{}) map[string]interface{} {
+	resp := ResponsePack(Err.SUCCESS)
+	bys, err := HexToBytes(cmd["Hash"].(string))
+	if err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	var codeHash Uint160
+	if err := codeHash.Deserialize(bytes.NewReader(bys)); err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	key, err := HexToBytes(cmd["Key"].(string))
+	if err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	height, err := proofHeight(cmd)
+	if err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	proof, err := NewStorageStateProof(&states.StorageKey{CodeHash: codeHash, Key: key}, height)
+	if err != nil {
+		resp["Error"] = Err.INVALID_PARAMS
+		return resp
+	}
+	resp["Result"] = proof
+	return resp
+}
+
 func ResponsePack(errCode int64) map[string]interface{} {
 	resp := map[string]interface{}{
 		"Action":  "",
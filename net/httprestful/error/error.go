@@ -21,6 +21,8 @@ const (
 	UNKNOWN_TRANSACTION int64 = 44001
 	UNKNOWN_ASSET int64 = 44002
 	UNKNOWN_BLOCK int64 = 44003
+	PRUNED_DATA int64 = 44005
+	HISTORY_UNAVAILABLE int64 = 44006
 
 	INVALID_VERSION int64 = 45001
 	INTERNAL_ERROR int64 = 45002
@@ -48,6 +50,8 @@ var ErrMap = map[int64]string{
 	UNKNOWN_TRANSACTION: "UNKNOWN TRANSACTION",
 	UNKNOWN_ASSET:       "UNKNOWN ASSET",
 	UNKNOWN_BLOCK:       "UNKNOWN BLOCK",
+	PRUNED_DATA:         "PRUNED DATA",
+	HISTORY_UNAVAILABLE: "HISTORY UNAVAILABLE",
 
 	INVALID_VERSION:                "INVALID VERSION",
 	INTERNAL_ERROR:                 "INTERNAL ERROR",
@@ -63,4 +67,7 @@ var ErrMap = map[int64]string{
 	int64(ErrStateUpdaterVaild):    "INTERNAL ERROR, ErrStateUpdaterVaild",
 	int64(ErrSummaryAsset):         "INTERNAL ERROR, ErrSummaryAsset",
 	int64(ErrXmitFail):             "INTERNAL ERROR, ErrXmitFail",
+	int64(ErrTxPoolFull):           "INTERNAL ERROR, ErrTxPoolFull",
+	int64(ErrReplaceUnderpriced):   "INTERNAL ERROR, ErrReplaceUnderpriced",
+	int64(ErrSenderQuota):          "INTERNAL ERROR, ErrSenderQuota",
 }
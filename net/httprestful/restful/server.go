@@ -48,6 +48,7 @@ const (
 	Api_GetBalancebyAsset = "/api/v1/asset/balance/:addr/:assetid"
 	Api_GetUTXObyAsset = "/api/v1/asset/utxo/:addr/:assetid"
 	Api_GetUTXObyAddr = "/api/v1/asset/utxos/:addr"
+	Api_GetAddressHistory = "/api/v1/address/history/:addr"
 	Api_SendRawTx = "/api/v1/transaction"
 	Api_SendRcdTxByTrans = "/api/v1/custom/transaction/record"
 	Api_SendClaimTxByTrans  = "/api/v1/custom/transaction/claim"
@@ -58,7 +59,13 @@ const (
 	Api_WebsocketState = "/api/v1/config/websocket/state"
 	Api_Restart = "/api/v1/restart"
 	Api_GetContract = "/api/v1/contract/:hash"
+	Api_GetStorage = "/api/v1/storage/:hash/:key"
 	Api_GetSmartCodeEvent = "/api/v1/smartcode/event/:height"
+	Api_GetEvents = "/api/v1/smartcode/events/:hash"
+	Api_GetMerkleProof = "/api/v1/merkleproof/:txhash"
+	Api_GetConsistencyProof = "/api/v1/consistencyproof/:from/:to"
+	Api_GetAccountProof = "/api/v1/stateproof/account/:addr"
+	Api_GetStorageProof = "/api/v1/stateproof/storage/:hash/:key"
 )
 
 func InitRestServer(checkAccessToken func(string, string) (string, int64, interface{})) ApiServer {
@@ -154,11 +161,18 @@ func (rt *restServer) registryMethod() {
 		Api_GetUTXObyAsset:      {name: "getutxobyasset", handler: GetUnspendOutput},
 		Api_GetBalanceByAddr:    {name: "getbalancebyaddr", handler: GetBalanceByAddr},
 		Api_GetBalancebyAsset:   {name: "getbalancebyasset", handler: GetBalanceByAsset},
+		Api_GetAddressHistory:   {name: "getaddresshistory", handler: GetAddressHistory},
 		Api_OauthServerUrl:      {name: "getoauthserverurl", handler: GetOauthServerUrl},
 		Api_NoticeServerUrl:     {name: "getnoticeserverurl", handler: GetNoticeServerUrl},
 		Api_Restart:             {name: "restart", handler: rt.Restart},
 		Api_GetStateUpdate:      {name: "getstateupdate", handler: GetStateUpdate},
 		Api_GetSmartCodeEvent:{name: "getsmartcodeevent", handler: GetSmartCodeEvent},
+		Api_GetEvents:           {name: "getevents", handler: GetEvents},
+		Api_GetStorage:          {name: "getstorage", handler: GetStorage},
+		Api_GetMerkleProof:      {name: "getmerkleproof", handler: GetMerkleProof},
+		Api_GetConsistencyProof: {name: "getconsistencyproof", handler: GetConsistencyProof},
+		Api_GetAccountProof:     {name: "getaccountproof", handler: GetAccountProof},
+		Api_GetStorageProof:     {name: "getstorageproof", handler: GetStorageProof},
 	}
 
 	sendRawTransaction := func(cmd map[string]interface{}) map[string]interface{} {
@@ -203,6 +217,8 @@ func (rt *restServer) getPath(url string) string {
 		return Api_GetBalanceByAddr
 	} else if strings.Contains(url, strings.TrimRight(Api_GetBalancebyAsset, ":addr/:assetid")) {
 		return Api_GetBalancebyAsset
+	} else if strings.Contains(url, strings.TrimRight(Api_GetAddressHistory, ":addr")) {
+		return Api_GetAddressHistory
 	} else if strings.Contains(url, strings.TrimRight(Api_GetUTXObyAddr, ":addr")) {
 		return Api_GetUTXObyAddr
 	} else if strings.Contains(url, strings.TrimRight(Api_GetUTXObyAsset, ":addr/:assetid")) {
@@ -211,8 +227,20 @@ func (rt *restServer) getPath(url string) string {
 		return Api_Getasset
 	} else if strings.Contains(url, strings.TrimRight(Api_GetStateUpdate, ":namespace/:key")) {
 		return Api_GetStateUpdate
+	} else if strings.Contains(url, strings.TrimRight(Api_GetEvents, ":hash")) {
+		return Api_GetEvents
 	} else if strings.Contains(url, strings.TrimRight(Api_GetSmartCodeEvent, ":height")) {
 		return Api_GetSmartCodeEvent
+	} else if strings.Contains(url, strings.TrimRight(Api_GetStorage, ":hash/:key")) {
+		return Api_GetStorage
+	} else if strings.Contains(url, strings.TrimRight(Api_GetMerkleProof, ":txhash")) {
+		return Api_GetMerkleProof
+	} else if strings.Contains(url, strings.TrimRight(Api_GetConsistencyProof, ":from/:to")) {
+		return Api_GetConsistencyProof
+	} else if strings.Contains(url, strings.TrimRight(Api_GetAccountProof, ":addr")) {
+		return Api_GetAccountProof
+	} else if strings.Contains(url, strings.TrimRight(Api_GetStorageProof, ":hash/:key")) {
+		return Api_GetStorageProof
 	}
 	return url
 }
@@ -252,17 +280,25 @@ func (rt *restServer) getParams(r *http.Request, url string, req map[string]inte
 	case Api_Getasset:
 		req["Hash"] = getParam(r, "hash")
 		req["Raw"] = r.FormValue("raw")
+		req["Height"] = r.FormValue("height")
 		break
 	case Api_GetBalancebyAsset:
 		req["Addr"] = getParam(r, "addr")
 		req["Assetid"] = getParam(r, "assetid")
+		req["Height"] = r.FormValue("height")
 		break
 	case Api_GetBalanceByAddr:
 		req["Addr"] = getParam(r, "addr")
+		req["Height"] = r.FormValue("height")
 		break
 	case Api_GetUTXObyAddr:
 		req["Addr"] = getParam(r, "addr")
 		break
+	case Api_GetAddressHistory:
+		req["Addr"] = getParam(r, "addr")
+		req["Limit"] = r.FormValue("limit")
+		req["Cursor"] = r.FormValue("cursor")
+		break
 	case Api_GetUTXObyAsset:
 		req["Addr"] = getParam(r, "addr")
 		req["Assetid"] = getParam(r, "assetid")
@@ -288,6 +324,36 @@ func (rt *restServer) getParams(r *http.Request, url string, req map[string]inte
 	case Api_GetSmartCodeEvent:
 		req["Height"] = getParam(r, "height")
 		break
+	case Api_GetEvents:
+		req["Hash"] = getParam(r, "hash")
+		req["Topic"] = r.FormValue("topic")
+		req["From"] = r.FormValue("from")
+		req["To"] = r.FormValue("to")
+		req["Limit"] = r.FormValue("limit")
+		req["Cursor"] = r.FormValue("cursor")
+		break
+	case Api_GetStorage:
+		req["Hash"] = getParam(r, "hash")
+		req["Key"] = getParam(r, "key")
+		req["Height"] = r.FormValue("height")
+		break
+	case Api_GetMerkleProof:
+		req["Hash"] = getParam(r, "txhash")
+		req["Height"] = r.FormValue("height")
+		break
+	case Api_GetConsistencyProof:
+		req["From"] = getParam(r, "from")
+		req["To"] = getParam(r, "to")
+		break
+	case Api_GetAccountProof:
+		req["Addr"] = getParam(r, "addr")
+		req["Height"] = r.FormValue("height")
+		break
+	case Api_GetStorageProof:
+		req["Hash"] = getParam(r, "hash")
+		req["Key"] = getParam(r, "key")
+		req["Height"] = r.FormValue("height")
+		break
 	case Api_OauthServerUrl:
 	case Api_NoticeServerUrl:
 	case Api_NoticeServerState:
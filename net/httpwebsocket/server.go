@@ -5,13 +5,16 @@ import (
 	. "github.com/Ontology/common"
 	. "github.com/Ontology/common/config"
 	"github.com/Ontology/core/ledger"
+	tx "github.com/Ontology/core/transaction"
 	"github.com/Ontology/events"
+	"github.com/Ontology/net/httpjsonrpc"
 	"github.com/Ontology/net/httprestful/common"
 	Err "github.com/Ontology/net/httprestful/error"
 	"github.com/Ontology/net/httpwebsocket/websocket"
 	. "github.com/Ontology/net/protocol"
 	"github.com/Ontology/smartcontract/event"
 	sc "github.com/Ontology/smartcontract/common"
+	"github.com/Ontology/vm/neovm/types"
 )
 
 var ws *websocket.WsServer
@@ -25,12 +28,19 @@ func StartServer(n Noder) {
 	common.SetNode(n)
 	ledger.DefaultLedger.Blockchain.BCEvents.Subscribe(events.EventBlockPersistCompleted, SendBlock2WSclient)
 	ledger.DefaultLedger.Blockchain.BCEvents.Subscribe(events.EventSmartCode, PushSmartCodeEvent)
+	ledger.DefaultLedger.Blockchain.BCEvents.Subscribe(events.EventSaveBlock, PushHeader)
+	ledger.DefaultLedger.Blockchain.BCEvents.Subscribe(events.EventNewTransaction, PushTxPoolTransaction)
 	go func() {
 		ws = websocket.InitWsServer(common.CheckAccessToken)
 		ws.Start()
 	}()
 }
 func SendBlock2WSclient(v interface{}) {
+	if Parameters.HttpWsPort != 0 {
+		go func() {
+			PushBlockTopics(v)
+		}()
+	}
 	if Parameters.HttpWsPort != 0 && pushBlockFlag {
 		go func() {
 			PushBlock(v)
@@ -118,6 +128,12 @@ func PushSmartCodeEvent(v interface{}) {
 					BlockHeight: ledger.DefaultLedger.Store.GetHeight() + 1,
 				}
 				PushEvent(rs["TxHash"].(string), rs["Error"].(int64), rs["Action"].(string), msg)
+				ws.PushTopicEvent(&websocket.TopicEvent{
+					Topic:        websocket.TopicNotify,
+					ContractHash: msg.CodeHash,
+					EventName:    eventName(object.States),
+					Result:       msg,
+				})
 				return
 			default:
 				PushEvent(rs["TxHash"].(string), rs["Error"].(int64), rs["Action"].(string), rs["Result"])
@@ -169,3 +185,98 @@ func PushBlockTransactions(v interface{}) {
 		ws.BroadcastResult(resp)
 	}
 }
+
+// eventName returns the name of a notification, which is by convention the
+// first item of the notified states
+func eventName(states types.StackItemInterface) string {
+	if array, ok := states.(*types.Array); ok {
+		items := array.GetArray()
+		if len(items) == 0 {
+			return ""
+		}
+		states = items[0]
+	}
+	if name, ok := states.(*types.ByteArray); ok {
+		return string(name.GetByteArray())
+	}
+	return ""
+}
+
+func PushHeader(v interface{}) {
+	if ws == nil {
+		return
+	}
+	if header, ok := v.(*ledger.Header); ok {
+		ws.PushTopicEvent(&websocket.TopicEvent{
+			Topic:  websocket.TopicHeader,
+			Result: common.GetHeaderInfo(header),
+		})
+	}
+}
+
+func PushTxPoolTransaction(v interface{}) {
+	if ws == nil {
+		return
+	}
+	if txn, ok := v.(*tx.Transaction); ok {
+		ws.PushTopicEvent(&websocket.TopicEvent{
+			Topic:  websocket.TopicTxPool,
+			Result: httpjsonrpc.TransArryByteToHexString(txn),
+		})
+	}
+}
+
+// PushBlockTopics pushes a persisted block to the block subscriptions and
+// its transactions to the transfer subscriptions of the addresses they touch
+func PushBlockTopics(v interface{}) {
+	if ws == nil {
+		return
+	}
+	block, ok := v.(*ledger.Block)
+	if !ok {
+		return
+	}
+	ws.PushTopicEvent(&websocket.TopicEvent{
+		Topic:  websocket.TopicBlock,
+		Result: common.GetBlockInfo(block),
+	})
+	type TransferInfo struct {
+		BlockHeight uint32
+		Transaction *httpjsonrpc.Transactions
+	}
+	for _, txn := range block.Transactions {
+		addresses := transferAddresses(txn)
+		if len(addresses) == 0 {
+			continue
+		}
+		ws.PushTopicEvent(&websocket.TopicEvent{
+			Topic:     websocket.TopicTransfer,
+			Addresses: addresses,
+			Result: TransferInfo{
+				BlockHeight: block.Header.Height,
+				Transaction: httpjsonrpc.TransArryByteToHexString(txn),
+			},
+		})
+	}
+}
+
+// transferAddresses lists the addresses of the outputs a transaction spends
+// and creates
+func transferAddresses(txn *tx.Transaction) []string {
+	outputs := txn.Outputs
+	if refer, err := txn.GetReference(); err == nil {
+		outputs = append(refer[:len(refer):len(refer)], outputs...)
+	}
+	var addresses []string
+	seen := make(map[Uint160]bool)
+	for _, output := range outputs {
+		if seen[output.ProgramHash] {
+			continue
+		}
+		seen[output.ProgramHash] = true
+		if address, err := output.ProgramHash.ToAddress(); err == nil {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
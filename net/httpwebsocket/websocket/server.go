@@ -33,6 +33,7 @@ type WsServer struct {
 	ActionMap        map[string]Handler
 	TxHashMap        map[string]string //key: txHash   value:sessionid
 	BroadcastMap     map[string]string
+	SubscribeMap     map[string]map[string]*Subscription //key: sessionid value: subscriptions by id
 	checkAccessToken func(auth_type, access_token string) (string, int64, interface{})
 }
 
@@ -42,6 +43,7 @@ func InitWsServer(checkAccessToken func(string, string) (string, int64, interfac
 		SessionList: NewSessionList(),
 		TxHashMap:   make(map[string]string),
 		BroadcastMap:   make(map[string]string),
+		SubscribeMap:   make(map[string]map[string]*Subscription),
 	}
 	ws.checkAccessToken = checkAccessToken
 	return ws
@@ -127,6 +129,21 @@ func (ws *WsServer) registryMethod() {
 		}()
 		return heartbeat(cmd)
 	}
+	subscribe := func(cmd map[string]interface{}) map[string]interface{} {
+		resp := ResponsePack(ws.subscribe(cmd))
+		resp["Result"] = cmd["SubscriptionId"]
+		return resp
+	}
+	unsubscribe := func(cmd map[string]interface{}) map[string]interface{} {
+		userid, _ := cmd["Userid"].(string)
+		id, _ := cmd["SubscriptionId"].(string)
+		if !ws.unsubscribe(userid, id) {
+			return ResponsePack(Err.INVALID_PARAMS)
+		}
+		resp := ResponsePack(Err.SUCCESS)
+		resp["Result"] = id
+		return resp
+	}
 	getsessioncount := func(cmd map[string]interface{}) map[string]interface{} {
 		resp := ResponsePack(Err.SUCCESS)
 		resp["Action"] = "getsessioncount"
@@ -145,6 +162,8 @@ func (ws *WsServer) registryMethod() {
 		"sendrawtransaction": {handler: sendRawTransaction},
 		"sendrecord":         {handler: SendRecord},
 		"heartbeat":          {handler: heartbeat},
+		"subscribe":          {handler: subscribe},
+		"unsubscribe":        {handler: unsubscribe},
 
 		"sendtest": {handler: sendtest, pushFlag: true},
 
@@ -211,6 +230,7 @@ func (ws *WsServer) webSocketHandler(w http.ResponseWriter, r *http.Request) {
 
 	defer func() {
 		ws.deleteTxHashs(nsSession.GetSessionId())
+		ws.deleteSubscriptions(nsSession.GetSessionId())
 		ws.SessionList.CloseSession(nsSession)
 		if err := recover(); err != nil {
 			log.Fatal("websocket recover:", err)
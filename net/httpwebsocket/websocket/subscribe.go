@@ -0,0 +1,134 @@
+package websocket
+
+import (
+	"strings"
+
+	. "github.com/Ontology/net/httprestful/common"
+	Err "github.com/Ontology/net/httprestful/error"
+	"github.com/pborman/uuid"
+)
+
+const (
+	TopicBlock    = "block"
+	TopicHeader   = "header"
+	TopicNotify   = "notify"
+	TopicTransfer = "transfer"
+	TopicTxPool   = "txpool"
+)
+
+var topics = map[string]bool{
+	TopicBlock:    true,
+	TopicHeader:   true,
+	TopicNotify:   true,
+	TopicTransfer: true,
+	TopicTxPool:   true,
+}
+
+// Subscription is a topic a session listens to. ContractHash and EventName
+// filter the notify topic, Address is required by the transfer topic.
+type Subscription struct {
+	Id           string
+	Topic        string
+	ContractHash string
+	EventName    string
+	Address      string
+}
+
+// TopicEvent is an event pushed to the subscriptions of its topic
+type TopicEvent struct {
+	Topic        string
+	ContractHash string
+	EventName    string
+	Addresses    []string
+	Result       interface{}
+}
+
+func (s *Subscription) match(e *TopicEvent) bool {
+	if s.Topic != e.Topic {
+		return false
+	}
+	switch s.Topic {
+	case TopicNotify:
+		if len(s.ContractHash) > 0 && !strings.EqualFold(s.ContractHash, e.ContractHash) {
+			return false
+		}
+		if len(s.EventName) > 0 && s.EventName != e.EventName {
+			return false
+		}
+	case TopicTransfer:
+		for _, addr := range e.Addresses {
+			if addr == s.Address {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func (ws *WsServer) subscribe(cmd map[string]interface{}) int64 {
+	sessionId, _ := cmd["Userid"].(string)
+	sub := &Subscription{}
+	sub.Topic, _ = cmd["Topic"].(string)
+	sub.ContractHash, _ = cmd["ContractHash"].(string)
+	sub.EventName, _ = cmd["EventName"].(string)
+	sub.Address, _ = cmd["Addr"].(string)
+	if !topics[sub.Topic] {
+		return Err.INVALID_PARAMS
+	}
+	if sub.Topic == TopicTransfer && len(sub.Address) == 0 {
+		return Err.INVALID_PARAMS
+	}
+	sub.Id = uuid.NewUUID().String()
+
+	ws.Lock()
+	defer ws.Unlock()
+	subs, ok := ws.SubscribeMap[sessionId]
+	if !ok {
+		subs = make(map[string]*Subscription)
+		ws.SubscribeMap[sessionId] = subs
+	}
+	subs[sub.Id] = sub
+	cmd["SubscriptionId"] = sub.Id
+	return Err.SUCCESS
+}
+
+func (ws *WsServer) unsubscribe(sessionId string, id string) bool {
+	ws.Lock()
+	defer ws.Unlock()
+	subs := ws.SubscribeMap[sessionId]
+	if _, ok := subs[id]; !ok {
+		return false
+	}
+	delete(subs, id)
+	if len(subs) == 0 {
+		delete(ws.SubscribeMap, sessionId)
+	}
+	return true
+}
+
+func (ws *WsServer) deleteSubscriptions(sSessionId string) {
+	ws.Lock()
+	defer ws.Unlock()
+	delete(ws.SubscribeMap, sSessionId)
+}
+
+// PushTopicEvent sends e to every subscription it matches, the message holds
+// the id of the subscription so a session can tell its subscriptions apart
+func (ws *WsServer) PushTopicEvent(e *TopicEvent) {
+	ws.RLock()
+	defer ws.RUnlock()
+	for sessionId, subs := range ws.SubscribeMap {
+		for _, sub := range subs {
+			if !sub.match(e) {
+				continue
+			}
+			resp := ResponsePack(Err.SUCCESS)
+			resp["Action"] = "subscription"
+			resp["Topic"] = e.Topic
+			resp["SubscriptionId"] = sub.Id
+			resp["Result"] = e.Result
+			ws.response(sessionId, resp)
+		}
+	}
+}
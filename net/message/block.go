@@ -27,13 +27,30 @@ type block struct {
 func (msg block) Handle(node Noder) error {
 	log.Debug("RX block message")
 	hash := msg.blk.Hash()
+	if node.LocalNode().IsStateSyncing() {
+		// the blocks below the snapshot are never persisted
+		return nil
+	}
 	if ledger.DefaultLedger.BlockInLedger(hash) {
 		ReceiveDuplicateBlockCnt++
 		log.Debug("Receive ", ReceiveDuplicateBlockCnt, " duplicated block.")
+		// a requested block may have arrived from another peer first
+		for _, height := range node.GetFlightHeights() {
+			if height == msg.blk.Header.Height {
+				node.RemoveFlightHeight(height)
+				return nil
+			}
+		}
+		node.DuplicateBlock()
 		return nil
 	}
 	if err := ledger.DefaultLedger.Blockchain.AddBlock(&msg.blk); err != nil {
 		log.Warnf("Block add failed: %s,block hash is %x\n", err, hash)
+		// a block above the known headers may be valid once they are synced
+		if msg.blk.Header.Height <= ledger.DefaultLedger.Store.GetHeaderHeight()+1 {
+			node.Misbehave(INVALIDBLOCKSCORE, "invalid block")
+			SendReject(node, "block", REJECTINVALID, err.Error(), hash)
+		}
 		return err
 	}
 	node.RemoveFlightHeight(msg.blk.Header.Height)
@@ -62,6 +79,26 @@ func (msg dataReq) Handle(node Noder) error {
 		}
 		node.Tx(buf)
 
+	case common.FILTEREDBLOCK:
+		block, err := NewBlockFromHash(hash)
+		if err != nil {
+			b, err := NewNotFound(hash)
+			node.Tx(b)
+			return err
+		}
+		buf, matched, err := NewMerkleBlockFromBlock(block, node.GetBloomFilter())
+		if err != nil {
+			return err
+		}
+		node.Tx(buf)
+		for _, txn := range matched {
+			buf, err := NewTxn(txn)
+			if err != nil {
+				return err
+			}
+			node.Tx(buf)
+		}
+
 	case common.TRANSACTION:
 		txn, err := NewTxnFromHash(hash)
 		if err != nil {
@@ -118,8 +155,18 @@ func NewBlock(bk *ledger.Block) ([]byte, error) {
 }
 
 func ReqBlkData(node Noder, hash common.Uint256) error {
+	return reqData(node, common.BLOCK, hash)
+}
+
+// ReqFilteredBlkData asks node for the merkleblock of hash filtered by the
+// bloom filter loaded on it
+func ReqFilteredBlkData(node Noder, hash common.Uint256) error {
+	return reqData(node, common.FILTEREDBLOCK, hash)
+}
+
+func reqData(node Noder, dataType common.InventoryType, hash common.Uint256) error {
 	var msg dataReq
-	msg.dataType = common.BLOCK
+	msg.dataType = dataType
 	msg.hash = hash
 
 	msg.msgHdr.Magic = NETMAGIC
@@ -103,6 +103,11 @@ func (cp *ConsensusPayload) GetMessage() []byte {
 
 func (msg consensus) Handle(node Noder) error {
 	log.Debug()
+	if err := msg.cons.Verify(); err != nil {
+		node.Misbehave(INVALIDCONSENSUSSCORE, "invalid consensus payload")
+		SendReject(node, "consensus", REJECTINVALID, err.Error(), common.Uint256{})
+		return err
+	}
 	node.LocalNode().GetEvent("consensus").Notify(events.EventNewInventory, &msg.cons)
 	return nil
 }
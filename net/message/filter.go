@@ -0,0 +1,168 @@
+package message
+
+import (
+	"bytes"
+	"errors"
+	"github.com/Ontology/common/log"
+	"github.com/Ontology/common/serialization"
+	"github.com/Ontology/net/bloom"
+	. "github.com/Ontology/net/protocol"
+)
+
+type filterload struct {
+	msgHdr
+	filter    []byte
+	hashFuncs uint32
+	tweak     uint32
+	flags     uint8
+}
+
+type filteradd struct {
+	msgHdr
+	data []byte
+}
+
+type filterclear struct {
+	msgHdr
+}
+
+// NewFilterLoad builds a filterload message asking the peer to relay only
+// transactions matching filter.
+func NewFilterLoad(filter *bloom.Filter) ([]byte, error) {
+	var msg filterload
+	data, hashFuncs, tweak, flags := filter.Data()
+	msg.filter = data
+	msg.hashFuncs = hashFuncs
+	msg.tweak = tweak
+	msg.flags = uint8(flags)
+
+	p := new(bytes.Buffer)
+	if err := msg.serializePayload(p); err != nil {
+		log.Error("Serialize payload failed at new filterload Msg")
+		return nil, err
+	}
+	msg.msgHdr.init("filterload", checkSum(p.Bytes()), uint32(p.Len()))
+	return msg.Serialization()
+}
+
+func (msg filterload) Verify(buf []byte) error {
+	return msg.msgHdr.Verify(buf)
+}
+
+func (msg filterload) Handle(node Noder) error {
+	log.Debug("RX filterload message")
+	err := node.GetBloomFilter().Load(msg.filter, msg.hashFuncs, msg.tweak, bloom.UpdateFlag(msg.flags))
+	if err != nil {
+		log.Warn("Load bloom filter failed: ", err)
+	}
+	return err
+}
+
+func (msg filterload) serializePayload(buf *bytes.Buffer) error {
+	if err := serialization.WriteVarBytes(buf, msg.filter); err != nil {
+		return err
+	}
+	if err := serialization.WriteUint32(buf, msg.hashFuncs); err != nil {
+		return err
+	}
+	if err := serialization.WriteUint32(buf, msg.tweak); err != nil {
+		return err
+	}
+	return serialization.WriteUint8(buf, msg.flags)
+}
+
+func (msg filterload) Serialization() ([]byte, error) {
+	hdrBuf, err := msg.msgHdr.Serialization()
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer(hdrBuf)
+	err = msg.serializePayload(buf)
+	return buf.Bytes(), err
+}
+
+func (msg *filterload) Deserialization(p []byte) error {
+	err := msg.msgHdr.Deserialization(p)
+	if err != nil {
+		return err
+	}
+	buf := bytes.NewBuffer(p[MSGHDRLEN:])
+	if msg.filter, err = serialization.ReadVarBytes(buf); err != nil {
+		return err
+	}
+	if msg.hashFuncs, err = serialization.ReadUint32(buf); err != nil {
+		return err
+	}
+	if msg.tweak, err = serialization.ReadUint32(buf); err != nil {
+		return err
+	}
+	msg.flags, err = serialization.ReadUint8(buf)
+	return err
+}
+
+// NewFilterAdd builds a filteradd message inserting data into the filter
+// loaded on the peer.
+func NewFilterAdd(data []byte) ([]byte, error) {
+	var msg filteradd
+	msg.data = data
+
+	p := new(bytes.Buffer)
+	if err := serialization.WriteVarBytes(p, data); err != nil {
+		log.Error("Serialize payload failed at new filteradd Msg")
+		return nil, err
+	}
+	msg.msgHdr.init("filteradd", checkSum(p.Bytes()), uint32(p.Len()))
+	return msg.Serialization()
+}
+
+func (msg filteradd) Verify(buf []byte) error {
+	return msg.msgHdr.Verify(buf)
+}
+
+func (msg filteradd) Handle(node Noder) error {
+	log.Debug("RX filteradd message")
+	err := node.GetBloomFilter().Add(msg.data)
+	if err != nil {
+		log.Warn("Add to bloom filter failed: ", err)
+	}
+	return err
+}
+
+func (msg filteradd) Serialization() ([]byte, error) {
+	hdrBuf, err := msg.msgHdr.Serialization()
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer(hdrBuf)
+	err = serialization.WriteVarBytes(buf, msg.data)
+	return buf.Bytes(), err
+}
+
+func (msg *filteradd) Deserialization(p []byte) error {
+	err := msg.msgHdr.Deserialization(p)
+	if err != nil {
+		return err
+	}
+	msg.data, err = serialization.ReadVarBytes(bytes.NewBuffer(p[MSGHDRLEN:]))
+	if err == nil && len(msg.data) > bloom.MaxFilterAddDataSize {
+		return errors.New("Filteradd data too large")
+	}
+	return err
+}
+
+// NewFilterClear builds a filterclear message restoring full relay
+func NewFilterClear() ([]byte, error) {
+	var msg filterclear
+	msg.msgHdr.init("filterclear", checkSum([]byte{}), 0)
+	return msg.Serialization()
+}
+
+func (msg filterclear) Handle(node Noder) error {
+	log.Debug("RX filterclear message")
+	node.GetBloomFilter().Clear()
+	return nil
+}
+
+func (msg *filterclear) Deserialization(p []byte) error {
+	return msg.msgHdr.Deserialization(p)
+}
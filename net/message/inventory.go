@@ -129,6 +129,11 @@ func (msg Inv) Handle(node Noder) error {
 		}
 	case BLOCK:
 		log.Debug("RX block message")
+		if node.LocalNode().IsLightNode() || node.LocalNode().IsStateSyncing() {
+			// light nodes learn new blocks from the synced headers, a state
+			// syncing node fetches them once the snapshot is restored
+			break
+		}
 		var i uint32
 		count := msg.P.Cnt
 		log.Debug("RX inv-block message, hash is ", msg.P.Blk)
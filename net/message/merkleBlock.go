@@ -0,0 +1,124 @@
+package message
+
+import (
+	"bytes"
+	"errors"
+	. "github.com/Ontology/common"
+	"github.com/Ontology/common/log"
+	"github.com/Ontology/core/ledger"
+	"github.com/Ontology/core/transaction"
+	"github.com/Ontology/crypto"
+	"github.com/Ontology/net/bloom"
+	. "github.com/Ontology/net/protocol"
+)
+
+// merkleBlock carries a block header and the partial merkle branch proving
+// the transactions that matched the bloom filter of the requesting peer.
+type merkleBlock struct {
+	msgHdr
+	header ledger.Header
+	tree   crypto.PartialMerkleTree
+}
+
+// NewMerkleBlockFromBlock filters the block transactions with filter. It
+// returns the merkleblock message and the matched transactions, which are
+// sent right after it.
+func NewMerkleBlockFromBlock(block *ledger.Block, filter *bloom.Filter) ([]byte, []*transaction.Transaction, error) {
+	hashes := make([]Uint256, len(block.Transactions))
+	matches := make([]bool, len(block.Transactions))
+	var matched []*transaction.Transaction
+	for i, txn := range block.Transactions {
+		hashes[i] = txn.Hash()
+		if filter.MatchTxAndUpdate(txn) {
+			matches[i] = true
+			matched = append(matched, txn)
+		}
+	}
+	tree, err := crypto.NewPartialMerkleTree(hashes, matches)
+	if err != nil {
+		return nil, nil, err
+	}
+	buf, err := NewMerkleBlock(block.Header, tree)
+	if err != nil {
+		return nil, nil, err
+	}
+	return buf, matched, nil
+}
+
+func NewMerkleBlock(header *ledger.Header, tree *crypto.PartialMerkleTree) ([]byte, error) {
+	var msg merkleBlock
+	msg.header = *header
+	msg.tree = *tree
+
+	p := new(bytes.Buffer)
+	if err := msg.serializePayload(p); err != nil {
+		log.Error("Serialize payload failed at new merkleblock Msg")
+		return nil, err
+	}
+	msg.msgHdr.init("merkleblock", checkSum(p.Bytes()), uint32(p.Len()))
+	return msg.Serialization()
+}
+
+// VerifyMerkleBlock checks the partial merkle branch against the
+// TransactionsRoot of header and returns the hashes of the matched
+// transactions. Light clients call it with a header they already trust.
+func VerifyMerkleBlock(header *ledger.Header, tree *crypto.PartialMerkleTree) ([]Uint256, error) {
+	root, matches, err := tree.ExtractMatches()
+	if err != nil {
+		return nil, err
+	}
+	if root != header.TransactionsRoot {
+		return nil, errors.New("Merkle block root mismatch")
+	}
+	return matches, nil
+}
+
+func (msg merkleBlock) Verify(buf []byte) error {
+	return msg.msgHdr.Verify(buf)
+}
+
+func (msg merkleBlock) Handle(node Noder) error {
+	log.Debug("RX merkleblock message")
+	hash := msg.header.Hash()
+	header, err := ledger.DefaultLedger.Store.GetHeader(hash)
+	if err != nil {
+		log.Debug("Merkle block of unknown header: ", hash)
+		return nil
+	}
+	matches, err := VerifyMerkleBlock(header, &msg.tree)
+	if err != nil {
+		log.Warn("Verify merkle block failed: ", err)
+		return err
+	}
+	log.Debugf("Merkle block %x matched %d transactions", hash, len(matches))
+	node.LocalNode().MerkleBlockReceived(node.GetID(), header.Height, matches)
+	return nil
+}
+
+func (msg merkleBlock) serializePayload(buf *bytes.Buffer) error {
+	msg.header.Serialize(buf)
+	return msg.tree.Serialize(buf)
+}
+
+func (msg merkleBlock) Serialization() ([]byte, error) {
+	hdrBuf, err := msg.msgHdr.Serialization()
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer(hdrBuf)
+	err = msg.serializePayload(buf)
+	return buf.Bytes(), err
+}
+
+func (msg *merkleBlock) Deserialization(p []byte) error {
+	err := msg.msgHdr.Deserialization(p)
+	if err != nil {
+		return err
+	}
+	buf := bytes.NewBuffer(p[MSGHDRLEN:])
+	if err := msg.header.Deserialize(buf); err != nil {
+		log.Warn("Parse merkleblock header error")
+		return errors.New("Parse merkleblock header error")
+	}
+	return msg.tree.Deserialize(buf)
+}
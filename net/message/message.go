@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"github.com/Ontology/common"
 	"github.com/Ontology/common/log"
 	. "github.com/Ontology/net/protocol"
 )
@@ -38,21 +39,6 @@ type varStr struct {
 	buf []byte
 }
 
-type filteradd struct {
-	msgHdr
-	//TBD
-}
-
-type filterclear struct {
-	msgHdr
-	//TBD
-}
-
-type filterload struct {
-	msgHdr
-	//TBD
-}
-
 // Alloc different message stucture
 // @t the message name or type
 // @len the message length only valid for varible length structure
@@ -140,8 +126,9 @@ func AllocMsg(t string, length int) Messager {
 		log.Warn("Not supported message type - alert")
 		return nil
 	case "merkleblock":
-		log.Warn("Not supported message type - merkleblock")
-		return nil
+		var msg merkleBlock
+		copy(msg.msgHdr.CMD[0:len(t)], t)
+		return &msg
 	case "notfound":
 		var msg notFound
 		copy(msg.msgHdr.CMD[0:len(t)], t)
@@ -155,8 +142,17 @@ func AllocMsg(t string, length int) Messager {
 		copy(msg.msgHdr.CMD[0:len(t)], t)
 		return &msg
 	case "reject":
-		log.Warn("Not supported message type - reject")
-		return nil
+		var msg reject
+		copy(msg.msgHdr.CMD[0:len(t)], t)
+		return &msg
+	case "getsnapshot":
+		var msg getSnapshot
+		copy(msg.msgHdr.CMD[0:len(t)], t)
+		return &msg
+	case "snapshot":
+		var msg snapshot
+		copy(msg.msgHdr.CMD[0:len(t)], t)
+		return &msg
 	default:
 		log.Warn("Unknown message type")
 		return nil
@@ -211,9 +207,16 @@ func HandleNodeMsg(node Noder, buf []byte, len int) error {
 		return errors.New("Allocation message failed")
 	}
 	// Todo attach a node pointer to each message
-	// Todo drop the message when verify/deseria packet error
-	msg.Deserialization(buf[:len])
-	msg.Verify(buf[MSGHDRLEN:len])
+	if err := msg.Deserialization(buf[:len]); err != nil {
+		node.Misbehave(INVALIDMSGSCORE, fmt.Sprintf("malformed %s message", s))
+		SendReject(node, s, REJECTMALFORMED, err.Error(), common.Uint256{})
+		return err
+	}
+	if err := msg.Verify(buf[MSGHDRLEN:len]); err != nil {
+		node.Misbehave(INVALIDMSGSCORE, fmt.Sprintf("invalid %s message", s))
+		SendReject(node, s, REJECTMALFORMED, err.Error(), common.Uint256{})
+		return err
+	}
 
 	return msg.Handle(node)
 }
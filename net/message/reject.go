@@ -0,0 +1,109 @@
+package message
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/Ontology/common"
+	"github.com/Ontology/common/log"
+	"github.com/Ontology/common/serialization"
+	. "github.com/Ontology/net/protocol"
+)
+
+// reject tells the peer a message it sent was rejected and why
+type reject struct {
+	msgHdr
+	cmd    string         // The command of the rejected message
+	code   uint8          // The REJECT* code
+	reason string         // Human readable reason
+	hash   common.Uint256 // The hash of the rejected block or transaction
+}
+
+func NewReject(cmd string, code uint8, reason string, hash common.Uint256) ([]byte, error) {
+	var msg reject
+	msg.cmd = cmd
+	msg.code = code
+	msg.reason = reason
+	msg.hash = hash
+
+	p := new(bytes.Buffer)
+	if err := msg.serializePayload(p); err != nil {
+		log.Error("Serialize reject message payload failed")
+		return nil, err
+	}
+	msg.msgHdr.init("reject", checkSum(p.Bytes()), uint32(p.Len()))
+
+	m, err := msg.Serialization()
+	if err != nil {
+		log.Error("Error Convert net message ", err.Error())
+		return nil, err
+	}
+	return m, nil
+}
+
+// SendReject sends a reject message for a message received from the node
+func SendReject(node Noder, cmd string, code uint8, reason string, hash common.Uint256) {
+	buf, err := NewReject(cmd, code, reason, hash)
+	if err != nil {
+		return
+	}
+	go node.Tx(buf)
+}
+
+func (msg reject) serializePayload(w *bytes.Buffer) error {
+	if err := serialization.WriteVarString(w, msg.cmd); err != nil {
+		return err
+	}
+	if err := serialization.WriteUint8(w, msg.code); err != nil {
+		return err
+	}
+	if err := serialization.WriteVarString(w, msg.reason); err != nil {
+		return err
+	}
+	_, err := msg.hash.Serialize(w)
+	return err
+}
+
+func (msg reject) Verify(buf []byte) error {
+	return msg.msgHdr.Verify(buf)
+}
+
+func (msg reject) Serialization() ([]byte, error) {
+	hdrBuf, err := msg.msgHdr.Serialization()
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer(hdrBuf)
+	if err := msg.serializePayload(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msg *reject) Deserialization(p []byte) error {
+	if len(p) < MSGHDRLEN {
+		return errors.New("Parse reject message hdr error")
+	}
+	if err := msg.msgHdr.Deserialization(p); err != nil {
+		return err
+	}
+	buf := bytes.NewBuffer(p[MSGHDRLEN:])
+	var err error
+	if msg.cmd, err = serialization.ReadVarString(buf); err != nil {
+		return err
+	}
+	if msg.code, err = serialization.ReadUint8(buf); err != nil {
+		return err
+	}
+	if msg.reason, err = serialization.ReadVarString(buf); err != nil {
+		return err
+	}
+	return msg.hash.Deserialize(buf)
+}
+
+func (msg reject) Handle(node Noder) error {
+	log.Warn(fmt.Sprintf("Peer %s rejected %s message, code 0x%02x: %s, hash %x",
+		node.GetAddr(), msg.cmd, msg.code, msg.reason, msg.hash))
+	return nil
+}
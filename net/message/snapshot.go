@@ -0,0 +1,193 @@
+package message
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/Ontology/common"
+	"github.com/Ontology/common/log"
+	"github.com/Ontology/common/serialization"
+	"github.com/Ontology/core/ledger"
+	. "github.com/Ontology/net/protocol"
+)
+
+// getSnapshot requests a chunk of the state snapshot of the peer
+type getSnapshot struct {
+	msgHdr
+	height uint32 // The height of the snapshot, zero for the latest one
+	index  uint32 // The index of the chunk
+}
+
+// snapshot is a chunk of the state snapshot at height
+type snapshot struct {
+	msgHdr
+	height uint32
+	index  uint32
+	total  uint32 // The number of chunks of the snapshot
+	data   []byte
+}
+
+func NewGetSnapshot(height, index uint32) ([]byte, error) {
+	var msg getSnapshot
+	msg.height = height
+	msg.index = index
+
+	p := new(bytes.Buffer)
+	if err := msg.serializePayload(p); err != nil {
+		log.Error("Serialize getsnapshot message payload failed")
+		return nil, err
+	}
+	msg.msgHdr.init("getsnapshot", checkSum(p.Bytes()), uint32(p.Len()))
+
+	m, err := msg.Serialization()
+	if err != nil {
+		log.Error("Error Convert net message ", err.Error())
+		return nil, err
+	}
+	return m, nil
+}
+
+// ReqSnapshotChunk requests the chunk at index of the snapshot at height
+func ReqSnapshotChunk(node Noder, height, index uint32) {
+	buf, err := NewGetSnapshot(height, index)
+	if err != nil {
+		return
+	}
+	go node.Tx(buf)
+}
+
+func (msg getSnapshot) serializePayload(w *bytes.Buffer) error {
+	if err := serialization.WriteUint32(w, msg.height); err != nil {
+		return err
+	}
+	return serialization.WriteUint32(w, msg.index)
+}
+
+func (msg getSnapshot) Verify(buf []byte) error {
+	return msg.msgHdr.Verify(buf)
+}
+
+func (msg getSnapshot) Serialization() ([]byte, error) {
+	hdrBuf, err := msg.msgHdr.Serialization()
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer(hdrBuf)
+	if err := msg.serializePayload(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msg *getSnapshot) Deserialization(p []byte) error {
+	if len(p) < MSGHDRLEN {
+		return errors.New("Parse getsnapshot message hdr error")
+	}
+	if err := msg.msgHdr.Deserialization(p); err != nil {
+		return err
+	}
+	buf := bytes.NewBuffer(p[MSGHDRLEN:])
+	var err error
+	if msg.height, err = serialization.ReadUint32(buf); err != nil {
+		return err
+	}
+	msg.index, err = serialization.ReadUint32(buf)
+	return err
+}
+
+func (msg getSnapshot) Handle(node Noder) error {
+	log.Debug("RX getsnapshot message")
+	height, total, data, err := ledger.DefaultLedger.Store.GetSnapshotChunk(msg.index)
+	if err == nil && msg.height != 0 && msg.height != height {
+		err = errors.New("snapshot replaced")
+	}
+	if err != nil {
+		SendReject(node, "getsnapshot", REJECTINVALID, "snapshot not available: "+err.Error(), common.Uint256{})
+		return nil
+	}
+	buf, err := NewSnapshot(height, msg.index, total, data)
+	if err != nil {
+		return err
+	}
+	go node.Tx(buf)
+	return nil
+}
+
+func NewSnapshot(height, index, total uint32, data []byte) ([]byte, error) {
+	var msg snapshot
+	msg.height = height
+	msg.index = index
+	msg.total = total
+	msg.data = data
+
+	p := new(bytes.Buffer)
+	if err := msg.serializePayload(p); err != nil {
+		log.Error("Serialize snapshot message payload failed")
+		return nil, err
+	}
+	msg.msgHdr.init("snapshot", checkSum(p.Bytes()), uint32(p.Len()))
+
+	m, err := msg.Serialization()
+	if err != nil {
+		log.Error("Error Convert net message ", err.Error())
+		return nil, err
+	}
+	return m, nil
+}
+
+func (msg snapshot) serializePayload(w *bytes.Buffer) error {
+	if err := serialization.WriteUint32(w, msg.height); err != nil {
+		return err
+	}
+	if err := serialization.WriteUint32(w, msg.index); err != nil {
+		return err
+	}
+	if err := serialization.WriteUint32(w, msg.total); err != nil {
+		return err
+	}
+	return serialization.WriteVarBytes(w, msg.data)
+}
+
+func (msg snapshot) Verify(buf []byte) error {
+	return msg.msgHdr.Verify(buf)
+}
+
+func (msg snapshot) Serialization() ([]byte, error) {
+	hdrBuf, err := msg.msgHdr.Serialization()
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer(hdrBuf)
+	if err := msg.serializePayload(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msg *snapshot) Deserialization(p []byte) error {
+	if len(p) < MSGHDRLEN {
+		return errors.New("Parse snapshot message hdr error")
+	}
+	if err := msg.msgHdr.Deserialization(p); err != nil {
+		return err
+	}
+	buf := bytes.NewBuffer(p[MSGHDRLEN:])
+	var err error
+	if msg.height, err = serialization.ReadUint32(buf); err != nil {
+		return err
+	}
+	if msg.index, err = serialization.ReadUint32(buf); err != nil {
+		return err
+	}
+	if msg.total, err = serialization.ReadUint32(buf); err != nil {
+		return err
+	}
+	msg.data, err = serialization.ReadVarBytes(buf)
+	return err
+}
+
+func (msg snapshot) Handle(node Noder) error {
+	log.Debugf("RX snapshot chunk %d/%d of height %d", msg.index+1, msg.total, msg.height)
+	node.LocalNode().SnapshotChunkReceived(node, msg.height, msg.index, msg.total, msg.data)
+	return nil
+}
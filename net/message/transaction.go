@@ -32,8 +32,13 @@ func (msg trn) Handle(node Noder) error {
 	log.Debug()
 	log.Debug("RX Transaction message")
 	tx := &msg.txn
+	if node.LocalNode().IsLightNode() {
+		node.LocalNode().LightTxnReceived(tx)
+		return nil
+	}
 	if !node.LocalNode().ExistedID(tx.Hash()) {
 		if errCode := node.LocalNode().AppendTxnPool(&(msg.txn)); errCode != ErrNoError {
+			SendReject(node, "tx", REJECTINVALID, errCode.Error(), tx.Hash())
 			return errors.New("[message] VerifyTransaction failed when AppendTxnPool.")
 		}
 		node.LocalNode().IncRxTxnCnt()
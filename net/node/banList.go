@@ -0,0 +1,268 @@
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Ontology/common/log"
+	. "github.com/Ontology/net/protocol"
+)
+
+// BanListPath is the file the banned peer addresses are kept in
+const BanListPath = "Chain/banlist.json"
+
+type peerScore struct {
+	score          int
+	lastUpdate     time.Time
+	duplicates     int // The duplicated blocks received since duplicateStart
+	duplicateStart time.Time
+}
+
+// banList tracks the misbehaviour scores and the bans of the peer addresses,
+// only the local node's banList is used
+type banList struct {
+	sync.Mutex
+	path   string
+	scores map[string]*peerScore
+	bans   map[string]time.Time // The time the ban of an address expires
+}
+
+func (this *banList) init(path string) {
+	this.path = path
+	this.scores = make(map[string]*peerScore)
+	this.bans = make(map[string]time.Time)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		log.Error("Read ban list failed: ", err)
+		return
+	}
+	var bans map[string]int64
+	if err := json.Unmarshal(data, &bans); err != nil {
+		log.Error("Parse ban list failed: ", err)
+		return
+	}
+	now := time.Now()
+	for addr, until := range bans {
+		if t := time.Unix(until, 0); t.After(now) {
+			this.bans[addr] = t
+		}
+	}
+}
+
+// save writes the bans to disk, the lock is held by the caller
+func (this *banList) save() error {
+	if this.path == "" {
+		return nil
+	}
+	bans := make(map[string]int64, len(this.bans))
+	for addr, until := range this.bans {
+		bans[addr] = until.Unix()
+	}
+	data, err := json.MarshalIndent(bans, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(this.path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(this.path, data, 0644)
+}
+
+// addScore adds to the decayed score of an address and returns the total
+func (this *banList) addScore(addr string, score int) int {
+	this.Lock()
+	defer this.Unlock()
+	ps := this.decayedScore(addr, time.Now())
+	ps.score += score
+	return ps.score
+}
+
+// addDuplicate counts a duplicated block of an address and reports whether
+// the address sent more than MAXDUPLICATEBLOCKS within SCOREDECAYTIME
+func (this *banList) addDuplicate(addr string) bool {
+	this.Lock()
+	defer this.Unlock()
+	now := time.Now()
+	ps := this.decayedScore(addr, now)
+	if now.Sub(ps.duplicateStart) >= SCOREDECAYTIME {
+		ps.duplicates = 0
+		ps.duplicateStart = now
+	}
+	ps.duplicates++
+	return ps.duplicates > MAXDUPLICATEBLOCKS
+}
+
+// decayedScore returns the score of an address with the decay since its last
+// update applied, the lock is held by the caller
+func (this *banList) decayedScore(addr string, now time.Time) *peerScore {
+	ps, ok := this.scores[addr]
+	if !ok {
+		ps = &peerScore{lastUpdate: now}
+		this.scores[addr] = ps
+		return ps
+	}
+	decay := int(now.Sub(ps.lastUpdate) / SCOREDECAYTIME)
+	if decay > 0 {
+		ps.score -= decay
+		if ps.score < 0 {
+			ps.score = 0
+		}
+		ps.lastUpdate = ps.lastUpdate.Add(time.Duration(decay) * SCOREDECAYTIME)
+	}
+	return ps
+}
+
+func (this *banList) getScore(addr string) int {
+	this.Lock()
+	defer this.Unlock()
+	if _, ok := this.scores[addr]; !ok {
+		return 0
+	}
+	return this.decayedScore(addr, time.Now()).score
+}
+
+func (this *banList) isBanned(addr string) bool {
+	this.Lock()
+	defer this.Unlock()
+	until, ok := this.bans[addr]
+	if !ok {
+		return false
+	}
+	if time.Now().Before(until) {
+		return true
+	}
+	delete(this.bans, addr)
+	delete(this.scores, addr)
+	if err := this.save(); err != nil {
+		log.Error("Save ban list failed: ", err)
+	}
+	return false
+}
+
+func (this *banList) ban(addr string, duration time.Duration) error {
+	this.Lock()
+	defer this.Unlock()
+	this.bans[addr] = time.Now().Add(duration)
+	return this.save()
+}
+
+func (this *banList) unban(addr string) error {
+	this.Lock()
+	defer this.Unlock()
+	if _, ok := this.bans[addr]; !ok {
+		return fmt.Errorf("peer %s is not banned", addr)
+	}
+	delete(this.bans, addr)
+	delete(this.scores, addr)
+	return this.save()
+}
+
+func (this *banList) getBans() []BanInfo {
+	this.Lock()
+	defer this.Unlock()
+	now := time.Now()
+	bans := make([]BanInfo, 0, len(this.bans))
+	for addr, until := range this.bans {
+		if until.After(now) {
+			bans = append(bans, BanInfo{Addr: addr, Until: until.Unix()})
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].Addr < bans[j].Addr })
+	return bans
+}
+
+// peerIP returns the IP of an "ip" or "ip:port" peer address
+func peerIP(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// Misbehave adds to the misbehaviour score of the peer and bans its address
+// when the score reaches BANSCORE
+func (node *node) Misbehave(score int, reason string) {
+	local := node.local
+	if local == nil || local == node {
+		return
+	}
+	addr := node.GetAddr()
+	total := local.banList.addScore(addr, score)
+	log.Warn(fmt.Sprintf("Peer %s misbehaved: %s, score %d", addr, reason, total))
+	if total >= BANSCORE {
+		if err := local.BanPeer(addr, DEFAULTBANTIME); err != nil {
+			log.Error("Ban peer failed: ", err)
+		}
+	}
+}
+
+// DuplicateBlock counts a duplicated block the peer sent unrequested, a node
+// syncing from several peers receives some, so only the duplicates above
+// MAXDUPLICATEBLOCKS within SCOREDECAYTIME are misbehaviour
+func (node *node) DuplicateBlock() {
+	local := node.local
+	if local == nil || local == node {
+		return
+	}
+	if local.banList.addDuplicate(node.GetAddr()) {
+		node.Misbehave(DUPLICATEBLOCKSCORE, "duplicated block")
+	}
+}
+
+func (node *node) IsBanned(addr string) bool {
+	return node.local.banList.isBanned(peerIP(addr))
+}
+
+// BanPeer bans a peer address and disconnects its nodes
+func (node *node) BanPeer(addr string, duration time.Duration) error {
+	addr = peerIP(addr)
+	if net.ParseIP(addr) == nil {
+		return fmt.Errorf("invalid peer address %s", addr)
+	}
+	local := node.local
+	if err := local.banList.ban(addr, duration); err != nil {
+		return err
+	}
+	log.Warn(fmt.Sprintf("Peer %s banned for %s", addr, duration))
+	for _, n := range local.GetNeighborNoder() {
+		if n.GetAddr() == addr {
+			n.SetState(INACTIVITY)
+			n.CloseConn()
+		}
+	}
+	return nil
+}
+
+func (node *node) UnbanPeer(addr string) error {
+	return node.local.banList.unban(peerIP(addr))
+}
+
+func (node *node) GetPeerInfos() []PeerInfo {
+	local := node.local
+	var peers []PeerInfo
+	for _, n := range local.GetNeighborNoder() {
+		peers = append(peers, PeerInfo{
+			Addr:   n.GetAddr(),
+			Port:   n.GetPort(),
+			ID:     n.GetID(),
+			State:  n.GetState(),
+			Height: n.GetHeight(),
+			Score:  local.banList.getScore(n.GetAddr()),
+		})
+	}
+	return peers
+}
+
+func (node *node) GetBannedPeers() []BanInfo {
+	return node.local.banList.getBans()
+}
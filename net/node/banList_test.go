@@ -0,0 +1,71 @@
+package node
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/Ontology/net/protocol"
+)
+
+func TestBanList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "banlist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "banlist.json")
+
+	var bl banList
+	bl.init(path)
+	if score := bl.addScore("10.0.0.1", INVALIDMSGSCORE); score != INVALIDMSGSCORE {
+		t.Fatal("unexpected score", score)
+	}
+	// the score decays by one every SCOREDECAYTIME
+	bl.scores["10.0.0.1"].lastUpdate = time.Now().Add(-5 * SCOREDECAYTIME)
+	if score := bl.getScore("10.0.0.1"); score != INVALIDMSGSCORE-5 {
+		t.Fatal("score not decayed", score)
+	}
+
+	// only the duplicated blocks above MAXDUPLICATEBLOCKS within
+	// SCOREDECAYTIME are misbehaviour
+	for i := 0; i < MAXDUPLICATEBLOCKS; i++ {
+		if bl.addDuplicate("10.0.0.5") {
+			t.Fatal("duplicated block within the limit penalised")
+		}
+	}
+	if !bl.addDuplicate("10.0.0.5") {
+		t.Fatal("duplicated block above the limit not penalised")
+	}
+	bl.scores["10.0.0.5"].duplicateStart = time.Now().Add(-SCOREDECAYTIME)
+	if bl.addDuplicate("10.0.0.5") {
+		t.Fatal("duplicated blocks not reset")
+	}
+
+	if err := bl.ban("10.0.0.2", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.ban("10.0.0.3", -time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if !bl.isBanned("10.0.0.2") || bl.isBanned("10.0.0.3") {
+		t.Fatal("unexpected bans")
+	}
+
+	var reloaded banList
+	reloaded.init(path)
+	if bans := reloaded.getBans(); len(bans) != 1 || bans[0].Addr != "10.0.0.2" {
+		t.Fatal("bans not persisted", bans)
+	}
+	if err := reloaded.unban("10.0.0.2"); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.unban("10.0.0.2") == nil || reloaded.isBanned("10.0.0.2") {
+		t.Fatal("peer not unbanned")
+	}
+	if peerIP("10.0.0.4:20338") != "10.0.0.4" || peerIP("10.0.0.4") != "10.0.0.4" {
+		t.Fatal("unexpected peer ip")
+	}
+}
@@ -108,6 +108,7 @@ func (node *node) HeartBeatMonitor() {
 			t := n.GetLastRXTime()
 			if t.Before(time.Now().Add(-1 * time.Second * time.Duration(periodUpdateTime) * KEEPALIVETIMEOUT)) {
 				log.Warn("keepalive timeout!!!")
+				n.Misbehave(UNRESPONSIVESCORE, "keepalive timeout")
 				n.SetState(INACTIVITY)
 				n.CloseConn()
 			}
@@ -229,7 +230,13 @@ func (node *node) updateNodeInfo() {
 		case <-ticker.C:
 			node.SendPingToNbr()
 			node.GetBlkHdrs()
-			node.SyncBlk()
+			if node.IsLightNode() {
+				node.SyncFilteredBlk()
+			} else if node.IsStateSyncing() {
+				node.SyncState()
+			} else {
+				node.SyncBlk()
+			}
 			node.HeartBeatMonitor()
 		case <-quit:
 			ticker.Stop()
@@ -0,0 +1,185 @@
+package node
+
+import (
+	"sync"
+	"time"
+
+	. "github.com/Ontology/common"
+	"github.com/Ontology/common/log"
+	"github.com/Ontology/core/ledger"
+	"github.com/Ontology/core/transaction"
+	"github.com/Ontology/net/bloom"
+	. "github.com/Ontology/net/message"
+	. "github.com/Ontology/net/protocol"
+)
+
+const (
+	// LIGHTFILTERFPRATE is the false positive rate of the wallet filter
+	LIGHTFILTERFPRATE = 0.0001
+	// LIGHTREQTIMEOUT is the time to wait for a merkleblock before asking again
+	LIGHTREQTIMEOUT = 30 * time.Second
+)
+
+// lightRequest is a merkleblock request in flight
+type lightRequest struct {
+	peer uint64 // The neighbor the merkleblock was requested from
+	time time.Time
+}
+
+// lightSync keeps the state of a light node. Only headers are synced with
+// the chain, the transactions of the watched addresses are fetched through
+// merkleblock messages and stored once proven against the stored headers.
+type lightSync struct {
+	sync.Mutex
+	filter         *bloom.Filter
+	filteredHeight uint32                  // The height below which all merkleblocks were received
+	filterSent     map[uint64]bool         // The neighbors which loaded the filter
+	requested      map[uint32]lightRequest // The merkleblock requests in flight
+	received       map[uint32]bool         // The merkleblocks received above filteredHeight
+	proven         map[Uint256]uint32      // The matched transaction hashes with their height
+	unproven       map[Uint256]*transaction.Transaction
+}
+
+func (ls *lightSync) init() {
+	ls.filterSent = make(map[uint64]bool)
+	ls.requested = make(map[uint32]lightRequest)
+	ls.received = make(map[uint32]bool)
+	ls.proven = make(map[Uint256]uint32)
+	ls.unproven = make(map[Uint256]*transaction.Transaction)
+}
+
+func (node *node) IsLightNode() bool {
+	return node.services == LIGHTNODE
+}
+
+// WatchAddresses builds the filter sent to the neighbors from the program
+// hashes of the wallet accounts
+func (node *node) WatchAddresses(programHashes []Uint160) {
+	if !node.IsLightNode() {
+		return
+	}
+	filter := bloom.NewFilter(uint32(len(programHashes)), LIGHTFILTERFPRATE, uint32(node.id), bloom.UpdateAll)
+	for _, programHash := range programHashes {
+		filter.Add(programHash.ToArray())
+	}
+
+	node.lightSync.Lock()
+	defer node.lightSync.Unlock()
+	node.lightSync.filter = filter
+	node.lightSync.filterSent = make(map[uint64]bool)
+	node.lightSync.requested = make(map[uint32]lightRequest)
+	node.lightSync.received = make(map[uint32]bool)
+	node.lightSync.filteredHeight = 0
+}
+
+// SyncFilteredBlk loads the filter on the neighbors and requests the
+// merkleblocks of the synced headers
+func (node *node) SyncFilteredBlk() {
+	node.lightSync.Lock()
+	defer node.lightSync.Unlock()
+	if node.lightSync.filter == nil {
+		return
+	}
+	filterBuf, err := NewFilterLoad(node.lightSync.filter)
+	if err != nil {
+		log.Error("failed build a new filterload message")
+		return
+	}
+
+	var noders []Noder
+	for _, n := range node.local.GetNeighborNoder() {
+		if n.GetState() != ESTABLISH {
+			continue
+		}
+		if !node.lightSync.filterSent[n.GetID()] {
+			n.Tx(filterBuf)
+			node.lightSync.filterSent[n.GetID()] = true
+		}
+		noders = append(noders, n)
+	}
+	if len(noders) == 0 {
+		return
+	}
+
+	headerHeight := ledger.DefaultLedger.Store.GetHeaderHeight()
+	now := time.Now()
+	var reqCnt uint32
+	for height := node.lightSync.filteredHeight + 1; height <= headerHeight && reqCnt < MAXREQBLKONCE; height++ {
+		if node.lightSync.received[height] {
+			continue
+		}
+		if req, ok := node.lightSync.requested[height]; ok && now.Sub(req.time) < LIGHTREQTIMEOUT {
+			continue
+		}
+		n := noders[int(height)%len(noders)]
+		if uint32(n.GetHeight()) < height {
+			continue
+		}
+		hash := ledger.DefaultLedger.Store.GetHeaderHashByHeight(height)
+		ReqFilteredBlkData(n, hash)
+		node.lightSync.requested[height] = lightRequest{peer: n.GetID(), time: now}
+		reqCnt++
+	}
+}
+
+// MerkleBlockReceived records the transactions proven to be in the block
+// at height and stores those already received. Only the merkleblock
+// requested from the neighbor peer, which loaded the filter, is taken.
+func (node *node) MerkleBlockReceived(peer uint64, height uint32, matches []Uint256) {
+	if !node.IsLightNode() {
+		return
+	}
+	node.lightSync.Lock()
+	defer node.lightSync.Unlock()
+	if req, ok := node.lightSync.requested[height]; !ok || req.peer != peer || !node.lightSync.filterSent[peer] {
+		return
+	}
+	delete(node.lightSync.requested, height)
+	// only the heights whose merkleblock arrived are passed, the others are
+	// requested again by the next sync
+	node.lightSync.received[height] = true
+	for node.lightSync.received[node.lightSync.filteredHeight+1] {
+		delete(node.lightSync.received, node.lightSync.filteredHeight+1)
+		node.lightSync.filteredHeight++
+	}
+
+	for _, hash := range matches {
+		if txn, ok := node.lightSync.unproven[hash]; ok {
+			delete(node.lightSync.unproven, hash)
+			node.saveLightTxn(txn, height)
+			continue
+		}
+		node.lightSync.proven[hash] = height
+	}
+	if len(node.lightSync.requested) == 0 {
+		node.lightSync.unproven = make(map[Uint256]*transaction.Transaction)
+	}
+}
+
+// LightTxnReceived stores a transaction sent after a merkleblock once its
+// inclusion is proven, unrequested transactions are dropped
+func (node *node) LightTxnReceived(txn *transaction.Transaction) {
+	if !node.IsLightNode() {
+		return
+	}
+	hash := txn.Hash()
+	node.lightSync.Lock()
+	defer node.lightSync.Unlock()
+	if height, ok := node.lightSync.proven[hash]; ok {
+		delete(node.lightSync.proven, hash)
+		node.saveLightTxn(txn, height)
+		return
+	}
+	// the merkleblock may still be handled by another goroutine
+	if len(node.lightSync.requested) > 0 && len(node.lightSync.unproven) < MAXCHANBUF {
+		node.lightSync.unproven[hash] = txn
+	}
+}
+
+func (node *node) saveLightTxn(txn *transaction.Transaction, height uint32) {
+	if err := ledger.DefaultLedger.Store.SaveVerifiedTransaction(txn, height); err != nil {
+		log.Error("Save verified transaction failed: ", err)
+		return
+	}
+	log.Infof("Light node stored transaction %x at height %d", txn.Hash(), height)
+}
@@ -0,0 +1,63 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/Ontology/common"
+	. "github.com/Ontology/net/protocol"
+)
+
+func TestMerkleBlockReceived(t *testing.T) {
+	n := &node{services: LIGHTNODE}
+	n.lightSync.init()
+	const peer, other = 1, 2
+	n.lightSync.filterSent[peer] = true
+	n.lightSync.filterSent[other] = true
+
+	// a sync window which skipped height 3, the heights above 17 are not
+	// requested yet
+	now := time.Now()
+	for height := uint32(1); height <= 17; height++ {
+		if height != 3 {
+			n.lightSync.requested[height] = lightRequest{peer: peer, time: now}
+		}
+	}
+	for height := uint32(17); height >= 1; height-- {
+		n.MerkleBlockReceived(peer, height, nil)
+	}
+	if n.lightSync.filteredHeight != 2 {
+		t.Fatalf("filtered height %d past a height not received", n.lightSync.filteredHeight)
+	}
+
+	// unrequested merkleblocks are ignored
+	n.MerkleBlockReceived(peer, 3, []Uint256{{1}})
+	if n.lightSync.filteredHeight != 2 || len(n.lightSync.proven) != 0 {
+		t.Fatal("unrequested merkleblock accepted")
+	}
+
+	// so are the merkleblocks of another neighbor or of a neighbor which
+	// did not load the filter
+	n.lightSync.requested[3] = lightRequest{peer: peer, time: now}
+	n.MerkleBlockReceived(other, 3, []Uint256{{1}})
+	if n.lightSync.filteredHeight != 2 || len(n.lightSync.proven) != 0 {
+		t.Fatal("merkleblock of another neighbor accepted")
+	}
+	n.lightSync.filterSent[peer] = false
+	n.MerkleBlockReceived(peer, 3, []Uint256{{1}})
+	if n.lightSync.filteredHeight != 2 || len(n.lightSync.proven) != 0 {
+		t.Fatal("merkleblock of a neighbor without the filter accepted")
+	}
+
+	n.lightSync.filterSent[peer] = true
+	n.MerkleBlockReceived(peer, 3, []Uint256{{1}})
+	if n.lightSync.filteredHeight != 17 {
+		t.Fatalf("filtered height %d, want 17", n.lightSync.filteredHeight)
+	}
+	if height, ok := n.lightSync.proven[Uint256{1}]; !ok || height != 3 {
+		t.Fatal("matched transaction not recorded")
+	}
+	if len(n.lightSync.received) != 0 {
+		t.Fatal("received heights kept below the filtered height")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	. "github.com/Ontology/common/config"
 	"github.com/Ontology/common/log"
 	"github.com/Ontology/events"
+	"github.com/Ontology/net/bloom"
 	msg "github.com/Ontology/net/message"
 	. "github.com/Ontology/net/protocol"
 	"io"
@@ -32,6 +33,7 @@ type link struct {
 			     len int
 		     }
 	connCnt      uint64    // The connection count
+	bloomFilter  bloom.Filter // The SPV filter loaded by the peer
 }
 
 // Shrinking the buf to the exactly reading in byte length
@@ -146,6 +148,11 @@ func (n *node) initConnection() {
 			return
 		}
 		log.Info("Remote node connect with ", conn.RemoteAddr(), conn.LocalAddr())
+		if n.IsBanned(conn.RemoteAddr().String()) {
+			log.Info("Reject connection of banned peer ", conn.RemoteAddr())
+			conn.Close()
+			continue
+		}
 
 		n.link.connCnt++
 
@@ -222,6 +229,9 @@ func (node *node) Connect(nodeAddr string) error {
 	if node.IsAddrInNbrList(nodeAddr) == true {
 		return nil
 	}
+	if node.IsBanned(nodeAddr) {
+		return errors.New("peer is banned, cancel")
+	}
 	if added := node.SetAddrInConnectingList(nodeAddr); added == false {
 		return errors.New("node exist in connecting list, cancel")
 	}
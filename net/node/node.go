@@ -12,6 +12,7 @@ import (
 	"github.com/Ontology/core/transaction"
 	"github.com/Ontology/crypto"
 	"github.com/Ontology/events"
+	"github.com/Ontology/net/bloom"
 	. "github.com/Ontology/net/message"
 	. "github.com/Ontology/net/protocol"
 	"math/rand"
@@ -67,6 +68,9 @@ type node struct {
 	ConnectingNodes
 	RetryConnAddrs
 	SyncReqSem               Semaphore
+	lightSync                                  // The header only sync state of a light node
+	banList                                    // The misbehaviour scores and bans of the peer addresses
+	stateSync                                  // The snapshot download of a fast syncing node
 }
 
 type RetryConnAddrs struct {
@@ -167,6 +171,9 @@ func InitNode(pubKey *crypto.PubKey) Noder {
 		n.services = uint64(SERVICENODE)
 	} else if Parameters.NodeType == VERIFYNODENAME {
 		n.services = uint64(VERIFYNODE)
+	} else if Parameters.NodeType == LIGHTNODENAME {
+		n.services = uint64(LIGHTNODE)
+		n.lightSync.init()
 	}
 
 	if Parameters.MaxHdrSyncReqs <= 0 {
@@ -192,7 +199,16 @@ func InitNode(pubKey *crypto.PubKey) Noder {
 	n.nbrNodes.init()
 	n.local = n
 	n.publicKey = pubKey
+	n.banList.init(BanListPath)
 	n.TXNPool.init()
+	if Parameters.NodeType != LIGHTNODENAME {
+		if err := n.TXNPool.LoadTxnJournal(TxnJournalPath); err != nil {
+			log.Error("Load transaction journal failed: ", err)
+		}
+	}
+	// a fast syncing node restores the state of a neighbor's snapshot
+	// instead of replaying the blocks below it
+	n.stateSync.active = Parameters.FastSync && !n.IsLightNode() && ledger.DefaultLedger.Store.GetHeight() == 0
 	n.eventQueue.init()
 	n.nodeDisconnectSubscriber = n.eventQueue.GetEvent("disconnect").Subscribe(events.EventNodeDisconnect, n.NodeDisconnect)
 	go n.initConnection()
@@ -328,6 +344,8 @@ func (node *node) Xmit(message interface{}) error {
 			return err
 		}
 		node.txnCnt++
+		node.nbrNodes.BroadcastTxn(buffer, txn)
+		return nil
 	case *ledger.Block:
 		log.Debug("TX block message")
 		block := message.(*ledger.Block)
@@ -398,7 +416,7 @@ func (node *node) GetBookKeepersAddrs() ([]*crypto.PubKey, uint64) {
 	i = 1
 	//TODO read lock
 	for _, n := range node.nbrNodes.List {
-		if n.GetState() == ESTABLISH && n.services != SERVICENODE {
+		if n.GetState() == ESTABLISH && n.services != SERVICENODE && n.services != LIGHTNODE {
 			pktmp := n.GetBookKeeperAddr()
 			pks = append(pks, pktmp)
 			i++
@@ -516,4 +534,7 @@ func (node *node) AcqSyncReqSem() {
 
 func (node *node) RelSyncReqSem() {
 	node.SyncReqSem.release()
-}
\ No newline at end of file
+}
+func (node *node) GetBloomFilter() *bloom.Filter {
+	return &node.link.bloomFilter
+}
@@ -3,6 +3,7 @@ package node
 import (
 	"fmt"
 	"github.com/Ontology/common/config"
+	"github.com/Ontology/core/transaction"
 	. "github.com/Ontology/net/protocol"
 	"strings"
 	"sync"
@@ -25,6 +26,18 @@ func (nm *nbrNodes) Broadcast(buf []byte) {
 	}
 }
 
+// BroadcastTxn relays the transaction to the neighbors whose bloom filter
+// matches it, neighbors without a filter receive every transaction
+func (nm *nbrNodes) BroadcastTxn(buf []byte, txn *transaction.Transaction) {
+	nm.RLock()
+	defer nm.RUnlock()
+	for _, node := range nm.List {
+		if node.state == ESTABLISH && node.relay == true && node.bloomFilter.MatchTxAndUpdate(txn) {
+			node.Tx(buf)
+		}
+	}
+}
+
 func (nm *nbrNodes) NodeExisted(uid uint64) bool {
 	_, ok := nm.List[uid]
 	return ok
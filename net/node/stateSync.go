@@ -0,0 +1,178 @@
+package node
+
+import (
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Ontology/common/log"
+	"github.com/Ontology/core/ledger"
+	. "github.com/Ontology/net/message"
+	. "github.com/Ontology/net/protocol"
+)
+
+const (
+	// SnapshotDownloadPath is the file the snapshot of a peer is downloaded to
+	SnapshotDownloadPath = "Chain/state.snapshot.download"
+	// STATESYNCREQTIMEOUT is the time to wait for a snapshot chunk
+	STATESYNCREQTIMEOUT = 30 * time.Second
+	// STATESYNCMAXRETRY is the number of failed downloads after which the
+	// node falls back to syncing blocks
+	STATESYNCMAXRETRY = 3
+)
+
+// stateSync keeps the state of a node started in fast sync mode. The headers
+// are synced as usual while the state snapshot of a neighbor is downloaded
+// chunk by chunk, the snapshot is restored once the header after it is synced
+// and the blocks above it are synced afterwards.
+type stateSync struct {
+	sync.Mutex
+	active    bool
+	peer      Noder     // The neighbor the snapshot is downloaded from
+	height    uint32    // The height of the snapshot
+	total     uint32    // The number of chunks of the snapshot
+	next      uint32    // The index of the next chunk
+	requested time.Time // The time the next chunk was requested
+	retries   int
+	file      *os.File
+}
+
+// reset drops the current download
+func (ss *stateSync) reset() {
+	if ss.file != nil {
+		ss.file.Close()
+		ss.file = nil
+	}
+	os.Remove(SnapshotDownloadPath)
+	ss.peer = nil
+	ss.height = 0
+	ss.total = 0
+	ss.next = 0
+}
+
+// fail gives up the current download, state sync stops after
+// STATESYNCMAXRETRY failures
+func (ss *stateSync) fail() {
+	ss.reset()
+	ss.retries++
+	if ss.retries >= STATESYNCMAXRETRY {
+		log.Warn("State sync failed, syncing blocks instead")
+		ss.active = false
+	}
+}
+
+func (node *node) IsStateSyncing() bool {
+	local := node.local
+	if local == nil {
+		return false
+	}
+	local.stateSync.Lock()
+	defer local.stateSync.Unlock()
+	return local.stateSync.active
+}
+
+// SyncState requests the next snapshot chunk and restores the downloaded
+// snapshot
+func (node *node) SyncState() {
+	ss := &node.stateSync
+	ss.Lock()
+	defer ss.Unlock()
+	if !ss.active {
+		return
+	}
+	if ss.total > 0 && ss.next == ss.total {
+		if ledger.DefaultLedger.Store.GetHeaderHeight() > ss.height {
+			node.restoreSnapshot()
+		}
+		return
+	}
+	if ss.peer != nil {
+		if time.Since(ss.requested) < STATESYNCREQTIMEOUT {
+			return
+		}
+		ss.peer.Misbehave(UNRESPONSIVESCORE, "snapshot request timeout")
+		ss.fail()
+		if !ss.active {
+			return
+		}
+	}
+
+	var noders []Noder
+	for _, n := range node.local.GetNeighborNoder() {
+		if n.GetState() == ESTABLISH && n.GetHeight() > 0 {
+			noders = append(noders, n)
+		}
+	}
+	if len(noders) == 0 {
+		return
+	}
+	ss.peer = noders[rand.Intn(len(noders))]
+	ss.requested = time.Now()
+	ReqSnapshotChunk(ss.peer, 0, 0)
+}
+
+// SnapshotChunkReceived stores a chunk of the snapshot being downloaded and
+// requests the next one
+func (node *node) SnapshotChunkReceived(from Noder, height, index, total uint32, data []byte) {
+	ss := &node.stateSync
+	ss.Lock()
+	defer ss.Unlock()
+	if !ss.active || ss.peer == nil || ss.peer.GetID() != from.GetID() || index != ss.next {
+		return
+	}
+	if index == 0 {
+		if height == 0 || total == 0 {
+			ss.fail()
+			return
+		}
+		f, err := os.Create(SnapshotDownloadPath)
+		if err != nil {
+			log.Error("Create snapshot file failed: ", err)
+			ss.reset()
+			return
+		}
+		ss.file = f
+		ss.height = height
+		ss.total = total
+	} else if height != ss.height || total != ss.total {
+		// the peer replaced its snapshot
+		ss.reset()
+		return
+	}
+	if _, err := ss.file.Write(data); err != nil {
+		log.Error("Write snapshot file failed: ", err)
+		ss.reset()
+		return
+	}
+	ss.next++
+	ss.requested = time.Now()
+	if ss.next < ss.total {
+		ReqSnapshotChunk(from, ss.height, ss.next)
+		return
+	}
+	if err := ss.file.Close(); err != nil {
+		log.Error("Write snapshot file failed: ", err)
+		ss.file = nil
+		ss.reset()
+		return
+	}
+	ss.file = nil
+	log.Infof("Snapshot of block %d downloaded", ss.height)
+}
+
+// restoreSnapshot replaces the state with the downloaded snapshot, the
+// peer which sent an invalid snapshot is punished
+func (node *node) restoreSnapshot() {
+	ss := &node.stateSync
+	height, err := ledger.DefaultLedger.Store.RestoreSnapshot(SnapshotDownloadPath, ledger.DefaultLedger)
+	if err != nil {
+		log.Error("Restore snapshot failed: ", err)
+		ss.peer.Misbehave(INVALIDBLOCKSCORE, "invalid snapshot")
+		ss.fail()
+		return
+	}
+	os.Remove(SnapshotDownloadPath)
+	ss.active = false
+	log.Infof("State synced to block %d, syncing the following blocks", height)
+}
@@ -11,15 +11,54 @@ import (
 	"github.com/Ontology/core/transaction/utxo"
 	va "github.com/Ontology/core/validation"
 	ontError "github.com/Ontology/errors"
+	"github.com/Ontology/events"
 	"sort"
 	"sync"
 )
 
+const (
+	DefaultTxPoolCapacity  = 10000 // transactions the pool holds
+	DefaultTxPoolExpiry    = 1000  // blocks a transaction stays in the pool
+	DefaultTxPoolPerSender = 100   // transactions of one address in the pool
+
+	// a transaction replaces the pool transactions spending the same UTXO
+	// only if it pays this percentage more network fee than all of them
+	MinReplaceFeeBump = 10
+)
+
 // Genesis transaction will not be added to pool, so use height == 0 to indicate transaction not packed in block
 type PoolTransaction struct {
-	tx *transaction.Transaction
-	//height uint32
-	fee common.Fixed64
+	tx      *transaction.Transaction
+	height  uint32         // block height when the transaction entered the pool
+	fee     common.Fixed64 // network fee
+	feeRate common.Fixed64 // network fee per byte
+	senders []common.Uint160
+}
+
+func NewPoolTransaction(txn *transaction.Transaction, height uint32) (*PoolTransaction, error) {
+	fee, err := txn.GetNetworkFee()
+	if err != nil {
+		return nil, err
+	}
+	senders, err := txn.GetProgramHashes()
+	if err != nil {
+		return nil, err
+	}
+	return &PoolTransaction{
+		tx:      txn,
+		height:  height,
+		fee:     fee,
+		feeRate: fee / common.Fixed64(len(txn.ToArray())),
+		senders: senders,
+	}, nil
+}
+
+// higherPriority reports whether the transaction is packed before another
+func (this *PoolTransaction) higherPriority(other *PoolTransaction) bool {
+	if this.feeRate != other.feeRate {
+		return this.feeRate > other.feeRate
+	}
+	return this.fee > other.fee
 }
 
 type TXNPool struct {
@@ -27,12 +66,36 @@ type TXNPool struct {
 	txnList       map[common.Uint256]*PoolTransaction // transaction which have been verifyed will put into this map
 	issueSummary  map[common.Uint256]common.Fixed64   // transaction which pass the verify will summary the amout to this map
 	inputUTXOList map[string]*transaction.Transaction // transaction which pass the verify will add the UTXO to this map
+	senderCount   map[common.Uint160]int              // number of transactions each address signed in pool
+	journal       *txnJournal                         // transactions in pool kept on disk across restarts
 }
 
 func (this *TXNPool) init() {
 	this.inputUTXOList = make(map[string]*transaction.Transaction)
 	this.issueSummary = make(map[common.Uint256]common.Fixed64)
 	this.txnList = make(map[common.Uint256]*PoolTransaction)
+	this.senderCount = make(map[common.Uint160]int)
+}
+
+func txPoolCapacity() int {
+	if config.Parameters.TxPoolCapacity > 0 {
+		return config.Parameters.TxPoolCapacity
+	}
+	return DefaultTxPoolCapacity
+}
+
+func txPoolExpiry() uint32 {
+	if config.Parameters.TxPoolExpiry > 0 {
+		return config.Parameters.TxPoolExpiry
+	}
+	return DefaultTxPoolExpiry
+}
+
+func txPoolPerSender() int {
+	if config.Parameters.TxPoolPerSender > 0 {
+		return config.Parameters.TxPoolPerSender
+	}
+	return DefaultTxPoolPerSender
 }
 
 //append transaction to txnpool when check ok.
@@ -47,19 +110,26 @@ func (this *TXNPool) AppendTxnPool(txn *transaction.Transaction) ontError.ErrCod
 		log.Infof("Transaction verification with ledger failed %x\n", txn.Hash())
 		return errCode
 	}
+	ptx, err := NewPoolTransaction(txn, ledger.DefaultLedger.Blockchain.BlockHeight)
+	if err != nil {
+		log.Infof("Transaction fee calculation failed %x\n", txn.Hash())
+		return ontError.ErrTransactionBalance
+	}
 	//verify transaction by pool with lock
-	if errCode := this.verifyTransactionWithTxnPool(txn); errCode != ontError.ErrNoError {
-		log.Info("Transaction verification with transaction pool failed", txn.Hash())
+	added, errCode := this.addTransaction(ptx)
+	if errCode != ontError.ErrNoError {
+		log.Info("Transaction verification with transaction pool failed", txn.Hash(), errCode)
 		return errCode
 	}
-	//add the transaction to process scope
-	this.addtxnList(txn)
+	if added {
+		ledger.DefaultLedger.Blockchain.BCEvents.Notify(events.EventNewTransaction, txn)
+	}
 	return ontError.ErrNoError
 }
 
 //get the transaction in txnpool
 func (this *TXNPool) GetTxnPool(byCount bool) (map[common.Uint256]*transaction.Transaction, common.Fixed64) {
-	this.Lock()
+	this.RLock()
 
 	orderByFee := make([]*PoolTransaction, 0, len(this.txnList))
 	for _, ptx := range this.txnList {
@@ -80,7 +150,7 @@ func (this *TXNPool) GetTxnPool(byCount bool) (map[common.Uint256]*transaction.T
 		txnMap[ptx.tx.Hash()] = ptx.tx
 	}
 
-	this.Unlock()
+	this.RUnlock()
 	return txnMap, networkFeeSum
 }
 
@@ -95,11 +165,19 @@ func (this *TXNPool) GetTxnPoolTxlist() []common.Uint256 {
 	return list
 }
 
-//clean the trasaction Pool with committed block.
+//clean the trasaction Pool with committed block, the transactions conflicting
+//with the block and the expired transactions are dropped too.
 func (this *TXNPool) CleanSubmittedTransactions(block *ledger.Block) error {
+	this.Lock()
+	defer this.Unlock()
 	this.cleanTransactionList(block.Transactions)
 	this.cleanUTXOList(block.Transactions)
-	this.cleanIssueSummary(block.Transactions)
+	this.cleanExpiredTransactions(block.Header.Height)
+	if this.journal != nil {
+		if err := this.journal.rotate(this.poolTransactions()); err != nil {
+			log.Error("[CleanSubmittedTransactions] rotate transaction journal failed: ", err)
+		}
+	}
 	return nil
 }
 
@@ -107,36 +185,208 @@ func (this *TXNPool) CleanSubmittedTransactions(block *ledger.Block) error {
 func (this *TXNPool) GetTransaction(hash common.Uint256) *transaction.Transaction {
 	this.RLock()
 	defer this.RUnlock()
-	return this.txnList[hash].tx
+	ptx, ok := this.txnList[hash]
+	if !ok {
+		return nil
+	}
+	return ptx.tx
 }
 
-//verify transaction with txnpool
-func (this *TXNPool) verifyTransactionWithTxnPool(txn *transaction.Transaction) ontError.ErrCode {
+//check the transaction with the pool and add it, the pool lock is held
+//so the checks and the update are atomic.
+func (this *TXNPool) addTransaction(ptx *PoolTransaction) (bool, ontError.ErrCode) {
+	this.Lock()
+	defer this.Unlock()
+	txn := ptx.tx
+	if _, ok := this.txnList[txn.Hash()]; ok {
+		return false, ontError.ErrNoError
+	}
 	// check if the transaction includes double spent UTXO inputs
-	ok, duplicateTxn := this.apendToUTXOPool(txn)
-	if !ok && duplicateTxn != nil {
-		log.Info(fmt.Sprintf("txn=%x duplicateTxn UTXO occurs with txn in pool=%x,keep the latest one.", txn.Hash(), duplicateTxn.Hash()))
-		this.removeTransaction(duplicateTxn)
+	conflicts := this.getConflicts(txn)
+	if len(conflicts) > 0 && !canReplace(ptx, conflicts) {
+		log.Info(fmt.Sprintf("txn=%x conflicts with %d txn in pool and pays too low fee to replace them.", txn.Hash(), len(conflicts)))
+		return false, ontError.ErrReplaceUnderpriced
+	}
+	// the replaced transactions are dropped with the ones spending them
+	replaced := this.withDependents(conflicts)
+	if spendsOutputsOf(txn, replaced) {
+		log.Info(fmt.Sprintf("txn=%x spends the outputs of a txn it replaces.", txn.Hash()))
+		return false, ontError.ErrDoubleSpend
+	}
+	if !this.checkSenderQuota(ptx, replaced) {
+		return false, ontError.ErrSenderQuota
+	}
+	var evicted []*PoolTransaction
+	if len(this.txnList)-len(replaced) >= txPoolCapacity() {
+		lowest := this.getLowestPriority(replaced)
+		if lowest == nil || !ptx.higherPriority(lowest) {
+			return false, ontError.ErrTxPoolFull
+		}
+		evicted = this.withDependents([]*PoolTransaction{lowest})
+		if spendsOutputsOf(txn, evicted) {
+			return false, ontError.ErrTxPoolFull
+		}
 	}
 	//check issue transaction weather occur exceed issue range.
-	if ok := this.summaryAssetIssueAmount(txn); !ok {
+	if ok := this.checkAssetIssueAmount(txn, append(replaced, evicted...)); !ok {
 		log.Info(fmt.Sprintf("Check summary Asset Issue Amount failed with txn=%x", txn.Hash()))
-		this.removeTransaction(txn)
-		return ontError.ErrSummaryAsset
+		return false, ontError.ErrSummaryAsset
 	}
 
-	return ontError.ErrNoError
+	for _, r := range replaced {
+		log.Info(fmt.Sprintf("txn=%x replaces txn=%x in pool spending the same UTXO or its outputs.", txn.Hash(), r.tx.Hash()))
+		this.removeTransaction(r.tx)
+	}
+	for _, e := range evicted {
+		log.Info(fmt.Sprintf("Transaction pool is full, txn=%x with the lowest fee or spending its outputs is evicted.", e.tx.Hash()))
+		this.removeTransaction(e.tx)
+	}
+	this.txnList[txn.Hash()] = ptx
+	for _, input := range txn.UTXOInputs {
+		this.inputUTXOList[input.ToString()] = txn
+	}
+	for _, sender := range ptx.senders {
+		this.senderCount[sender]++
+	}
+	if txn.TxType == transaction.IssueAsset {
+		for k, delta := range txn.GetMergedAssetIDValueFromOutputs() {
+			this.incrAssetIssueAmountSummary(k, delta)
+		}
+	}
+	if this.journal != nil {
+		if err := this.journal.insert(txn); err != nil {
+			log.Error("[addTransaction] write transaction journal failed: ", err)
+		}
+	}
+	return true, ontError.ErrNoError
+}
+
+//get the transactions in pool spending the same UTXO as the transaction
+func (this *TXNPool) getConflicts(txn *transaction.Transaction) []*PoolTransaction {
+	var conflicts []*PoolTransaction
+	seen := make(map[common.Uint256]bool)
+	for _, input := range txn.UTXOInputs {
+		t := this.inputUTXOList[input.ToString()]
+		if t == nil || seen[t.Hash()] {
+			continue
+		}
+		seen[t.Hash()] = true
+		if ptx, ok := this.txnList[t.Hash()]; ok {
+			conflicts = append(conflicts, ptx)
+		}
+	}
+	return conflicts
+}
+
+//get the transactions and the pool transactions spending their outputs,
+//directly or through other pool transactions
+func (this *TXNPool) withDependents(ptxs []*PoolTransaction) []*PoolTransaction {
+	all := make([]*PoolTransaction, 0, len(ptxs))
+	seen := make(map[common.Uint256]bool)
+	for _, ptx := range ptxs {
+		if !seen[ptx.tx.Hash()] {
+			seen[ptx.tx.Hash()] = true
+			all = append(all, ptx)
+		}
+	}
+	for i := 0; i < len(all); i++ {
+		for hash, ptx := range this.txnList {
+			if !seen[hash] && spendsOutputsOf(ptx.tx, all[i:i+1]) {
+				seen[hash] = true
+				all = append(all, ptx)
+			}
+		}
+	}
+	return all
+}
+
+//check whether the transaction spends an output of one of the transactions
+func spendsOutputsOf(txn *transaction.Transaction, ptxs []*PoolTransaction) bool {
+	for _, input := range txn.UTXOInputs {
+		for _, ptx := range ptxs {
+			if input.ReferTxID == ptx.tx.Hash() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//a transaction replaces the conflicting ones if it pays MinReplaceFeeBump
+//percent more than all of them and doesn't lower the fee per byte, so a
+//pending spend can't be evicted by a cheaper conflict.
+func canReplace(ptx *PoolTransaction, conflicts []*PoolTransaction) bool {
+	var feeSum common.Fixed64
+	for _, conflict := range conflicts {
+		if ptx.feeRate < conflict.feeRate {
+			return false
+		}
+		feeSum += conflict.fee
+	}
+	return ptx.fee > feeSum && ptx.fee*100 >= feeSum*(100+MinReplaceFeeBump)
+}
+
+//check the signers of the transaction don't exceed their quota, the
+//transactions it replaces are not counted
+func (this *TXNPool) checkSenderQuota(ptx *PoolTransaction, replaced []*PoolTransaction) bool {
+	quota := txPoolPerSender()
+	for _, sender := range ptx.senders {
+		count := this.senderCount[sender]
+		for _, r := range replaced {
+			for _, s := range r.senders {
+				if s == sender {
+					count--
+				}
+			}
+		}
+		if count >= quota {
+			address, _ := sender.ToAddress()
+			log.Info(fmt.Sprintf("txn=%x exceeds the quota of %d transactions in pool of %s.", ptx.tx.Hash(), quota, address))
+			return false
+		}
+	}
+	return true
+}
+
+//get the transaction packed last, except the given ones
+func (this *TXNPool) getLowestPriority(except []*PoolTransaction) *PoolTransaction {
+	var lowest *PoolTransaction
+	for _, ptx := range this.txnList {
+		skip := false
+		for _, e := range except {
+			if e == ptx {
+				skip = true
+				break
+			}
+		}
+		if !skip && (lowest == nil || lowest.higherPriority(ptx)) {
+			lowest = ptx
+		}
+	}
+	return lowest
 }
 
 //remove from associated map
 func (this *TXNPool) removeTransaction(txn *transaction.Transaction) {
 	//1.remove from txnList
-	this.deltxnList(txn)
+	ptx, ok := this.txnList[txn.Hash()]
+	if !ok {
+		return
+	}
+	delete(this.txnList, txn.Hash())
 	//2.remove from UTXO list map
 	for _, input := range txn.UTXOInputs {
-		this.delInputUTXOList(input)
+		if t, ok := this.inputUTXOList[input.ToString()]; ok && t == txn {
+			delete(this.inputUTXOList, input.ToString())
+		}
+	}
+	//3.remove from sender count map
+	for _, sender := range ptx.senders {
+		if this.senderCount[sender]--; this.senderCount[sender] <= 0 {
+			delete(this.senderCount, sender)
+		}
 	}
-	//3.remove From Asset Issue Summary map
+	//4.remove From Asset Issue Summary map
 	if txn.TxType != transaction.IssueAsset {
 		return
 	}
@@ -146,32 +396,56 @@ func (this *TXNPool) removeTransaction(txn *transaction.Transaction) {
 	}
 }
 
-//check and add to utxo list pool
-func (this *TXNPool) apendToUTXOPool(txn *transaction.Transaction) (bool, *transaction.Transaction) {
-	for _, input := range txn.UTXOInputs {
-		t := this.getInputUTXOList(input)
-		if t != nil {
-			return false, t
+//clean txnpool utxo map, the pool transactions spending the UTXO the
+//committed transactions spent are invalid now
+func (this *TXNPool) cleanUTXOList(txs []*transaction.Transaction) {
+	for _, txn := range txs {
+		for _, input := range txn.UTXOInputs {
+			if t, ok := this.inputUTXOList[input.ToString()]; ok {
+				log.Info(fmt.Sprintf("txn=%x in pool double spends committed txn=%x, removed.", t.Hash(), txn.Hash()))
+				this.removeTransaction(t)
+			}
 		}
-		this.addInputUTXOList(txn, input)
 	}
-	return true, nil
 }
 
-//clean txnpool utxo map
-func (this *TXNPool) cleanUTXOList(txs []*transaction.Transaction) {
-	for _, txn := range txs {
-		for _, input := range txn.UTXOInputs {
-			this.delInputUTXOList(input)
+//remove the transactions which have been in pool for too many blocks
+func (this *TXNPool) cleanExpiredTransactions(height uint32) {
+	expiry := txPoolExpiry()
+	var expired []*PoolTransaction
+	for _, ptx := range this.txnList {
+		if ptx.height+expiry <= height {
+			expired = append(expired, ptx)
 		}
 	}
+	// the transactions spending their outputs go with them
+	expired = this.withDependents(expired)
+	for _, ptx := range expired {
+		this.removeTransaction(ptx.tx)
+	}
+	if len(expired) > 0 {
+		log.Info(fmt.Sprintf("[cleanExpiredTransactions] %d transactions expired at height %d", len(expired), height))
+	}
 }
 
-//check and summary to issue amount Pool
-func (this *TXNPool) summaryAssetIssueAmount(txn *transaction.Transaction) bool {
+//check the issue amount with the pool won't exceed the registered amount,
+//the amounts of the transactions removed for it are not counted
+func (this *TXNPool) checkAssetIssueAmount(txn *transaction.Transaction, removed []*PoolTransaction) bool {
 	if txn.TxType != transaction.IssueAsset {
 		return true
 	}
+	pending := make(map[common.Uint256]common.Fixed64)
+	for k, v := range this.issueSummary {
+		pending[k] = v
+	}
+	for _, r := range removed {
+		if r.tx.TxType != transaction.IssueAsset {
+			continue
+		}
+		for k, delta := range r.tx.GetMergedAssetIDValueFromOutputs() {
+			pending[k] -= delta
+		}
+	}
 	transactionResult := txn.GetMergedAssetIDValueFromOutputs()
 	for k, delta := range transactionResult {
 		//Check weather occur exceed the amount when RegisterAsseted
@@ -199,17 +473,11 @@ func (this *TXNPool) summaryAssetIssueAmount(txn *transaction.Transaction) bool
 		//3. calc weather out off the amount when Registed.
 		//AssetReg.Amount : amount when RegisterAsset of this assedID
 		//quantity_issued : amount has been issued of this assedID
-		//txnPool.issueSummary[k] : amount in transactionPool of this assedID
-		if AssetReg.Amount < quantity_issued+this.getAssetIssueAmount(k)+delta {
+		//pending[k] : amount in transactionPool of this assedID
+		if AssetReg.Amount < quantity_issued+pending[k]+delta {
 			return false
 		}
 	}
-
-	for k, delta := range transactionResult {
-		//update the amount in txnPool
-		this.incrAssetIssueAmountSummary(k, delta)
-	}
-
 	return true
 }
 
@@ -222,43 +490,18 @@ func (this *TXNPool) cleanTransactionList(txns []*transaction.Transaction) error
 			txnsNum = txnsNum - 1
 			continue
 		}
-		if this.deltxnList(txn) {
+		if _, ok := this.txnList[txn.Hash()]; ok {
+			this.removeTransaction(txn)
 			cleaned++
 		}
 	}
 	if txnsNum != cleaned {
 		log.Info(fmt.Sprintf("The Transactions num Unmatched. Expect %d, got %d .\n", txnsNum, cleaned))
 	}
-	log.Debug(fmt.Sprintf("[cleanTransactionList],transaction %d Requested, %d cleaned, Remains %d in TxPool", txnsNum, cleaned, this.GetTransactionCount()))
+	log.Debug(fmt.Sprintf("[cleanTransactionList],transaction %d Requested, %d cleaned, Remains %d in TxPool", txnsNum, cleaned, len(this.txnList)))
 	return nil
 }
 
-func (this *TXNPool) addtxnList(txn *transaction.Transaction) bool {
-	this.Lock()
-	defer this.Unlock()
-	txnHash := txn.Hash()
-	if _, ok := this.txnList[txnHash]; ok {
-		return false
-	}
-	nfw, err := txn.GetNetworkFee()
-	if err != nil {
-		return false
-	}
-	this.txnList[txnHash] = &PoolTransaction{tx: txn, fee: nfw}
-	return true
-}
-
-func (this *TXNPool) deltxnList(tx *transaction.Transaction) bool {
-	this.Lock()
-	defer this.Unlock()
-	txHash := tx.Hash()
-	if _, ok := this.txnList[txHash]; !ok {
-		return false
-	}
-	delete(this.txnList, txHash)
-	return true
-}
-
 func (this *TXNPool) copytxnList() map[common.Uint256]*transaction.Transaction {
 	this.RLock()
 	defer this.RUnlock()
@@ -281,40 +524,11 @@ func (this *TXNPool) getInputUTXOList(input *utxo.UTXOTxInput) *transaction.Tran
 	return this.inputUTXOList[input.ToString()]
 }
 
-func (this *TXNPool) addInputUTXOList(tx *transaction.Transaction, input *utxo.UTXOTxInput) bool {
-	this.Lock()
-	defer this.Unlock()
-	id := input.ToString()
-	_, ok := this.inputUTXOList[id]
-	if ok {
-		return false
-	}
-	this.inputUTXOList[id] = tx
-
-	return true
-}
-
-func (this *TXNPool) delInputUTXOList(input *utxo.UTXOTxInput) bool {
-	this.Lock()
-	defer this.Unlock()
-	id := input.ToString()
-	_, ok := this.inputUTXOList[id]
-	if !ok {
-		return false
-	}
-	delete(this.inputUTXOList, id)
-	return true
-}
-
 func (this *TXNPool) incrAssetIssueAmountSummary(assetId common.Uint256, delta common.Fixed64) {
-	this.Lock()
-	defer this.Unlock()
 	this.issueSummary[assetId] = this.issueSummary[assetId] + delta
 }
 
 func (this *TXNPool) decrAssetIssueAmountSummary(assetId common.Uint256, delta common.Fixed64) {
-	this.Lock()
-	defer this.Unlock()
 	amount, ok := this.issueSummary[assetId]
 	if !ok {
 		return
@@ -326,27 +540,18 @@ func (this *TXNPool) decrAssetIssueAmountSummary(assetId common.Uint256, delta c
 	this.issueSummary[assetId] = amount
 }
 
-func (this *TXNPool) cleanIssueSummary(txs []*transaction.Transaction) {
-	for _, v := range txs {
-		if v.TxType == transaction.IssueAsset {
-			transactionResult := v.GetMergedAssetIDValueFromOutputs()
-			for k, delta := range transactionResult {
-				this.decrAssetIssueAmountSummary(k, delta)
-			}
-		}
-	}
-}
-
 func (this *TXNPool) getAssetIssueAmount(assetId common.Uint256) common.Fixed64 {
 	this.RLock()
 	defer this.RUnlock()
 	return this.issueSummary[assetId]
 }
 
+// OrderByNetWorkFee orders the transactions by network fee per byte, then
+// by network fee, highest first
 type OrderByNetWorkFee []*PoolTransaction
 
 func (n OrderByNetWorkFee) Len() int { return len(n) }
 
 func (n OrderByNetWorkFee) Swap(i, j int) { n[i], n[j] = n[j], n[i] }
 
-func (n OrderByNetWorkFee) Less(i, j int) bool { return n[j].fee < n[i].fee }
+func (n OrderByNetWorkFee) Less(i, j int) bool { return n[i].higherPriority(n[j]) }
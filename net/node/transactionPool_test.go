@@ -0,0 +1,194 @@
+package node
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Ontology/common"
+	"github.com/Ontology/common/config"
+	"github.com/Ontology/common/log"
+	"github.com/Ontology/core/transaction"
+	"github.com/Ontology/core/transaction/payload"
+	"github.com/Ontology/core/transaction/utxo"
+	ontError "github.com/Ontology/errors"
+)
+
+func newTestPoolTransaction(referTxID byte, fee common.Fixed64, sender byte, height uint32) *PoolTransaction {
+	txn, _ := transaction.NewTransferAssetTransaction([]*utxo.UTXOTxInput{
+		{ReferTxID: common.Uint256{referTxID}},
+	}, nil)
+	// the fee and the sender make the hash of conflicting transactions differ
+	attr := transaction.NewTxAttribute(transaction.Nonce, []byte(fmt.Sprint(fee, sender)))
+	txn.Attributes = []*transaction.TxAttribute{&attr}
+	return &PoolTransaction{
+		tx:      txn,
+		height:  height,
+		fee:     fee,
+		feeRate: fee / common.Fixed64(len(txn.ToArray())),
+		senders: []common.Uint160{{sender}},
+	}
+}
+
+func TestTxnPoolPolicy(t *testing.T) {
+	log.Init(log.Path, log.Stdout)
+	defer func(p config.Configuration) { *config.Parameters = p }(*config.Parameters)
+	config.Parameters.TxPoolCapacity = 3
+	config.Parameters.TxPoolPerSender = 2
+	config.Parameters.TxPoolExpiry = 10
+
+	var pool TXNPool
+	pool.init()
+	pending := newTestPoolTransaction(1, 10000, 1, 0)
+	if _, errCode := pool.addTransaction(pending); errCode != ontError.ErrNoError {
+		t.Fatal(errCode)
+	}
+
+	// a conflicting spend has to pay more to replace the pending one
+	for _, fee := range []common.Fixed64{0, 10000, 10500} {
+		if _, errCode := pool.addTransaction(newTestPoolTransaction(1, fee, 2, 0)); errCode != ontError.ErrReplaceUnderpriced {
+			t.Fatal("conflict with fee", fee, "not rejected:", errCode)
+		}
+	}
+	replacement := newTestPoolTransaction(1, 20000, 2, 0)
+	if _, errCode := pool.addTransaction(replacement); errCode != ontError.ErrNoError {
+		t.Fatal(errCode)
+	}
+	if pool.GetTransaction(pending.tx.Hash()) != nil || pool.GetTransaction(replacement.tx.Hash()) == nil {
+		t.Fatal("pending transaction not replaced")
+	}
+
+	// per sender quota
+	if _, errCode := pool.addTransaction(newTestPoolTransaction(2, 10000, 2, 5)); errCode != ontError.ErrNoError {
+		t.Fatal(errCode)
+	}
+	if _, errCode := pool.addTransaction(newTestPoolTransaction(3, 90000, 2, 5)); errCode != ontError.ErrSenderQuota {
+		t.Fatal("sender quota not enforced:", errCode)
+	}
+
+	// the lowest fee transaction is evicted when the pool is full
+	if _, errCode := pool.addTransaction(newTestPoolTransaction(4, 30000, 3, 5)); errCode != ontError.ErrNoError {
+		t.Fatal(errCode)
+	}
+	if _, errCode := pool.addTransaction(newTestPoolTransaction(5, 100, 4, 5)); errCode != ontError.ErrTxPoolFull {
+		t.Fatal("low fee transaction accepted in full pool:", errCode)
+	}
+	if _, errCode := pool.addTransaction(newTestPoolTransaction(5, 40000, 4, 5)); errCode != ontError.ErrNoError {
+		t.Fatal(errCode)
+	}
+	if pool.GetTransactionCount() != 3 {
+		t.Fatal("pool exceeds its capacity")
+	}
+	txns, feeSum := pool.GetTxnPool(false)
+	if len(txns) != 3 || feeSum != 90000 {
+		t.Fatal("unexpected pool transactions", len(txns), feeSum)
+	}
+
+	// transactions expire after TxPoolExpiry blocks
+	pool.cleanExpiredTransactions(10)
+	if pool.GetTransactionCount() != 2 || pool.GetTransaction(replacement.tx.Hash()) != nil {
+		t.Fatal("transaction not expired")
+	}
+	if pool.senderCount[common.Uint160{2}] != 0 || pool.getInputUTXOList(replacement.tx.UTXOInputs[0]) != nil {
+		t.Fatal("expired transaction not removed from the pool indexes")
+	}
+}
+
+func TestTxnJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	log.Init(log.Path, log.Stdout)
+
+	path := filepath.Join(dir, "txpool.journal")
+	journal := newTxnJournal(path)
+	if txns, err := journal.load(); err != nil || len(txns) != 0 {
+		t.Fatal("missing journal not empty", err)
+	}
+	first := newTestPoolTransaction(1, 100, 1, 0).tx
+	second := newTestPoolTransaction(2, 200, 1, 0).tx
+	if err := journal.rotate([]*transaction.Transaction{first}); err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.insert(second); err != nil {
+		t.Fatal(err)
+	}
+	// a record cut off by a crash is skipped
+	if _, err := journal.file.Write([]byte{0xfd, 0x10}); err != nil {
+		t.Fatal(err)
+	}
+	journal.close()
+
+	txns, err := newTxnJournal(path).load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txns) != 2 || txns[0].Hash() != first.Hash() || txns[1].Hash() != second.Hash() {
+		t.Fatal("journal not restored", len(txns))
+	}
+}
+
+type testTxStore map[common.Uint256]*transaction.Transaction
+
+func (s testTxStore) GetTransaction(hash common.Uint256) (*transaction.Transaction, error) {
+	if txn, ok := s[hash]; ok {
+		return txn, nil
+	}
+	return nil, fmt.Errorf("transaction %x not found", hash)
+}
+
+func (s testTxStore) GetQuantityIssued(assetId common.Uint256) (common.Fixed64, error) {
+	return 0, nil
+}
+
+func TestTxnPoolReplaceDependents(t *testing.T) {
+	log.Init(log.Path, log.Stdout)
+	defer func(store transaction.ILedgerStore) { transaction.TxStore = store }(transaction.TxStore)
+	assetId := common.Uint256{9}
+	transaction.TxStore = testTxStore{assetId: {
+		TxType:  transaction.RegisterAsset,
+		Payload: &payload.RegisterAsset{Amount: 100},
+	}}
+
+	var pool TXNPool
+	pool.init()
+	// the replacement of an issue fits once the replaced one is not counted
+	issue := func(amount, fee common.Fixed64) *PoolTransaction {
+		ptx := newTestPoolTransaction(1, fee, 1, 0)
+		ptx.tx.TxType = transaction.IssueAsset
+		ptx.tx.Payload = &payload.IssueAsset{}
+		ptx.tx.Outputs = []*utxo.TxOutput{{AssetID: assetId, Value: amount}}
+		return ptx
+	}
+	if _, errCode := pool.addTransaction(issue(60, 10000)); errCode != ontError.ErrNoError {
+		t.Fatal(errCode)
+	}
+	replacement := issue(70, 20000)
+	if _, errCode := pool.addTransaction(replacement); errCode != ontError.ErrNoError {
+		t.Fatal("issue replacement rejected:", errCode)
+	}
+	if pool.getAssetIssueAmount(assetId) != 70 {
+		t.Fatal("unexpected issue amount in pool", pool.getAssetIssueAmount(assetId))
+	}
+
+	// the transactions spending the outputs of a replaced one are dropped
+	child := newTestPoolTransaction(2, 10000, 2, 0)
+	child.tx.UTXOInputs[0].ReferTxID = replacement.tx.Hash()
+	grandchild := newTestPoolTransaction(3, 10000, 3, 0)
+	grandchild.tx.UTXOInputs[0].ReferTxID = child.tx.Hash()
+	for _, ptx := range []*PoolTransaction{child, grandchild} {
+		if _, errCode := pool.addTransaction(ptx); errCode != ontError.ErrNoError {
+			t.Fatal(errCode)
+		}
+	}
+	if _, errCode := pool.addTransaction(issue(10, 90000)); errCode != ontError.ErrNoError {
+		t.Fatal(errCode)
+	}
+	if pool.GetTransactionCount() != 1 || pool.GetTransaction(child.tx.Hash()) != nil || pool.GetTransaction(grandchild.tx.Hash()) != nil {
+		t.Fatal("transactions spending a replaced one kept")
+	}
+}
@@ -0,0 +1,150 @@
+package node
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/Ontology/common/log"
+	"github.com/Ontology/common/serialization"
+	"github.com/Ontology/core/transaction"
+	ontError "github.com/Ontology/errors"
+)
+
+// TxnJournalPath is the file the transactions accepted into the pool are
+// kept in, so they survive a restart of the node
+const TxnJournalPath = "Chain/txpool.journal"
+
+// txnJournal appends every transaction accepted into the pool to a file and
+// is rewritten with the transactions left in the pool after each block
+type txnJournal struct {
+	path string
+	file *os.File
+}
+
+func newTxnJournal(path string) *txnJournal {
+	return &txnJournal{path: path}
+}
+
+// load reads the transactions of the journal, a record truncated by a crash
+// ends the journal
+func (this *txnJournal) load() ([]*transaction.Transaction, error) {
+	file, err := os.Open(this.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var txns []*transaction.Transaction
+	r := bufio.NewReader(file)
+	for {
+		data, err := serialization.ReadVarBytes(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Warn("[txnJournal] truncated record at the end of the journal")
+			break
+		}
+		txn := new(transaction.Transaction)
+		if err := txn.Deserialize(bytes.NewReader(data)); err != nil {
+			log.Warn("[txnJournal] invalid transaction in the journal: ", err)
+			continue
+		}
+		txns = append(txns, txn)
+	}
+	return txns, nil
+}
+
+// insert appends a transaction to the journal
+func (this *txnJournal) insert(txn *transaction.Transaction) error {
+	if this.file == nil {
+		return fmt.Errorf("[txnJournal] journal %s is not open", this.path)
+	}
+	var buffer bytes.Buffer
+	if err := serialization.WriteVarBytes(&buffer, txn.ToArray()); err != nil {
+		return err
+	}
+	_, err := this.file.Write(buffer.Bytes())
+	return err
+}
+
+// rotate replaces the journal with the given transactions and reopens it
+// for appending
+func (this *txnJournal) rotate(txns []*transaction.Transaction) error {
+	if err := os.MkdirAll(filepath.Dir(this.path), 0755); err != nil {
+		return err
+	}
+	tmp := this.path + ".new"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	for _, txn := range txns {
+		if err := serialization.WriteVarBytes(w, txn.ToArray()); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	this.close()
+	if err := os.Rename(tmp, this.path); err != nil {
+		return err
+	}
+	this.file, err = os.OpenFile(this.path, os.O_WRONLY|os.O_APPEND, 0644)
+	return err
+}
+
+func (this *txnJournal) close() {
+	if this.file != nil {
+		this.file.Close()
+		this.file = nil
+	}
+}
+
+// LoadTxnJournal replays the journal through the pool validation, the
+// transactions which became invalid against the current ledger are dropped
+func (this *TXNPool) LoadTxnJournal(path string) error {
+	journal := newTxnJournal(path)
+	txns, err := journal.load()
+	if err != nil {
+		return err
+	}
+	dropped := 0
+	for _, txn := range txns {
+		if errCode := this.AppendTxnPool(txn); errCode != ontError.ErrNoError {
+			dropped++
+		}
+	}
+	if len(txns) > 0 {
+		log.Info(fmt.Sprintf("[LoadTxnJournal] %d transactions restored from journal, %d dropped", len(txns)-dropped, dropped))
+	}
+	this.Lock()
+	defer this.Unlock()
+	if err := journal.rotate(this.poolTransactions()); err != nil {
+		return err
+	}
+	this.journal = journal
+	return nil
+}
+
+func (this *TXNPool) poolTransactions() []*transaction.Transaction {
+	txns := make([]*transaction.Transaction, 0, len(this.txnList))
+	for _, ptx := range this.txnList {
+		txns = append(txns, ptx.tx)
+	}
+	return txns
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/Ontology/crypto"
 	. "github.com/Ontology/errors"
 	"github.com/Ontology/events"
+	"github.com/Ontology/net/bloom"
 	"time"
 )
 
@@ -24,11 +25,13 @@ type NodeAddr struct {
 const (
 	VERIFYNODE = 1
 	SERVICENODE = 2
+	LIGHTNODE = 4
 )
 
 const (
 	VERIFYNODENAME = "verify"
 	SERVICENODENAME = "service"
+	LIGHTNODENAME = "light"
 )
 
 const (
@@ -71,6 +74,45 @@ const (
 	INACTIVITY = 5
 )
 
+// The misbehaviour scores of a peer, the address of the peer is banned for
+// DEFAULTBANTIME when its score reaches BANSCORE. The scores decay by one
+// every SCOREDECAYTIME. A peer misbehaves by duplicated blocks when it sends
+// more than MAXDUPLICATEBLOCKS unrequested within SCOREDECAYTIME.
+const (
+	BANSCORE = 100
+	INVALIDMSGSCORE = 20
+	INVALIDBLOCKSCORE = 50
+	INVALIDCONSENSUSSCORE = 20
+	DUPLICATEBLOCKSCORE = 1
+	MAXDUPLICATEBLOCKS = 16
+	UNRESPONSIVESCORE = 10
+	SCOREDECAYTIME = time.Minute
+	DEFAULTBANTIME = 24 * time.Hour
+)
+
+// The reject message codes
+const (
+	REJECTMALFORMED = 0x01
+	REJECTINVALID = 0x10
+	REJECTDUPLICATE = 0x12
+)
+
+// PeerInfo is a neighbor node and its misbehaviour score
+type PeerInfo struct {
+	Addr   string
+	Port   uint16
+	ID     uint64
+	State  uint32
+	Height uint64
+	Score  int
+}
+
+// BanInfo is a banned peer address and the unix time its ban expires
+type BanInfo struct {
+	Addr  string
+	Until int64
+}
+
 var ReceiveDuplicateBlockCnt uint64 //an index to detecting networking status
 
 type Noder interface {
@@ -140,6 +182,20 @@ type Noder interface {
 	RemoveFromRetryList(addr string)
 	AcqSyncReqSem()
 	RelSyncReqSem()
+	GetBloomFilter() *bloom.Filter
+	IsLightNode() bool
+	WatchAddresses(programHashes []common.Uint160)
+	MerkleBlockReceived(peer uint64, height uint32, matches []common.Uint256)
+	LightTxnReceived(txn *transaction.Transaction)
+	Misbehave(score int, reason string)
+	DuplicateBlock()
+	IsBanned(addr string) bool
+	BanPeer(addr string, duration time.Duration) error
+	UnbanPeer(addr string) error
+	GetPeerInfos() []PeerInfo
+	GetBannedPeers() []BanInfo
+	IsStateSyncing() bool
+	SnapshotChunkReceived(from Noder, height, index, total uint32, data []byte)
 }
 
 func (msg *NodeAddr) Deserialization(p []byte) error {
@@ -7,6 +7,7 @@ import (
 	_ "github.com/Ontology/cli"
 	"github.com/Ontology/cli/asset"
 	"github.com/Ontology/cli/bookkeeper"
+	"github.com/Ontology/cli/chain"
 	. "github.com/Ontology/cli/common"
 	"github.com/Ontology/cli/data"
 	"github.com/Ontology/cli/debug"
@@ -44,6 +45,7 @@ func main() {
 		*privpayload.NewCommand(),
 		*data.NewCommand(),
 		*bookkeeper.NewCommand(),
+		*chain.NewCommand(),
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 	sort.Sort(cli.FlagsByName(app.Flags))
@@ -17,3 +17,22 @@ type NotifyEventInfo struct {
 	States interface{}
 }
 
+
+// Topic returns the first state of the notification, which names the event.
+// The byte arrays of the states are hex encoded, the name is decoded from it.
+func (n *NotifyEventInfo) Topic() string {
+	states, ok := n.States.([]interface{})
+	if ok && len(states) == 1 {
+		if inner, ok := states[0].([]interface{}); ok {
+			states = inner
+		}
+	}
+	if len(states) == 0 {
+		return ""
+	}
+	topic, _ := states[0].(string)
+	if name, err := common.HexToBytes(topic); err == nil {
+		return string(name)
+	}
+	return topic
+}
@@ -20,7 +20,7 @@ func PreExec(code []byte, container interfaces.ICodeContainer) ([]interface{}, e
 	crypto = new(neovm.ECDsaCrypto)
 	stateStore := ChainStore.NewStateStore(statestore.NewMemDatabase(), ledger.DefaultLedger.Store.(*ChainStore.ChainStore), Uint256{})
 	stateMachine := service.NewStateMachine(stateStore, types.Application, nil)
-	se := neovm.NewExecutionEngine(container, crypto, ChainStore.NewCacheCodeTable(stateStore), stateMachine)
+	se := neovm.NewExecutionEngine(container, crypto, ChainStore.NewCacheCodeTable(stateStore), stateMachine, 0)
 	se.LoadCode(code, false)
 	err = se.Execute()
 	if err != nil {
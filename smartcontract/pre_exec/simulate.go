@@ -0,0 +1,172 @@
+package pre_exec
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"time"
+
+	. "github.com/Ontology/common"
+	"github.com/Ontology/core/ledger"
+	"github.com/Ontology/core/states"
+	"github.com/Ontology/core/store"
+	"github.com/Ontology/core/store/ChainStore"
+	"github.com/Ontology/core/store/statestore"
+	tx "github.com/Ontology/core/transaction"
+	"github.com/Ontology/core/transaction/payload"
+	"github.com/Ontology/errors"
+	sc "github.com/Ontology/smartcontract"
+	"github.com/Ontology/smartcontract/service"
+	"github.com/Ontology/smartcontract/types"
+	"github.com/Ontology/vm/neovm"
+)
+
+// SimulateResult is the outcome of an invoke transaction run in a sandbox,
+// State is HALT when the invocation succeeded and FAULT otherwise.
+type SimulateResult struct {
+	State          string
+	GasConsumed    string
+	Result         interface{}
+	Error          string
+	Notifications  []*SimulateNotify
+	Logs           []*SimulateLog
+	StorageChanges []*StorageChange
+}
+
+type SimulateNotify struct {
+	CodeHash string
+	States   interface{}
+}
+
+type SimulateLog struct {
+	CodeHash string
+	Message  string
+}
+
+// StorageChange is a storage item the invocation writes or deletes
+type StorageChange struct {
+	CodeHash string
+	Key      string
+	Value    string
+	Deleted  bool
+}
+
+var (
+	// ErrNotInvokeTransaction is returned when the simulated transaction does
+	// not invoke a contract
+	ErrNotInvokeTransaction = errors.NewErr("[Simulate] Transaction is not an invoke transaction!")
+	// ErrContractNotFound is returned when the invoked contract is not deployed
+	ErrContractNotFound = errors.NewErr("[Simulate] Contract not found!")
+)
+
+// Simulate runs the invoke transaction txn against the current state as if it
+// were in the next block, nothing is persisted and no event is pushed. The
+// caller is the program hash of the first program of txn, which may be given
+// without its signature.
+func Simulate(txn *tx.Transaction) (*SimulateResult, error) {
+	chainStore, ok := ledger.DefaultLedger.Store.(*ChainStore.ChainStore)
+	if !ok {
+		return nil, errors.NewErr("[Simulate] Ledger store is not a chain store!")
+	}
+	stateStore := ChainStore.NewStateStore(statestore.NewMemDatabase(), chainStore, Uint256{})
+	return simulate(txn, stateStore, chainStore.GetHeight()+1)
+}
+
+// simulate runs txn on stateStore in the block at height
+func simulate(txn *tx.Transaction, stateStore store.IStateStore, height uint32) (*SimulateResult, error) {
+	invoke, ok := txn.Payload.(*payload.InvokeCode)
+	if !ok || txn.TxType != tx.Invoke {
+		return nil, ErrNotInvokeTransaction
+	}
+	item, err := stateStore.TryGet(store.ST_Contract, invoke.CodeHash.ToArray())
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, ErrContractNotFound
+	}
+	contract := item.Value.(*states.ContractState)
+
+	stateMachine := service.NewStateMachine(stateStore, types.Application, nil)
+	stateMachine.Simulate = true
+	smc, err := sc.NewSmartContract(&sc.Context{
+		VmType:         contract.VmType,
+		Caller:         ChainStore.Invoker(txn),
+		StateMachine:   stateMachine,
+		SignableData:   txn,
+		CacheCodeTable: ChainStore.NewCacheCodeTable(stateStore),
+		Input:          invoke.Code,
+		Code:           contract.Code.Code,
+		CodeHash:       invoke.CodeHash,
+		Time:           big.NewInt(time.Now().Unix()),
+		BlockNumber:    big.NewInt(int64(height)),
+		ReturnType:     contract.Code.ReturnType,
+		Gas:            invoke.GasLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the events and the storage changes until the fault are returned too
+	result := &SimulateResult{State: "HALT"}
+	result.Result, err = smc.InvokeContract()
+	result.GasConsumed = smc.GasConsumed().String()
+	// a THROW faults the NeoVM engine without an error
+	if engine, ok := smc.Engine.(*neovm.ExecutionEngine); ok && err == nil && engine.GetState() == neovm.FAULT {
+		err = errors.NewErr("[Simulate] Execution engine fault!")
+	}
+	if err != nil {
+		result.State = "FAULT"
+		result.Result = nil
+		result.Error = err.Error()
+	}
+	notifications := append(stateMachine.Notifications, smc.Notifications(txn.Hash())...)
+	for _, n := range notifications {
+		result.Notifications = append(result.Notifications, &SimulateNotify{
+			CodeHash: ToHexString(n.CodeHash.ToArray()),
+			States:   n.States,
+		})
+	}
+	for _, l := range stateMachine.Logs {
+		result.Logs = append(result.Logs, &SimulateLog{
+			CodeHash: ToHexString(l.CodeHash.ToArray()),
+			Message:  l.Message,
+		})
+	}
+	result.StorageChanges, err = storageChanges(stateMachine)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// storageChanges lists the storage items of the state machine cache, sorted
+// by key
+func storageChanges(stateMachine *service.StateMachine) ([]*StorageChange, error) {
+	var keys []string
+	for k, v := range stateMachine.CloneCache.Memory {
+		if v.Prefix == store.ST_Storage && v.State != store.None {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := make([]*StorageChange, 0, len(keys))
+	for _, k := range keys {
+		v := stateMachine.CloneCache.Memory[k]
+		var key states.StorageKey
+		if err := key.Deserialize(bytes.NewReader([]byte(v.Key))); err != nil {
+			return nil, err
+		}
+		change := &StorageChange{
+			CodeHash: ToHexString(key.CodeHash.ToArray()),
+			Key:      ToHexString(key.Key),
+			Deleted:  v.State == store.Deleted,
+		}
+		if item, ok := v.Value.(*states.StorageItem); ok && !change.Deleted {
+			change.Value = ToHexString(item.Value)
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
@@ -0,0 +1,79 @@
+package pre_exec
+
+import (
+	"testing"
+
+	. "github.com/Ontology/common"
+	"github.com/Ontology/common/config"
+	"github.com/Ontology/common/log"
+	"github.com/Ontology/core/code"
+	"github.com/Ontology/core/contract/program"
+	"github.com/Ontology/core/states"
+	"github.com/Ontology/core/store"
+	"github.com/Ontology/core/store/ChainStore"
+	"github.com/Ontology/core/store/statestore"
+	tx "github.com/Ontology/core/transaction"
+	"github.com/Ontology/smartcontract/types"
+)
+
+func syscall(name string) []byte {
+	return append([]byte{0x68, byte(len(name))}, name...)
+}
+
+func TestSimulate(t *testing.T) {
+	log.Init(log.Path, log.Stdout)
+	defer func(backend string) { config.Parameters.StoreBackend = backend }(config.Parameters.StoreBackend)
+	config.Parameters.StoreBackend = ChainStore.MemoryBackend
+	chainStore, err := ChainStore.NewChainStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chainStore.Close()
+	stateStore := ChainStore.NewStateStore(statestore.NewMemDatabase(), chainStore, Uint256{})
+
+	// the contract stores k=v and notifies its caller, then halts or faults
+	body := []byte{0x01, 'v', 0x01, 'k'}
+	body = append(body, syscall("Neo.Storage.GetContext")...)
+	body = append(body, syscall("Neo.Storage.Put")...)
+	body = append(body, syscall("Neo.Runtime.GetCaller")...)
+	body = append(body, syscall("Neo.Runtime.Notify")...)
+	signer := []byte{0x51}
+	for _, c := range []struct {
+		end   byte
+		state string
+	}{{0x66, "HALT"}, {0xf0, "FAULT"}} {
+		contract := append(append([]byte{}, body...), c.end)
+		codeHash := ToCodeHash(contract)
+		stateStore.TryAdd(store.ST_Contract, codeHash.ToArray(), &states.ContractState{
+			Code:   &code.FunctionCode{Code: contract},
+			VmType: types.NEOVM,
+		}, false)
+
+		txn, _ := tx.NewInvokeTransaction(nil, codeHash, 0)
+		txn.Programs = []*program.Program{{Code: signer, Parameter: []byte{}}}
+		result, err := simulate(txn, stateStore, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.State != c.state {
+			t.Fatalf("state %s, want %s: %s", result.State, c.state, result.Error)
+		}
+		caller := ToCodeHash(signer)
+		if len(result.Notifications) != 1 || result.Notifications[0].States.([]interface{})[0] != ToHexString(caller.ToArray()) {
+			t.Fatalf("%s: notifications %v, want the caller", c.state, result.Notifications)
+		}
+		if len(result.StorageChanges) != 1 || result.StorageChanges[0].Key != ToHexString([]byte("k")) ||
+			result.StorageChanges[0].Value != ToHexString([]byte("v")) {
+			t.Fatalf("%s: storage changes %v", c.state, result.StorageChanges)
+		}
+	}
+
+	txn, _ := tx.NewInvokeTransaction(nil, Uint160{1}, 0)
+	if _, err := simulate(txn, stateStore, 1); err != ErrContractNotFound {
+		t.Fatal("missing contract simulated:", err)
+	}
+	txn.TxType = tx.TransferAsset
+	if _, err := simulate(txn, stateStore, 1); err != ErrNotInvokeTransaction {
+		t.Fatal("transfer simulated:", err)
+	}
+}
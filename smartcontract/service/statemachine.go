@@ -344,6 +344,9 @@ func (s *StateMachine) StoragePut(engine *vm.ExecutionEngine) (bool, error) {
 		return false, errors.NewErr("[StoragePut] Get StorageContext nil")
 	}
 	context := opInterface.(*StorageContext)
+	if err := checkStorageWrite(engine, context); err != nil {
+		return false, err
+	}
 	key := vm.PopByteArray(engine)
 	if len(key) > 1024 {
 		return false, errors.NewErr("[StoragePut] Get Storage key to long")
@@ -366,6 +369,9 @@ func (s *StateMachine) StorageDelete(engine *vm.ExecutionEngine) (bool, error) {
 		return false, errors.NewErr("[StorageDelete] Get StorageContext nil")
 	}
 	context := opInterface.(*StorageContext)
+	if err := checkStorageWrite(engine, context); err != nil {
+		return false, err
+	}
 	key := vm.PopByteArray(engine)
 	k, err := serializeStorageKey(context.codeHash, key)
 	if err != nil {
@@ -436,6 +442,23 @@ func (s *StateMachine) GetStorageContext(engine *vm.ExecutionEngine) (bool, erro
 	return true, nil
 }
 
+// checkStorageWrite only lets the executing contract write to its own storage,
+// a storage context passed to a called contract can be read but not written
+func checkStorageWrite(engine *vm.ExecutionEngine, context *StorageContext) error {
+	current, err := engine.CurrentContext()
+	if err != nil {
+		return err
+	}
+	codeHash, err := current.GetCodeHash()
+	if err != nil {
+		return err
+	}
+	if codeHash.CompareTo(context.codeHash) != 0 {
+		return errors.NewErr("[checkStorageWrite] Storage context does not belong to the executing contract!")
+	}
+	return nil
+}
+
 func contains(programHashes []common.Uint160, programHash common.Uint160) bool {
 	for _, v := range programHashes {
 		if v.CompareTo(programHash) == 0 {
@@ -30,6 +30,10 @@ type StateReader struct {
 	serviceMap map[string]func(*vm.ExecutionEngine) (bool, error)
 	trigger    trigger.TriggerType
 	Notifications []*event.NotifyEventInfo
+	Logs          []*event.LogEventArgs
+	// Simulate keeps the notifications and logs without pushing them to
+	// the event subscribers
+	Simulate      bool
 }
 
 func NewStateReader(trigger trigger.TriggerType) *StateReader {
@@ -42,6 +46,7 @@ func NewStateReader(trigger trigger.TriggerType) *StateReader {
 	stateReader.Register("Neo.Runtime.CheckWitness", stateReader.RuntimeCheckWitness)
 	stateReader.Register("Neo.Runtime.Notify", stateReader.RuntimeNotify)
 	stateReader.Register("Neo.Runtime.Log", stateReader.RuntimeLog)
+	stateReader.Register("Neo.Runtime.GetCaller", stateReader.RuntimeGetCaller)
 
 	stateReader.Register("Neo.Blockchain.GetHeight", stateReader.BlockChainGetHeight)
 	stateReader.Register("Neo.Blockchain.GetHeader", stateReader.BlockChainGetHeader)
@@ -126,6 +131,22 @@ func (s *StateReader) RuntimeGetTime(e *vm.ExecutionEngine) (bool, error) {
 	return true, nil
 }
 
+// RuntimeGetCaller pushes the code hash of the contract which invoked the
+// executing one, the entry contract is invoked by the transaction invoker
+func (s *StateReader) RuntimeGetCaller(e *vm.ExecutionEngine) (bool, error) {
+	context, err := e.CurrentContext()
+	if err != nil {
+		return false, err
+	}
+	if context.IsContractCall() {
+		vm.PushData(e, context.CallingCodeHash.ToArray())
+		return true, nil
+	}
+	caller := e.GetCaller()
+	vm.PushData(e, caller.ToArray())
+	return true, nil
+}
+
 func (s *StateReader) RuntimeNotify(e *vm.ExecutionEngine) (bool, error) {
 	item := vm.PopStackItem(e)
 	container := e.GetCodeContainer()
@@ -148,7 +169,9 @@ func (s *StateReader) RuntimeNotify(e *vm.ExecutionEngine) (bool, error) {
 	}
 	txid := tran.Hash()
 	s.Notifications = append(s.Notifications, &event.NotifyEventInfo{Container: txid, CodeHash: hash, States: ConvertReturnTypes(item)})
-	event.PushSmartCodeEvent(tran.Hash(), 0, Notify, event.NotifyEventArgs{Container: txid, CodeHash: hash, States: item})
+	if !s.Simulate {
+		event.PushSmartCodeEvent(tran.Hash(), 0, Notify, event.NotifyEventArgs{Container: txid, CodeHash: hash, States: item})
+	}
 	return true, nil
 }
 
@@ -172,7 +195,10 @@ func (s *StateReader) RuntimeLog(e *vm.ExecutionEngine) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	event.PushSmartCodeEvent(tran.Hash(), 0, Log, event.LogEventArgs{tran.Hash(), hash, string(item)})
+	s.Logs = append(s.Logs, &event.LogEventArgs{Container: tran.Hash(), CodeHash: hash, Message: string(item)})
+	if !s.Simulate {
+		event.PushSmartCodeEvent(tran.Hash(), 0, Log, event.LogEventArgs{tran.Hash(), hash, string(item)})
+	}
 	return true, nil
 }
 
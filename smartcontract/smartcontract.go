@@ -18,10 +18,12 @@ package smartcontract
 
 import (
 	"github.com/Ontology/common"
+	"github.com/Ontology/common/config"
 	"github.com/Ontology/core/contract"
 	sig "github.com/Ontology/core/signature"
 	"github.com/Ontology/smartcontract/service"
 	"github.com/Ontology/smartcontract/types"
+	"github.com/Ontology/vm/evm"
 	"github.com/Ontology/vm/neovm"
 	"github.com/Ontology/vm/neovm/interfaces"
 	"math/big"
@@ -29,6 +31,8 @@ import (
 	"github.com/Ontology/errors"
 	"github.com/Ontology/common/log"
 	scommon "github.com/Ontology/smartcontract/common"
+	"github.com/Ontology/smartcontract/event"
+	"github.com/Ontology/smartcontract/storage"
 	"reflect"
 )
 
@@ -69,11 +73,32 @@ func NewSmartContract(context *Context) (*SmartContract, error) {
 	var e Engine
 	switch context.VmType {
 	case types.NEOVM:
-		e = neovm.NewExecutionEngine(
+		engine := neovm.NewExecutionEngine(
 			context.SignableData,
 			new(neovm.ECDsaCrypto),
 			context.CacheCodeTable,
 			context.StateMachine,
+			context.Gas,
+		)
+		if config.Parameters.MaxCallDepth > 0 {
+			engine.SetMaxCallDepth(config.Parameters.MaxCallDepth)
+		}
+		e = engine
+	case types.EVM:
+		var cache evm.IStorage
+		if context.StateMachine != nil {
+			cache = context.StateMachine.CloneCache
+		} else if context.DBCache != nil {
+			cache = storage.NewCloneCache(context.DBCache)
+		} else {
+			return nil, errors.NewErr("[NewSmartContract] EVM needs a state store!")
+		}
+		e = evm.NewExecutionEngine(
+			cache,
+			context.CodeHash,
+			context.Time,
+			context.BlockNumber,
+			context.Gas,
 		)
 	default:
 		return nil, errors.NewErr("[NewSmartContract] Invalid vm type!")
@@ -102,6 +127,36 @@ func (sc *SmartContract) InvokeContract() (interface{}, error) {
 	return sc.InvokeResult()
 }
 
+func (sc *SmartContract) GasConsumed() common.Fixed64 {
+	switch sc.VMType {
+	case types.NEOVM:
+		engine := sc.Engine.(*neovm.ExecutionEngine)
+		return common.Fixed64(engine.GasConsumed())
+	case types.EVM:
+		engine := sc.Engine.(*evm.ExecutionEngine)
+		return common.Fixed64(engine.GasConsumed())
+	}
+	return common.Fixed64(0)
+}
+
+// Notifications returns the logs of an EVM contract as notifications of the
+// transaction txid, NEOVM contracts notify through the state machine
+func (sc *SmartContract) Notifications(txid common.Uint256) []*event.NotifyEventInfo {
+	engine, ok := sc.Engine.(*evm.ExecutionEngine)
+	if !ok {
+		return nil
+	}
+	var notifications []*event.NotifyEventInfo
+	for _, l := range engine.Logs() {
+		var states []interface{}
+		for _, topic := range l.Topics {
+			states = append(states, common.ToHexString(topic.ToArray()))
+		}
+		states = append(states, common.ToHexString(l.Data))
+		notifications = append(notifications, &event.NotifyEventInfo{Container: txid, CodeHash: l.Address, States: states})
+	}
+	return notifications
+}
 
 func (sc *SmartContract) InvokeResult() (interface{}, error) {
 	switch sc.VMType {
@@ -138,6 +193,22 @@ func (sc *SmartContract) InvokeResult() (interface{}, error) {
 				return common.ToHexString(neovm.PopByteArray(engine)), nil
 			}
 		}
+	case types.EVM:
+		engine := sc.Engine.(*evm.ExecutionEngine)
+		ret := engine.ReturnData()
+		if len(ret) == 0 {
+			return nil, nil
+		}
+		switch sc.ReturnType {
+		case contract.Boolean:
+			return new(big.Int).SetBytes(ret).Sign() != 0, nil
+		case contract.Integer:
+			return evm.S256(new(big.Int).SetBytes(ret)).Int64(), nil
+		case contract.String:
+			return evm.DecodeString(ret)
+		default:
+			return common.ToHexString(ret), nil
+		}
 	}
 	return nil, nil
 }
\ No newline at end of file
@@ -4,6 +4,7 @@ import (
 	crand "crypto/rand"
 	"testing"
 	"bytes"
+	"github.com/Ontology/common"
 )
 
 func TestSimpleProof(t *testing.T) {
@@ -44,6 +45,34 @@ func TestRandomTrie(t *testing.T) {
 	t.Log("Test Random Trie Proof Successful")
 }
 
+func TestSecureProof(t *testing.T) {
+	trie, err := NewSecure(common.Uint256{}, NewMemDatabase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, vals := randomTrie(100)
+	for _, kv := range vals {
+		trie.Update(kv.k, kv.v)
+	}
+	root, err := trie.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kv := range vals {
+		val, err := VerifySecureProof(root, kv.k, trie.Prove(kv.k))
+		if err != nil {
+			t.Fatalf("VerifySecureProof error for key %x: %v", kv.k, err)
+		}
+		if !bytes.Equal(val, kv.v) {
+			t.Fatalf("VerifySecureProof returned wrong value for key %x: got %x, want %x", kv.k, val, kv.v)
+		}
+	}
+	val, err := VerifySecureProof(root, []byte("missing"), trie.Prove([]byte("missing")))
+	if err != nil || val != nil {
+		t.Fatalf("VerifySecureProof of missing key: %x, %v", val, err)
+	}
+}
+
 type kv struct {
 	k, v []byte
 	t    bool
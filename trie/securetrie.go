@@ -4,6 +4,7 @@ import (
 	"github.com/Ontology/common"
 	"github.com/Ontology/common/log"
 	"fmt"
+	"github.com/Ontology/rlp"
 )
 
 var secureKeyPrefix = []byte{20}
@@ -112,4 +113,13 @@ func (t *SecureTrie) Hash() common.Uint256 {
 func (t *SecureTrie) Copy() *SecureTrie {
 	cpy := *t
 	return &cpy
-}
\ No newline at end of file
+}
+// Prove returns the proof of the hashed key, see Trie.Prove
+func (t *SecureTrie) Prove(key []byte) []rlp.RawValue {
+	return t.trie.Prove(t.hashKey(key))
+}
+
+// VerifySecureProof checks a proof returned by SecureTrie.Prove
+func VerifySecureProof(rootHash common.Uint256, key []byte, proof []rlp.RawValue) ([]byte, error) {
+	return VerifyProof(rootHash, ToHash256(key), proof)
+}
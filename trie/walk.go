@@ -0,0 +1,88 @@
+package trie
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/Ontology/common"
+)
+
+// SkipNode is returned by the onNode callback of Walk to skip the children
+// of the node, e.g. of a node already visited from another root
+var SkipNode = errors.New("skip node")
+
+// Walk visits every node of the trie with the given root in db. The nodes
+// loaded from db are checked against the hash they are referenced by, onNode
+// is called with the hash and the encoding of each of them and onLeaf with
+// the hashed key and the value of every leaf. Either callback may be nil.
+func Walk(root common.Uint256, db DatabaseReader, onNode func(hash, enc []byte) error, onLeaf func(key, value []byte) error) error {
+	if root == (common.Uint256{}) {
+		return nil
+	}
+	w := &walker{db: db, onNode: onNode, onLeaf: onLeaf}
+	return w.walkHash(root.ToArray(), nil)
+}
+
+// NodeKey returns the database key of the node with the given hash
+func NodeKey(hash []byte) []byte {
+	return append(append([]byte{}, secureKeyPrefix...), hash...)
+}
+
+type walker struct {
+	db     DatabaseReader
+	onNode func(hash, enc []byte) error
+	onLeaf func(key, value []byte) error
+}
+
+func (w *walker) walkHash(hash hashNode, path []byte) error {
+	enc, err := w.db.Get(NodeKey(hash))
+	if err != nil {
+		return fmt.Errorf("[Walk] missing node %x: %v", []byte(hash), err)
+	}
+	if !bytes.Equal(ToHash256(enc), hash) {
+		return fmt.Errorf("[Walk] node %x hash mismatch", []byte(hash))
+	}
+	n, err := decodeNode(hash, enc)
+	if err != nil {
+		return fmt.Errorf("[Walk] bad node %x: %v", []byte(hash), err)
+	}
+	if w.onNode != nil {
+		if err := w.onNode(hash, enc); err == SkipNode {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return w.walk(n, path)
+}
+
+func (w *walker) walk(n node, path []byte) error {
+	switch n := n.(type) {
+	case nil:
+		return nil
+	case hashNode:
+		return w.walkHash(n, path)
+	case *shortNode:
+		return w.walk(n.Val, concat(path, n.Key...))
+	case *fullNode:
+		for i, child := range n.Children {
+			if err := w.walk(child, concat(path, byte(i))); err != nil {
+				return err
+			}
+		}
+		return nil
+	case valueNode:
+		if !hasTerm(path) || len(path)%2 != 1 {
+			return fmt.Errorf("[Walk] value at invalid path %x", path)
+		}
+		key := make([]byte, len(path)/2)
+		decodeNibbles(path[:len(path)-1], key)
+		if w.onLeaf != nil {
+			return w.onLeaf(key, n)
+		}
+		return nil
+	default:
+		return fmt.Errorf("[Walk] invalid node type %T", n)
+	}
+}
@@ -0,0 +1,65 @@
+package trie
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Ontology/common"
+)
+
+func TestWalk(t *testing.T) {
+	db := NewMemDatabase()
+	trie, err := NewSecure(common.Uint256{}, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, vals := randomTrie(100)
+	for _, kv := range vals {
+		trie.Update(kv.k, ToHash256(kv.v))
+	}
+	root, err := trie.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodes := 0
+	leaves := make(map[string][]byte)
+	err = Walk(root, db, func(hash, enc []byte) error {
+		nodes++
+		return nil
+	}, func(key, value []byte) error {
+		leaves[string(key)] = value
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nodes == 0 || len(leaves) != len(vals) {
+		t.Fatalf("walked %d nodes and %d leaves, want %d leaves", nodes, len(leaves), len(vals))
+	}
+	for _, kv := range vals {
+		if !bytes.Equal(leaves[string(ToHash256(kv.k))], ToHash256(kv.v)) {
+			t.Fatalf("leaf of key %x not found", kv.k)
+		}
+	}
+
+	skipped := 0
+	err = Walk(root, db, func(hash, enc []byte) error {
+		return SkipNode
+	}, func(key, value []byte) error {
+		skipped++
+		return nil
+	})
+	if err != nil || skipped != 0 {
+		t.Fatal("children of a skipped node walked:", skipped, err)
+	}
+
+	// a tampered node doesn't match the hash it is referenced by
+	rootKey := NodeKey(root.ToArray())
+	enc, _ := db.Get(rootKey)
+	enc[len(enc)-1] ^= 0xff
+	db.BatchPut(rootKey, enc)
+	if err := Walk(root, db, nil, nil); err == nil {
+		t.Fatal("tampered node not detected")
+	}
+}
@@ -0,0 +1,68 @@
+package evm
+
+import (
+	"math/big"
+
+	. "github.com/Ontology/vm/evm/errors"
+)
+
+var (
+	tt255   = new(big.Int).Lsh(big.NewInt(1), 255)
+	tt256   = new(big.Int).Lsh(big.NewInt(1), 256)
+	tt256m1 = new(big.Int).Sub(tt256, big.NewInt(1))
+)
+
+// U256 wraps x into [0, 2^256), x is modified
+func U256(x *big.Int) *big.Int {
+	return x.And(x, tt256m1)
+}
+
+// S256 interprets the word x as a two's complement signed integer
+func S256(x *big.Int) *big.Int {
+	if x.Cmp(tt255) < 0 {
+		return x
+	}
+	return new(big.Int).Sub(x, tt256)
+}
+
+// Word returns x as a 32 bytes big endian word
+func Word(x *big.Int) []byte {
+	buf := make([]byte, 32)
+	b := x.Bytes()
+	copy(buf[32-len(b):], b)
+	return buf
+}
+
+func boolToWord(b bool) *big.Int {
+	if b {
+		return big.NewInt(1)
+	}
+	return new(big.Int)
+}
+
+// getData returns size bytes of data at offset, padded with zeros
+func getData(data []byte, offset *big.Int, size uint64) []byte {
+	buf := make([]byte, size)
+	if !offset.IsUint64() || offset.Uint64() >= uint64(len(data)) {
+		return buf
+	}
+	copy(buf, data[offset.Uint64():])
+	return buf
+}
+
+// DecodeString decodes the ABI encoding of a string returned by a contract
+func DecodeString(ret []byte) (string, error) {
+	if len(ret) < 64 {
+		return "", ErrBadReturnValue
+	}
+	offset := new(big.Int).SetBytes(ret[:32])
+	if !offset.IsUint64() || offset.Uint64() > uint64(len(ret))-32 {
+		return "", ErrBadReturnValue
+	}
+	start := offset.Uint64() + 32
+	size := new(big.Int).SetBytes(ret[offset.Uint64():start])
+	if !size.IsUint64() || size.Uint64() > uint64(len(ret))-start {
+		return "", ErrBadReturnValue
+	}
+	return string(ret[start : start+size.Uint64()]), nil
+}
@@ -0,0 +1,19 @@
+package errors
+
+import "errors"
+
+var (
+	ErrOutOfGas              = errors.New("out of gas")
+	ErrStackUnderflow        = errors.New("the count under the stack length")
+	ErrStackOverflow         = errors.New("the stack over max size")
+	ErrInvalidJump           = errors.New("invalid jump destination")
+	ErrInvalidOpCode         = errors.New("invalid operation code")
+	ErrNotSupportOpCode      = errors.New("does not support the operation code")
+	ErrExecutionReverted     = errors.New("execution reverted")
+	ErrMemoryOverflow        = errors.New("the memory over max size")
+	ErrReturnDataOutOfBounds = errors.New("return data out of bounds")
+	ErrCodeSizeLimit         = errors.New("the contract code over max size")
+	ErrStorageNil            = errors.New("storage is nil")
+	ErrBadStorageValue       = errors.New("bad storage value")
+	ErrBadReturnValue        = errors.New("bad return value")
+)
@@ -0,0 +1,162 @@
+package evm
+
+import (
+	"math/big"
+
+	"github.com/Ontology/common"
+	"github.com/Ontology/core/states"
+	"github.com/Ontology/core/store"
+	. "github.com/Ontology/vm/evm/errors"
+)
+
+// IStorage is the contract storage of the engine, CloneCache implements it
+// on top of the IStateStore of the ledger
+type IStorage interface {
+	Get(prefix store.DataEntryPrefix, key []byte) (states.IStateValue, error)
+	Add(prefix store.DataEntryPrefix, key []byte, value states.IStateValue)
+	Delete(prefix store.DataEntryPrefix, key []byte)
+}
+
+// Log is a record appended by the LOG0-LOG4 instructions
+type Log struct {
+	Address common.Uint160
+	Topics  []common.Uint256
+	Data    []byte
+}
+
+func NewExecutionEngine(storage IStorage, codeHash common.Uint160, time, blockNumber *big.Int, gas common.Fixed64) *ExecutionEngine {
+	var engine ExecutionEngine
+
+	engine.storage = storage
+	engine.codeHash = codeHash
+	engine.time = new(big.Int)
+	if time != nil {
+		engine.time.Set(time)
+	}
+	engine.blockNumber = new(big.Int)
+	if blockNumber != nil {
+		engine.blockNumber.Set(blockNumber)
+	}
+
+	engine.gas = GasFree + gas.GetData()
+	engine.gasConsumed = 0
+	return &engine
+}
+
+type ExecutionEngine struct {
+	storage     IStorage
+	codeHash    common.Uint160
+	time        *big.Int
+	blockNumber *big.Int
+
+	caller    common.Uint160
+	code      []byte
+	input     []byte
+	jumpDests []bool
+
+	stack      *Stack
+	memory     *Memory
+	returnData []byte
+	output     []byte
+	logs       []*Log
+
+	//current opcode and the position after it
+	opCode OpCode
+	pc     uint64
+	halted bool
+
+	gas         int64
+	gasConsumed int64
+}
+
+// Create runs the init code of a contract and returns the runtime code to store
+func (e *ExecutionEngine) Create(caller common.Uint160, code []byte) ([]byte, error) {
+	ret, err := e.run(caller, code, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(ret) > MaxCodeSize {
+		return nil, ErrCodeSizeLimit
+	}
+	return ret, nil
+}
+
+// Call runs the runtime code of a contract with input as call data
+func (e *ExecutionEngine) Call(caller common.Uint160, code, input []byte) ([]byte, error) {
+	return e.run(caller, code, input)
+}
+
+// ReturnData returns the output of the last RETURN or REVERT
+func (e *ExecutionEngine) ReturnData() []byte {
+	return e.output
+}
+
+// Logs returns the records logged by the execution
+func (e *ExecutionEngine) Logs() []*Log {
+	return e.logs
+}
+
+func (e *ExecutionEngine) run(caller common.Uint160, code, input []byte) ([]byte, error) {
+	if e.storage == nil {
+		return nil, ErrStorageNil
+	}
+	e.caller = caller
+	e.code = code
+	e.input = input
+	e.jumpDests = jumpDestAnalysis(code)
+	e.stack = NewStack()
+	e.memory = NewMemory()
+	e.returnData = nil
+	e.output = nil
+	e.logs = nil
+	e.pc = 0
+	e.halted = false
+
+	for !e.halted {
+		if err := e.StepInto(); err != nil {
+			if err != ErrExecutionReverted {
+				e.output = nil
+			}
+			return nil, err
+		}
+	}
+	return e.output, nil
+}
+
+func (e *ExecutionEngine) StepInto() error {
+	if e.pc >= uint64(len(e.code)) {
+		e.halted = true
+		return nil
+	}
+	e.opCode = OpCode(e.code[e.pc])
+	e.pc++
+
+	opExec := OpExecList[e.opCode]
+	if opExec.Exec == nil {
+		return ErrInvalidOpCode
+	}
+	if e.stack.Len() < opExec.Pops {
+		return ErrStackUnderflow
+	}
+	if e.stack.Len()-opExec.Pops+opExec.Pushes > StackLimit {
+		return ErrStackOverflow
+	}
+	if !e.useGas(opExec.Price) {
+		return ErrOutOfGas
+	}
+	return opExec.Exec(e)
+}
+
+// jumpDestAnalysis marks the JUMPDEST instructions of code, skipping push data
+func jumpDestAnalysis(code []byte) []bool {
+	dests := make([]bool, len(code))
+	for pc := 0; pc < len(code); pc++ {
+		op := OpCode(code[pc])
+		if op == JUMPDEST {
+			dests[pc] = true
+		} else if op >= PUSH1 && op <= PUSH32 {
+			pc += int(op-PUSH1) + 1
+		}
+	}
+	return dests
+}
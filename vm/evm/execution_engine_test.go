@@ -0,0 +1,147 @@
+package evm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Ontology/common"
+	"github.com/Ontology/core/states"
+	"github.com/Ontology/core/store"
+	. "github.com/Ontology/vm/evm/errors"
+)
+
+type memStorage map[string]states.IStateValue
+
+func (m memStorage) Get(prefix store.DataEntryPrefix, key []byte) (states.IStateValue, error) {
+	return m[string(append([]byte{byte(prefix)}, key...))], nil
+}
+
+func (m memStorage) Add(prefix store.DataEntryPrefix, key []byte, value states.IStateValue) {
+	m[string(append([]byte{byte(prefix)}, key...))] = value
+}
+
+func (m memStorage) Delete(prefix store.DataEntryPrefix, key []byte) {
+	delete(m, string(append([]byte{byte(prefix)}, key...)))
+}
+
+func run(t *testing.T, storage IStorage, hexCode string, gas common.Fixed64) ([]byte, error) {
+	code, err := common.HexToBytes(hexCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewExecutionEngine(storage, common.Uint160{1}, big.NewInt(100), big.NewInt(7), gas)
+	return engine.Call(common.Uint160{2}, code, nil)
+}
+
+func TestExecutionEngine_Call(t *testing.T) {
+	word := func(x int64) string {
+		return common.ToHexString(Word(U256(big.NewInt(x))))
+	}
+	cases := []struct {
+		code   string
+		result string
+	}{
+		// 2 + 3
+		{"600260030160005260206000f3", word(5)},
+		// -6 / 2
+		{"60027ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffa0560005260206000f3", word(-3)},
+		// -8 >> 1
+		{"7ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff860011d60005260206000f3", word(-4)},
+		// keccak256 of nothing
+		{"600060002060005260206000f3", "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		// block number and timestamp
+		{"436000524260205260406000f3", word(7) + word(100)},
+	}
+	for i, c := range cases {
+		ret, err := run(t, memStorage{}, c.code, 0)
+		if err != nil {
+			t.Errorf("case %d: %v", i, err)
+			continue
+		}
+		if common.ToHexString(ret) != c.result {
+			t.Errorf("case %d: expected %s, got %x", i, c.result, ret)
+		}
+	}
+}
+
+func TestExecutionEngine_Create(t *testing.T) {
+	storage := memStorage{}
+	// stores 7 at slot 0 and returns the runtime code which increments slot 0
+	// and returns the new value
+	initCode := mustHex(t, "6007600055"+"601260116000"+"39"+"60126000f3"+
+		"600054600101806000556000526020"+"6000f3")
+	engine := NewExecutionEngine(storage, common.Uint160{1}, nil, nil, 0)
+	code, err := engine.Create(common.Uint160{2}, initCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code) != 18 {
+		t.Fatalf("unexpected runtime code %x", code)
+	}
+	for _, expected := range []int64{8, 9} {
+		engine := NewExecutionEngine(storage, common.Uint160{1}, nil, nil, 0)
+		ret, err := engine.Call(common.Uint160{2}, code, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if new(big.Int).SetBytes(ret).Int64() != expected {
+			t.Errorf("expected %d, got %x", expected, ret)
+		}
+		if engine.GasConsumed() <= 0 {
+			t.Error("no gas consumed")
+		}
+	}
+
+	// another contract does not see the storage
+	engine = NewExecutionEngine(storage, common.Uint160{3}, nil, nil, 0)
+	ret, err := engine.Call(common.Uint160{2}, code, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if new(big.Int).SetBytes(ret).Int64() != 1 {
+		t.Errorf("expected 1, got %x", ret)
+	}
+}
+
+func TestExecutionEngine_Errors(t *testing.T) {
+	if _, err := run(t, memStorage{}, "60006000fd", 0); err != ErrExecutionReverted {
+		t.Error("expected revert, got", err)
+	}
+	if _, err := run(t, memStorage{}, "5b600056", 0); err != ErrOutOfGas {
+		t.Error("expected out of gas, got", err)
+	}
+	if _, err := run(t, memStorage{}, "600356", 0); err != ErrInvalidJump {
+		t.Error("expected invalid jump, got", err)
+	}
+	// the JUMPDEST is push data
+	if _, err := run(t, memStorage{}, "600456605b", 0); err != ErrInvalidJump {
+		t.Error("expected invalid jump, got", err)
+	}
+	if _, err := run(t, memStorage{}, "01", 0); err != ErrStackUnderflow {
+		t.Error("expected stack underflow, got", err)
+	}
+	if _, err := run(t, memStorage{}, "6000600060006000600060006000f1", 0); err != ErrNotSupportOpCode {
+		t.Error("expected unsupported opcode, got", err)
+	}
+}
+
+func TestDecodeString(t *testing.T) {
+	ret := mustHex(t, "0000000000000000000000000000000000000000000000000000000000000020"+
+		"0000000000000000000000000000000000000000000000000000000000000005"+
+		"68656c6c6f000000000000000000000000000000000000000000000000000000")
+	str, err := DecodeString(ret)
+	if err != nil || str != "hello" {
+		t.Errorf("expected hello, got %q %v", str, err)
+	}
+	if _, err := DecodeString(ret[:40]); err == nil {
+		t.Error("short return value decoded")
+	}
+}
+
+func mustHex(t *testing.T, str string) []byte {
+	buf, err := common.HexToBytes(str)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
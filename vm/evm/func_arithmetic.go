@@ -0,0 +1,149 @@
+package evm
+
+import (
+	"math/big"
+
+	. "github.com/Ontology/vm/evm/errors"
+)
+
+func opAdd(e *ExecutionEngine) error {
+	x, y := e.stack.Pop(), e.stack.Pop()
+	e.stack.Push(U256(x.Add(x, y)))
+	return nil
+}
+
+func opMul(e *ExecutionEngine) error {
+	x, y := e.stack.Pop(), e.stack.Pop()
+	e.stack.Push(U256(x.Mul(x, y)))
+	return nil
+}
+
+func opSub(e *ExecutionEngine) error {
+	x, y := e.stack.Pop(), e.stack.Pop()
+	e.stack.Push(U256(x.Sub(x, y)))
+	return nil
+}
+
+func opDiv(e *ExecutionEngine) error {
+	x, y := e.stack.Pop(), e.stack.Pop()
+	if y.Sign() == 0 {
+		e.stack.Push(new(big.Int))
+		return nil
+	}
+	e.stack.Push(x.Div(x, y))
+	return nil
+}
+
+func opSdiv(e *ExecutionEngine) error {
+	x, y := S256(e.stack.Pop()), S256(e.stack.Pop())
+	if y.Sign() == 0 {
+		e.stack.Push(new(big.Int))
+		return nil
+	}
+	e.stack.Push(U256(new(big.Int).Quo(x, y)))
+	return nil
+}
+
+func opMod(e *ExecutionEngine) error {
+	x, y := e.stack.Pop(), e.stack.Pop()
+	if y.Sign() == 0 {
+		e.stack.Push(new(big.Int))
+		return nil
+	}
+	e.stack.Push(x.Mod(x, y))
+	return nil
+}
+
+func opSmod(e *ExecutionEngine) error {
+	x, y := S256(e.stack.Pop()), S256(e.stack.Pop())
+	if y.Sign() == 0 {
+		e.stack.Push(new(big.Int))
+		return nil
+	}
+	e.stack.Push(U256(new(big.Int).Rem(x, y)))
+	return nil
+}
+
+func opAddmod(e *ExecutionEngine) error {
+	x, y, z := e.stack.Pop(), e.stack.Pop(), e.stack.Pop()
+	if z.Sign() == 0 {
+		e.stack.Push(new(big.Int))
+		return nil
+	}
+	x.Add(x, y)
+	e.stack.Push(x.Mod(x, z))
+	return nil
+}
+
+func opMulmod(e *ExecutionEngine) error {
+	x, y, z := e.stack.Pop(), e.stack.Pop(), e.stack.Pop()
+	if z.Sign() == 0 {
+		e.stack.Push(new(big.Int))
+		return nil
+	}
+	x.Mul(x, y)
+	e.stack.Push(x.Mod(x, z))
+	return nil
+}
+
+func opExp(e *ExecutionEngine) error {
+	base, exponent := e.stack.Pop(), e.stack.Pop()
+	if !e.useGas(gasExpByte * int64((exponent.BitLen()+7)/8)) {
+		return ErrOutOfGas
+	}
+	e.stack.Push(base.Exp(base, exponent, tt256))
+	return nil
+}
+
+func opSignExtend(e *ExecutionEngine) error {
+	back, num := e.stack.Pop(), e.stack.Pop()
+	if back.Cmp(big.NewInt(31)) < 0 {
+		bit := uint(back.Uint64()*8 + 7)
+		mask := new(big.Int).Lsh(big.NewInt(1), bit)
+		mask.Sub(mask, big.NewInt(1))
+		if num.Bit(int(bit)) > 0 {
+			num.Or(num, new(big.Int).Not(mask))
+		} else {
+			num.And(num, mask)
+		}
+		U256(num)
+	}
+	e.stack.Push(num)
+	return nil
+}
+
+func opLt(e *ExecutionEngine) error {
+	x, y := e.stack.Pop(), e.stack.Pop()
+	e.stack.Push(boolToWord(x.Cmp(y) < 0))
+	return nil
+}
+
+func opGt(e *ExecutionEngine) error {
+	x, y := e.stack.Pop(), e.stack.Pop()
+	e.stack.Push(boolToWord(x.Cmp(y) > 0))
+	return nil
+}
+
+func opSlt(e *ExecutionEngine) error {
+	x, y := S256(e.stack.Pop()), S256(e.stack.Pop())
+	e.stack.Push(boolToWord(x.Cmp(y) < 0))
+	return nil
+}
+
+func opSgt(e *ExecutionEngine) error {
+	x, y := S256(e.stack.Pop()), S256(e.stack.Pop())
+	e.stack.Push(boolToWord(x.Cmp(y) > 0))
+	return nil
+}
+
+func opEq(e *ExecutionEngine) error {
+	x, y := e.stack.Pop(), e.stack.Pop()
+	e.stack.Push(boolToWord(x.Cmp(y) == 0))
+	return nil
+}
+
+func opIsZero(e *ExecutionEngine) error {
+	x := e.stack.Pop()
+	e.stack.Push(boolToWord(x.Sign() == 0))
+	return nil
+}
@@ -0,0 +1,71 @@
+package evm
+
+import "math/big"
+
+func opAnd(e *ExecutionEngine) error {
+	x, y := e.stack.Pop(), e.stack.Pop()
+	e.stack.Push(x.And(x, y))
+	return nil
+}
+
+func opOr(e *ExecutionEngine) error {
+	x, y := e.stack.Pop(), e.stack.Pop()
+	e.stack.Push(x.Or(x, y))
+	return nil
+}
+
+func opXor(e *ExecutionEngine) error {
+	x, y := e.stack.Pop(), e.stack.Pop()
+	e.stack.Push(x.Xor(x, y))
+	return nil
+}
+
+func opNot(e *ExecutionEngine) error {
+	x := e.stack.Pop()
+	e.stack.Push(U256(x.Not(x)))
+	return nil
+}
+
+func opByte(e *ExecutionEngine) error {
+	i, x := e.stack.Pop(), e.stack.Pop()
+	if i.Cmp(big.NewInt(32)) >= 0 {
+		e.stack.Push(new(big.Int))
+		return nil
+	}
+	e.stack.Push(new(big.Int).SetUint64(uint64(Word(x)[i.Uint64()])))
+	return nil
+}
+
+func opShl(e *ExecutionEngine) error {
+	shift, value := e.stack.Pop(), e.stack.Pop()
+	if shift.Cmp(big.NewInt(256)) >= 0 {
+		e.stack.Push(new(big.Int))
+		return nil
+	}
+	e.stack.Push(U256(value.Lsh(value, uint(shift.Uint64()))))
+	return nil
+}
+
+func opShr(e *ExecutionEngine) error {
+	shift, value := e.stack.Pop(), e.stack.Pop()
+	if shift.Cmp(big.NewInt(256)) >= 0 {
+		e.stack.Push(new(big.Int))
+		return nil
+	}
+	e.stack.Push(value.Rsh(value, uint(shift.Uint64())))
+	return nil
+}
+
+func opSar(e *ExecutionEngine) error {
+	shift, value := e.stack.Pop(), S256(e.stack.Pop())
+	if shift.Cmp(big.NewInt(256)) >= 0 {
+		if value.Sign() < 0 {
+			e.stack.Push(new(big.Int).Set(tt256m1))
+		} else {
+			e.stack.Push(new(big.Int))
+		}
+		return nil
+	}
+	e.stack.Push(U256(new(big.Int).Rsh(value, uint(shift.Uint64()))))
+	return nil
+}
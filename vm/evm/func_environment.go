@@ -0,0 +1,97 @@
+package evm
+
+import (
+	"math/big"
+
+	. "github.com/Ontology/vm/evm/errors"
+)
+
+func opAddress(e *ExecutionEngine) error {
+	e.stack.Push(new(big.Int).SetBytes(e.codeHash[:]))
+	return nil
+}
+
+func opCaller(e *ExecutionEngine) error {
+	e.stack.Push(new(big.Int).SetBytes(e.caller[:]))
+	return nil
+}
+
+func opPushZero(e *ExecutionEngine) error {
+	e.stack.Push(new(big.Int))
+	return nil
+}
+
+func opCallDataLoad(e *ExecutionEngine) error {
+	offset := e.stack.Pop()
+	e.stack.Push(new(big.Int).SetBytes(getData(e.input, offset, 32)))
+	return nil
+}
+
+func opCallDataSize(e *ExecutionEngine) error {
+	e.stack.Push(big.NewInt(int64(len(e.input))))
+	return nil
+}
+
+func opCallDataCopy(e *ExecutionEngine) error {
+	return e.copyToMemory(e.input)
+}
+
+func opCodeSize(e *ExecutionEngine) error {
+	e.stack.Push(big.NewInt(int64(len(e.code))))
+	return nil
+}
+
+func opCodeCopy(e *ExecutionEngine) error {
+	return e.copyToMemory(e.code)
+}
+
+func opReturnDataSize(e *ExecutionEngine) error {
+	e.stack.Push(big.NewInt(int64(len(e.returnData))))
+	return nil
+}
+
+func opReturnDataCopy(e *ExecutionEngine) error {
+	end := new(big.Int).Add(e.stack.Back(1), e.stack.Back(2))
+	if end.Cmp(big.NewInt(int64(len(e.returnData)))) > 0 {
+		return ErrReturnDataOutOfBounds
+	}
+	return e.copyToMemory(e.returnData)
+}
+
+func opTimestamp(e *ExecutionEngine) error {
+	e.stack.Push(new(big.Int).Set(e.time))
+	return nil
+}
+
+func opNumber(e *ExecutionEngine) error {
+	e.stack.Push(new(big.Int).Set(e.blockNumber))
+	return nil
+}
+
+func opGasLimit(e *ExecutionEngine) error {
+	e.stack.Push(big.NewInt(e.gas / GasRatio))
+	return nil
+}
+
+func opGas(e *ExecutionEngine) error {
+	e.stack.Push(big.NewInt(e.gasLeft()))
+	return nil
+}
+
+func opNotSupport(e *ExecutionEngine) error {
+	return ErrNotSupportOpCode
+}
+
+// copyToMemory pops memory offset, data offset and size and copies data to memory
+func (e *ExecutionEngine) copyToMemory(data []byte) error {
+	memOffset, dataOffset, size := e.stack.Pop(), e.stack.Pop(), e.stack.Pop()
+	off, sz, err := e.memoryRange(memOffset, size)
+	if err != nil {
+		return err
+	}
+	if !e.useGas(gasCopyWord * int64(toWords(sz))) {
+		return ErrOutOfGas
+	}
+	e.memory.Set(off, getData(data, dataOffset, sz))
+	return nil
+}
@@ -0,0 +1,59 @@
+package evm
+
+import (
+	"math/big"
+
+	. "github.com/Ontology/vm/evm/errors"
+)
+
+func opStop(e *ExecutionEngine) error {
+	e.halted = true
+	return nil
+}
+
+func opJump(e *ExecutionEngine) error {
+	return e.jump(e.stack.Pop())
+}
+
+func opJumpi(e *ExecutionEngine) error {
+	dest, cond := e.stack.Pop(), e.stack.Pop()
+	if cond.Sign() == 0 {
+		return nil
+	}
+	return e.jump(dest)
+}
+
+func opJumpDest(e *ExecutionEngine) error {
+	return nil
+}
+
+func opPc(e *ExecutionEngine) error {
+	e.stack.Push(new(big.Int).SetUint64(e.pc - 1))
+	return nil
+}
+
+func opReturn(e *ExecutionEngine) error {
+	offset, size := e.stack.Pop(), e.stack.Pop()
+	off, sz, err := e.memoryRange(offset, size)
+	if err != nil {
+		return err
+	}
+	e.output = e.memory.Get(off, sz)
+	e.halted = true
+	return nil
+}
+
+func opRevert(e *ExecutionEngine) error {
+	if err := opReturn(e); err != nil {
+		return err
+	}
+	return ErrExecutionReverted
+}
+
+func (e *ExecutionEngine) jump(dest *big.Int) error {
+	if !dest.IsUint64() || dest.Uint64() >= uint64(len(e.code)) || !e.jumpDests[dest.Uint64()] {
+		return ErrInvalidJump
+	}
+	e.pc = dest.Uint64()
+	return nil
+}
@@ -0,0 +1,132 @@
+package evm
+
+import (
+	"math/big"
+
+	"github.com/Ontology/common"
+	"github.com/Ontology/core/states"
+	"github.com/Ontology/core/store"
+	. "github.com/Ontology/vm/evm/errors"
+	"golang.org/x/crypto/sha3"
+)
+
+func opMload(e *ExecutionEngine) error {
+	offset := e.stack.Pop()
+	off, _, err := e.memoryRange(offset, big.NewInt(32))
+	if err != nil {
+		return err
+	}
+	e.stack.Push(new(big.Int).SetBytes(e.memory.Get(off, 32)))
+	return nil
+}
+
+func opMstore(e *ExecutionEngine) error {
+	offset, value := e.stack.Pop(), e.stack.Pop()
+	off, _, err := e.memoryRange(offset, big.NewInt(32))
+	if err != nil {
+		return err
+	}
+	e.memory.Set(off, Word(value))
+	return nil
+}
+
+func opMstore8(e *ExecutionEngine) error {
+	offset, value := e.stack.Pop(), e.stack.Pop()
+	off, _, err := e.memoryRange(offset, big.NewInt(1))
+	if err != nil {
+		return err
+	}
+	e.memory.Set(off, []byte{byte(value.Uint64())})
+	return nil
+}
+
+func opMsize(e *ExecutionEngine) error {
+	e.stack.Push(new(big.Int).SetUint64(e.memory.Len()))
+	return nil
+}
+
+func opSha3(e *ExecutionEngine) error {
+	offset, size := e.stack.Pop(), e.stack.Pop()
+	off, sz, err := e.memoryRange(offset, size)
+	if err != nil {
+		return err
+	}
+	if !e.useGas(gasSha3Word * int64(toWords(sz))) {
+		return ErrOutOfGas
+	}
+	hasher := sha3.NewLegacyKeccak256()
+	hasher.Write(e.memory.Get(off, sz))
+	e.stack.Push(new(big.Int).SetBytes(hasher.Sum(nil)))
+	return nil
+}
+
+func opSload(e *ExecutionEngine) error {
+	key := e.stack.Pop()
+	value, err := e.storageGet(key)
+	if err != nil {
+		return err
+	}
+	e.stack.Push(value)
+	return nil
+}
+
+func opSstore(e *ExecutionEngine) error {
+	key, value := e.stack.Pop(), e.stack.Pop()
+	current, err := e.storageGet(key)
+	if err != nil {
+		return err
+	}
+	price := gasSstoreReset
+	if current.Sign() == 0 && value.Sign() != 0 {
+		price = gasSstoreSet
+	}
+	if !e.useGas(price) {
+		return ErrOutOfGas
+	}
+	k := e.storageKey(key)
+	if value.Sign() == 0 {
+		e.storage.Delete(store.ST_Storage, k)
+	} else {
+		e.storage.Add(store.ST_Storage, k, &states.StorageItem{Value: Word(value)})
+	}
+	return nil
+}
+
+func opLog(e *ExecutionEngine) error {
+	n := int(e.opCode - LOG0)
+	offset, size := e.stack.Pop(), e.stack.Pop()
+	topics := make([]common.Uint256, n)
+	for i := 0; i < n; i++ {
+		copy(topics[i][:], Word(e.stack.Pop()))
+	}
+	off, sz, err := e.memoryRange(offset, size)
+	if err != nil {
+		return err
+	}
+	if !e.useGas(gasLogTopic*int64(n) + gasLogByte*int64(sz)) {
+		return ErrOutOfGas
+	}
+	e.logs = append(e.logs, &Log{Address: e.codeHash, Topics: topics, Data: e.memory.Get(off, sz)})
+	return nil
+}
+
+// storageKey maps a storage word of the contract to its ST_Storage key
+func (e *ExecutionEngine) storageKey(key *big.Int) []byte {
+	storageKey := &states.StorageKey{CodeHash: e.codeHash, Key: Word(key)}
+	return storageKey.ToArray()
+}
+
+func (e *ExecutionEngine) storageGet(key *big.Int) (*big.Int, error) {
+	item, err := e.storage.Get(store.ST_Storage, e.storageKey(key))
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return new(big.Int), nil
+	}
+	storageItem, ok := item.(*states.StorageItem)
+	if !ok {
+		return nil, ErrBadStorageValue
+	}
+	return new(big.Int).SetBytes(storageItem.Value), nil
+}
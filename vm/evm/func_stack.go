@@ -0,0 +1,29 @@
+package evm
+
+import "math/big"
+
+func opPush(e *ExecutionEngine) error {
+	n := uint64(e.opCode-PUSH1) + 1
+	buf := make([]byte, n)
+	if e.pc < uint64(len(e.code)) {
+		copy(buf, e.code[e.pc:])
+	}
+	e.pc += n
+	e.stack.Push(new(big.Int).SetBytes(buf))
+	return nil
+}
+
+func opPop(e *ExecutionEngine) error {
+	e.stack.Pop()
+	return nil
+}
+
+func opDup(e *ExecutionEngine) error {
+	e.stack.Dup(int(e.opCode-DUP1) + 1)
+	return nil
+}
+
+func opSwap(e *ExecutionEngine) error {
+	e.stack.Swap(int(e.opCode-SWAP1) + 1)
+	return nil
+}
@@ -0,0 +1,85 @@
+package evm
+
+import (
+	"math/big"
+
+	. "github.com/Ontology/vm/evm/errors"
+)
+
+// prices in EVM gas units, they follow the Ethereum yellow paper
+const (
+	gasZero     int64 = 0
+	gasBase     int64 = 2
+	gasVeryLow  int64 = 3
+	gasLow      int64 = 5
+	gasMid      int64 = 8
+	gasHigh     int64 = 10
+	gasJumpDest int64 = 1
+
+	gasExpByte      int64 = 50
+	gasSha3         int64 = 30
+	gasSha3Word     int64 = 6
+	gasCopyWord     int64 = 3
+	gasMemoryWord   int64 = 3
+	gasQuadCoeffDiv int64 = 512
+	gasSload        int64 = 200
+	gasSstoreSet    int64 = 20000
+	gasSstoreReset  int64 = 5000
+	gasLog          int64 = 375
+	gasLogTopic     int64 = 375
+	gasLogByte      int64 = 8
+)
+
+// GasConsumed returns the gas consumed by the engine so far
+func (e *ExecutionEngine) GasConsumed() int64 {
+	return e.gasConsumed
+}
+
+// GasLimit returns the maximum gas the engine may consume, including GasFree
+func (e *ExecutionEngine) GasLimit() int64 {
+	return e.gas
+}
+
+func (e *ExecutionEngine) useGas(price int64) bool {
+	e.gasConsumed += price * GasRatio
+	return e.gasConsumed <= e.gas
+}
+
+func (e *ExecutionEngine) gasLeft() int64 {
+	if e.gasConsumed >= e.gas {
+		return 0
+	}
+	return (e.gas - e.gasConsumed) / GasRatio
+}
+
+func toWords(size uint64) uint64 {
+	return (size + 31) / 32
+}
+
+func memoryGas(words uint64) int64 {
+	w := int64(words)
+	return gasMemoryWord*w + w*w/gasQuadCoeffDiv
+}
+
+// memoryRange charges for and grows the memory to cover size bytes at offset
+func (e *ExecutionEngine) memoryRange(offset, size *big.Int) (uint64, uint64, error) {
+	if size.Sign() == 0 {
+		return 0, 0, nil
+	}
+	if !offset.IsUint64() || !size.IsUint64() {
+		return 0, 0, ErrMemoryOverflow
+	}
+	off, sz := offset.Uint64(), size.Uint64()
+	end := off + sz
+	if end < off || end > MaxMemorySize {
+		return 0, 0, ErrMemoryOverflow
+	}
+	words := toWords(end)
+	if words*32 > e.memory.Len() {
+		if !e.useGas(memoryGas(words) - memoryGas(e.memory.Len()/32)) {
+			return 0, 0, ErrOutOfGas
+		}
+		e.memory.Resize(words * 32)
+	}
+	return off, sz, nil
+}
@@ -0,0 +1,36 @@
+package evm
+
+// Memory is the byte addressed memory of the engine, it only grows in words
+type Memory struct {
+	store []byte
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Len() uint64 {
+	return uint64(len(m.store))
+}
+
+// Resize grows the memory to size bytes, size is a multiple of 32
+func (m *Memory) Resize(size uint64) {
+	if m.Len() < size {
+		m.store = append(m.store, make([]byte, size-m.Len())...)
+	}
+}
+
+// Set copies value to offset, the memory must have been resized before
+func (m *Memory) Set(offset uint64, value []byte) {
+	copy(m.store[offset:offset+uint64(len(value))], value)
+}
+
+// Get returns a copy of size bytes at offset
+func (m *Memory) Get(offset, size uint64) []byte {
+	if size == 0 {
+		return nil
+	}
+	buf := make([]byte, size)
+	copy(buf, m.store[offset:offset+size])
+	return buf
+}
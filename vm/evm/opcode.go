@@ -0,0 +1,105 @@
+package evm
+
+type OpCode byte
+
+const (
+	// Stop and arithmetic
+	STOP       OpCode = 0x00 // Halts execution.
+	ADD        OpCode = 0x01 // Addition modulo 2^256.
+	MUL        OpCode = 0x02 // Multiplication modulo 2^256.
+	SUB        OpCode = 0x03 // Subtraction modulo 2^256.
+	DIV        OpCode = 0x04 // Unsigned integer division, division by zero gives zero.
+	SDIV       OpCode = 0x05 // Signed integer division, division by zero gives zero.
+	MOD        OpCode = 0x06 // Unsigned modulo, modulo by zero gives zero.
+	SMOD       OpCode = 0x07 // Signed modulo, modulo by zero gives zero.
+	ADDMOD     OpCode = 0x08 // Addition modulo a third operand.
+	MULMOD     OpCode = 0x09 // Multiplication modulo a third operand.
+	EXP        OpCode = 0x0A // Exponentiation modulo 2^256.
+	SIGNEXTEND OpCode = 0x0B // Extends the sign of a smaller two's complement integer.
+
+	// Comparison and bitwise logic
+	LT     OpCode = 0x10 // Unsigned less-than comparison.
+	GT     OpCode = 0x11 // Unsigned greater-than comparison.
+	SLT    OpCode = 0x12 // Signed less-than comparison.
+	SGT    OpCode = 0x13 // Signed greater-than comparison.
+	EQ     OpCode = 0x14 // Equality comparison.
+	ISZERO OpCode = 0x15 // Simple not operator.
+	AND    OpCode = 0x16 // Bitwise AND.
+	OR     OpCode = 0x17 // Bitwise OR.
+	XOR    OpCode = 0x18 // Bitwise XOR.
+	NOT    OpCode = 0x19 // Bitwise NOT.
+	BYTE   OpCode = 0x1A // Retrieves a single byte from a word.
+	SHL    OpCode = 0x1B // Shift left.
+	SHR    OpCode = 0x1C // Logical shift right.
+	SAR    OpCode = 0x1D // Arithmetic shift right.
+
+	SHA3 OpCode = 0x20 // Keccak-256 hash of a memory range.
+
+	// Environmental information
+	ADDRESS        OpCode = 0x30 // Address of the executing contract, its code hash.
+	BALANCE        OpCode = 0x31 // Balance of an account, not supported.
+	ORIGIN         OpCode = 0x32 // Origin address of the execution.
+	CALLER         OpCode = 0x33 // Caller address.
+	CALLVALUE      OpCode = 0x34 // Value sent with the call, always zero.
+	CALLDATALOAD   OpCode = 0x35 // Loads a word of the input data.
+	CALLDATASIZE   OpCode = 0x36 // Size of the input data.
+	CALLDATACOPY   OpCode = 0x37 // Copies the input data to memory.
+	CODESIZE       OpCode = 0x38 // Size of the executing code.
+	CODECOPY       OpCode = 0x39 // Copies the executing code to memory.
+	GASPRICE       OpCode = 0x3A // Gas price, always zero.
+	EXTCODESIZE    OpCode = 0x3B // Size of the code of an account, not supported.
+	EXTCODECOPY    OpCode = 0x3C // Copies the code of an account to memory, not supported.
+	RETURNDATASIZE OpCode = 0x3D // Size of the output of the last call.
+	RETURNDATACOPY OpCode = 0x3E // Copies the output of the last call to memory.
+	EXTCODEHASH    OpCode = 0x3F // Hash of the code of an account, not supported.
+
+	// Block information
+	BLOCKHASH  OpCode = 0x40 // Hash of a recent block, not supported.
+	COINBASE   OpCode = 0x41 // Beneficiary of the block, always zero.
+	TIMESTAMP  OpCode = 0x42 // Timestamp of the block.
+	NUMBER     OpCode = 0x43 // Height of the block.
+	DIFFICULTY OpCode = 0x44 // Difficulty of the block, always zero.
+	GASLIMIT   OpCode = 0x45 // Gas limit of the execution.
+
+	// Stack, memory, storage and flow operations
+	POP      OpCode = 0x50 // Removes the top item from the stack.
+	MLOAD    OpCode = 0x51 // Loads a word from memory.
+	MSTORE   OpCode = 0x52 // Saves a word to memory.
+	MSTORE8  OpCode = 0x53 // Saves a byte to memory.
+	SLOAD    OpCode = 0x54 // Loads a word from storage.
+	SSTORE   OpCode = 0x55 // Saves a word to storage.
+	JUMP     OpCode = 0x56 // Alters the program counter.
+	JUMPI    OpCode = 0x57 // Conditionally alters the program counter.
+	PC       OpCode = 0x58 // Program counter of this instruction.
+	MSIZE    OpCode = 0x59 // Size of the active memory in bytes.
+	GAS      OpCode = 0x5A // Remaining gas.
+	JUMPDEST OpCode = 0x5B // Marks a valid jump destination.
+
+	// Push operations, PUSH1-PUSH32 push the next 1-32 bytes of code
+	PUSH1  OpCode = 0x60
+	PUSH32 OpCode = 0x7F
+
+	// Duplication operations, DUP1-DUP16 duplicate the 1st-16th stack item
+	DUP1  OpCode = 0x80
+	DUP16 OpCode = 0x8F
+
+	// Exchange operations, SWAP1-SWAP16 exchange the top with the 2nd-17th stack item
+	SWAP1  OpCode = 0x90
+	SWAP16 OpCode = 0x9F
+
+	// Logging operations, LOG0-LOG4 append a log record with 0-4 topics
+	LOG0 OpCode = 0xA0
+	LOG4 OpCode = 0xA4
+
+	// System operations
+	CREATE       OpCode = 0xF0 // Creates a new contract, not supported.
+	CALL         OpCode = 0xF1 // Calls another contract, not supported.
+	CALLCODE     OpCode = 0xF2 // Calls another contract with this storage, not supported.
+	RETURN       OpCode = 0xF3 // Halts execution returning output data.
+	DELEGATECALL OpCode = 0xF4 // Calls another contract with this context, not supported.
+	CREATE2      OpCode = 0xF5 // Creates a new contract at a derived address, not supported.
+	STATICCALL   OpCode = 0xFA // Calls another contract without changes, not supported.
+	REVERT       OpCode = 0xFD // Halts execution reverting state changes and returning output data.
+	INVALID      OpCode = 0xFE // Designated invalid instruction.
+	SELFDESTRUCT OpCode = 0xFF // Destroys the contract, not supported.
+)
@@ -0,0 +1,111 @@
+package evm
+
+import "fmt"
+
+type OpExec struct {
+	Opcode OpCode
+	Name   string
+	Exec   func(*ExecutionEngine) error
+	Price  int64
+	Pops   int
+	Pushes int
+}
+
+var (
+	OpExecList = [256]OpExec{
+		STOP:       {Opcode: STOP, Name: "STOP", Exec: opStop, Price: gasZero},
+		ADD:        {Opcode: ADD, Name: "ADD", Exec: opAdd, Price: gasVeryLow, Pops: 2, Pushes: 1},
+		MUL:        {Opcode: MUL, Name: "MUL", Exec: opMul, Price: gasLow, Pops: 2, Pushes: 1},
+		SUB:        {Opcode: SUB, Name: "SUB", Exec: opSub, Price: gasVeryLow, Pops: 2, Pushes: 1},
+		DIV:        {Opcode: DIV, Name: "DIV", Exec: opDiv, Price: gasLow, Pops: 2, Pushes: 1},
+		SDIV:       {Opcode: SDIV, Name: "SDIV", Exec: opSdiv, Price: gasLow, Pops: 2, Pushes: 1},
+		MOD:        {Opcode: MOD, Name: "MOD", Exec: opMod, Price: gasLow, Pops: 2, Pushes: 1},
+		SMOD:       {Opcode: SMOD, Name: "SMOD", Exec: opSmod, Price: gasLow, Pops: 2, Pushes: 1},
+		ADDMOD:     {Opcode: ADDMOD, Name: "ADDMOD", Exec: opAddmod, Price: gasMid, Pops: 3, Pushes: 1},
+		MULMOD:     {Opcode: MULMOD, Name: "MULMOD", Exec: opMulmod, Price: gasMid, Pops: 3, Pushes: 1},
+		EXP:        {Opcode: EXP, Name: "EXP", Exec: opExp, Price: gasHigh, Pops: 2, Pushes: 1},
+		SIGNEXTEND: {Opcode: SIGNEXTEND, Name: "SIGNEXTEND", Exec: opSignExtend, Price: gasLow, Pops: 2, Pushes: 1},
+
+		LT:     {Opcode: LT, Name: "LT", Exec: opLt, Price: gasVeryLow, Pops: 2, Pushes: 1},
+		GT:     {Opcode: GT, Name: "GT", Exec: opGt, Price: gasVeryLow, Pops: 2, Pushes: 1},
+		SLT:    {Opcode: SLT, Name: "SLT", Exec: opSlt, Price: gasVeryLow, Pops: 2, Pushes: 1},
+		SGT:    {Opcode: SGT, Name: "SGT", Exec: opSgt, Price: gasVeryLow, Pops: 2, Pushes: 1},
+		EQ:     {Opcode: EQ, Name: "EQ", Exec: opEq, Price: gasVeryLow, Pops: 2, Pushes: 1},
+		ISZERO: {Opcode: ISZERO, Name: "ISZERO", Exec: opIsZero, Price: gasVeryLow, Pops: 1, Pushes: 1},
+		AND:    {Opcode: AND, Name: "AND", Exec: opAnd, Price: gasVeryLow, Pops: 2, Pushes: 1},
+		OR:     {Opcode: OR, Name: "OR", Exec: opOr, Price: gasVeryLow, Pops: 2, Pushes: 1},
+		XOR:    {Opcode: XOR, Name: "XOR", Exec: opXor, Price: gasVeryLow, Pops: 2, Pushes: 1},
+		NOT:    {Opcode: NOT, Name: "NOT", Exec: opNot, Price: gasVeryLow, Pops: 1, Pushes: 1},
+		BYTE:   {Opcode: BYTE, Name: "BYTE", Exec: opByte, Price: gasVeryLow, Pops: 2, Pushes: 1},
+		SHL:    {Opcode: SHL, Name: "SHL", Exec: opShl, Price: gasVeryLow, Pops: 2, Pushes: 1},
+		SHR:    {Opcode: SHR, Name: "SHR", Exec: opShr, Price: gasVeryLow, Pops: 2, Pushes: 1},
+		SAR:    {Opcode: SAR, Name: "SAR", Exec: opSar, Price: gasVeryLow, Pops: 2, Pushes: 1},
+
+		SHA3: {Opcode: SHA3, Name: "SHA3", Exec: opSha3, Price: gasSha3, Pops: 2, Pushes: 1},
+
+		ADDRESS:        {Opcode: ADDRESS, Name: "ADDRESS", Exec: opAddress, Price: gasBase, Pushes: 1},
+		BALANCE:        {Opcode: BALANCE, Name: "BALANCE", Exec: opNotSupport},
+		ORIGIN:         {Opcode: ORIGIN, Name: "ORIGIN", Exec: opCaller, Price: gasBase, Pushes: 1},
+		CALLER:         {Opcode: CALLER, Name: "CALLER", Exec: opCaller, Price: gasBase, Pushes: 1},
+		CALLVALUE:      {Opcode: CALLVALUE, Name: "CALLVALUE", Exec: opPushZero, Price: gasBase, Pushes: 1},
+		CALLDATALOAD:   {Opcode: CALLDATALOAD, Name: "CALLDATALOAD", Exec: opCallDataLoad, Price: gasVeryLow, Pops: 1, Pushes: 1},
+		CALLDATASIZE:   {Opcode: CALLDATASIZE, Name: "CALLDATASIZE", Exec: opCallDataSize, Price: gasBase, Pushes: 1},
+		CALLDATACOPY:   {Opcode: CALLDATACOPY, Name: "CALLDATACOPY", Exec: opCallDataCopy, Price: gasVeryLow, Pops: 3},
+		CODESIZE:       {Opcode: CODESIZE, Name: "CODESIZE", Exec: opCodeSize, Price: gasBase, Pushes: 1},
+		CODECOPY:       {Opcode: CODECOPY, Name: "CODECOPY", Exec: opCodeCopy, Price: gasVeryLow, Pops: 3},
+		GASPRICE:       {Opcode: GASPRICE, Name: "GASPRICE", Exec: opPushZero, Price: gasBase, Pushes: 1},
+		EXTCODESIZE:    {Opcode: EXTCODESIZE, Name: "EXTCODESIZE", Exec: opNotSupport},
+		EXTCODECOPY:    {Opcode: EXTCODECOPY, Name: "EXTCODECOPY", Exec: opNotSupport},
+		RETURNDATASIZE: {Opcode: RETURNDATASIZE, Name: "RETURNDATASIZE", Exec: opReturnDataSize, Price: gasBase, Pushes: 1},
+		RETURNDATACOPY: {Opcode: RETURNDATACOPY, Name: "RETURNDATACOPY", Exec: opReturnDataCopy, Price: gasVeryLow, Pops: 3},
+		EXTCODEHASH:    {Opcode: EXTCODEHASH, Name: "EXTCODEHASH", Exec: opNotSupport},
+
+		BLOCKHASH:  {Opcode: BLOCKHASH, Name: "BLOCKHASH", Exec: opNotSupport},
+		COINBASE:   {Opcode: COINBASE, Name: "COINBASE", Exec: opPushZero, Price: gasBase, Pushes: 1},
+		TIMESTAMP:  {Opcode: TIMESTAMP, Name: "TIMESTAMP", Exec: opTimestamp, Price: gasBase, Pushes: 1},
+		NUMBER:     {Opcode: NUMBER, Name: "NUMBER", Exec: opNumber, Price: gasBase, Pushes: 1},
+		DIFFICULTY: {Opcode: DIFFICULTY, Name: "DIFFICULTY", Exec: opPushZero, Price: gasBase, Pushes: 1},
+		GASLIMIT:   {Opcode: GASLIMIT, Name: "GASLIMIT", Exec: opGasLimit, Price: gasBase, Pushes: 1},
+
+		POP:      {Opcode: POP, Name: "POP", Exec: opPop, Price: gasBase, Pops: 1},
+		MLOAD:    {Opcode: MLOAD, Name: "MLOAD", Exec: opMload, Price: gasVeryLow, Pops: 1, Pushes: 1},
+		MSTORE:   {Opcode: MSTORE, Name: "MSTORE", Exec: opMstore, Price: gasVeryLow, Pops: 2},
+		MSTORE8:  {Opcode: MSTORE8, Name: "MSTORE8", Exec: opMstore8, Price: gasVeryLow, Pops: 2},
+		SLOAD:    {Opcode: SLOAD, Name: "SLOAD", Exec: opSload, Price: gasSload, Pops: 1, Pushes: 1},
+		SSTORE:   {Opcode: SSTORE, Name: "SSTORE", Exec: opSstore, Price: gasZero, Pops: 2},
+		JUMP:     {Opcode: JUMP, Name: "JUMP", Exec: opJump, Price: gasMid, Pops: 1},
+		JUMPI:    {Opcode: JUMPI, Name: "JUMPI", Exec: opJumpi, Price: gasHigh, Pops: 2},
+		PC:       {Opcode: PC, Name: "PC", Exec: opPc, Price: gasBase, Pushes: 1},
+		MSIZE:    {Opcode: MSIZE, Name: "MSIZE", Exec: opMsize, Price: gasBase, Pushes: 1},
+		GAS:      {Opcode: GAS, Name: "GAS", Exec: opGas, Price: gasBase, Pushes: 1},
+		JUMPDEST: {Opcode: JUMPDEST, Name: "JUMPDEST", Exec: opJumpDest, Price: gasJumpDest},
+
+		CREATE:       {Opcode: CREATE, Name: "CREATE", Exec: opNotSupport},
+		CALL:         {Opcode: CALL, Name: "CALL", Exec: opNotSupport},
+		CALLCODE:     {Opcode: CALLCODE, Name: "CALLCODE", Exec: opNotSupport},
+		RETURN:       {Opcode: RETURN, Name: "RETURN", Exec: opReturn, Price: gasZero, Pops: 2},
+		DELEGATECALL: {Opcode: DELEGATECALL, Name: "DELEGATECALL", Exec: opNotSupport},
+		CREATE2:      {Opcode: CREATE2, Name: "CREATE2", Exec: opNotSupport},
+		STATICCALL:   {Opcode: STATICCALL, Name: "STATICCALL", Exec: opNotSupport},
+		REVERT:       {Opcode: REVERT, Name: "REVERT", Exec: opRevert, Price: gasZero, Pops: 2},
+		SELFDESTRUCT: {Opcode: SELFDESTRUCT, Name: "SELFDESTRUCT", Exec: opNotSupport},
+	}
+)
+
+func init() {
+	for op := PUSH1; op <= PUSH32; op++ {
+		OpExecList[op] = OpExec{Opcode: op, Name: fmt.Sprintf("PUSH%d", op-PUSH1+1), Exec: opPush, Price: gasVeryLow, Pushes: 1}
+	}
+	for op := DUP1; op <= DUP16; op++ {
+		n := int(op-DUP1) + 1
+		OpExecList[op] = OpExec{Opcode: op, Name: fmt.Sprintf("DUP%d", n), Exec: opDup, Price: gasVeryLow, Pops: n, Pushes: n + 1}
+	}
+	for op := SWAP1; op <= SWAP16; op++ {
+		n := int(op-SWAP1) + 1
+		OpExecList[op] = OpExec{Opcode: op, Name: fmt.Sprintf("SWAP%d", n), Exec: opSwap, Price: gasVeryLow, Pops: n + 1, Pushes: n + 1}
+	}
+	for op := LOG0; op <= LOG4; op++ {
+		n := int(op - LOG0)
+		OpExecList[op] = OpExec{Opcode: op, Name: fmt.Sprintf("LOG%d", n), Exec: opLog, Price: gasLog, Pops: n + 2}
+	}
+}
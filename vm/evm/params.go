@@ -0,0 +1,12 @@
+package evm
+
+const (
+	StackLimit           = 1024
+	MaxCodeSize          = 24576
+	MaxMemorySize uint64 = 32 * 1024 * 1024
+
+	// GasRatio converts EVM gas units to Fixed64 gas amounts, one unit costs 0.00001 gas
+	GasRatio int64 = 1000
+	// GasFree is the amount of gas every invocation may consume without paying for it, it is the same as the NEOVM one
+	GasFree int64 = 10 * 100000000
+)
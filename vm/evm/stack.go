@@ -0,0 +1,46 @@
+package evm
+
+import "math/big"
+
+// Stack is the word stack of the engine, all items are in [0, 2^256)
+type Stack struct {
+	data []*big.Int
+}
+
+func NewStack() *Stack {
+	return &Stack{data: make([]*big.Int, 0, 16)}
+}
+
+func (s *Stack) Len() int {
+	return len(s.data)
+}
+
+func (s *Stack) Push(x *big.Int) {
+	s.data = append(s.data, x)
+}
+
+func (s *Stack) Pop() *big.Int {
+	x := s.data[len(s.data)-1]
+	s.data = s.data[:len(s.data)-1]
+	return x
+}
+
+func (s *Stack) Peek() *big.Int {
+	return s.data[len(s.data)-1]
+}
+
+// Back returns the n-th item from the top, the top item is Back(0)
+func (s *Stack) Back(n int) *big.Int {
+	return s.data[len(s.data)-n-1]
+}
+
+// Dup pushes a copy of the n-th item, the top item is Dup(1)
+func (s *Stack) Dup(n int) {
+	s.Push(new(big.Int).Set(s.data[len(s.data)-n]))
+}
+
+// Swap exchanges the top item with the (n+1)-th one
+func (s *Stack) Swap(n int) {
+	top := len(s.data) - 1
+	s.data[top], s.data[top-n] = s.data[top-n], s.data[top]
+}
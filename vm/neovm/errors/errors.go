@@ -26,4 +26,6 @@ var (
 	ErrCallingContextNil     = errors.New("calling context is nil")
 	ErrEntryContextNil       = errors.New("entry context is nil")
 	ErrAppendNotArray        = errors.New("append not array")
+	ErrOverCallDepth         = errors.New("the call depth over max size")
+	ErrContractNotFound      = errors.New("contract not found")
 )
@@ -14,6 +14,9 @@ type ExecutionContext struct {
 	BreakPoints        []uint
 	InstructionPointer int
 	CodeHash           common.Uint160
+	// CallingCodeHash is the code hash of the script which invoked this one
+	CallingCodeHash    common.Uint160
+	contractCall       bool
 	engine             *ExecutionEngine
 }
 
@@ -44,6 +47,11 @@ func (ec *ExecutionContext) GetCodeHash() (common.Uint160, error) {
 	return ec.CodeHash, nil
 }
 
+// IsContractCall reports whether the context was invoked by another contract
+func (ec *ExecutionContext) IsContractCall() bool {
+	return ec.contractCall
+}
+
 func (ec *ExecutionContext) NextInstruction() OpCode {
 	return OpCode(ec.Code[ec.OpReader.Position()])
 }
@@ -51,6 +59,9 @@ func (ec *ExecutionContext) NextInstruction() OpCode {
 func (ec *ExecutionContext) Clone() *ExecutionContext {
 	executionContext := NewExecutionContext(ec.engine, ec.Code, ec.PushOnly, ec.BreakPoints)
 	executionContext.InstructionPointer = ec.InstructionPointer
+	executionContext.CodeHash = ec.CodeHash
+	executionContext.CallingCodeHash = ec.CallingCodeHash
+	executionContext.contractCall = ec.contractCall
 	executionContext.SetInstructionPointer(int64(ec.GetInstructionPointer()))
 	return executionContext
 }
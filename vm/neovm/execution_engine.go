@@ -26,7 +26,7 @@ import (
 	"github.com/Ontology/common/log"
 )
 
-func NewExecutionEngine(container interfaces.ICodeContainer, crypto interfaces.ICrypto, table interfaces.ICodeTable, service IInteropService) *ExecutionEngine {
+func NewExecutionEngine(container interfaces.ICodeContainer, crypto interfaces.ICrypto, table interfaces.ICodeTable, service IInteropService, gas common.Fixed64) *ExecutionEngine {
 	var engine ExecutionEngine
 
 	engine.crypto = crypto
@@ -43,6 +43,10 @@ func NewExecutionEngine(container interfaces.ICodeContainer, crypto interfaces.I
 	engine.context = nil
 	engine.opCode = 0
 
+	engine.gas = GasFree + gas.GetData()
+	engine.gasConsumed = 0
+	engine.maxCallDepth = DefaultMaxCallDepth
+
 	engine.service = NewInteropService()
 
 	if service != nil {
@@ -69,6 +73,10 @@ type ExecutionEngine struct {
 	//current opcode
 	opCode          OpCode
 	gas             int64
+	gasConsumed     int64
+
+	caller          common.Uint160
+	maxCallDepth    int
 }
 
 func (e *ExecutionEngine) Create(caller common.Uint160, code []byte) ([]byte, error) {
@@ -76,7 +84,11 @@ func (e *ExecutionEngine) Create(caller common.Uint160, code []byte) ([]byte, er
 }
 
 func (e *ExecutionEngine) Call(caller common.Uint160, code, input []byte) ([]byte, error) {
+	e.caller = caller
 	e.LoadCode(code, false)
+	if len(input) > 0 {
+		e.invocationStack.Peek(0).GetExecutionContext().CallingCodeHash = common.ToCodeHash(input)
+	}
 	e.LoadCode(input, false)
 	err := e.Execute()
 	if err != nil {
@@ -85,6 +97,27 @@ func (e *ExecutionEngine) Call(caller common.Uint160, code, input []byte) ([]byt
 	return nil, nil
 }
 
+// GetCaller returns the account which invoked the engine
+func (e *ExecutionEngine) GetCaller() common.Uint160 {
+	return e.caller
+}
+
+// SetMaxCallDepth limits the number of nested contract invocations
+func (e *ExecutionEngine) SetMaxCallDepth(depth int) {
+	e.maxCallDepth = depth
+}
+
+// CallDepth returns the number of nested contract invocations
+func (e *ExecutionEngine) CallDepth() int {
+	depth := 0
+	for i := 0; i < e.invocationStack.Count(); i++ {
+		if e.invocationStack.Peek(i).GetExecutionContext().IsContractCall() {
+			depth++
+		}
+	}
+	return depth
+}
+
 func (e *ExecutionEngine) GetCodeContainer() interfaces.ICodeContainer {
 	return e.codeContainer
 }
@@ -148,6 +181,46 @@ func (e *ExecutionEngine) LoadCode(script []byte, pushOnly bool) {
 	e.invocationStack.Push(NewExecutionContext(e, script, pushOnly, nil))
 }
 
+// callContract loads the code of the contract codeHash in a new context called
+// by the executing contract, a tail call replaces the executing context
+func (e *ExecutionEngine) callContract(codeHash []byte, tail bool) error {
+	hash, err := common.Uint160ParseFromBytes(codeHash)
+	if err != nil {
+		return ErrBadValue
+	}
+	depth := e.CallDepth()
+	if tail && e.context.IsContractCall() {
+		depth--
+	}
+	if depth >= e.maxCallDepth {
+		return ErrOverCallDepth
+	}
+	code, err := e.table.GetCode(codeHash)
+	if err != nil {
+		return err
+	}
+	if code == nil {
+		return ErrContractNotFound
+	}
+
+	context := NewExecutionContext(e, code, false, nil)
+	context.CodeHash = hash
+	if tail {
+		context.CallingCodeHash = e.context.CallingCodeHash
+		context.contractCall = e.context.IsContractCall()
+		e.invocationStack.Pop()
+	} else {
+		calling, err := e.context.GetCodeHash()
+		if err != nil {
+			return err
+		}
+		context.CallingCodeHash = calling
+		context.contractCall = true
+	}
+	e.invocationStack.Push(context)
+	return nil
+}
+
 func (e *ExecutionEngine) Execute() error {
 	e.state = e.state & (^BREAK)
 	for {
@@ -188,6 +261,10 @@ func (e *ExecutionEngine) StepInto() error {
 	if !e.checkStackSize() {
 		return ErrOverLimitStack
 	}
+	if !e.checkGas() {
+		e.state = FAULT
+		return ErrOutOfGas
+	}
 	state, err := e.ExecuteOp()
 
 	if state == HALT || state == FAULT {
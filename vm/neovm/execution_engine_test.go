@@ -1,10 +1,12 @@
 package neovm
 
 import (
+	"bytes"
 	"testing"
 	"github.com/Ontology/common/log"
 	"github.com/Ontology/common"
 	"fmt"
+	. "github.com/Ontology/vm/neovm/errors"
 )
 
 func init()  {
@@ -19,7 +21,7 @@ const(
 	)
 
 func TestNewExecutionEngine(t *testing.T) {
-	engine := NewExecutionEngine(nil,nil,nil,nil)
+	engine := NewExecutionEngine(nil,nil,nil,nil,0)
 
 	if engine == nil{
 		t.Error("TestNewExecutionEngine failed")
@@ -30,7 +32,7 @@ func TestNewExecutionEngine(t *testing.T) {
 
 func TestExecutionEngine_Call(t *testing.T) {
 	caller := common.Uint160{}
-	engine := NewExecutionEngine(nil,nil,nil,nil)
+	engine := NewExecutionEngine(nil,nil,nil,nil,0)
 	//engine.Call(caller,[]byte(CODE),nil)
 
 	_, err := engine.Call(caller,[]byte(CODE),nil)
@@ -50,7 +52,7 @@ func TestExecutionEngine_Call(t *testing.T) {
 
 func TestExecutionEngine_CurrentContext(t *testing.T) {
 	//caller := common.Uint160{}
-	engine := NewExecutionEngine(nil,nil,nil,nil)
+	engine := NewExecutionEngine(nil,nil,nil,nil,0)
 	_ ,err:= engine.CurrentContext()
 	if err == nil{
 		t.Error("TestExecutionEngine_CurrentContext failed:should return an error")
@@ -65,7 +67,7 @@ func TestExecutionEngine_CurrentContext(t *testing.T) {
 
 func TestExecutionEngine_AddBreakPoint(t *testing.T) {
 	caller := common.Uint160{}
-	engine := NewExecutionEngine(nil,nil,nil,nil)
+	engine := NewExecutionEngine(nil,nil,nil,nil,0)
 
 
 
@@ -79,3 +81,131 @@ func TestExecutionEngine_AddBreakPoint(t *testing.T) {
 	fmt.Println(ctx.GetInstructionPointer())*/
 	engine.StepOver()
 }
+
+func TestExecutionEngine_OutOfGas(t *testing.T) {
+	caller := common.Uint160{}
+	// JMP 0: loops forever
+	loop := []byte{byte(JMP), 0x00, 0x00}
+	engine := NewExecutionEngine(nil,nil,nil,nil,0)
+	_, err := engine.Call(caller,loop,nil)
+	if err != ErrOutOfGas {
+		t.Error("TestExecutionEngine_OutOfGas failed: expect out of gas, got", err)
+	}
+	if engine.GetState() != FAULT {
+		t.Error("TestExecutionEngine_OutOfGas failed: state should be FAULT")
+	}
+	if engine.GasConsumed() <= engine.GasLimit() {
+		t.Error("TestExecutionEngine_OutOfGas failed: consumed gas should exceed limit")
+	}
+
+	engine = NewExecutionEngine(nil,nil,nil,nil,common.Fixed64(GasFree))
+	_, err = engine.Call(caller,loop,nil)
+	if err != ErrOutOfGas {
+		t.Error("TestExecutionEngine_OutOfGas failed: expect out of gas, got", err)
+	}
+	if engine.GasLimit() != 2*GasFree {
+		t.Error("TestExecutionEngine_OutOfGas failed: gas limit should include free gas")
+	}
+
+	// the price of a key count taken from the stack must not overflow
+	multiSig := []byte{byte(PUSHBYTES1) + 7, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f, byte(CHECKMULTISIG)}
+	engine = NewExecutionEngine(nil,nil,nil,nil,0)
+	_, err = engine.Call(caller,multiSig,nil)
+	if err != ErrOutOfGas {
+		t.Error("TestExecutionEngine_OutOfGas failed: expect out of gas for a huge key count, got", err)
+	}
+	if engine.GasConsumed() <= engine.GasLimit() {
+		t.Error("TestExecutionEngine_OutOfGas failed: consumed gas overflowed", engine.GasConsumed())
+	}
+}
+
+type testCodeTable map[common.Uint160][]byte
+
+func (t testCodeTable) GetCode(codeHash []byte) ([]byte, error) {
+	hash, err := common.Uint160ParseFromBytes(codeHash)
+	if err != nil {
+		return nil, err
+	}
+	if code, ok := t[hash]; ok {
+		return code, nil
+	}
+	return t[common.Uint160{}], nil
+}
+
+func sysCall(api string) []byte {
+	return append([]byte{byte(SYSCALL), byte(len(api))}, api...)
+}
+
+func TestExecutionEngine_ContractCall(t *testing.T) {
+	callee := append(sysCall("System.ExecutionEngine.GetCallingScriptHash"), byte(RET))
+	calleeHash := common.ToCodeHash(callee)
+	code := append([]byte{byte(PUSHBYTES1) + 19}, calleeHash.ToArray()...)
+	code = append(append(code, sysCall("System.Contract.Call")...), byte(RET))
+
+	engine := NewExecutionEngine(nil, nil, testCodeTable{calleeHash: callee}, nil, 0)
+	if _, err := engine.Call(common.Uint160{}, code, nil); err != nil {
+		t.Fatal("TestExecutionEngine_ContractCall failed:", err)
+	}
+	codeHash := common.ToCodeHash(code)
+	if calling := PopByteArray(engine); !bytes.Equal(calling, codeHash.ToArray()) {
+		t.Errorf("TestExecutionEngine_ContractCall failed: calling hash %x, expect %x", calling, codeHash.ToArray())
+	}
+
+	// a contract calling itself for ever
+	recursive := append([]byte{byte(PUSHBYTES1) + 19}, make([]byte, 20)...)
+	recursive = append(recursive, sysCall("System.Contract.Call")...)
+	engine = NewExecutionEngine(nil, nil, testCodeTable{common.Uint160{}: recursive}, nil, 0)
+	engine.SetMaxCallDepth(4)
+	if _, err := engine.Call(common.Uint160{}, recursive, nil); err != ErrOverCallDepth {
+		t.Error("TestExecutionEngine_ContractCall failed: expect over call depth, got", err)
+	}
+	if engine.CallDepth() != 4 {
+		t.Error("TestExecutionEngine_ContractCall failed: call depth", engine.CallDepth())
+	}
+}
+
+func TestExecutionEngine_ContractCallSubroutine(t *testing.T) {
+	service := NewInteropService()
+	service.Register("Test.IsContractCall", func(e *ExecutionEngine) (bool, error) {
+		context, err := e.CurrentContext()
+		if err != nil {
+			return false, err
+		}
+		PushData(e, context.IsContractCall())
+		return true, nil
+	})
+	// the callee asks from a subroutine whether it was called by a contract
+	callee := []byte{byte(CALL), 4, 0, byte(RET)}
+	callee = append(append(callee, sysCall("Test.IsContractCall")...), byte(RET))
+	calleeHash := common.ToCodeHash(callee)
+	code := append([]byte{byte(PUSHBYTES1) + 19}, calleeHash.ToArray()...)
+	code = append(append(code, sysCall("System.Contract.Call")...), byte(RET))
+
+	engine := NewExecutionEngine(nil, nil, testCodeTable{calleeHash: callee}, service, 0)
+	if _, err := engine.Call(common.Uint160{}, code, nil); err != nil {
+		t.Fatal("TestExecutionEngine_ContractCallSubroutine failed:", err)
+	}
+	if !PopBoolean(engine) {
+		t.Error("TestExecutionEngine_ContractCallSubroutine failed: subroutine of a called contract not called by a contract")
+	}
+}
+
+func TestExecutionEngine_ContractCallPrice(t *testing.T) {
+	callee := []byte{byte(RET)}
+	calleeHash := common.ToCodeHash(callee)
+	static := append(append([]byte{byte(APPCALL)}, calleeHash.ToArray()...), byte(RET))
+	dynamic := append([]byte{byte(PUSHBYTES1) + 19}, calleeHash.ToArray()...)
+	dynamic = append(append(dynamic, sysCall("System.Contract.Call")...), byte(RET))
+
+	var consumed []int64
+	for _, code := range [][]byte{static, dynamic} {
+		engine := NewExecutionEngine(nil, nil, testCodeTable{calleeHash: callee}, nil, 0)
+		if _, err := engine.Call(common.Uint160{}, code, nil); err != nil {
+			t.Fatal("TestExecutionEngine_ContractCallPrice failed:", err)
+		}
+		consumed = append(consumed, engine.GasConsumed())
+	}
+	if consumed[1] < consumed[0] {
+		t.Errorf("TestExecutionEngine_ContractCallPrice failed: dynamic call costs %d, static call %d", consumed[1], consumed[0])
+	}
+}
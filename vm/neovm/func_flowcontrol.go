@@ -62,15 +62,9 @@ func opAppCall(e *ExecutionEngine) (VMState, error) {
 		codeHash = PopByteArray(e)
 	}
 
-	code, err := e.table.GetCode(codeHash)
-	if code == nil {
+	if err := e.callContract(codeHash, e.opCode == TAILCALL); err != nil {
 		return FAULT, err
 	}
-
-	if e.opCode == TAILCALL {
-		e.invocationStack.Pop()
-	}
-	e.LoadCode(code, false)
 	return NONE, nil
 }
 
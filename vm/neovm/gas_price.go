@@ -0,0 +1,116 @@
+package neovm
+
+import (
+	"io"
+	"math"
+)
+
+var (
+	// OpCodePrices is the price of an opcode in units of GasRatio, opcodes not listed cost one unit
+	OpCodePrices = map[OpCode]int64{
+		NOP:      0,
+		APPCALL:  10,
+		TAILCALL: 10,
+		SHA1:     10,
+		SHA256:   10,
+		HASH160:  20,
+		HASH256:  20,
+		CHECKSIG: 100,
+	}
+
+	// ServicePrices is the price of a SYSCALL in units of GasRatio, services not listed cost one unit.
+	// A dynamic System.Contract.Call costs as much as an APPCALL.
+	ServicePrices = map[string]int64{
+		"Neo.Runtime.CheckWitness":      200,
+		"Neo.Blockchain.GetHeader":      100,
+		"Neo.Blockchain.GetBlock":       200,
+		"Neo.Blockchain.GetTransaction": 100,
+		"Neo.Blockchain.GetAccount":     100,
+		"Neo.Blockchain.GetAsset":       100,
+		"Neo.Blockchain.GetContract":    100,
+		"Neo.Transaction.GetReferences": 200,
+		"Neo.Account.SetVotes":          1000,
+		"Neo.Validator.Register":        1000 * 1000,
+		"Neo.Asset.Create":              5000 * 1000,
+		"Neo.Asset.Renew":               5000 * 1000,
+		"Neo.Contract.Create":           500 * 1000,
+		"Neo.Contract.Migrate":          500 * 1000,
+		"Neo.Storage.Get":               100,
+		"Neo.Storage.Delete":            100,
+	}
+)
+
+const (
+	checkMultiSigPrice  int64 = 100
+	storagePutPricePerK int64 = 1000
+)
+
+// GasConsumed returns the gas consumed by the engine so far
+func (e *ExecutionEngine) GasConsumed() int64 {
+	return e.gasConsumed
+}
+
+// GasLimit returns the maximum gas the engine may consume, including GasFree
+func (e *ExecutionEngine) GasLimit() int64 {
+	return e.gas
+}
+
+func (e *ExecutionEngine) checkGas() bool {
+	price := e.getPrice()
+	if price > (math.MaxInt64-e.gasConsumed)/GasRatio {
+		e.gasConsumed = math.MaxInt64
+		return false
+	}
+	e.gasConsumed += price * GasRatio
+	return e.gasConsumed <= e.gas
+}
+
+func (e *ExecutionEngine) getPrice() int64 {
+	if e.opCode <= PUSH16 {
+		return 0
+	}
+	switch e.opCode {
+	case SYSCALL:
+		return e.getPriceForSysCall()
+	case CHECKMULTISIG:
+		if EvaluationStackCount(e) == 0 {
+			return 1
+		}
+		n := PeekInt(e)
+		if n < 1 {
+			return 1
+		}
+		// the count is taken from the stack, more keys than an array
+		// holds are charged as a full array
+		if n > int(MaxArraySize) {
+			n = int(MaxArraySize)
+		}
+		return checkMultiSigPrice * int64(n)
+	}
+	if price, ok := OpCodePrices[e.opCode]; ok {
+		return price
+	}
+	return 1
+}
+
+func (e *ExecutionEngine) getPriceForSysCall() int64 {
+	reader := e.context.OpReader
+	position := reader.Position()
+	defer reader.Seek(int64(position), io.SeekStart)
+
+	name := reader.ReadVarString()
+	switch name {
+	case "System.Contract.Call":
+		return OpCodePrices[APPCALL]
+	case "Neo.Storage.Put":
+		if EvaluationStackCount(e) < 3 {
+			return 1
+		}
+		size := int64(len(PeekNByteArray(1, e)) + len(PeekNByteArray(2, e)))
+		return ((size-1)/1024 + 1) * storagePutPricePerK
+	}
+	if price, ok := ServicePrices[name]; ok {
+		return price
+	}
+	return 1
+}
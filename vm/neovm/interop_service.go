@@ -1,6 +1,7 @@
 package neovm
 
 import (
+	"github.com/Ontology/common"
 	. "github.com/Ontology/vm/neovm/errors"
 	"github.com/Ontology/common/log"
 )
@@ -21,6 +22,7 @@ func NewInteropService() *InteropService {
 	i.Register("System.ExecutionEngine.GetExecutingScriptHash", i.GetExecutingCodeHash)
 	i.Register("System.ExecutionEngine.GetCallingScriptHash", i.GetCallingCodeHash)
 	i.Register("System.ExecutionEngine.GetEntryScriptHash", i.GetEntryCodeHash)
+	i.Register("System.Contract.Call", i.ContractCall)
 	return &i
 }
 
@@ -71,15 +73,14 @@ func (i *InteropService) GetExecutingCodeHash(engine *ExecutionEngine) (bool, er
 }
 
 func (i *InteropService) GetCallingCodeHash(engine *ExecutionEngine) (bool, error) {
-	context, err := engine.CallingContext()
+	context, err := engine.CurrentContext()
 	if err != nil {
 		return false, err
 	}
-	codeHash, err := context.GetCodeHash()
-	if err != nil {
-		return false, err
+	if context.CallingCodeHash.CompareTo(common.Uint160{}) == 0 {
+		return false, ErrCallingContextNil
 	}
-	PushData(engine, codeHash.ToArray())
+	PushData(engine, context.CallingCodeHash.ToArray())
 	return true, nil
 }
 func (i *InteropService) GetEntryCodeHash(engine *ExecutionEngine) (bool, error) {
@@ -94,3 +95,18 @@ func (i *InteropService) GetEntryCodeHash(engine *ExecutionEngine) (bool, error)
 	PushData(engine, codeHash.ToArray())
 	return true, nil
 }
+
+
+// ContractCall invokes the contract whose code hash is on top of the stack
+func (i *InteropService) ContractCall(engine *ExecutionEngine) (bool, error) {
+	if EvaluationStackCount(engine) < 1 {
+		return false, ErrUnderStackLen
+	}
+	if err := validateAppCall(engine); err != nil {
+		return false, err
+	}
+	if err := engine.callContract(PopByteArray(engine), false); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -6,5 +6,11 @@ const (
 	MaxSizeForBigInteger = 32
 	MaxItemSize uint32 = 1024 * 1024
 	MaxArraySize uint32 = 1024
-)
+	// DefaultMaxCallDepth is the default number of nested contract invocations
+	DefaultMaxCallDepth = 32
 
+	// GasRatio converts price units to Fixed64 gas amounts, one unit costs 0.001 gas
+	GasRatio int64 = 100000
+	// GasFree is the amount of gas every invocation may consume without paying for it
+	GasFree int64 = 10 * 100000000
+)